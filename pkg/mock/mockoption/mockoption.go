@@ -17,7 +17,9 @@ import (
 	"time"
 
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/pkg/typeutil"
 	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule/placement"
 )
 
 const (
@@ -143,6 +145,151 @@ func (mso *ScheduleOptions) GetHotRegionScheduleLimit(name string) uint64 {
 	return mso.HotRegionScheduleLimit
 }
 
+// GetStoreLeaderWeight mocks method
+func (mso *ScheduleOptions) GetStoreLeaderWeight(name string, storeID uint64) (float64, bool) {
+	return 0, false
+}
+
+// GetNamespaceMaxPendingPeerCount mocks method
+func (mso *ScheduleOptions) GetNamespaceMaxPendingPeerCount(name string) uint64 {
+	return 0
+}
+
+// GetSamplingStrategy mocks method
+func (mso *ScheduleOptions) GetSamplingStrategy(name string) core.SamplingStrategy {
+	return core.UniformSampling
+}
+
+// GetSchedulePauseWindows mocks method
+func (mso *ScheduleOptions) GetSchedulePauseWindows(name string) []typeutil.TimeWindow {
+	return nil
+}
+
+// GetMaxConcurrentOperators mocks method
+func (mso *ScheduleOptions) GetMaxConcurrentOperators(name string) uint64 {
+	return 0
+}
+
+// GetSpareStores mocks method
+func (mso *ScheduleOptions) GetSpareStores(name string) []uint64 {
+	return nil
+}
+
+// GetSnapshotSpeed mocks method
+func (mso *ScheduleOptions) GetSnapshotSpeed(name string) float64 {
+	return 0
+}
+
+// GetThermalSizeWeight mocks method
+func (mso *ScheduleOptions) GetThermalSizeWeight(name string) float64 {
+	return 0
+}
+
+// GetThermalFlowWeight mocks method
+func (mso *ScheduleOptions) GetThermalFlowWeight(name string) float64 {
+	return 0
+}
+
+// GetRegionLabels mocks method
+func (mso *ScheduleOptions) GetRegionLabels(name string) []*placement.RegionLabel {
+	return nil
+}
+
+// GetStoreReservedCapacity mocks method
+func (mso *ScheduleOptions) GetStoreReservedCapacity(name string, storeID uint64) uint64 {
+	return 0
+}
+
+// GetAntiAffinityGroups mocks method
+func (mso *ScheduleOptions) GetAntiAffinityGroups(name string) [][]uint64 {
+	return nil
+}
+
+// GetStoreMaintenanceWindows mocks method
+func (mso *ScheduleOptions) GetStoreMaintenanceWindows(name string, storeID uint64) []typeutil.TimeWindow {
+	return nil
+}
+
+// GetMaxMigrationBytesPerSec mocks method
+func (mso *ScheduleOptions) GetMaxMigrationBytesPerSec(name string) uint64 {
+	return 0
+}
+
+// GetReadReplicaCount mocks method
+func (mso *ScheduleOptions) GetReadReplicaCount(name string) uint64 {
+	return 0
+}
+
+// GetReadReplicaStores mocks method
+func (mso *ScheduleOptions) GetReadReplicaStores(name string) []uint64 {
+	return nil
+}
+
+// IsAdaptiveScheduleLimitEnabled mocks method
+func (mso *ScheduleOptions) IsAdaptiveScheduleLimitEnabled(name string) bool {
+	return false
+}
+
+// GetMinRegionScheduleLimit mocks method
+func (mso *ScheduleOptions) GetMinRegionScheduleLimit(name string) uint64 {
+	return 0
+}
+
+// GetMaxRegionScheduleLimit mocks method
+func (mso *ScheduleOptions) GetMaxRegionScheduleLimit(name string) uint64 {
+	return 0
+}
+
+// GetJoinThrottleTicks mocks method
+func (mso *ScheduleOptions) GetJoinThrottleTicks(name string) uint64 {
+	return 0
+}
+
+// GetMaxRegionsPerStore mocks method
+func (mso *ScheduleOptions) GetMaxRegionsPerStore(name string) uint64 {
+	return 0
+}
+
+// GetRegionMaxSize mocks method
+func (mso *ScheduleOptions) GetRegionMaxSize(name string) uint64 {
+	return 0
+}
+
+// GetRegionMaxKeys mocks method
+func (mso *ScheduleOptions) GetRegionMaxKeys(name string) uint64 {
+	return 0
+}
+
+// GetZoneLabel mocks method
+func (mso *ScheduleOptions) GetZoneLabel(name string) string {
+	return ""
+}
+
+// GetPreferredLeaderZone mocks method
+func (mso *ScheduleOptions) GetPreferredLeaderZone(name string) string {
+	return ""
+}
+
+// GetMergeDirectionPreference mocks method
+func (mso *ScheduleOptions) GetMergeDirectionPreference(name string) string {
+	return ""
+}
+
+// IsStoreWeightAutoTuningEnabled mocks method
+func (mso *ScheduleOptions) IsStoreWeightAutoTuningEnabled(name string) bool {
+	return false
+}
+
+// GetPlacementRules mocks method
+func (mso *ScheduleOptions) GetPlacementRules(name string) []*placement.Rule {
+	return nil
+}
+
+// GetMaxOperatorSteps mocks method
+func (mso *ScheduleOptions) GetMaxOperatorSteps(name string) uint64 {
+	return 0
+}
+
 // GetStoreBalanceRate mocks method
 func (mso *ScheduleOptions) GetStoreBalanceRate() float64 {
 	return mso.StoreBalanceRate
@@ -13,7 +13,11 @@
 
 package typeutil
 
-import "time"
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
 
 // ZeroTime is a zero time.
 var ZeroTime = time.Time{}
@@ -32,3 +36,43 @@ func ParseTimestamp(data []byte) (time.Time, error) {
 func SubTimeByWallClock(after time.Time, before time.Time) time.Duration {
 	return time.Duration(after.UnixNano() - before.UnixNano())
 }
+
+// timeWindowLayout is the layout of the start and end of a TimeWindow.
+const timeWindowLayout = "15:04"
+
+// TimeWindow is a daily time window in the form of "15:04", e.g. from "09:00"
+// to "18:00". A window whose end is earlier than its start crosses midnight.
+type TimeWindow struct {
+	Start string `toml:"start" json:"start"`
+	End   string `toml:"end" json:"end"`
+}
+
+// Validate returns an error if the start or the end of the window is not in
+// the form of "15:04".
+func (w TimeWindow) Validate() error {
+	for _, t := range []string{w.Start, w.End} {
+		if _, err := time.Parse(timeWindowLayout, t); err != nil {
+			return errors.Errorf("invalid time %q of window, should be like 15:04", t)
+		}
+	}
+	return nil
+}
+
+// Contains returns true if the time of day of t falls in the window, the start
+// is inclusive and the end is exclusive. An invalid window contains nothing.
+func (w TimeWindow) Contains(t time.Time) bool {
+	start, err := time.Parse(timeWindowLayout, w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse(timeWindowLayout, w.End)
+	if err != nil {
+		return false
+	}
+	s, e := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+	now := t.Hour()*60 + t.Minute()
+	if s <= e {
+		return s <= now && now < e
+	}
+	return now >= s || now < e
+}
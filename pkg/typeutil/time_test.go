@@ -47,3 +47,26 @@ func (s *testTimeSuite) TestSubTimeByWallClock(c *C) {
 		c.Assert(duration, Equals, time.Second*time.Duration(r))
 	}
 }
+
+func (s *testTimeSuite) TestTimeWindow(c *C) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2019, 1, 1, hour, min, 0, 0, time.Local)
+	}
+	w := TimeWindow{Start: "09:00", End: "18:30"}
+	c.Assert(w.Contains(at(9, 0)), IsTrue)
+	c.Assert(w.Contains(at(18, 29)), IsTrue)
+	c.Assert(w.Contains(at(18, 30)), IsFalse)
+	c.Assert(w.Contains(at(8, 59)), IsFalse)
+
+	// The window crosses midnight.
+	w = TimeWindow{Start: "22:00", End: "02:00"}
+	c.Assert(w.Contains(at(23, 0)), IsTrue)
+	c.Assert(w.Contains(at(1, 0)), IsTrue)
+	c.Assert(w.Contains(at(12, 0)), IsFalse)
+
+	c.Assert(w.Validate(), IsNil)
+
+	w = TimeWindow{Start: "9am", End: "18:00"}
+	c.Assert(w.Contains(at(12, 0)), IsFalse)
+	c.Assert(w.Validate(), NotNil)
+}
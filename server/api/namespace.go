@@ -0,0 +1,178 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/pd/pkg/apiutil"
+	"github.com/pingcap/pd/server"
+	"github.com/pingcap/pd/server/core"
+	"github.com/unrolled/render"
+)
+
+type namespaceHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newNamespaceHandler(svr *server.Server, rd *render.Render) *namespaceHandler {
+	return &namespaceHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// getStoreID returns the store ID of the request. It writes the error response
+// and returns false if the ID is invalid.
+func (h *namespaceHandler) getStoreID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return 0, false
+	}
+	return id, true
+}
+
+// getCluster returns the raft cluster and the namespace name of the request.
+// It writes the error response and returns nil if the cluster is not
+// bootstrapped or the namespace does not exist.
+func (h *namespaceHandler) getCluster(w http.ResponseWriter, r *http.Request) (*server.RaftCluster, string) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		return nil, ""
+	}
+	name := mux.Vars(r)["name"]
+	for _, ns := range cluster.GetNamespaceClassifier().GetAllNamespaces() {
+		if ns == name {
+			return cluster, name
+		}
+	}
+	h.rd.JSON(w, http.StatusNotFound, fmt.Sprintf("invalid namespace Name %s, not found", name))
+	return nil, ""
+}
+
+// NamespaceScatterProgress is the progress of the incremental scatter of a
+// namespace.
+type NamespaceScatterProgress struct {
+	Scattered int `json:"scattered"`
+	Total     int `json:"total"`
+}
+
+func (h *namespaceHandler) Scatter(w http.ResponseWriter, r *http.Request) {
+	cluster, name := h.getCluster(w, r)
+	if cluster == nil {
+		return
+	}
+	var input struct {
+		RegionIDs []uint64 `json:"region_ids"`
+	}
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, cluster.StartNamespaceScatter(name, input.RegionIDs))
+}
+
+func (h *namespaceHandler) GetScatterProgress(w http.ResponseWriter, r *http.Request) {
+	cluster, name := h.getCluster(w, r)
+	if cluster == nil {
+		return
+	}
+	scattered, total := cluster.GetNamespaceScatterProgress(name)
+	h.rd.JSON(w, http.StatusOK, &NamespaceScatterProgress{Scattered: scattered, Total: total})
+}
+
+func (h *namespaceHandler) SetStoreUpgrading(w http.ResponseWriter, r *http.Request) {
+	h.setStoreUpgrading(w, r, true)
+}
+
+func (h *namespaceHandler) ClearStoreUpgrading(w http.ResponseWriter, r *http.Request) {
+	h.setStoreUpgrading(w, r, false)
+}
+
+func (h *namespaceHandler) setStoreUpgrading(w http.ResponseWriter, r *http.Request, upgrading bool) {
+	cluster, name := h.getCluster(w, r)
+	if cluster == nil {
+		return
+	}
+	storeID, ok := h.getStoreID(w, r)
+	if !ok {
+		return
+	}
+	cluster.SetNamespaceStoreUpgrading(name, storeID, upgrading)
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+func (h *namespaceHandler) BeginStoreShutdown(w http.ResponseWriter, r *http.Request) {
+	cluster, name := h.getCluster(w, r)
+	if cluster == nil {
+		return
+	}
+	storeID, ok := h.getStoreID(w, r)
+	if !ok {
+		return
+	}
+	if err := cluster.BeginNamespaceStoreShutdown(name, storeID); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+func (h *namespaceHandler) EndStoreShutdown(w http.ResponseWriter, r *http.Request) {
+	cluster, name := h.getCluster(w, r)
+	if cluster == nil {
+		return
+	}
+	storeID, ok := h.getStoreID(w, r)
+	if !ok {
+		return
+	}
+	cluster.EndNamespaceStoreShutdown(name, storeID)
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+func (h *namespaceHandler) GetStoreWeights(w http.ResponseWriter, r *http.Request) {
+	cluster, name := h.getCluster(w, r)
+	if cluster == nil {
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, cluster.GetNamespaceStoreWeights(name))
+}
+
+func (h *namespaceHandler) SetStoreWeights(w http.ResponseWriter, r *http.Request) {
+	cluster, name := h.getCluster(w, r)
+	if cluster == nil {
+		return
+	}
+	var weights map[uint64]core.StoreWeight
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &weights); err != nil {
+		return
+	}
+	for id, weight := range weights {
+		if weight.LeaderWeight < 0 || weight.RegionWeight < 0 {
+			h.rd.JSON(w, http.StatusBadRequest, fmt.Sprintf("badformat weight of store %d", id))
+			return
+		}
+	}
+	if err := cluster.SetNamespaceStoreWeights(name, weights); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/namespace"
+)
+
+var _ = Suite(&testNamespaceSuite{})
+
+type testNamespaceSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testNamespaceSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1/namespace", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testNamespaceSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testNamespaceSuite) TestScatter(c *C) {
+	region := s.svr.GetRaftCluster().GetRegionInfoByKey([]byte("foo"))
+	url := fmt.Sprintf("%s/%s/scatter", s.urlPrefix, namespace.DefaultNamespace)
+
+	// The region not found is ignored.
+	b, err := json.Marshal(map[string][]uint64{"region_ids": {region.GetID(), 1000}})
+	c.Assert(err, IsNil)
+	var queued int
+	err = postJSON(url, b, func(res []byte) bool {
+		return json.Unmarshal(res, &queued) == nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(queued, Equals, 1)
+
+	progress := &NamespaceScatterProgress{}
+	c.Assert(readJSONWithURL(url, progress), IsNil)
+	c.Assert(progress.Total, Equals, 1)
+
+	// The namespace not found.
+	url = fmt.Sprintf("%s/%s/scatter", s.urlPrefix, "ns1")
+	c.Assert(postJSON(url, b), NotNil)
+	c.Assert(readJSONWithURL(url, progress), NotNil)
+}
+
+func (s *testNamespaceSuite) TestStoreUpgrading(c *C) {
+	storeID := s.svr.GetRaftCluster().GetStores()[0].GetID()
+	url := fmt.Sprintf("%s/%s/store/%d/upgrading", s.urlPrefix, namespace.DefaultNamespace, storeID)
+	c.Assert(postJSON(url, nil), IsNil)
+	c.Assert(doDelete(url), IsNil)
+	// The invalid store ID.
+	url = fmt.Sprintf("%s/%s/store/%s/upgrading", s.urlPrefix, namespace.DefaultNamespace, "abc")
+	c.Assert(postJSON(url, nil), NotNil)
+}
+
+func (s *testNamespaceSuite) TestStoreShutdown(c *C) {
+	storeID := s.svr.GetRaftCluster().GetStores()[0].GetID()
+	url := fmt.Sprintf("%s/%s/store/%d/shutdown", s.urlPrefix, namespace.DefaultNamespace, storeID)
+	c.Assert(postJSON(url, nil), IsNil)
+	c.Assert(doDelete(url), IsNil)
+	// The store not in the namespace cannot shut down.
+	url = fmt.Sprintf("%s/%s/store/%d/shutdown", s.urlPrefix, namespace.DefaultNamespace, 1000)
+	c.Assert(postJSON(url, nil), NotNil)
+}
+
+func (s *testNamespaceSuite) TestStoreWeights(c *C) {
+	url := fmt.Sprintf("%s/%s/store-weights", s.urlPrefix, namespace.DefaultNamespace)
+	weights := map[uint64]core.StoreWeight{1: {LeaderWeight: 2, RegionWeight: 3}}
+	b, err := json.Marshal(weights)
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(url, b), IsNil)
+	c.Assert(s.svr.GetRaftCluster().GetNamespaceStoreWeights(namespace.DefaultNamespace), DeepEquals, weights)
+	got := make(map[uint64]core.StoreWeight)
+	c.Assert(readJSONWithURL(url, &got), IsNil)
+	c.Assert(got, DeepEquals, weights)
+
+	// The negative weights are invalid.
+	b, err = json.Marshal(map[uint64]core.StoreWeight{1: {LeaderWeight: -1}})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(url, b), NotNil)
+	c.Assert(s.svr.GetRaftCluster().GetNamespaceStoreWeights(namespace.DefaultNamespace), DeepEquals, weights)
+
+	// The weights are cleared with an empty map.
+	c.Assert(postJSON(url, []byte("{}")), IsNil)
+	c.Assert(s.svr.GetRaftCluster().GetNamespaceStoreWeights(namespace.DefaultNamespace), HasLen, 0)
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/pingcap/pd/server"
 	"github.com/pingcap/pd/server/config"
 	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule/operator"
 )
 
 var _ = Suite(&testOperatorSuite{})
@@ -107,6 +108,33 @@ func (s *testOperatorSuite) TestAddRemovePeer(c *C) {
 	c.Assert(err, NotNil)
 }
 
+func (s *testOperatorSuite) TestManualMoveCancelsOperator(c *C) {
+	mustPutStore(c, s.svr, 1, metapb.StoreState_Up, nil)
+	mustPutStore(c, s.svr, 2, metapb.StoreState_Up, nil)
+	mustPutStore(c, s.svr, 5, metapb.StoreState_Up, nil)
+
+	peer := &metapb.Peer{Id: 41, StoreId: 1}
+	region := &metapb.Region{
+		Id:    40,
+		Peers: []*metapb.Peer{peer},
+		RegionEpoch: &metapb.RegionEpoch{
+			ConfVer: 1,
+			Version: 1,
+		},
+	}
+	regionInfo := core.NewRegionInfo(region, peer)
+	mustRegionHeartbeat(c, s.svr, regionInfo)
+
+	// A pending operator of schedulers.
+	oc := s.svr.GetRaftCluster().GetOperatorController()
+	op := operator.CreateAddPeerOperator("balance-region", regionInfo, 42, 2, operator.OpRegion)
+	c.Assert(oc.AddOperator(op), IsTrue)
+
+	err := postJSON(fmt.Sprintf("%s/operators", s.urlPrefix), []byte(`{"name":"transfer-peer", "region_id": 40, "from_store_id": 1, "to_store_id": 5}`))
+	c.Assert(err, IsNil)
+	c.Assert(oc.GetOperator(40).Desc(), Equals, "admin-move-peer")
+}
+
 func (s *testOperatorSuite) TestMergeRegionOperator(c *C) {
 	r1 := newTestRegionInfo(10, 1, []byte(""), []byte("b"), core.SetWrittenBytes(1000), core.SetReadBytes(1000), core.SetRegionConfVer(1), core.SetRegionVersion(1))
 	mustRegionHeartbeat(c, s.svr, r1)
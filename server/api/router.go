@@ -64,6 +64,16 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	router.HandleFunc("/api/v1/config/cluster-version", confHandler.GetClusterVersion).Methods("GET")
 	router.HandleFunc("/api/v1/config/cluster-version", confHandler.SetClusterVersion).Methods("POST")
 
+	namespaceHandler := newNamespaceHandler(svr, rd)
+	router.HandleFunc("/api/v1/namespace/{name}/scatter", namespaceHandler.GetScatterProgress).Methods("GET")
+	router.HandleFunc("/api/v1/namespace/{name}/scatter", namespaceHandler.Scatter).Methods("POST")
+	router.HandleFunc("/api/v1/namespace/{name}/store-weights", namespaceHandler.GetStoreWeights).Methods("GET")
+	router.HandleFunc("/api/v1/namespace/{name}/store-weights", namespaceHandler.SetStoreWeights).Methods("POST")
+	router.HandleFunc("/api/v1/namespace/{name}/store/{id}/upgrading", namespaceHandler.SetStoreUpgrading).Methods("POST")
+	router.HandleFunc("/api/v1/namespace/{name}/store/{id}/upgrading", namespaceHandler.ClearStoreUpgrading).Methods("DELETE")
+	router.HandleFunc("/api/v1/namespace/{name}/store/{id}/shutdown", namespaceHandler.BeginStoreShutdown).Methods("POST")
+	router.HandleFunc("/api/v1/namespace/{name}/store/{id}/shutdown", namespaceHandler.EndStoreShutdown).Methods("DELETE")
+
 	storeHandler := newStoreHandler(handler, rd)
 	router.HandleFunc("/api/v1/store/{id}", storeHandler.Get).Methods("GET")
 	router.HandleFunc("/api/v1/store/{id}", storeHandler.Delete).Methods("DELETE")
@@ -71,6 +81,7 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	router.HandleFunc("/api/v1/store/{id}/label", storeHandler.SetLabels).Methods("POST")
 	router.HandleFunc("/api/v1/store/{id}/weight", storeHandler.SetWeight).Methods("POST")
 	router.HandleFunc("/api/v1/store/{id}/limit", storeHandler.SetLimit).Methods("POST")
+	router.HandleFunc("/api/v1/store/{id}/lease-unstable", storeHandler.SetLeaseUnstable).Methods("POST")
 	storesHandler := newStoresHandler(handler, rd)
 	router.Handle("/api/v1/stores", storesHandler).Methods("GET")
 	router.HandleFunc("/api/v1/stores/remove-tombstone", storesHandler.RemoveTombStone).Methods("DELETE")
@@ -129,6 +140,7 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 
 	statsHandler := newStatsHandler(svr, rd)
 	router.HandleFunc("/api/v1/stats/region", statsHandler.Region).Methods("GET")
+	router.HandleFunc("/api/v1/stats/namespace-fairness", statsHandler.NamespaceFairness).Methods("GET")
 
 	trendHandler := newTrendHandler(svr, rd)
 	router.HandleFunc("/api/v1/trend", trendHandler.Handle).Methods("GET")
@@ -71,6 +71,37 @@ func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+	case "balance-namespace-flow-scheduler":
+		if err := h.AddBalanceNamespaceFlowScheduler(); err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	case "balance-namespace-thermal-scheduler":
+		if err := h.AddBalanceNamespaceThermalScheduler(); err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	case "balance-namespace-domain-scheduler":
+		if err := h.AddBalanceNamespaceDomainScheduler(); err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	case "balance-namespace-leader-ratio-scheduler":
+		if err := h.AddBalanceNamespaceLeaderRatioScheduler(); err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	case "optimize-namespace-balance-scheduler":
+		objective, _ := input["objective"].(string)
+		if err := h.AddOptimizeNamespaceBalanceScheduler(objective); err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	case "populate-namespace-store-scheduler":
+		if err := h.AddPopulateNamespaceStoreScheduler(); err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
 	case "label-scheduler":
 		if err := h.AddLabelScheduler(); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
@@ -161,6 +192,41 @@ func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+	case "shuffle-namespace-leader-scheduler":
+		limit := uint64(1)
+		l, ok := input["limit"].(float64)
+		if ok {
+			limit = uint64(l)
+		}
+		if err := h.AddShuffleNamespaceLeaderScheduler(limit); err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	case "namespace-adjacent-region-scheduler":
+		limit := uint64(1)
+		l, ok := input["limit"].(float64)
+		if ok {
+			limit = uint64(l)
+		}
+		if err := h.AddNamespaceAdjacentRegionScheduler(limit); err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	case "scheduler-chain":
+		var types []string
+		items, _ := input["scheduler_types"].([]interface{})
+		for _, item := range items {
+			typ, ok := item.(string)
+			if !ok {
+				h.r.JSON(w, http.StatusBadRequest, "invalid scheduler type")
+				return
+			}
+			types = append(types, typ)
+		}
+		if err := h.AddSchedulerChain(types...); err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
 	default:
 		h.r.JSON(w, http.StatusBadRequest, "unknown scheduler")
 		return
@@ -62,6 +62,16 @@ func (s *testScheduleSuite) TestAPI(c *C) {
 		{name: "balance-region-scheduler"},
 		{name: "shuffle-leader-scheduler"},
 		{name: "shuffle-region-scheduler"},
+		{name: "optimize-namespace-balance-scheduler"},
+		{
+			name: "optimize-namespace-balance-scheduler",
+			args: []arg{{"objective", "max-load"}},
+		},
+		{
+			name:        "scheduler-chain",
+			createdName: "scheduler-chain(balance-leader-scheduler,balance-region-scheduler)",
+			args:        []arg{{"scheduler_types", []string{"balance-leader", "balance-region"}}},
+		},
 		{
 			name:        "grant-leader-scheduler",
 			createdName: "grant-leader-scheduler-1",
@@ -42,3 +42,12 @@ func (h *statsHandler) Region(w http.ResponseWriter, r *http.Request) {
 	stats := cluster.GetRegionStats([]byte(startKey), []byte(endKey))
 	h.rd.JSON(w, http.StatusOK, stats)
 }
+
+func (h *statsHandler) NamespaceFairness(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, cluster.GetScheduleFairnessStats())
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/pingcap/pd/pkg/apiutil"
 	"github.com/pingcap/pd/server"
 	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/namespace"
 	"github.com/pingcap/pd/server/statistics"
 )
 
@@ -176,3 +177,10 @@ func (s *testStatsSuite) TestRegionStats(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(stats, DeepEquals, stats23)
 }
+
+func (s *testStatsSuite) TestNamespaceFairness(c *C) {
+	stats := make(map[string]*server.NamespaceFairnessStats)
+	err := readJSONWithURL(s.urlPrefix+"/stats/namespace-fairness", &stats)
+	c.Assert(err, IsNil)
+	c.Assert(stats, HasKey, namespace.DefaultNamespace)
+}
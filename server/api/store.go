@@ -340,6 +340,44 @@ func (h *storeHandler) SetLimit(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, nil)
 }
 
+func (h *storeHandler) SetLeaseUnstable(w http.ResponseWriter, r *http.Request) {
+	cluster := h.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		return
+	}
+
+	vars := mux.Vars(r)
+	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+
+	var input map[string]interface{}
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+
+	durationVal, ok := input["duration"].(string)
+	if !ok {
+		h.rd.JSON(w, http.StatusBadRequest, "duration unset")
+		return
+	}
+	duration, err := time.ParseDuration(durationVal)
+	if err != nil || duration < 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "badformat duration")
+		return
+	}
+
+	if err := cluster.MarkStoreLeaseUnstable(storeID, duration); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
 type storesHandler struct {
 	*server.Handler
 	rd *render.Render
@@ -96,3 +96,24 @@ func (s *testStoreNsSuite) TestCreateNamespace(c *C) {
 	err = postJSON(fmt.Sprintf("%s/classifier/table/namespaces", s.urlPrefix), b)
 	c.Assert(err, IsNil)
 }
+
+func (s *testStoreNsSuite) TestOperatorOutOfNamespace(c *C) {
+	b, err := json.Marshal(map[string]string{"namespace": "nsoperator"})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(fmt.Sprintf("%s/classifier/table/namespaces", s.urlPrefix), b), IsNil)
+	b, err = json.Marshal(map[string]string{"namespace": "nsoperator", "action": "add"})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(fmt.Sprintf("%s/classifier/table/store_ns/%d", s.urlPrefix, 4), b), IsNil)
+	mustPutStore(c, s.svr, 8, metapb.StoreState_Up, nil)
+
+	// Store 4 is out of the namespace of the region.
+	url := fmt.Sprintf("%s/operators", s.urlPrefix)
+	c.Assert(postJSON(url, []byte(`{"name":"add-peer", "region_id": 8, "store_id": 4}`)), NotNil)
+	c.Assert(postJSON(url, []byte(`{"name":"add-learner", "region_id": 8, "store_id": 4}`)), NotNil)
+	c.Assert(postJSON(url, []byte(`{"name":"transfer-peer", "region_id": 8, "from_store_id": 1, "to_store_id": 4}`)), NotNil)
+	c.Assert(postJSON(url, []byte(`{"name":"transfer-region", "region_id": 8, "to_store_ids": [1, 4]}`)), NotNil)
+	c.Assert(s.svr.GetRaftCluster().GetOperatorController().GetOperator(8), IsNil)
+
+	// Store 8 is in the same namespace.
+	c.Assert(postJSON(url, []byte(`{"name":"add-peer", "region_id": 8, "store_id": 8}`)), IsNil)
+}
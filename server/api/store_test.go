@@ -270,6 +270,19 @@ func (s *testStoreSuite) TestStoreSetState(c *C) {
 	c.Assert(info.Store.State, Equals, metapb.StoreState_Up)
 }
 
+func (s *testStoreSuite) TestStoreLeaseUnstable(c *C) {
+	url := fmt.Sprintf("%s/store/1/lease-unstable", s.urlPrefix)
+	c.Assert(postJSON(url, []byte(`{"duration": "30s"}`)), IsNil)
+	c.Assert(s.svr.GetRaftCluster().IsStoreLeaseStable(1), IsFalse)
+	// The duration is invalid.
+	c.Assert(postJSON(url, []byte(`{"duration": "30"}`)), NotNil)
+	c.Assert(postJSON(url, []byte(`{"duration": "-30s"}`)), NotNil)
+	c.Assert(postJSON(url, []byte(`{}`)), NotNil)
+	// The store does not exist.
+	url = fmt.Sprintf("%s/store/1000/lease-unstable", s.urlPrefix)
+	c.Assert(postJSON(url, []byte(`{"duration": "30s"}`)), NotNil)
+}
+
 func (s *testStoreSuite) TestUrlStoreFilter(c *C) {
 	table := []struct {
 		u    string
@@ -17,6 +17,7 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"strconv"
 	"sync"
 	"time"
 
@@ -36,6 +37,7 @@ import (
 	syncer "github.com/pingcap/pd/server/region_syncer"
 	"github.com/pingcap/pd/server/schedule"
 	"github.com/pingcap/pd/server/schedule/checker"
+	"github.com/pingcap/pd/server/schedule/operator"
 	"github.com/pingcap/pd/server/statistics"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
@@ -44,6 +46,9 @@ import (
 var (
 	backgroundJobInterval      = time.Minute
 	defaultChangedRegionsLimit = 10000
+	// staleRegionHeartbeatThreshold is the time after which a region without
+	// heartbeats is counted as stale in the namespace metrics.
+	staleRegionHeartbeatThreshold = 5 * time.Minute
 )
 
 // RaftCluster is used for cluster config management.
@@ -78,6 +83,7 @@ type RaftCluster struct {
 	regionStats     *statistics.RegionStatistics
 	storesStats     *statistics.StoresStats
 	hotSpotCache    *statistics.HotCache
+	namespaceStates *namespaceStates
 
 	coordinator *coordinator
 
@@ -153,6 +159,7 @@ func (c *RaftCluster) initCluster(id id.Allocator, opt *config.ScheduleOption, s
 	c.prepareChecker = newPrepareChecker()
 	c.changedRegions = make(chan *core.RegionInfo, defaultChangedRegionsLimit)
 	c.hotSpotCache = statistics.NewHotCache()
+	c.namespaceStates = newNamespaceStates()
 }
 
 func (c *RaftCluster) start() error {
@@ -177,6 +184,8 @@ func (c *RaftCluster) start() error {
 	if err != nil {
 		return err
 	}
+	// The stores loaded may have joined the namespaces long ago.
+	c.recordNamespaceStoresSeen(c.s.classifier, time.Time{})
 
 	c.coordinator = newCoordinator(c.ctx, cluster, c.s.hbStreams, c.s.classifier)
 	c.regionStats = statistics.NewRegionStatistics(c.s.scheduleOpt, c.s.classifier)
@@ -226,6 +235,12 @@ func (c *RaftCluster) loadClusterInfo() (*RaftCluster, error) {
 	for _, store := range c.GetStores() {
 		c.storesStats.CreateRollingStoreStats(store.GetID())
 	}
+	if err := c.loadNamespaceStoreBlacklists(); err != nil {
+		return nil, err
+	}
+	if err := c.loadNamespaceStoreWeights(); err != nil {
+		return nil, err
+	}
 	return c, nil
 }
 
@@ -246,6 +261,9 @@ func (c *RaftCluster) runBackgroundJobs(interval time.Duration) {
 		case <-ticker.C:
 			c.checkStores()
 			c.collectMetrics()
+			c.recordNamespaceRegionCounts(c.GetNamespaceClassifier(), time.Now())
+			c.recordNamespaceStoresSeen(c.GetNamespaceClassifier(), time.Now())
+			c.recordNamespaceUnderReplicated(c.GetNamespaceClassifier(), time.Now())
 			c.coordinator.opController.PruneHistory()
 		}
 	}
@@ -322,6 +340,9 @@ func (c *RaftCluster) handleStoreHeartbeat(stats *pdpb.StoreStats) error {
 	if store == nil {
 		return core.NewStoreNotFoundErr(storeID)
 	}
+	if !store.GetLastHeartbeatTS().IsZero() && store.IsDisconnected() {
+		c.namespaceStates.storeFlapping.recordTransition(storeID, time.Now())
+	}
 	newStore := store.Clone(core.SetStoreStats(stats), core.SetLastHeartbeatTS(time.Now()))
 	c.core.PutStore(newStore)
 	c.storesStats.Observe(newStore.GetID(), newStore.GetStoreStats())
@@ -378,6 +399,7 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 				zap.Uint64("old-confver", o.GetConfVer()),
 				zap.Uint64("new-confver", r.GetConfVer()),
 			)
+			c.namespaceStates.replicaChurn.recordPeerChanges(origin, region, time.Now())
 			saveKV, saveCache = true, true
 		}
 		if region.GetLeader().GetId() != origin.GetLeader().GetId() {
@@ -389,6 +411,7 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 					zap.Uint64("from", origin.GetLeader().GetStoreId()),
 					zap.Uint64("to", region.GetLeader().GetStoreId()),
 				)
+				c.namespaceStates.leaderOscillation.recordLeaderMove(region.GetID(), origin.GetLeader().GetStoreId(), region.GetLeader().GetStoreId(), time.Now())
 			}
 			saveCache = true
 		}
@@ -415,6 +438,8 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 		}
 	}
 
+	c.namespaceStates.regionHeartbeats.record(region.GetID(), time.Now())
+
 	if saveKV && c.storage != nil {
 		if err := c.storage.SaveRegion(region.GetMeta()); err != nil {
 			// Not successfully saved to storage is not fatal, it only leads to longer warm-up
@@ -457,6 +482,9 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 				c.regionStats.ClearDefunctRegion(item.GetID())
 			}
 			c.labelLevelStats.ClearDefunctRegion(item.GetID(), c.GetLocationLabels())
+			c.namespaceStates.replicaChurn.removeRegion(item.GetID())
+			c.namespaceStates.leaderOscillation.removeRegion(item.GetID())
+			c.namespaceStates.regionHeartbeats.remove(item.GetID())
 		}
 
 		// Update related stores.
@@ -699,6 +727,9 @@ func (c *RaftCluster) DropCacheRegion(id uint64) {
 	defer c.RUnlock()
 	if region := c.GetRegion(id); region != nil {
 		c.core.RemoveRegion(region)
+		c.namespaceStates.replicaChurn.removeRegion(id)
+		c.namespaceStates.leaderOscillation.removeRegion(id)
+		c.namespaceStates.regionHeartbeats.remove(id)
 	}
 }
 
@@ -1023,6 +1054,7 @@ func (c *RaftCluster) collectMetrics() {
 	c.coordinator.collectSchedulerMetrics()
 	c.coordinator.collectHotSpotMetrics()
 	c.collectClusterMetrics()
+	c.collectNamespaceMetrics(c.GetNamespaceClassifier())
 	c.collectHealthStatus()
 }
 
@@ -1033,6 +1065,7 @@ func (c *RaftCluster) resetMetrics() {
 	c.coordinator.resetSchedulerMetrics()
 	c.coordinator.resetHotSpotMetrics()
 	c.resetClusterMetrics()
+	namespaceStatusGauge.Reset()
 }
 
 func (c *RaftCluster) collectClusterMetrics() {
@@ -1059,6 +1092,31 @@ func (c *RaftCluster) resetClusterMetrics() {
 	c.hotSpotCache.ResetMetrics()
 }
 
+func (c *RaftCluster) collectNamespaceMetrics(classifier namespace.Classifier) {
+	for _, name := range classifier.GetAllNamespaces() {
+		nc := newNamespaceCluster(c, classifier, name)
+		namespaceStatusGauge.WithLabelValues(name, "capacity_utilization_skew").Set(nc.GetCapacityUtilizationSkew())
+		namespaceStatusGauge.WithLabelValues(name, "scheduling_backlog").Set(float64(nc.GetSchedulingBacklog()))
+		namespaceStatusGauge.WithLabelValues(name, "data_loss_risk").Set(nc.GetDataLossRisk())
+		namespaceStatusGauge.WithLabelValues(name, "stale_heartbeat_regions").Set(float64(nc.GetStaleHeartbeatRegionCount(staleRegionHeartbeatThreshold)))
+		namespaceStatusGauge.WithLabelValues(name, "scheduling_efficiency").Set(nc.GetSchedulingEfficiency())
+		namespaceStatusGauge.WithLabelValues(name, "region_schedule_limit").Set(float64(nc.GetRegionScheduleLimit()))
+		namespaceStatusGauge.WithLabelValues(name, "thrashing_regions").Set(float64(len(nc.DetectReplicaThrashing())))
+		namespaceStatusGauge.WithLabelValues(name, "oscillating_regions").Set(float64(len(nc.DetectLeaderOscillation())))
+		for _, s := range nc.GetStores() {
+			storeID := strconv.FormatUint(s.GetID(), 10)
+			if s.IsOffline() {
+				namespaceDecommissionGauge.WithLabelValues(name, storeID).Set(nc.GetDecommissionProgress(s.GetID()))
+				continue
+			}
+			// The store is up again or tombstone, the progress is not tracked
+			// any more.
+			namespaceDecommissionGauge.DeleteLabelValues(name, storeID)
+			nc.clearDecommissionBaseline(s.GetID())
+		}
+	}
+}
+
 func (c *RaftCluster) collectHealthStatus() {
 	client := c.s.GetClient()
 	members, err := GetMembers(client)
@@ -1192,6 +1250,205 @@ func (c *RaftCluster) putConfig(meta *metapb.Cluster) error {
 	return c.putMetaLocked(proto.Clone(meta).(*metapb.Cluster))
 }
 
+func (c *RaftCluster) getNamespaceStates() *namespaceStates {
+	return c.namespaceStates
+}
+
+func (c *RaftCluster) getNamespaceState(name string) *namespaceState {
+	return c.namespaceStates.get(name)
+}
+
+// GetOperatorHistory returns at most limit latest operators produced by
+// schedulers for the namespace, the latest one comes first.
+func (c *RaftCluster) GetOperatorHistory(namespace string, limit int) []*NamespaceOpRecord {
+	return c.getNamespaceState(namespace).getOperatorHistory(limit)
+}
+
+// MarkNamespaceDecommissioning marks the namespace as decommissioning, so that
+// only drain schedulers keep scheduling it.
+func (c *RaftCluster) MarkNamespaceDecommissioning(namespace string) {
+	c.getNamespaceState(namespace).markDecommissioning()
+}
+
+// recordNamespaceRegionCounts samples the region counts of all namespaces.
+func (c *RaftCluster) recordNamespaceRegionCounts(classifier namespace.Classifier, now time.Time) {
+	counts := make(map[string]int)
+	for _, r := range c.ScanRegions(nil, nil, 0) {
+		counts[classifier.GetRegionNamespace(r)]++
+	}
+	for _, name := range classifier.GetAllNamespaces() {
+		c.getNamespaceState(name).recordRegionCount(now, counts[name])
+	}
+}
+
+// recordNamespaceUnderReplicated records the under-replicated regions of all
+// namespaces at the time, so that their repair SLA is tracked.
+func (c *RaftCluster) recordNamespaceUnderReplicated(classifier namespace.Classifier, now time.Time) {
+	for _, name := range classifier.GetAllNamespaces() {
+		newNamespaceCluster(c, classifier, name).RecordUnderReplicated(now)
+	}
+}
+
+// recordNamespaceStoresSeen records the stores of all namespaces not seen
+// before as first seen at the time.
+func (c *RaftCluster) recordNamespaceStoresSeen(classifier namespace.Classifier, now time.Time) {
+	storeIDs := make(map[string][]uint64)
+	for _, s := range c.GetStores() {
+		ns := classifier.GetStoreNamespace(s)
+		storeIDs[ns] = append(storeIDs[ns], s.GetID())
+	}
+	for ns, ids := range storeIDs {
+		c.getNamespaceState(ns).recordStoresSeen(ids, now)
+	}
+}
+
+// tuneNamespaceStoreWeights runs a round of the store weight auto tuning for
+// the namespaces which enable it and are not paused.
+func (c *RaftCluster) tuneNamespaceStoreWeights(classifier namespace.Classifier) {
+	now := time.Now()
+	for _, name := range classifier.GetAllNamespaces() {
+		nc := newNamespaceCluster(c, classifier, name)
+		if nc.IsStoreWeightAutoTuningEnabled() && !nc.isSchedulePaused(now) {
+			nc.TuneStoreWeights()
+		}
+	}
+}
+
+// recordNamespaceStoreJoinReceived records the peers added by the operators
+// onto the stores joining the namespaces of the regions.
+func (c *RaftCluster) recordNamespaceStoreJoinReceived(classifier namespace.Classifier, ops []*operator.Operator) {
+	for _, op := range ops {
+		region := c.GetRegion(op.RegionID())
+		if region == nil {
+			continue
+		}
+		state := c.getNamespaceState(classifier.GetRegionNamespace(region))
+		for _, storeID := range addPeerStores(op) {
+			state.recordStoreJoinReceived(storeID)
+		}
+	}
+}
+
+// SetNamespaceStoreUpgrading sets or clears the upgrade mode of the store in
+// the namespace. The leaders of the namespace regions are evicted from the
+// store in upgrade mode, and it does not receive new leaders until the mode is
+// cleared.
+func (c *RaftCluster) SetNamespaceStoreUpgrading(namespace string, storeID uint64, upgrading bool) {
+	c.getNamespaceState(namespace).setStoreUpgrading(storeID, upgrading)
+}
+
+// BeginNamespaceStoreShutdown marks the store as shutting down gracefully in
+// the namespace. The leaders of the namespace regions are evicted from the
+// store, and it is no longer the target of new peers or leaders until
+// EndNamespaceStoreShutdown.
+func (c *RaftCluster) BeginNamespaceStoreShutdown(namespace string, storeID uint64) error {
+	return newNamespaceCluster(c, c.GetNamespaceClassifier(), namespace).BeginStoreShutdown(storeID)
+}
+
+// EndNamespaceStoreShutdown clears the shutting down mark of the store in the
+// namespace, e.g. after the store is restarted.
+func (c *RaftCluster) EndNamespaceStoreShutdown(namespace string, storeID uint64) {
+	c.getNamespaceState(namespace).setStoreShuttingDown(storeID, false)
+}
+
+// StartNamespaceScatter queues the regions of the namespace to be scattered
+// incrementally, and returns the number of regions queued. The regions not in
+// the namespace or already queued are ignored.
+func (c *RaftCluster) StartNamespaceScatter(namespace string, regionIDs []uint64) int {
+	return newNamespaceCluster(c, c.GetNamespaceClassifier(), namespace).StartIncrementalScatter(regionIDs)
+}
+
+// GetNamespaceScatterProgress returns the numbers of regions scattered and
+// queued by the incremental scatter of the namespace since its queue was last
+// empty.
+func (c *RaftCluster) GetNamespaceScatterProgress(namespace string) (scattered, total int) {
+	return c.getNamespaceState(namespace).getScatterProgress()
+}
+
+// MarkStoreLeaseUnstable marks the leader lease of the store as unstable for
+// the duration, e.g. the lease is about to expire, so that namespace
+// schedulers do not transfer leaders to the store during the time.
+func (c *RaftCluster) MarkStoreLeaseUnstable(storeID uint64, d time.Duration) error {
+	if c.GetStore(storeID) == nil {
+		return core.NewStoreNotFoundErr(storeID)
+	}
+	c.namespaceStates.storeLeases.markUnstable(storeID, time.Now().Add(d))
+	return nil
+}
+
+// IsStoreLeaseStable returns false if the leader lease of the store is marked
+// as unstable.
+func (c *RaftCluster) IsStoreLeaseStable(storeID uint64) bool {
+	return c.namespaceStates.storeLeases.isStable(storeID, time.Now())
+}
+
+// SetNamespaceStoreBlacklist saves the store blacklist of the namespace, the
+// stores in it are not used as scheduling targets of the namespace.
+func (c *RaftCluster) SetNamespaceStoreBlacklist(namespace string, storeIDs []uint64) error {
+	if err := c.storage.SaveNamespaceStoreBlacklist(namespace, storeIDs); err != nil {
+		return err
+	}
+	c.getNamespaceState(namespace).setStoreBlacklist(storeIDs)
+	return nil
+}
+
+// GetNamespaceStoreBlacklist returns the store blacklist of the namespace.
+func (c *RaftCluster) GetNamespaceStoreBlacklist(namespace string) []uint64 {
+	return c.getNamespaceState(namespace).getStoreBlacklist()
+}
+
+// loadNamespaceStoreBlacklists restores the store blacklists of namespaces
+// from storage.
+func (c *RaftCluster) loadNamespaceStoreBlacklists() error {
+	blacklists, err := c.storage.LoadNamespaceStoreBlacklists()
+	if err != nil {
+		return err
+	}
+	for namespace, storeIDs := range blacklists {
+		c.getNamespaceState(namespace).setStoreBlacklist(storeIDs)
+	}
+	return nil
+}
+
+// SetNamespaceStoreWeights saves the store weights of the namespace, keyed by
+// store ID. They overwrite the weights of the stores in the namespace since
+// the next scheduling round.
+func (c *RaftCluster) SetNamespaceStoreWeights(namespace string, weights map[uint64]core.StoreWeight) error {
+	if err := c.storage.SaveNamespaceStoreWeights(namespace, weights); err != nil {
+		return err
+	}
+	c.getNamespaceState(namespace).setPersistedStoreWeights(weights)
+	return nil
+}
+
+// GetNamespaceStoreWeights returns the store weights of the namespace.
+func (c *RaftCluster) GetNamespaceStoreWeights(namespace string) map[uint64]core.StoreWeight {
+	return c.getNamespaceState(namespace).getPersistedStoreWeights()
+}
+
+// loadNamespaceStoreWeights restores the store weights of namespaces from
+// storage.
+func (c *RaftCluster) loadNamespaceStoreWeights() error {
+	weights, err := c.storage.LoadNamespaceStoreWeights()
+	if err != nil {
+		return err
+	}
+	for namespace, w := range weights {
+		c.getNamespaceState(namespace).setPersistedStoreWeights(w)
+	}
+	return nil
+}
+
+// GetScheduleFairnessStats returns how many of the latest scheduling rounds
+// visited or skipped each namespace.
+func (c *RaftCluster) GetScheduleFairnessStats() map[string]*NamespaceFairnessStats {
+	stats := make(map[string]*NamespaceFairnessStats)
+	for _, name := range c.GetNamespaceClassifier().GetAllNamespaces() {
+		stats[name] = c.getNamespaceState(name).getFairnessStats()
+	}
+	return stats
+}
+
 // GetNamespaceClassifier returns current namespace classifier.
 func (c *RaftCluster) GetNamespaceClassifier() namespace.Classifier {
 	return c.s.classifier
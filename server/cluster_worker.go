@@ -21,6 +21,7 @@ import (
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/log"
 	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/namespace"
 	"github.com/pingcap/pd/server/schedule"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
@@ -41,10 +42,33 @@ func (c *RaftCluster) HandleRegionHeartbeat(region *core.RegionInfo) error {
 	c.RLock()
 	co := c.coordinator
 	c.RUnlock()
+	c.checkNamespaceOperator(co.opController, co.classifier, region)
 	co.opController.Dispatch(region, schedule.DispatchFromHeartBeat)
 	return nil
 }
 
+// checkNamespaceOperator cancels the operator produced for a namespace if any
+// store it moves replicas or leader to has left the namespace. The operator
+// of the region stops being tracked once it has ended.
+func (c *RaftCluster) checkNamespaceOperator(oc *schedule.OperatorController, classifier namespace.Classifier, region *core.RegionInfo) {
+	op := oc.GetOperator(region.GetID())
+	if op == nil {
+		c.namespaceStates.operators.prune(region.GetID(), oc.GetOperatorStatus(region.GetID()))
+		return
+	}
+	ns, ok := c.namespaceStates.operators.getNamespace(region.GetID(), op)
+	if !ok || newNamespaceCluster(c, classifier, ns).checkOperator(op) {
+		return
+	}
+	c.namespaceStates.operators.remove(op)
+	if oc.RemoveOperator(op) {
+		log.Info("operator store leaves namespace, cancel it",
+			zap.Uint64("region-id", region.GetID()),
+			zap.String("namespace", ns),
+			zap.Reflect("operator", op))
+	}
+}
+
 func (c *RaftCluster) handleAskSplit(request *pdpb.AskSplitRequest) (*pdpb.AskSplitResponse, error) {
 	reqRegion := request.GetRegion()
 	err := c.validRequestRegion(reqRegion)
@@ -32,6 +32,7 @@ import (
 	"github.com/pingcap/pd/server/core"
 	"github.com/pingcap/pd/server/namespace"
 	"github.com/pingcap/pd/server/schedule"
+	"github.com/pingcap/pd/server/schedule/placement"
 	"github.com/pkg/errors"
 	"go.etcd.io/etcd/embed"
 	"go.etcd.io/etcd/pkg/transport"
@@ -886,6 +887,123 @@ type NamespaceConfig struct {
 	HotRegionScheduleLimit uint64 `json:"hot-region-schedule-limit"`
 	// MaxReplicas is the number of replicas for each region.
 	MaxReplicas uint64 `json:"max-replicas"`
+	// StoreLeaderWeights overwrites the leader weight of stores for leaders
+	// within the namespace, keyed by store ID.
+	StoreLeaderWeights map[uint64]float64 `json:"store-leader-weights,omitempty"`
+	// MaxPendingPeerCount is the max number of pending peers in the namespace.
+	// Schedulers stop adding peers in the namespace once it is exceeded.
+	// 0 means no limit.
+	MaxPendingPeerCount uint64 `json:"max-pending-peer-count"`
+	// SamplingStrategy is the strategy to sample regions within the namespace,
+	// there are some strategies supported: ["uniform", "size-weighted",
+	// "flow-weighted"], default: "uniform"
+	SamplingStrategy string `json:"sampling-strategy,omitempty"`
+	// SchedulePauseWindows are the daily time windows in which schedulers
+	// stop scheduling the namespace.
+	SchedulePauseWindows []typeutil.TimeWindow `json:"schedule-pause-windows,omitempty"`
+	// MaxConcurrentOperators is the max number of coexist operators of regions
+	// in the namespace. 0 means no limit.
+	MaxConcurrentOperators uint64 `json:"max-concurrent-operators"`
+	// SpareStores are the stores kept idle as hot spares of the namespace. They
+	// receive new peers only if regions cannot be repaired otherwise.
+	SpareStores []uint64 `json:"spare-stores,omitempty"`
+	// SnapshotSpeed is the speed in MB/s that stores send and apply snapshots
+	// of regions in the namespace. It is used to estimate the time that
+	// operators take. 0 means using the default speed.
+	SnapshotSpeed float64 `json:"snapshot-speed"`
+	// EnableStoreWeightAutoTuning is the option to adjust the region weights
+	// of stores in the namespace by their capacities automatically.
+	EnableStoreWeightAutoTuning bool `json:"enable-store-weight-auto-tuning"`
+	// PlacementRules are the rules that regions in the namespace should
+	// satisfy.
+	PlacementRules []*placement.Rule `json:"placement-rules,omitempty"`
+	// MaxOperatorSteps is the max number of steps of an operator of regions
+	// in the namespace. 0 means no limit.
+	MaxOperatorSteps uint64 `json:"max-operator-steps"`
+	// ThermalSizeWeight and ThermalFlowWeight are the weights of region size
+	// and flow in the thermal scores of stores in the namespace. Both 0 means
+	// they are weighted equally.
+	ThermalSizeWeight float64 `json:"thermal-size-weight"`
+	ThermalFlowWeight float64 `json:"thermal-flow-weight"`
+	// RegionLabels are the labels of regions in the namespace. Peers of the
+	// labeled regions are placed on the stores with the same labels.
+	RegionLabels []*placement.RegionLabel `json:"region-labels,omitempty"`
+	// StoreReservedCapacities reserves the capacity of stores in bytes for
+	// future growth, keyed by store ID. The reserved capacity is not counted
+	// as available when balancing regions in the namespace.
+	StoreReservedCapacities map[uint64]uint64 `json:"store-reserved-capacities,omitempty"`
+	// AntiAffinityGroups are groups of region IDs. Regions in the same group
+	// should not have peers on the same store.
+	AntiAffinityGroups [][]uint64 `json:"anti-affinity-groups,omitempty"`
+	// StoreMaintenanceWindows are the daily time windows in which stores are
+	// under maintenance, keyed by store ID. Stores under maintenance are not
+	// the targets of scheduling in the namespace.
+	StoreMaintenanceWindows map[uint64][]typeutil.TimeWindow `json:"store-maintenance-windows,omitempty"`
+	// MaxMigrationBytesPerSec is the max bytes per second of region data moved
+	// by the operators in the namespace. Operators adding peers are delayed
+	// once it is exceeded. 0 means no limit.
+	MaxMigrationBytesPerSec uint64 `json:"max-migration-bytes-per-sec"`
+	// ReadReplicaCount is the number of read replicas of regions in the
+	// namespace. Read replicas are learners on the ReadReplicaStores, which
+	// serve reads and are not promoted to voters.
+	ReadReplicaCount  uint64   `json:"read-replica-count"`
+	ReadReplicaStores []uint64 `json:"read-replica-stores,omitempty"`
+	// EnableAdaptiveScheduleLimit is the option to adapt the region schedule
+	// limit of the namespace to the pending peers of its stores. The limit is
+	// scaled down from MaxRegionScheduleLimit as the stores have more pending
+	// peers, but never below MinRegionScheduleLimit. MaxRegionScheduleLimit
+	// is the region schedule limit if it is 0.
+	EnableAdaptiveScheduleLimit bool   `json:"enable-adaptive-schedule-limit"`
+	MinRegionScheduleLimit      uint64 `json:"min-region-schedule-limit"`
+	MaxRegionScheduleLimit      uint64 `json:"max-region-schedule-limit"`
+	// JoinThrottleTicks is the number of ticks, one every 3 seconds, over
+	// which the stores newly joining the namespace are ramped up. The peers
+	// moved onto such a store are limited to a share of the region schedule
+	// limit which grows with the ticks since it joins. 0 means no throttling.
+	JoinThrottleTicks uint64 `json:"join-throttle-ticks"`
+	// MaxRegionsPerStore is the max number of namespace region replicas a
+	// store is planned to hold, which is used to recommend the number of
+	// stores of the namespace. 0 means no cap.
+	MaxRegionsPerStore uint64 `json:"max-regions-per-store"`
+	// RegionMaxSize and RegionMaxKeys are the max approximate size in MB and
+	// the max number of keys of regions in the namespace, regions exceeding
+	// either of them should be split. 0 means the defaults of TiKV.
+	RegionMaxSize uint64 `json:"region-max-size"`
+	RegionMaxKeys uint64 `json:"region-max-keys"`
+	// ZoneLabel is the location label of the availability zones of stores in
+	// the namespace. If it is set, the replicas of regions are kept in
+	// distinct zones, and the leaders are kept in PreferredLeaderZone if it
+	// is set.
+	ZoneLabel           string `json:"zone-label"`
+	PreferredLeaderZone string `json:"preferred-leader-zone"`
+	// MergeDirection is the preferred direction to merge regions in the
+	// namespace, which is "left" to merge into the previous region, or
+	// "right" to merge into the next one. No direction is preferred if it is
+	// empty, and the smaller neighbor is picked. It takes no effect if one way
+	// merge is enabled, in which regions are always merged into the next one.
+	MergeDirection string `json:"merge-direction"`
+}
+
+// Validate is used to validate if some namespace configurations are right.
+func (c *NamespaceConfig) Validate() error {
+	switch c.MergeDirection {
+	case "", "left", "right":
+	default:
+		return errors.Errorf("merge-direction should be left or right, but %s", c.MergeDirection)
+	}
+	for _, w := range c.SchedulePauseWindows {
+		if err := w.Validate(); err != nil {
+			return errors.Wrap(err, "invalid schedule-pause-windows")
+		}
+	}
+	for storeID, windows := range c.StoreMaintenanceWindows {
+		for _, w := range windows {
+			if err := w.Validate(); err != nil {
+				return errors.Wrapf(err, "invalid store-maintenance-windows of store %d", storeID)
+			}
+		}
+	}
+	return nil
 }
 
 // Adjust is used to adjust the namespace configurations.
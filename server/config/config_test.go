@@ -24,6 +24,7 @@ import (
 
 	"github.com/BurntSushi/toml"
 	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/pkg/typeutil"
 	"github.com/pingcap/pd/server/core"
 	"github.com/pingcap/pd/server/kv"
 
@@ -99,6 +100,17 @@ func (s *testConfigSuite) TestValidation(c *C) {
 	c.Assert(cfg.Schedule.Validate(), IsNil)
 	cfg.Schedule.TolerantSizeRatio = -0.6
 	c.Assert(cfg.Schedule.Validate(), NotNil)
+
+	// check namespace config
+	nsCfg := &NamespaceConfig{MergeDirection: "left"}
+	c.Assert(nsCfg.Validate(), IsNil)
+	nsCfg.MergeDirection = "up"
+	c.Assert(nsCfg.Validate(), NotNil)
+	nsCfg.MergeDirection = ""
+	nsCfg.StoreMaintenanceWindows = map[uint64][]typeutil.TimeWindow{1: {{Start: "01:00", End: "03:00"}}}
+	c.Assert(nsCfg.Validate(), IsNil)
+	nsCfg.StoreMaintenanceWindows[1] = append(nsCfg.StoreMaintenanceWindows[1], typeutil.TimeWindow{Start: "1am", End: "03:00"})
+	c.Assert(nsCfg.Validate(), NotNil)
 }
 
 func (s *testConfigSuite) TestAdjust(c *C) {
@@ -27,6 +27,7 @@ import (
 	"github.com/pingcap/pd/server/core"
 	"github.com/pingcap/pd/server/kv"
 	"github.com/pingcap/pd/server/schedule"
+	"github.com/pingcap/pd/server/schedule/placement"
 )
 
 // ScheduleOption is a wrapper to access the configuration safely.
@@ -223,6 +224,262 @@ func (o *ScheduleOption) GetHotRegionScheduleLimit(name string) uint64 {
 	return o.Load().HotRegionScheduleLimit
 }
 
+// GetStoreLeaderWeight returns the leader weight of the store overwritten by
+// the namespace. The second return value reports whether it is overwritten.
+func (o *ScheduleOption) GetStoreLeaderWeight(name string, storeID uint64) (float64, bool) {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetStoreLeaderWeight(storeID)
+	}
+	return 0, false
+}
+
+// GetNamespaceMaxPendingPeerCount returns the max number of pending peers in
+// the namespace. 0 means no limit.
+func (o *ScheduleOption) GetNamespaceMaxPendingPeerCount(name string) uint64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetMaxPendingPeerCount()
+	}
+	return 0
+}
+
+// GetSamplingStrategy returns the strategy to sample regions in the namespace.
+func (o *ScheduleOption) GetSamplingStrategy(name string) core.SamplingStrategy {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetSamplingStrategy()
+	}
+	return core.UniformSampling
+}
+
+// GetSchedulePauseWindows returns the time windows in which scheduling of the
+// namespace is paused.
+func (o *ScheduleOption) GetSchedulePauseWindows(name string) []typeutil.TimeWindow {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetSchedulePauseWindows()
+	}
+	return nil
+}
+
+// GetMaxConcurrentOperators returns the max number of coexist operators of
+// regions in the namespace. 0 means no limit.
+func (o *ScheduleOption) GetMaxConcurrentOperators(name string) uint64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetMaxConcurrentOperators()
+	}
+	return 0
+}
+
+// GetSpareStores returns the IDs of the spare stores of the namespace.
+func (o *ScheduleOption) GetSpareStores(name string) []uint64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetSpareStores()
+	}
+	return nil
+}
+
+// GetSnapshotSpeed returns the speed in MB/s that stores send and apply
+// snapshots of regions in the namespace. 0 means it is not configured.
+func (o *ScheduleOption) GetSnapshotSpeed(name string) float64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetSnapshotSpeed()
+	}
+	return 0
+}
+
+// GetThermalSizeWeight returns the weight of region size in the thermal scores
+// of stores in the namespace.
+func (o *ScheduleOption) GetThermalSizeWeight(name string) float64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetThermalSizeWeight()
+	}
+	return 0
+}
+
+// GetThermalFlowWeight returns the weight of region flow in the thermal scores
+// of stores in the namespace.
+func (o *ScheduleOption) GetThermalFlowWeight(name string) float64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetThermalFlowWeight()
+	}
+	return 0
+}
+
+// GetRegionLabels returns the labels of regions in the namespace.
+func (o *ScheduleOption) GetRegionLabels(name string) []*placement.RegionLabel {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetRegionLabels()
+	}
+	return nil
+}
+
+// GetStoreReservedCapacity returns the capacity of the store reserved by the
+// namespace in bytes.
+func (o *ScheduleOption) GetStoreReservedCapacity(name string, storeID uint64) uint64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetStoreReservedCapacity(storeID)
+	}
+	return 0
+}
+
+// GetAntiAffinityGroups returns the anti-affinity groups of regions in the
+// namespace.
+func (o *ScheduleOption) GetAntiAffinityGroups(name string) [][]uint64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetAntiAffinityGroups()
+	}
+	return nil
+}
+
+// GetStoreMaintenanceWindows returns the daily maintenance windows of the store
+// in the namespace.
+func (o *ScheduleOption) GetStoreMaintenanceWindows(name string, storeID uint64) []typeutil.TimeWindow {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetStoreMaintenanceWindows(storeID)
+	}
+	return nil
+}
+
+// GetMaxMigrationBytesPerSec returns the max bytes per second of region data
+// moved in the namespace. 0 means no limit.
+func (o *ScheduleOption) GetMaxMigrationBytesPerSec(name string) uint64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetMaxMigrationBytesPerSec()
+	}
+	return 0
+}
+
+// GetReadReplicaCount returns the number of read replicas of regions in the
+// namespace.
+func (o *ScheduleOption) GetReadReplicaCount(name string) uint64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetReadReplicaCount()
+	}
+	return 0
+}
+
+// GetReadReplicaStores returns the IDs of the stores for the read replicas of
+// the namespace.
+func (o *ScheduleOption) GetReadReplicaStores(name string) []uint64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetReadReplicaStores()
+	}
+	return nil
+}
+
+// IsAdaptiveScheduleLimitEnabled returns if the region schedule limit of the
+// namespace adapts to the pending peers of its stores.
+func (o *ScheduleOption) IsAdaptiveScheduleLimitEnabled(name string) bool {
+	if n, ok := o.GetNS(name); ok {
+		return n.IsAdaptiveScheduleLimitEnabled()
+	}
+	return false
+}
+
+// GetMinRegionScheduleLimit returns the min adaptive region schedule limit of
+// the namespace.
+func (o *ScheduleOption) GetMinRegionScheduleLimit(name string) uint64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetMinRegionScheduleLimit()
+	}
+	return 0
+}
+
+// GetMaxRegionScheduleLimit returns the max adaptive region schedule limit of
+// the namespace.
+func (o *ScheduleOption) GetMaxRegionScheduleLimit(name string) uint64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetMaxRegionScheduleLimit()
+	}
+	return 0
+}
+
+// GetJoinThrottleTicks returns the number of ramp-up ticks over which the
+// stores joining the namespace are ramped up.
+func (o *ScheduleOption) GetJoinThrottleTicks(name string) uint64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetJoinThrottleTicks()
+	}
+	return 0
+}
+
+// GetMaxRegionsPerStore returns the max number of region replicas a store of
+// the namespace is planned to hold.
+func (o *ScheduleOption) GetMaxRegionsPerStore(name string) uint64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetMaxRegionsPerStore()
+	}
+	return 0
+}
+
+// GetRegionMaxSize returns the max approximate size in MB of regions in the
+// namespace.
+func (o *ScheduleOption) GetRegionMaxSize(name string) uint64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetRegionMaxSize()
+	}
+	return 0
+}
+
+// GetRegionMaxKeys returns the max number of keys of regions in the namespace.
+func (o *ScheduleOption) GetRegionMaxKeys(name string) uint64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetRegionMaxKeys()
+	}
+	return 0
+}
+
+// GetZoneLabel returns the location label of the availability zones of stores
+// in the namespace.
+func (o *ScheduleOption) GetZoneLabel(name string) string {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetZoneLabel()
+	}
+	return ""
+}
+
+// GetPreferredLeaderZone returns the availability zone which the leaders of
+// the namespace are kept in.
+func (o *ScheduleOption) GetPreferredLeaderZone(name string) string {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetPreferredLeaderZone()
+	}
+	return ""
+}
+
+// GetMergeDirectionPreference returns the preferred direction to merge regions
+// in the namespace.
+func (o *ScheduleOption) GetMergeDirectionPreference(name string) string {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetMergeDirectionPreference()
+	}
+	return ""
+}
+
+// IsStoreWeightAutoTuningEnabled returns if the region weights of stores in the
+// namespace are adjusted by their capacities automatically.
+func (o *ScheduleOption) IsStoreWeightAutoTuningEnabled(name string) bool {
+	if n, ok := o.GetNS(name); ok {
+		return n.IsStoreWeightAutoTuningEnabled()
+	}
+	return false
+}
+
+// GetPlacementRules returns the placement rules of the namespace.
+func (o *ScheduleOption) GetPlacementRules(name string) []*placement.Rule {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetPlacementRules()
+	}
+	return nil
+}
+
+// GetMaxOperatorSteps returns the max number of steps of an operator of
+// regions in the namespace. 0 means no limit.
+func (o *ScheduleOption) GetMaxOperatorSteps(name string) uint64 {
+	if n, ok := o.GetNS(name); ok {
+		return n.GetMaxOperatorSteps()
+	}
+	return 0
+}
+
 // GetStoreBalanceRate returns the balance rate of a store.
 func (o *ScheduleOption) GetStoreBalanceRate() float64 {
 	return o.Load().StoreBalanceRate
@@ -582,3 +839,171 @@ func (n *namespaceOption) GetMergeScheduleLimit() uint64 {
 func (n *namespaceOption) GetHotRegionScheduleLimit() uint64 {
 	return n.Load().HotRegionScheduleLimit
 }
+
+// GetStoreLeaderWeight returns the leader weight of the store in the namespace.
+func (n *namespaceOption) GetStoreLeaderWeight(storeID uint64) (float64, bool) {
+	weight, ok := n.Load().StoreLeaderWeights[storeID]
+	return weight, ok
+}
+
+// GetMaxPendingPeerCount returns the max number of pending peers in the namespace.
+func (n *namespaceOption) GetMaxPendingPeerCount() uint64 {
+	return n.Load().MaxPendingPeerCount
+}
+
+// GetSamplingStrategy returns the strategy to sample regions in the namespace.
+func (n *namespaceOption) GetSamplingStrategy() core.SamplingStrategy {
+	return core.StringToSamplingStrategy(n.Load().SamplingStrategy)
+}
+
+// GetSchedulePauseWindows returns the time windows in which scheduling of the
+// namespace is paused.
+func (n *namespaceOption) GetSchedulePauseWindows() []typeutil.TimeWindow {
+	return n.Load().SchedulePauseWindows
+}
+
+// GetMaxConcurrentOperators returns the max number of coexist operators of
+// regions in the namespace.
+func (n *namespaceOption) GetMaxConcurrentOperators() uint64 {
+	return n.Load().MaxConcurrentOperators
+}
+
+// GetSpareStores returns the IDs of the spare stores of the namespace.
+func (n *namespaceOption) GetSpareStores() []uint64 {
+	return n.Load().SpareStores
+}
+
+// GetSnapshotSpeed returns the speed in MB/s that stores send and apply
+// snapshots of regions in the namespace.
+func (n *namespaceOption) GetSnapshotSpeed() float64 {
+	return n.Load().SnapshotSpeed
+}
+
+// GetThermalSizeWeight returns the weight of region size in the thermal scores
+// of stores in the namespace.
+func (n *namespaceOption) GetThermalSizeWeight() float64 {
+	return n.Load().ThermalSizeWeight
+}
+
+// GetThermalFlowWeight returns the weight of region flow in the thermal scores
+// of stores in the namespace.
+func (n *namespaceOption) GetThermalFlowWeight() float64 {
+	return n.Load().ThermalFlowWeight
+}
+
+// GetRegionLabels returns the labels of regions in the namespace.
+func (n *namespaceOption) GetRegionLabels() []*placement.RegionLabel {
+	return n.Load().RegionLabels
+}
+
+// GetStoreReservedCapacity returns the reserved capacity of the store in the
+// namespace.
+func (n *namespaceOption) GetStoreReservedCapacity(storeID uint64) uint64 {
+	return n.Load().StoreReservedCapacities[storeID]
+}
+
+// GetAntiAffinityGroups returns the anti-affinity groups of regions in the
+// namespace.
+func (n *namespaceOption) GetAntiAffinityGroups() [][]uint64 {
+	return n.Load().AntiAffinityGroups
+}
+
+// GetStoreMaintenanceWindows returns the daily maintenance windows of the store
+// in the namespace.
+func (n *namespaceOption) GetStoreMaintenanceWindows(storeID uint64) []typeutil.TimeWindow {
+	return n.Load().StoreMaintenanceWindows[storeID]
+}
+
+// GetMaxMigrationBytesPerSec returns the max bytes per second of region data
+// moved in the namespace.
+func (n *namespaceOption) GetMaxMigrationBytesPerSec() uint64 {
+	return n.Load().MaxMigrationBytesPerSec
+}
+
+// GetReadReplicaCount returns the number of read replicas of regions in the
+// namespace.
+func (n *namespaceOption) GetReadReplicaCount() uint64 {
+	return n.Load().ReadReplicaCount
+}
+
+// GetReadReplicaStores returns the IDs of the stores for the read replicas of
+// the namespace.
+func (n *namespaceOption) GetReadReplicaStores() []uint64 {
+	return n.Load().ReadReplicaStores
+}
+
+// IsAdaptiveScheduleLimitEnabled returns if the region schedule limit of the
+// namespace adapts to the pending peers of its stores.
+func (n *namespaceOption) IsAdaptiveScheduleLimitEnabled() bool {
+	return n.Load().EnableAdaptiveScheduleLimit
+}
+
+// GetMinRegionScheduleLimit returns the min adaptive region schedule limit of
+// the namespace.
+func (n *namespaceOption) GetMinRegionScheduleLimit() uint64 {
+	return n.Load().MinRegionScheduleLimit
+}
+
+// GetMaxRegionScheduleLimit returns the max adaptive region schedule limit of
+// the namespace.
+func (n *namespaceOption) GetMaxRegionScheduleLimit() uint64 {
+	return n.Load().MaxRegionScheduleLimit
+}
+
+// GetJoinThrottleTicks returns the number of ramp-up ticks over which the
+// stores joining the namespace are ramped up.
+func (n *namespaceOption) GetJoinThrottleTicks() uint64 {
+	return n.Load().JoinThrottleTicks
+}
+
+// GetMaxRegionsPerStore returns the max number of region replicas a store of
+// the namespace is planned to hold.
+func (n *namespaceOption) GetMaxRegionsPerStore() uint64 {
+	return n.Load().MaxRegionsPerStore
+}
+
+// GetRegionMaxSize returns the max approximate size in MB of regions in the
+// namespace.
+func (n *namespaceOption) GetRegionMaxSize() uint64 {
+	return n.Load().RegionMaxSize
+}
+
+// GetRegionMaxKeys returns the max number of keys of regions in the namespace.
+func (n *namespaceOption) GetRegionMaxKeys() uint64 {
+	return n.Load().RegionMaxKeys
+}
+
+// GetZoneLabel returns the location label of the availability zones of stores
+// in the namespace.
+func (n *namespaceOption) GetZoneLabel() string {
+	return n.Load().ZoneLabel
+}
+
+// GetPreferredLeaderZone returns the availability zone which the leaders of
+// the namespace are kept in.
+func (n *namespaceOption) GetPreferredLeaderZone() string {
+	return n.Load().PreferredLeaderZone
+}
+
+// GetMergeDirectionPreference returns the preferred direction to merge regions
+// in the namespace.
+func (n *namespaceOption) GetMergeDirectionPreference() string {
+	return n.Load().MergeDirection
+}
+
+// IsStoreWeightAutoTuningEnabled returns if the region weights of stores in the
+// namespace are adjusted by their capacities automatically.
+func (n *namespaceOption) IsStoreWeightAutoTuningEnabled() bool {
+	return n.Load().EnableStoreWeightAutoTuning
+}
+
+// GetPlacementRules returns the placement rules of the namespace.
+func (n *namespaceOption) GetPlacementRules() []*placement.Rule {
+	return n.Load().PlacementRules
+}
+
+// GetMaxOperatorSteps returns the max number of steps of an operator of
+// regions in the namespace. 0 means no limit.
+func (n *namespaceOption) GetMaxOperatorSteps() uint64 {
+	return n.Load().MaxOperatorSteps
+}
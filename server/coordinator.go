@@ -41,6 +41,12 @@ const (
 	hotRegionScheduleName      = "balance-hot-region-scheduler"
 
 	patrolScanRegionLimit = 128 // It takes about 14 minutes to iterate 1 million regions.
+
+	namespaceScatterInterval     = time.Second
+	namespaceEvictLeaderInterval = 100 * time.Millisecond
+	namespaceJoinTickInterval    = 3 * time.Second
+	namespaceWeightTuneInterval  = 3 * time.Second
+	namespaceFairnessInterval    = 3 * time.Second
 )
 
 var (
@@ -68,6 +74,7 @@ type coordinator struct {
 func newCoordinator(ctx context.Context, cluster *RaftCluster, hbStreams *heartbeatStreams, classifier namespace.Classifier) *coordinator {
 	ctx, cancel := context.WithCancel(ctx)
 	opController := schedule.NewOperatorController(ctx, cluster, hbStreams)
+	opController.SetNamespaceClassifier(classifier)
 	return &coordinator{
 		ctx:             ctx,
 		cancel:          cancel,
@@ -134,6 +141,86 @@ func (c *coordinator) patrolRegions() {
 	}
 }
 
+// runNamespaceJobs is used to scatter the regions queued by the incremental
+// scatter of namespaces, to evict the leaders out of the namespace stores in
+// upgrade mode or shutting down, and to run the per-round jobs of namespaces
+// such as the store weight auto tuning.
+func (c *coordinator) runNamespaceJobs() {
+	defer logutil.LogPanic()
+
+	defer c.wg.Done()
+	scatterTicker := time.NewTicker(namespaceScatterInterval)
+	defer scatterTicker.Stop()
+	evictTicker := time.NewTicker(namespaceEvictLeaderInterval)
+	defer evictTicker.Stop()
+	joinTicker := time.NewTicker(namespaceJoinTickInterval)
+	defer joinTicker.Stop()
+	tuneTicker := time.NewTicker(namespaceWeightTuneInterval)
+	defer tuneTicker.Stop()
+	fairnessTicker := time.NewTicker(namespaceFairnessInterval)
+	defer fairnessTicker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			log.Info("namespace jobs have been stopped")
+			return
+		case <-scatterTicker.C:
+			c.scatterNamespaces()
+		case <-evictTicker.C:
+			c.evictNamespaceLeaders()
+		case <-joinTicker.C:
+			c.tickNamespaceStoreJoins()
+		case <-tuneTicker.C:
+			c.cluster.tuneNamespaceStoreWeights(c.classifier)
+		case <-fairnessTicker.C:
+			c.tickNamespaceFairness()
+		}
+	}
+}
+
+// scatterNamespaces scatters the regions queued by the incremental scatter of
+// each namespace. The namespaces without queued regions are skipped.
+func (c *coordinator) scatterNamespaces() {
+	for _, name := range c.classifier.GetAllNamespaces() {
+		if !c.cluster.getNamespaceState(name).hasScatterRegions() {
+			continue
+		}
+		nc := newNamespaceCluster(c.cluster, c.classifier, name)
+		// The operators are of different regions, so they are added one by
+		// one instead of as a merge pair.
+		for _, op := range nc.ScatterTick(c.regionScatterer) {
+			c.opController.AddWaitingOperator(op)
+		}
+	}
+}
+
+// tickNamespaceStoreJoins records a ramp-up tick for the stores joining each
+// namespace.
+func (c *coordinator) tickNamespaceStoreJoins() {
+	for _, name := range c.classifier.GetAllNamespaces() {
+		c.cluster.getNamespaceState(name).tickStoreJoins()
+	}
+}
+
+// tickNamespaceFairness ends the scheduling round of each namespace for the
+// scheduling fairness.
+func (c *coordinator) tickNamespaceFairness() {
+	for _, name := range c.classifier.GetAllNamespaces() {
+		c.cluster.getNamespaceState(name).tickFairness()
+	}
+}
+
+// evictNamespaceLeaders evicts the leaders out of the namespace stores in
+// upgrade mode or shutting down within the leader schedule limit.
+func (c *coordinator) evictNamespaceLeaders() {
+	if c.opController.OperatorCount(operator.OpLeader) >= c.cluster.GetLeaderScheduleLimit() {
+		return
+	}
+	for _, op := range evictNamespaceLeaders(c.cluster, c.classifier) {
+		c.opController.AddWaitingOperator(op)
+	}
+}
+
 // drivePushOperator is used to push the unfinished operator to the excutor.
 func (c *coordinator) drivePushOperator() {
 	defer logutil.LogPanic()
@@ -250,10 +337,11 @@ func (c *coordinator) run() {
 		log.Error("cannot persist schedule config", zap.Error(err))
 	}
 
-	c.wg.Add(2)
+	c.wg.Add(3)
 	// Starts to patrol regions.
 	go c.patrolRegions()
 	go c.drivePushOperator()
+	go c.runNamespaceJobs()
 }
 
 func (c *coordinator) stop() {
@@ -446,8 +534,8 @@ func (c *coordinator) runScheduler(s *scheduleController) {
 			if !s.AllowSchedule() {
 				continue
 			}
-			if op := s.Schedule(); op != nil {
-				c.opController.AddWaitingOperator(op...)
+			if op := s.Schedule(); op != nil && c.opController.AddWaitingOperator(op...) {
+				c.cluster.recordNamespaceStoreJoinReceived(c.classifier, op)
 			}
 
 		case <-s.Ctx().Done():
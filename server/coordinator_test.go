@@ -122,6 +122,24 @@ func (c *testCluster) updateLeaderCount(storeID uint64, leaderCount int) error {
 	return c.putStoreLocked(newStore)
 }
 
+func (c *testCluster) updateStore(storeID uint64, opts ...core.StoreCreateOption) error {
+	newStore := c.GetStore(storeID).Clone(opts...)
+	c.Lock()
+	defer c.Unlock()
+	return c.putStoreLocked(newStore)
+}
+
+func (c *testCluster) addLabelsStore(storeID uint64, regionCount int, labels map[string]string) error {
+	if err := c.addRegionStore(storeID, regionCount); err != nil {
+		return err
+	}
+	var storeLabels []*metapb.StoreLabel
+	for k, v := range labels {
+		storeLabels = append(storeLabels, &metapb.StoreLabel{Key: k, Value: v})
+	}
+	return c.updateStore(storeID, core.SetStoreLabels(storeLabels))
+}
+
 func (c *testCluster) addLeaderStore(storeID uint64, leaderCount int) error {
 	stats := &pdpb.StoreStats{}
 	newStore := core.NewStoreInfo(&metapb.Store{Id: storeID},
@@ -58,6 +58,29 @@ func (k ResourceKind) String() string {
 	}
 }
 
+// PeerState distinguishes different states of peers reported by region
+// heartbeats.
+type PeerState int
+
+const (
+	// PeerPending indicates the peer is pending, e.g. it is receiving or
+	// applying a snapshot.
+	PeerPending PeerState = iota
+	// PeerDown indicates the peer is down.
+	PeerDown
+)
+
+func (s PeerState) String() string {
+	switch s {
+	case PeerPending:
+		return "pending"
+	case PeerDown:
+		return "down"
+	default:
+		return "unknown"
+	}
+}
+
 // ScheduleStrategy distinguishes different kinds of schedule strategy
 type ScheduleStrategy int
 
@@ -90,3 +113,43 @@ func StringToScheduleStrategy(input string) ScheduleStrategy {
 		panic("invalid schedule strategy: " + input)
 	}
 }
+
+// SamplingStrategy distinguishes different strategies to sample regions.
+type SamplingStrategy int
+
+const (
+	// UniformSampling indicates that regions are sampled uniformly.
+	UniformSampling SamplingStrategy = iota
+	// SizeWeightedSampling indicates that regions are sampled in proportion to
+	// their approximate size.
+	SizeWeightedSampling
+	// FlowWeightedSampling indicates that regions are sampled in proportion to
+	// their read and written bytes.
+	FlowWeightedSampling
+)
+
+func (s SamplingStrategy) String() string {
+	switch s {
+	case UniformSampling:
+		return "uniform"
+	case SizeWeightedSampling:
+		return "size-weighted"
+	case FlowWeightedSampling:
+		return "flow-weighted"
+	default:
+		return "unknown"
+	}
+}
+
+// StringToSamplingStrategy creates a sampling strategy with string. It falls
+// back to UniformSampling if the input is empty or unknown.
+func StringToSamplingStrategy(input string) SamplingStrategy {
+	switch input {
+	case SizeWeightedSampling.String():
+		return SizeWeightedSampling
+	case FlowWeightedSampling.String():
+		return FlowWeightedSampling
+	default:
+		return UniformSampling
+	}
+}
@@ -37,6 +37,8 @@ const (
 	rulesPath    = "rules"
 
 	customScheduleConfigPath = "scheduler_config"
+	namespaceBlacklistPath   = "namespace_blacklist"
+	namespaceStoreWeightPath = "namespace_store_weight"
 )
 
 const (
@@ -333,6 +335,68 @@ func (s *Storage) LoadAllScheduleConfig() ([]string, []string, error) {
 	return keys, values, err
 }
 
+// SaveNamespaceStoreBlacklist saves the store blacklist of the namespace.
+func (s *Storage) SaveNamespaceStoreBlacklist(namespace string, storeIDs []uint64) error {
+	value, err := json.Marshal(storeIDs)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(path.Join(schedulePath, namespaceBlacklistPath, namespace), string(value))
+}
+
+// LoadNamespaceStoreBlacklists loads the store blacklists of all namespaces.
+func (s *Storage) LoadNamespaceStoreBlacklists() (map[string][]uint64, error) {
+	prefix := path.Join(schedulePath, namespaceBlacklistPath) + "/"
+	keys, values, err := s.LoadRange(prefix, clientv3.GetPrefixRangeEnd(prefix), maxKVRangeLimit)
+	if err != nil {
+		return nil, err
+	}
+	blacklists := make(map[string][]uint64, len(keys))
+	for i, key := range keys {
+		var storeIDs []uint64
+		if err := json.Unmarshal([]byte(values[i]), &storeIDs); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		blacklists[strings.TrimPrefix(key, prefix)] = storeIDs
+	}
+	return blacklists, nil
+}
+
+// StoreWeight is the leader and region weights of a store. A zero weight means
+// it is not set.
+type StoreWeight struct {
+	LeaderWeight float64 `json:"leader-weight"`
+	RegionWeight float64 `json:"region-weight"`
+}
+
+// SaveNamespaceStoreWeights saves the store weights of the namespace, keyed by
+// store ID.
+func (s *Storage) SaveNamespaceStoreWeights(namespace string, weights map[uint64]StoreWeight) error {
+	value, err := json.Marshal(weights)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(path.Join(schedulePath, namespaceStoreWeightPath, namespace), string(value))
+}
+
+// LoadNamespaceStoreWeights loads the store weights of all namespaces.
+func (s *Storage) LoadNamespaceStoreWeights() (map[string]map[uint64]StoreWeight, error) {
+	prefix := path.Join(schedulePath, namespaceStoreWeightPath) + "/"
+	keys, values, err := s.LoadRange(prefix, clientv3.GetPrefixRangeEnd(prefix), maxKVRangeLimit)
+	if err != nil {
+		return nil, err
+	}
+	weights := make(map[string]map[uint64]StoreWeight, len(keys))
+	for i, key := range keys {
+		var w map[uint64]StoreWeight
+		if err := json.Unmarshal([]byte(values[i]), &w); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		weights[strings.TrimPrefix(key, prefix)] = w
+	}
+	return weights, nil
+}
+
 func loadProto(s kv.Base, key string, msg proto.Message) (bool, error) {
 	value, err := s.Load(key)
 	if err != nil {
@@ -194,3 +194,34 @@ func newTestRegionMeta(regionID uint64) *metapb.Region {
 		EndKey:   []byte(fmt.Sprintf("%20d", regionID+1)),
 	}
 }
+
+func (s *testKVSuite) TestNamespaceStoreBlacklist(c *C) {
+	storage := NewStorage(kv.NewMemoryKV())
+	blacklists, err := storage.LoadNamespaceStoreBlacklists()
+	c.Assert(err, IsNil)
+	c.Assert(blacklists, HasLen, 0)
+
+	c.Assert(storage.SaveNamespaceStoreBlacklist("ns1", []uint64{1, 2}), IsNil)
+	c.Assert(storage.SaveNamespaceStoreBlacklist("ns2", []uint64{3}), IsNil)
+	c.Assert(storage.SaveNamespaceStoreBlacklist("ns1", []uint64{2}), IsNil)
+	blacklists, err = storage.LoadNamespaceStoreBlacklists()
+	c.Assert(err, IsNil)
+	c.Assert(blacklists, DeepEquals, map[string][]uint64{"ns1": {2}, "ns2": {3}})
+}
+
+func (s *testKVSuite) TestNamespaceStoreWeights(c *C) {
+	storage := NewStorage(kv.NewMemoryKV())
+	weights, err := storage.LoadNamespaceStoreWeights()
+	c.Assert(err, IsNil)
+	c.Assert(weights, HasLen, 0)
+
+	c.Assert(storage.SaveNamespaceStoreWeights("ns1", map[uint64]StoreWeight{1: {LeaderWeight: 2}}), IsNil)
+	c.Assert(storage.SaveNamespaceStoreWeights("ns2", map[uint64]StoreWeight{3: {RegionWeight: 0.5}}), IsNil)
+	c.Assert(storage.SaveNamespaceStoreWeights("ns1", map[uint64]StoreWeight{2: {LeaderWeight: 1, RegionWeight: 3}}), IsNil)
+	weights, err = storage.LoadNamespaceStoreWeights()
+	c.Assert(err, IsNil)
+	c.Assert(weights, DeepEquals, map[string]map[uint64]StoreWeight{
+		"ns1": {2: {LeaderWeight: 1, RegionWeight: 3}},
+		"ns2": {3: {RegionWeight: 0.5}},
+	})
+}
@@ -221,6 +221,40 @@ func (h *Handler) AddBalanceRegionScheduler() error {
 	return h.AddScheduler("balance-region")
 }
 
+// AddBalanceNamespaceFlowScheduler adds a balance-namespace-flow-scheduler.
+func (h *Handler) AddBalanceNamespaceFlowScheduler() error {
+	return h.AddScheduler("balance-namespace-flow")
+}
+
+// AddBalanceNamespaceThermalScheduler adds a balance-namespace-thermal-scheduler.
+func (h *Handler) AddBalanceNamespaceThermalScheduler() error {
+	return h.AddScheduler("balance-namespace-thermal")
+}
+
+// AddBalanceNamespaceDomainScheduler adds a balance-namespace-domain-scheduler.
+func (h *Handler) AddBalanceNamespaceDomainScheduler() error {
+	return h.AddScheduler("balance-namespace-domain")
+}
+
+// AddBalanceNamespaceLeaderRatioScheduler adds a balance-namespace-leader-ratio-scheduler.
+func (h *Handler) AddBalanceNamespaceLeaderRatioScheduler() error {
+	return h.AddScheduler("balance-namespace-leader-ratio")
+}
+
+// AddOptimizeNamespaceBalanceScheduler adds an optimize-namespace-balance-scheduler
+// with the objective, or the default one if it is empty.
+func (h *Handler) AddOptimizeNamespaceBalanceScheduler(objective string) error {
+	if objective == "" {
+		return h.AddScheduler("optimize-namespace-balance")
+	}
+	return h.AddScheduler("optimize-namespace-balance", objective)
+}
+
+// AddPopulateNamespaceStoreScheduler adds a populate-namespace-store-scheduler.
+func (h *Handler) AddPopulateNamespaceStoreScheduler() error {
+	return h.AddScheduler("populate-namespace-store")
+}
+
 // AddBalanceHotRegionScheduler adds a balance-hot-region-scheduler.
 func (h *Handler) AddBalanceHotRegionScheduler() error {
 	return h.AddScheduler("hot-region")
@@ -266,6 +300,22 @@ func (h *Handler) AddShuffleHotRegionScheduler(limit uint64) error {
 	return h.AddScheduler("shuffle-hot-region", strconv.FormatUint(limit, 10))
 }
 
+// AddShuffleNamespaceLeaderScheduler adds a shuffle-namespace-leader-scheduler.
+func (h *Handler) AddShuffleNamespaceLeaderScheduler(limit uint64) error {
+	return h.AddScheduler("shuffle-namespace-leader", strconv.FormatUint(limit, 10))
+}
+
+// AddNamespaceAdjacentRegionScheduler adds a namespace-adjacent-region-scheduler.
+func (h *Handler) AddNamespaceAdjacentRegionScheduler(limit uint64) error {
+	return h.AddScheduler("namespace-adjacent-region", strconv.FormatUint(limit, 10))
+}
+
+// AddSchedulerChain adds a scheduler chain which tries the schedulers of the
+// types in order.
+func (h *Handler) AddSchedulerChain(types ...string) error {
+	return h.AddScheduler(schedulerChainType, types...)
+}
+
 // AddRandomMergeScheduler adds a random-merge-scheduler.
 func (h *Handler) AddRandomMergeScheduler() error {
 	return h.AddScheduler("random-merge")
@@ -458,7 +508,11 @@ func (h *Handler) AddTransferRegionOperator(regionID uint64, storeIDs map[uint64
 	if err != nil {
 		return err
 	}
-	if ok := c.opController.AddOperator(op); !ok {
+	if err := validateNamespaceOperator(c, region, op); err != nil {
+		return err
+	}
+	// The pending operators of schedulers are canceled for the manual move.
+	if ok := c.opController.CancelAndAddOperator(op); !ok {
 		return errors.WithStack(ErrAddOperator)
 	}
 	return nil
@@ -498,12 +552,23 @@ func (h *Handler) AddTransferPeerOperator(regionID uint64, fromStoreID, toStoreI
 	if err != nil {
 		return err
 	}
-	if ok := c.opController.AddOperator(op); !ok {
+	if err := validateNamespaceOperator(c, region, op); err != nil {
+		return err
+	}
+	// The pending operators of schedulers are canceled for the manual move.
+	if ok := c.opController.CancelAndAddOperator(op); !ok {
 		return errors.WithStack(ErrAddOperator)
 	}
 	return nil
 }
 
+// validateNamespaceOperator checks if the operator keeps the peers of the
+// region within the namespace of the region.
+func validateNamespaceOperator(c *coordinator, region *core.RegionInfo, op *operator.Operator) error {
+	ns := c.classifier.GetRegionNamespace(region)
+	return newNamespaceCluster(c.cluster, c.classifier, ns).ValidateOperator(op)
+}
+
 // checkAdminAddPeerOperator checks adminAddPeer operator with given region ID and store ID.
 func (h *Handler) checkAdminAddPeerOperator(regionID uint64, toStoreID uint64) (*coordinator, *core.RegionInfo, error) {
 	c, err := h.getCoordinator()
@@ -544,6 +609,9 @@ func (h *Handler) AddAddPeerOperator(regionID uint64, toStoreID uint64) error {
 	}
 
 	op := operator.CreateAddPeerOperator("admin-add-peer", region, newPeer.GetId(), toStoreID, operator.OpAdmin)
+	if err := validateNamespaceOperator(c, region, op); err != nil {
+		return err
+	}
 	if ok := c.opController.AddOperator(op); !ok {
 		return errors.WithStack(ErrAddOperator)
 	}
@@ -563,6 +631,9 @@ func (h *Handler) AddAddLearnerOperator(regionID uint64, toStoreID uint64) error
 	}
 
 	op := operator.CreateAddLearnerOperator("admin-add-learner", region, newPeer.GetId(), toStoreID, operator.OpAdmin)
+	if err := validateNamespaceOperator(c, region, op); err != nil {
+		return err
+	}
 	if ok := c.opController.AddOperator(op); !ok {
 		return errors.WithStack(ErrAddOperator)
 	}
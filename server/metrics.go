@@ -73,6 +73,22 @@ var (
 			Help:      "Status of the hotspot.",
 		}, []string{"address", "store", "type"})
 
+	namespaceStatusGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "namespace",
+			Name:      "status",
+			Help:      "Status of the namespace.",
+		}, []string{"namespace", "type"})
+
+	namespaceDecommissionGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "namespace",
+			Name:      "decommission_progress",
+			Help:      "Progress of decommissioning stores of the namespace.",
+		}, []string{"namespace", "store"})
+
 	metadataGauge = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "pd",
@@ -115,6 +131,8 @@ func init() {
 	prometheus.MustRegister(regionEventCounter)
 	prometheus.MustRegister(regionHeartbeatLatency)
 	prometheus.MustRegister(hotSpotStatusGauge)
+	prometheus.MustRegister(namespaceStatusGauge)
+	prometheus.MustRegister(namespaceDecommissionGauge)
 	prometheus.MustRegister(metadataGauge)
 	prometheus.MustRegister(etcdStateGauge)
 	prometheus.MustRegister(patrolCheckRegionsHistogram)
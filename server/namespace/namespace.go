@@ -16,8 +16,10 @@ package namespace
 import (
 	"fmt"
 
+	"github.com/pingcap/pd/pkg/typeutil"
 	"github.com/pingcap/pd/server/core"
 	"github.com/pingcap/pd/server/id"
+	"github.com/pingcap/pd/server/schedule/placement"
 	"github.com/pkg/errors"
 )
 
@@ -32,6 +34,35 @@ type ScheduleOptions interface {
 	GetReplicaScheduleLimit(name string) uint64
 	GetMergeScheduleLimit(name string) uint64
 	GetMaxReplicas(name string) int
+	GetStoreLeaderWeight(name string, storeID uint64) (float64, bool)
+	GetNamespaceMaxPendingPeerCount(name string) uint64
+	GetSamplingStrategy(name string) core.SamplingStrategy
+	GetSchedulePauseWindows(name string) []typeutil.TimeWindow
+	GetMaxConcurrentOperators(name string) uint64
+	GetSpareStores(name string) []uint64
+	GetSnapshotSpeed(name string) float64
+	GetThermalSizeWeight(name string) float64
+	GetThermalFlowWeight(name string) float64
+	GetRegionLabels(name string) []*placement.RegionLabel
+	GetStoreReservedCapacity(name string, storeID uint64) uint64
+	GetAntiAffinityGroups(name string) [][]uint64
+	GetStoreMaintenanceWindows(name string, storeID uint64) []typeutil.TimeWindow
+	GetMaxMigrationBytesPerSec(name string) uint64
+	GetReadReplicaCount(name string) uint64
+	GetReadReplicaStores(name string) []uint64
+	IsAdaptiveScheduleLimitEnabled(name string) bool
+	GetMinRegionScheduleLimit(name string) uint64
+	GetMaxRegionScheduleLimit(name string) uint64
+	GetJoinThrottleTicks(name string) uint64
+	GetMaxRegionsPerStore(name string) uint64
+	GetRegionMaxSize(name string) uint64
+	GetRegionMaxKeys(name string) uint64
+	GetZoneLabel(name string) string
+	GetPreferredLeaderZone(name string) string
+	GetMergeDirectionPreference(name string) string
+	IsStoreWeightAutoTuningEnabled(name string) bool
+	GetPlacementRules(name string) []*placement.Rule
+	GetMaxOperatorSteps(name string) uint64
 }
 
 // DefaultClassifier is a classifier that classifies all regions and stores to
@@ -54,6 +85,15 @@ type Classifier interface {
 	IsStoreIDExist(uint64) bool
 }
 
+// BoundaryClassifier is implemented by classifiers which define namespaces by
+// key ranges, so that regions whose key ranges straddle the boundaries of
+// namespaces can be detected.
+type BoundaryClassifier interface {
+	// IsCrossBoundary returns true if the keys of the region belong to more
+	// than one namespace.
+	IsCrossBoundary(*core.RegionInfo) bool
+}
+
 type defaultClassifier struct{}
 
 func (c defaultClassifier) GetAllNamespaces() []string {
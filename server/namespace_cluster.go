@@ -14,14 +14,28 @@
 package server
 
 import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
 	"math/rand"
+	"sort"
+	"time"
 
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/pkg/typeutil"
 	"github.com/pingcap/pd/server/core"
 	"github.com/pingcap/pd/server/namespace"
 	"github.com/pingcap/pd/server/schedule"
+	"github.com/pingcap/pd/server/schedule/filter"
 	"github.com/pingcap/pd/server/schedule/operator"
 	"github.com/pingcap/pd/server/schedule/opt"
+	"github.com/pingcap/pd/server/schedule/placement"
 	"github.com/pingcap/pd/server/statistics"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
 )
 
 // namespaceCluster is part of a global cluster that contains stores and regions
@@ -31,23 +45,73 @@ type namespaceCluster struct {
 	classifier namespace.Classifier
 	namespace  string
 	stores     map[uint64]*core.StoreInfo
+	// SamplingStrategy decides how RandLeaderRegion and RandFollowerRegion
+	// sample regions.
+	SamplingStrategy core.SamplingStrategy
+	states           *namespaceStates
+	state            *namespaceState
 }
 
 func newNamespaceCluster(c opt.Cluster, classifier namespace.Classifier, namespace string) *namespaceCluster {
+	states := getNamespaceStates(c)
+	state := states.get(namespace)
+	autoTuning := c.GetOpt().IsStoreWeightAutoTuningEnabled(namespace)
 	stores := make(map[uint64]*core.StoreInfo)
 	for _, s := range c.GetStores() {
 		if classifier.GetStoreNamespace(s) == namespace {
+			if weight, ok := c.GetOpt().GetStoreLeaderWeight(namespace, s.GetID()); ok {
+				s = s.Clone(core.SetLeaderWeight(weight))
+			}
+			if weight, ok := state.getPersistedStoreWeight(s.GetID()); ok {
+				s = applyStoreWeight(s, weight)
+			}
+			if weight, ok := state.getStoreWeight(s.GetID()); ok && autoTuning {
+				s = s.Clone(core.SetRegionWeight(weight))
+			}
+			if reserved := c.GetOpt().GetStoreReservedCapacity(namespace, s.GetID()); reserved > 0 && s.GetStoreStats() != nil {
+				stats := *s.GetStoreStats()
+				if stats.Available > reserved {
+					stats.Available -= reserved
+				} else {
+					stats.Available = 0
+				}
+				s = s.Clone(core.SetStoreStats(&stats))
+			}
 			stores[s.GetID()] = s
 		}
 	}
 	return &namespaceCluster{
-		Cluster:    c,
-		classifier: classifier,
-		namespace:  namespace,
-		stores:     stores,
+		Cluster:          c,
+		classifier:       classifier,
+		namespace:        namespace,
+		stores:           stores,
+		SamplingStrategy: c.GetOpt().GetSamplingStrategy(namespace),
+		states:           states,
+		state:            state,
 	}
 }
 
+// applyStoreWeight overwrites the weights of the store by the ones set.
+func applyStoreWeight(store *core.StoreInfo, weight core.StoreWeight) *core.StoreInfo {
+	var opts []core.StoreCreateOption
+	if weight.LeaderWeight > 0 {
+		opts = append(opts, core.SetLeaderWeight(weight.LeaderWeight))
+	}
+	if weight.RegionWeight > 0 {
+		opts = append(opts, core.SetRegionWeight(weight.RegionWeight))
+	}
+	return store.Clone(opts...)
+}
+
+// getNamespaceStates returns the states of namespaces kept by the cluster. A
+// new one is returned if the cluster does not keep them.
+func getNamespaceStates(c opt.Cluster) *namespaceStates {
+	if p, ok := c.(namespaceStateProvider); ok {
+		return p.getNamespaceStates()
+	}
+	return newNamespaceStates()
+}
+
 func (c *namespaceCluster) checkRegion(region *core.RegionInfo) bool {
 	if c.classifier.GetRegionNamespace(region) != c.namespace {
 		return false
@@ -62,24 +126,84 @@ func (c *namespaceCluster) checkRegion(region *core.RegionInfo) bool {
 
 const randRegionMaxRetry = 10
 
+// Stores labeled with the TiFlash engine are not able to hold leaders.
+const (
+	engineLabel   = "engine"
+	tiflashEngine = "tiflash"
+)
+
 // RandFollowerRegion returns a random region that has a follower on the store.
 func (c *namespaceCluster) RandFollowerRegion(storeID uint64, opts ...core.RegionOption) *core.RegionInfo {
+	return c.sampleRegion(func() *core.RegionInfo {
+		return c.Cluster.RandFollowerRegion(storeID, opts...)
+	})
+}
+
+// RandLeaderRegion returns a random region that has leader on the store.
+func (c *namespaceCluster) RandLeaderRegion(storeID uint64, opts ...core.RegionOption) *core.RegionInfo {
+	return c.sampleRegion(func() *core.RegionInfo {
+		return c.Cluster.RandLeaderRegion(storeID, opts...)
+	})
+}
+
+// sampleRegion samples a region in the namespace with the sampling strategy.
+// For uniform sampling, the first region in the namespace returned by sample is
+// picked. Otherwise, up to randRegionMaxRetry regions are drawn from sample,
+// and one of those in the namespace is picked with probability in proportion
+// to its weight.
+func (c *namespaceCluster) sampleRegion(sample func() *core.RegionInfo) *core.RegionInfo {
+	var (
+		picked      *core.RegionInfo
+		totalWeight float64
+	)
 	for i := 0; i < randRegionMaxRetry; i++ {
-		r := c.Cluster.RandFollowerRegion(storeID, opts...)
+		r := sample()
 		if r == nil {
-			return nil
+			break
 		}
-		if c.checkRegion(r) {
+		if !c.checkRegion(r) {
+			continue
+		}
+		if c.SamplingStrategy == core.UniformSampling {
 			return r
 		}
+		weight := c.regionWeight(r)
+		totalWeight += weight
+		if rand.Float64()*totalWeight < weight {
+			picked = r
+		}
 	}
-	return nil
+	return picked
 }
 
-// RandLeaderRegion returns a random region that has leader on the store.
-func (c *namespaceCluster) RandLeaderRegion(storeID uint64, opts ...core.RegionOption) *core.RegionInfo {
+// regionWeight returns the weight of the region used by weighted sampling.
+// The weight is at least 1 so that every region has a chance to be picked.
+func (c *namespaceCluster) regionWeight(region *core.RegionInfo) float64 {
+	var weight float64
+	switch c.SamplingStrategy {
+	case core.SizeWeightedSampling:
+		weight = float64(region.GetApproximateSize())
+	case core.FlowWeightedSampling:
+		weight = float64(region.GetBytesRead() + region.GetBytesWritten())
+	}
+	return math.Max(weight, 1)
+}
+
+// RandRegionByPeerState returns a random region that has a peer in the state
+// on the store.
+func (c *namespaceCluster) RandRegionByPeerState(storeID uint64, state core.PeerState, opts ...core.RegionOption) *core.RegionInfo {
+	opts = append(opts[:len(opts):len(opts)], func(region *core.RegionInfo) bool {
+		return hasPeerInState(region, storeID, state)
+	})
 	for i := 0; i < randRegionMaxRetry; i++ {
-		r := c.Cluster.RandLeaderRegion(storeID, opts...)
+		var r *core.RegionInfo
+		switch state {
+		case core.PeerPending:
+			r = c.Cluster.RandPendingRegion(storeID, opts...)
+		case core.PeerDown:
+			// Leader peer can never be down.
+			r = c.Cluster.RandFollowerRegion(storeID, opts...)
+		}
 		if r == nil {
 			return nil
 		}
@@ -90,6 +214,36 @@ func (c *namespaceCluster) RandLeaderRegion(storeID uint64, opts ...core.RegionO
 	return nil
 }
 
+func hasPeerInState(region *core.RegionInfo, storeID uint64, state core.PeerState) bool {
+	peer := region.GetStorePeer(storeID)
+	if peer == nil {
+		return false
+	}
+	switch state {
+	case core.PeerPending:
+		return region.GetPendingPeer(peer.GetId()) != nil
+	case core.PeerDown:
+		return region.GetDownPeer(peer.GetId()) != nil
+	}
+	return false
+}
+
+// GetLearnerProgress returns how close the learner of the region on the store
+// has caught up with the leader, ranging from 0 to 1. Region heartbeats do not
+// carry raft indexes of peers, so a learner reported as pending or down by the
+// leader is regarded as not caught up at all, and others as fully caught up.
+// It returns 0 if the region is not in the namespace or has no learner on the
+// store.
+func (c *namespaceCluster) GetLearnerProgress(region *core.RegionInfo, storeID uint64) float64 {
+	if !c.checkRegion(region) || region.GetStoreLearner(storeID) == nil {
+		return 0
+	}
+	if hasPeerInState(region, storeID, core.PeerPending) || hasPeerInState(region, storeID, core.PeerDown) {
+		return 0
+	}
+	return 1
+}
+
 // GetAverageRegionSize returns the average region approximate size.
 func (c *namespaceCluster) GetAverageRegionSize() int64 {
 	var totalCount, totalSize int64
@@ -117,7 +271,326 @@ func (c *namespaceCluster) GetStore(id uint64) *core.StoreInfo {
 	return c.stores[id]
 }
 
+// GetStoreReservedCapacity returns the capacity of the store reserved by the
+// namespace in bytes. The available size of the namespace stores excludes it,
+// so that balance treats a store with a reservation as fuller than its raw
+// usage.
+func (c *namespaceCluster) GetStoreReservedCapacity(storeID uint64) uint64 {
+	return c.GetOpt().GetStoreReservedCapacity(c.namespace, storeID)
+}
+
+// GetStoreLeaderWeight returns the leader weight of the store for leaders in
+// the namespace. It defaults to the store's global leader weight if the
+// namespace does not overwrite it, by the weights persisted or configured.
+func (c *namespaceCluster) GetStoreLeaderWeight(storeID uint64) float64 {
+	if weight, ok := c.state.getPersistedStoreWeight(storeID); ok && weight.LeaderWeight > 0 {
+		return weight.LeaderWeight
+	}
+	if weight, ok := c.GetOpt().GetStoreLeaderWeight(c.namespace, storeID); ok {
+		return weight
+	}
+	if s := c.Cluster.GetStore(storeID); s != nil {
+		return s.GetLeaderWeight()
+	}
+	return 0
+}
+
+// GetLeaderEligibleStores returns the stores in the namespace which are able to
+// hold leaders. Stores with the reject-leader label property or the TiFlash
+// engine label are excluded, as well as stores in upgrade mode or shutting
+// down.
+func (c *namespaceCluster) GetLeaderEligibleStores() []*core.StoreInfo {
+	stores := make([]*core.StoreInfo, 0, len(c.stores))
+	for _, s := range c.stores {
+		if c.CheckLabelProperty(opt.RejectLeader, s.GetLabels()) || s.GetLabelValue(engineLabel) == tiflashEngine ||
+			c.state.isStoreUpgrading(s.GetID()) || c.state.isStoreShuttingDown(s.GetID()) {
+			continue
+		}
+		stores = append(stores, s)
+	}
+	return stores
+}
+
+// IsStoreUpgrading returns true if the namespace store is in upgrade mode.
+func (c *namespaceCluster) IsStoreUpgrading(storeID uint64) bool {
+	if _, ok := c.stores[storeID]; !ok {
+		return false
+	}
+	return c.state.isStoreUpgrading(storeID)
+}
+
+// EvictUpgradingLeaders creates operators which transfer the leaders of
+// namespace regions out of the stores in upgrade mode.
+func (c *namespaceCluster) EvictUpgradingLeaders() []*operator.Operator {
+	return c.evictLeaders("namespace-upgrade-evict-leader", c.state.getUpgradingStores())
+}
+
+// StartIncrementalScatter queues the namespace regions to be scattered
+// incrementally, so that only a bounded number of them are scattered in each
+// tick instead of all at once. The regions not in the namespace are ignored.
+// It returns the number of regions queued.
+func (c *namespaceCluster) StartIncrementalScatter(regionIDs []uint64) int {
+	var ids []uint64
+	for _, id := range regionIDs {
+		if r := c.GetRegion(id); r != nil && c.checkRegion(r) {
+			ids = append(ids, id)
+		}
+	}
+	return c.state.addScatterRegions(ids)
+}
+
+// ScatterTick scatters at most incrementalScatterBatchSize regions queued by
+// StartIncrementalScatter with the scatterer, and returns the operators
+// created. A region which is removed, hot or cannot be scattered is skipped,
+// and still counted as scattered.
+func (c *namespaceCluster) ScatterTick(scatterer *schedule.RegionScatterer) []*operator.Operator {
+	var ops []*operator.Operator
+	for _, id := range c.state.popScatterRegions(incrementalScatterBatchSize) {
+		region := c.GetRegion(id)
+		if region == nil || !c.checkRegion(region) || c.IsRegionHot(region) {
+			continue
+		}
+		op, err := scatterer.Scatter(region)
+		if err != nil {
+			log.Warn("failed to scatter region", zap.Uint64("region-id", id), zap.String("namespace", c.namespace), zap.Error(err))
+			continue
+		}
+		if op != nil {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// GetScatterProgress returns the numbers of regions scattered and queued by
+// the incremental scatter of the namespace since its queue was last empty.
+func (c *namespaceCluster) GetScatterProgress() (scattered, total int) {
+	return c.state.getScatterProgress()
+}
+
+// BeginStoreShutdown marks the namespace store as shutting down gracefully.
+// The leaders of the namespace regions are evicted from the store, and it is
+// no longer the target of new peers or leaders until EndStoreShutdown.
+func (c *namespaceCluster) BeginStoreShutdown(storeID uint64) error {
+	if _, ok := c.stores[storeID]; !ok {
+		return errors.Errorf("store %d is not in namespace %s", storeID, c.namespace)
+	}
+	c.state.setStoreShuttingDown(storeID, true)
+	return nil
+}
+
+// EndStoreShutdown clears the shutting down mark of the store, e.g. after the
+// store is restarted.
+func (c *namespaceCluster) EndStoreShutdown(storeID uint64) {
+	c.state.setStoreShuttingDown(storeID, false)
+}
+
+// IsStoreShuttingDown returns true if the namespace store is shutting down.
+func (c *namespaceCluster) IsStoreShuttingDown(storeID uint64) bool {
+	if _, ok := c.stores[storeID]; !ok {
+		return false
+	}
+	return c.state.isStoreShuttingDown(storeID)
+}
+
+// EvictShuttingDownLeaders creates operators which transfer the leaders of
+// namespace regions out of the stores shutting down.
+func (c *namespaceCluster) EvictShuttingDownLeaders() []*operator.Operator {
+	return c.evictLeaders("namespace-shutdown-evict-leader", c.state.getShuttingDownStores())
+}
+
+// evictLeaders creates operators which transfer the leaders of namespace
+// regions out of the stores. A random leader region is picked for each store,
+// and its leader is transferred to the follower suggested by SuggestLeader.
+func (c *namespaceCluster) evictLeaders(desc string, storeIDs []uint64) []*operator.Operator {
+	var ops []*operator.Operator
+	for _, id := range storeIDs {
+		if _, ok := c.stores[id]; !ok {
+			continue
+		}
+		region := c.RandLeaderRegion(id, core.HealthRegion())
+		if region == nil {
+			continue
+		}
+		target := c.SuggestLeader(region)
+		if target == nil {
+			log.Debug("no target to evict leader", zap.String("desc", desc), zap.String("namespace", c.namespace), zap.Uint64("store-id", id), zap.Uint64("region-id", region.GetID()))
+			continue
+		}
+		op := operator.CreateTransferLeaderOperator(desc, region, id, target.GetStoreId(), operator.OpLeader)
+		op.SetPriorityLevel(core.HighPriority)
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// SuggestLeader returns the voter of the region which is suggested to campaign
+// for leader. It is the voter on the least loaded healthy store among the
+// leader eligible stores in the namespace. Down and pending peers are not
+// considered. It returns nil if there is no such voter.
+func (c *namespaceCluster) SuggestLeader(region *core.RegionInfo) *metapb.Peer {
+	eligible := make(map[uint64]*core.StoreInfo)
+	for _, s := range c.GetLeaderEligibleStores() {
+		if s.IsUp() && !s.IsDisconnected() && !s.IsBusy() {
+			eligible[s.GetID()] = s
+		}
+	}
+	strategy := c.GetLeaderScheduleStrategy()
+	var (
+		best      *metapb.Peer
+		bestScore float64
+	)
+	for _, p := range region.GetVoters() {
+		s, ok := eligible[p.GetStoreId()]
+		if !ok || region.GetDownPeer(p.GetId()) != nil || region.GetPendingPeer(p.GetId()) != nil {
+			continue
+		}
+		if score := s.LeaderScore(strategy, 0); best == nil || score < bestScore {
+			best, bestScore = p, score
+		}
+	}
+	return best
+}
+
+// GetLeaderStore returns the namespace store that contains the region's
+// leader peer.
+func (c *namespaceCluster) GetLeaderStore(region *core.RegionInfo) *core.StoreInfo {
+	return c.stores[region.GetLeader().GetStoreId()]
+}
+
+// GetFollowerStores returns all namespace stores that contain the region's
+// follower peer.
+func (c *namespaceCluster) GetFollowerStores(region *core.RegionInfo) []*core.StoreInfo {
+	var stores []*core.StoreInfo
+	for id := range region.GetFollowers() {
+		if s, ok := c.stores[id]; ok {
+			stores = append(stores, s)
+		}
+	}
+	return stores
+}
+
+// GetRegionStores returns all namespace stores that contain the region's peer.
+func (c *namespaceCluster) GetRegionStores(region *core.RegionInfo) []*core.StoreInfo {
+	var stores []*core.StoreInfo
+	for id := range region.GetStoreIds() {
+		if s, ok := c.stores[id]; ok {
+			stores = append(stores, s)
+		}
+	}
+	return stores
+}
+
+// IsStoreFlapping returns true if the namespace store has gone up and down
+// repeatedly in recent time. Schedulers should avoid targeting such stores.
+func (c *namespaceCluster) IsStoreFlapping(storeID uint64) bool {
+	if _, ok := c.stores[storeID]; !ok {
+		return false
+	}
+	return c.states.storeFlapping.isFlapping(storeID, time.Now())
+}
+
+// DetectReplicaThrashing returns the IDs of namespace regions whose replicas
+// on some store are added or removed repeatedly within a short time, in
+// ascending order. It usually means schedulers are moving the replicas back
+// and forth.
+func (c *namespaceCluster) DetectReplicaThrashing() []uint64 {
+	var regionIDs []uint64
+	for _, id := range c.states.replicaChurn.getThrashingRegions(time.Now()) {
+		if r := c.Cluster.GetRegion(id); r != nil && c.classifier.GetRegionNamespace(r) == c.namespace {
+			regionIDs = append(regionIDs, id)
+		}
+	}
+	return regionIDs
+}
+
+// DetectLeaderOscillation returns the IDs of namespace regions whose leaders
+// are moved back and forth between stores within a short time, in ascending
+// order. It usually means schedulers with conflicting goals are bouncing the
+// leaders.
+func (c *namespaceCluster) DetectLeaderOscillation() []uint64 {
+	var regionIDs []uint64
+	for _, id := range c.states.leaderOscillation.getOscillatingRegions(time.Now()) {
+		if r := c.Cluster.GetRegion(id); r != nil && c.classifier.GetRegionNamespace(r) == c.namespace {
+			regionIDs = append(regionIDs, id)
+		}
+	}
+	return regionIDs
+}
+
+// IsLeaseStable returns true if the namespace store is not marked as having an
+// unstable leader lease. Leaders should not be transferred to stores whose
+// leases are unstable, to avoid brief unavailability of the regions.
+func (c *namespaceCluster) IsLeaseStable(storeID uint64) bool {
+	if _, ok := c.stores[storeID]; !ok {
+		return false
+	}
+	return c.states.storeLeases.isStable(storeID, time.Now())
+}
+
+// IsSlowStore returns true if the namespace store is much slower than the
+// others. The slow score of a store is the max operation latency it reports,
+// and a store is slow if its score exceeds slowStoreScoreRatio times the
+// average score of the other up stores in the namespace. Schedulers should
+// avoid slow stores as targets.
+func (c *namespaceCluster) IsSlowStore(storeID uint64) bool {
+	store, ok := c.stores[storeID]
+	if !ok || !store.IsUp() {
+		return false
+	}
+	score := storeSlowScore(store)
+	if score == 0 {
+		return false
+	}
+	var (
+		sum   uint64
+		count int
+	)
+	for id, s := range c.stores {
+		if id == storeID || !s.IsUp() {
+			continue
+		}
+		if v := storeSlowScore(s); v > 0 {
+			sum += v
+			count++
+		}
+	}
+	return count > 0 && float64(score) > slowStoreScoreRatio*float64(sum)/float64(count)
+}
+
+// GetStoresBySlowScore returns the up stores in the namespace in descending
+// order of slow scores, so that the laggard comes first. Ties are ordered by
+// store ID.
+func (c *namespaceCluster) GetStoresBySlowScore() []*core.StoreInfo {
+	var stores []*core.StoreInfo
+	for _, s := range c.stores {
+		if s.IsUp() {
+			stores = append(stores, s)
+		}
+	}
+	sort.Slice(stores, func(i, j int) bool {
+		si, sj := storeSlowScore(stores[i]), storeSlowScore(stores[j])
+		return si > sj || (si == sj && stores[i].GetID() < stores[j].GetID())
+	})
+	return stores
+}
+
+// storeSlowScore returns the max operation latency reported by the store, it
+// is 0 if the store reports no latency.
+func storeSlowScore(store *core.StoreInfo) uint64 {
+	var score uint64
+	for _, p := range store.GetStoreStats().GetOpLatencies() {
+		if p.GetValue() > score {
+			score = p.GetValue()
+		}
+	}
+	return score
+}
+
 // GetRegion searches for a region by ID.
+// NOTE: the returned region may have no leader, e.g. regions loaded from the
+// storage before the first heartbeat. Use GetRegionWithLeader if the caller
+// is going to access the leader.
 func (c *namespaceCluster) GetRegion(id uint64) *core.RegionInfo {
 	r := c.Cluster.GetRegion(id)
 	if r == nil || !c.checkRegion(r) {
@@ -126,36 +599,2553 @@ func (c *namespaceCluster) GetRegion(id uint64) *core.RegionInfo {
 	return r
 }
 
-// RegionWriteStats returns hot region's write stats.
-func (c *namespaceCluster) RegionWriteStats() map[uint64][]*statistics.HotPeerStat {
-	return c.Cluster.RegionWriteStats()
+// GetRegionWithLeader searches for a region by ID. It returns nil if the
+// region has no leader.
+func (c *namespaceCluster) GetRegionWithLeader(id uint64) *core.RegionInfo {
+	r := c.GetRegion(id)
+	if r == nil || r.GetLeader() == nil {
+		return nil
+	}
+	return r
 }
 
-func scheduleByNamespace(cluster opt.Cluster, classifier namespace.Classifier, scheduler schedule.Scheduler) []*operator.Operator {
-	namespaces := classifier.GetAllNamespaces()
-	for _, i := range rand.Perm(len(namespaces)) {
-		nc := newNamespaceCluster(cluster, classifier, namespaces[i])
-		if op := scheduler.Schedule(nc); op != nil {
-			return op
+// getRegions returns all regions in the namespace, ordered by start key.
+func (c *namespaceCluster) getRegions() []*core.RegionInfo {
+	var regions []*core.RegionInfo
+	for _, r := range c.Cluster.ScanRegions(nil, nil, 0) {
+		if c.checkRegion(r) {
+			regions = append(regions, r)
 		}
 	}
-	return nil
+	return regions
 }
 
-func (c *namespaceCluster) GetLeaderScheduleLimit() uint64 {
-	return c.GetOpt().GetLeaderScheduleLimit(c.namespace)
+// GetBoundaryRegions returns the regions of the namespace whose key ranges
+// straddle the boundaries of namespaces, they should be split at the
+// boundaries. Only regions are checked here, and it returns nil if the
+// classifier does not define namespaces by key ranges.
+func (c *namespaceCluster) GetBoundaryRegions() []*core.RegionInfo {
+	classifier, ok := c.classifier.(namespace.BoundaryClassifier)
+	if !ok {
+		return nil
+	}
+	var regions []*core.RegionInfo
+	for _, r := range c.Cluster.ScanRegions(nil, nil, 0) {
+		if c.classifier.GetRegionNamespace(r) == c.namespace && classifier.IsCrossBoundary(r) {
+			regions = append(regions, r)
+		}
+	}
+	return regions
 }
 
-func (c *namespaceCluster) GetRegionScheduleLimit() uint64 {
-	return c.GetOpt().GetRegionScheduleLimit(c.namespace)
+const (
+	// defaultRegionMaxSize and defaultRegionMaxKeys are the defaults of the
+	// region split thresholds of TiKV.
+	defaultRegionMaxSize = 144
+	defaultRegionMaxKeys = 1440000
+)
+
+// GetRegionMaxSize returns the max approximate size in MB of regions in the
+// namespace. It defaults to the one of TiKV if the namespace does not set it.
+func (c *namespaceCluster) GetRegionMaxSize() uint64 {
+	if size := c.GetOpt().GetRegionMaxSize(c.namespace); size > 0 {
+		return size
+	}
+	return defaultRegionMaxSize
 }
 
-func (c *namespaceCluster) GetReplicaScheduleLimit() uint64 {
-	return c.GetOpt().GetReplicaScheduleLimit(c.namespace)
+// GetRegionMaxKeys returns the max number of keys of regions in the namespace.
+// It defaults to the one of TiKV if the namespace does not set it.
+func (c *namespaceCluster) GetRegionMaxKeys() uint64 {
+	if keys := c.GetOpt().GetRegionMaxKeys(c.namespace); keys > 0 {
+		return keys
+	}
+	return defaultRegionMaxKeys
 }
 
-func (c *namespaceCluster) GetMergeScheduleLimit() uint64 {
-	return c.GetOpt().GetMergeScheduleLimit(c.namespace)
+// GetOversizedRegions returns the namespace regions whose approximate size or
+// number of keys exceeds the max, in ascending order of region ID. They are
+// the candidates to be split.
+func (c *namespaceCluster) GetOversizedRegions() []*core.RegionInfo {
+	maxSize, maxKeys := c.GetRegionMaxSize(), c.GetRegionMaxKeys()
+	var regions []*core.RegionInfo
+	for _, r := range c.getRegions() {
+		if uint64(r.GetApproximateSize()) > maxSize || uint64(r.GetApproximateKeys()) > maxKeys {
+			regions = append(regions, r)
+		}
+	}
+	sort.Slice(regions, func(i, j int) bool { return regions[i].GetID() < regions[j].GetID() })
+	return regions
+}
+
+// GetMergeableRegionPairs returns pairs of adjacent regions in the namespace
+// whose combined approximate size does not exceed the max merge region size.
+// A region appears in at most one pair, so that all the pairs can be merged
+// in a batch.
+func (c *namespaceCluster) GetMergeableRegionPairs() [][2]*core.RegionInfo {
+	maxSize := int64(c.GetMaxMergeRegionSize())
+	regions := c.getRegions()
+	var pairs [][2]*core.RegionInfo
+	for i := 0; i+1 < len(regions); i++ {
+		prev, next := regions[i], regions[i+1]
+		if !bytes.Equal(prev.GetEndKey(), next.GetStartKey()) {
+			continue
+		}
+		if prev.GetApproximateSize()+next.GetApproximateSize() > maxSize {
+			continue
+		}
+		pairs = append(pairs, [2]*core.RegionInfo{prev, next})
+		i++
+	}
+	return pairs
+}
+
+// GetPostGCMergeCandidates returns the empty namespace regions which are
+// adjacent to another empty namespace region, they can be merged right away,
+// e.g. after GC deletes the data of a large range. The regions are in key
+// order.
+func (c *namespaceCluster) GetPostGCMergeCandidates() []*core.RegionInfo {
+	isEmpty := func(r *core.RegionInfo) bool {
+		return r.GetApproximateSize() <= core.EmptyRegionApproximateSize
+	}
+	regions := c.getRegions()
+	var candidates []*core.RegionInfo
+	for i, r := range regions {
+		if !isEmpty(r) {
+			continue
+		}
+		if (i > 0 && isEmpty(regions[i-1]) && bytes.Equal(regions[i-1].GetEndKey(), r.GetStartKey())) ||
+			(i+1 < len(regions) && isEmpty(regions[i+1]) && bytes.Equal(r.GetEndKey(), regions[i+1].GetStartKey())) {
+			candidates = append(candidates, r)
+		}
+	}
+	return candidates
+}
+
+// SuggestSplitKeys returns keys which split the region in the namespace into
+// parts of roughly equal size. As PD does not know the keys inside regions,
+// the keys are interpolated between the start and end keys of the region,
+// which assumes keys are distributed evenly. The number of parts is capped by
+// the approximate key count of the region. It returns nil if the region is not
+// in the namespace or cannot be split.
+func (c *namespaceCluster) SuggestSplitKeys(region *core.RegionInfo, parts int) [][]byte {
+	if !c.checkRegion(region) {
+		return nil
+	}
+	if keys := region.GetApproximateKeys(); keys > 0 && int64(parts) > keys {
+		parts = int(keys)
+	}
+	if parts < 2 {
+		return nil
+	}
+	startKey, endKey := region.GetStartKey(), region.GetEndKey()
+	// One more byte is used to get more precision for short keys.
+	n := len(startKey) + 1
+	if len(endKey) >= n {
+		n = len(endKey) + 1
+	}
+	start := keyToInt(startKey, n)
+	end := keyToInt(endKey, n)
+	if len(endKey) == 0 {
+		end = new(big.Int).Lsh(big.NewInt(1), uint(n*8))
+	}
+	step := new(big.Int).Sub(end, start)
+	var splitKeys [][]byte
+	for i := 1; i < parts; i++ {
+		k := new(big.Int).Mul(step, big.NewInt(int64(i)))
+		k.Div(k, big.NewInt(int64(parts)))
+		key := intToKey(k.Add(k, start), n)
+		if bytes.Compare(key, startKey) <= 0 || (len(endKey) > 0 && bytes.Compare(key, endKey) >= 0) {
+			continue
+		}
+		if len(splitKeys) > 0 && bytes.Equal(key, splitKeys[len(splitKeys)-1]) {
+			continue
+		}
+		splitKeys = append(splitKeys, key)
+	}
+	return splitKeys
+}
+
+// hotSplitMaxParts is the max number of parts that a hot region is split into.
+const hotSplitMaxParts = 4
+
+// CreateHotSplitOperator creates an operator that splits the hottest region of
+// the namespace if its read and written bytes exceed the threshold. The region
+// is split into ceil(flow/threshold) parts, at most hotSplitMaxParts, at the
+// keys suggested by SuggestSplitKeys. It returns nil if no region is hot
+// enough or the region cannot be split.
+func (c *namespaceCluster) CreateHotSplitOperator(flowThreshold uint64) *operator.Operator {
+	var (
+		hottest *core.RegionInfo
+		maxFlow uint64
+	)
+	for _, r := range c.getRegions() {
+		if flow := r.GetBytesRead() + r.GetBytesWritten(); flow > maxFlow {
+			hottest, maxFlow = r, flow
+		}
+	}
+	if hottest == nil || maxFlow <= flowThreshold {
+		return nil
+	}
+	parts := hotSplitMaxParts
+	if flowThreshold > 0 && (maxFlow+flowThreshold-1)/flowThreshold < uint64(parts) {
+		parts = int((maxFlow + flowThreshold - 1) / flowThreshold)
+	}
+	keys := c.SuggestSplitKeys(hottest, parts)
+	if len(keys) == 0 {
+		return nil
+	}
+	return operator.CreateSplitRegionOperator("namespace-hot-split", hottest, operator.OpHotRegion, pdpb.CheckPolicy_USEKEY, keys)
+}
+
+// keyToInt converts the key padded by zeros to n bytes into an integer.
+func keyToInt(key []byte, n int) *big.Int {
+	buf := make([]byte, n)
+	copy(buf, key)
+	return new(big.Int).SetBytes(buf)
+}
+
+// intToKey converts the integer back into a key of n bytes, with trailing
+// zeros trimmed.
+func intToKey(i *big.Int, n int) []byte {
+	buf := make([]byte, n)
+	b := i.Bytes()
+	copy(buf[n-len(b):], b)
+	return bytes.TrimRight(buf, "\x00")
+}
+
+// GetRegionsWithDownPeersByUrgency returns the regions in the namespace which
+// have down peers. Regions with fewer healthy voters, which are closer to losing
+// quorum or data, come first. Ties are ordered by start key.
+func (c *namespaceCluster) GetRegionsWithDownPeersByUrgency() []*core.RegionInfo {
+	var regions []*core.RegionInfo
+	healthy := make(map[uint64]int)
+	for _, r := range c.getRegions() {
+		if len(r.GetDownPeers()) == 0 {
+			continue
+		}
+		regions = append(regions, r)
+		healthy[r.GetID()] = countHealthyVoters(r)
+	}
+	sort.SliceStable(regions, func(i, j int) bool {
+		return healthy[regions[i].GetID()] < healthy[regions[j].GetID()]
+	})
+	return regions
+}
+
+// GetStoreWithMostDownRegions returns the namespace store which hosts down
+// peers of the most regions in the namespace, together with the number of
+// such regions. The store with the smaller ID is returned on ties. It returns
+// 0 and 0 if no region has down peers.
+func (c *namespaceCluster) GetStoreWithMostDownRegions() (uint64, int) {
+	counts := make(map[uint64]int)
+	for _, r := range c.getRegions() {
+		for _, p := range r.GetDownPeers() {
+			counts[p.GetPeer().GetStoreId()]++
+		}
+	}
+	var storeID uint64
+	var count int
+	for id, n := range counts {
+		if n > count || (n == count && id < storeID) {
+			storeID, count = id, n
+		}
+	}
+	return storeID, count
+}
+
+// GetRegionsOnOfflineStores returns the namespace regions which have at least
+// one peer on an offline store of the namespace. Peers of these regions need
+// to be moved out before the stores can be removed.
+func (c *namespaceCluster) GetRegionsOnOfflineStores() []*core.RegionInfo {
+	var regions []*core.RegionInfo
+	for _, r := range c.getRegions() {
+		for _, p := range r.GetPeers() {
+			if s, ok := c.stores[p.GetStoreId()]; ok && s.IsOffline() {
+				regions = append(regions, r)
+				break
+			}
+		}
+	}
+	return regions
+}
+
+// countHealthyVoters returns the number of voters of the region which are
+// neither down nor pending.
+func countHealthyVoters(region *core.RegionInfo) int {
+	var count int
+	for _, p := range region.GetVoters() {
+		if region.GetDownPeer(p.GetId()) == nil && region.GetPendingPeer(p.GetId()) == nil {
+			count++
+		}
+	}
+	return count
+}
+
+// RecordUnderReplicated records the namespace regions under-replicated at the
+// time. A region is under-replicated if it has fewer voters than the max
+// replicas excluding the down ones. The time when a region is first detected
+// under-replicated is kept until it is repaired.
+func (c *namespaceCluster) RecordUnderReplicated(now time.Time) {
+	maxReplicas := c.GetMaxReplicas()
+	var underReplicated []uint64
+	for _, r := range c.getRegions() {
+		if len(r.GetVoters())-len(r.GetDownPeers()) < maxReplicas {
+			underReplicated = append(underReplicated, r.GetID())
+		}
+	}
+	c.state.recordUnderReplicated(underReplicated, now)
+}
+
+// GetRepairSLABreaches returns the IDs of the namespace regions which have been
+// under-replicated for longer than the SLA since RecordUnderReplicated first
+// detects them, in ascending order.
+func (c *namespaceCluster) GetRepairSLABreaches(sla time.Duration) []uint64 {
+	now := time.Now()
+	var breaches []uint64
+	for id, since := range c.state.getUnderReplicatedSince() {
+		if now.Sub(since) > sla {
+			breaches = append(breaches, id)
+		}
+	}
+	sort.Slice(breaches, func(i, j int) bool { return breaches[i] < breaches[j] })
+	return breaches
+}
+
+// SelectRegionToMoveOut selects a region which has a peer on the store and can
+// be moved out to relieve the store. It prefers regions of moderate size:
+// regions small enough to be merged are not worth moving, and among others the
+// one closest to the median size is picked to avoid moving huge regions. Only
+// healthy regions which have a viable target store in the namespace are
+// considered. It returns nil if there is no such region.
+func (c *namespaceCluster) SelectRegionToMoveOut(storeID uint64) *core.RegionInfo {
+	if _, ok := c.stores[storeID]; !ok {
+		return nil
+	}
+	var candidates []*core.RegionInfo
+	for _, r := range c.getRegions() {
+		if r.GetStorePeer(storeID) == nil || len(r.GetPendingPeers()) > 0 || len(r.GetDownPeers()) > 0 {
+			continue
+		}
+		if c.hasTargetStore(r) {
+			candidates = append(candidates, r)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].GetApproximateSize() < candidates[j].GetApproximateSize()
+	})
+	// Skips the regions which are small enough to be merged, unless all
+	// regions are small.
+	minSize := int64(c.GetMaxMergeRegionSize())
+	if i := sort.Search(len(candidates), func(i int) bool {
+		return candidates[i].GetApproximateSize() > minSize
+	}); i < len(candidates) {
+		candidates = candidates[i:]
+	}
+	return candidates[len(candidates)/2]
+}
+
+// hasTargetStore returns true if there is a store in the namespace which can
+// receive a replica of the region.
+func (c *namespaceCluster) hasTargetStore(region *core.RegionInfo) bool {
+	filters := []filter.Filter{
+		filter.NewExcludedFilter("namespace-cluster", nil, region.GetStoreIds()),
+		filter.NewStateFilter("namespace-cluster"),
+		filter.NewHealthFilter("namespace-cluster"),
+		filter.NewPendingPeerCountFilter("namespace-cluster"),
+		filter.NewSnapshotCountFilter("namespace-cluster"),
+	}
+	for _, store := range c.stores {
+		if !filter.Target(c, store, filters) && !c.IsStoreFlapping(store.GetID()) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAchievedIsolationLevel returns the weakest isolation level achieved by the
+// regions in the namespace, which is the innermost location label that some
+// region is only isolated at. It returns statistics.NonIsolation if some
+// region is not isolated at any location label.
+func (c *namespaceCluster) GetAchievedIsolationLevel() string {
+	labels := c.GetLocationLabels()
+	weakest := 0
+	for _, r := range c.getRegions() {
+		isolation := statistics.GetRegionLabelIsolation(c.GetRegionStores(r), labels)
+		level := len(labels)
+		for i, label := range labels {
+			if label == isolation {
+				level = i
+				break
+			}
+		}
+		if level > weakest {
+			weakest = level
+		}
+	}
+	if weakest >= len(labels) {
+		return statistics.NonIsolation
+	}
+	return labels[weakest]
+}
+
+// GetIsolationDelta returns how much the isolation of the region is improved
+// by moving its peer from fromStore to toStore. The isolation of a region is
+// the sum of the numbers of distinct values of its stores at each location
+// label level, so a positive delta means the peers are spread wider after the
+// move. It returns 0 if any of the stores is unknown.
+func (c *namespaceCluster) GetIsolationDelta(region *core.RegionInfo, fromStore, toStore uint64) int {
+	labels := c.GetLocationLabels()
+	from, to := c.Cluster.GetStore(fromStore), c.Cluster.GetStore(toStore)
+	if len(labels) == 0 || from == nil || to == nil {
+		return 0
+	}
+	var others []*core.StoreInfo
+	for _, p := range region.GetPeers() {
+		if p.GetStoreId() == fromStore || p.GetStoreId() == toStore {
+			continue
+		}
+		if s := c.Cluster.GetStore(p.GetStoreId()); s != nil {
+			others = append(others, s)
+		}
+	}
+	return locationIsolation(append(others, to), labels) - locationIsolation(append(others, from), labels)
+}
+
+// locationIsolation returns the sum of the numbers of distinct location label
+// values of the stores at each level.
+func locationIsolation(stores []*core.StoreInfo, labels []string) int {
+	var isolation int
+	for i := range labels {
+		values := make(map[string]struct{})
+		for _, s := range stores {
+			var key string
+			for _, label := range labels[:i+1] {
+				key += s.GetLabelValue(label) + "/"
+			}
+			values[key] = struct{}{}
+		}
+		isolation += len(values)
+	}
+	return isolation
+}
+
+// GetLeaderPeerRatio returns the ratio of the leaders to the peers of the
+// namespace regions on the store. A store with a high ratio is a leader
+// hotspot. It returns 0 if the store has no peer of the namespace regions.
+func (c *namespaceCluster) GetLeaderPeerRatio(storeID uint64) float64 {
+	leaders, peers := c.GetLeaderPeerCounts()
+	if peers[storeID] == 0 {
+		return 0
+	}
+	return float64(leaders[storeID]) / float64(peers[storeID])
+}
+
+// GetLeaderPeerCounts returns the numbers of leaders and peers of the
+// namespace regions on each store, keyed by store ID, with a single scan of
+// the regions.
+func (c *namespaceCluster) GetLeaderPeerCounts() (leaders, peers map[uint64]int) {
+	leaders, peers = make(map[uint64]int), make(map[uint64]int)
+	for _, r := range c.getRegions() {
+		for _, p := range r.GetPeers() {
+			peers[p.GetStoreId()]++
+		}
+		leaders[r.GetLeader().GetStoreId()]++
+	}
+	return leaders, peers
+}
+
+// GetFreshStores returns the IDs of the fresh stores of the namespace in
+// ascending order. A store is fresh if it joins the namespace within
+// freshStoreWindow and it is up but has fewer regions than the average of the
+// up stores of the namespace, so that regions should be moved onto it. The
+// stores loaded when the cluster starts or not seen yet are not fresh.
+func (c *namespaceCluster) GetFreshStores() []uint64 {
+	now := time.Now()
+	var (
+		joined       []*core.StoreInfo
+		total, count int
+	)
+	for id, s := range c.stores {
+		if !s.IsUp() {
+			continue
+		}
+		if firstSeen, ok := c.state.getStoreFirstSeen(id); ok && now.Sub(firstSeen) < freshStoreWindow {
+			joined = append(joined, s)
+		}
+		total += s.GetRegionCount()
+		count++
+	}
+	if count < 2 {
+		return nil
+	}
+	var fresh []uint64
+	for _, s := range joined {
+		if float64(s.GetRegionCount()) < float64(total)/float64(count) {
+			fresh = append(fresh, s.GetID())
+		}
+	}
+	sort.Slice(fresh, func(i, j int) bool { return fresh[i] < fresh[j] })
+	return fresh
+}
+
+// GetStoreDomain returns the failure domain of the store, which is the value
+// of its outermost location label. It returns an empty string if no location
+// label is configured, or the store is not in the namespace or has no such
+// label.
+func (c *namespaceCluster) GetStoreDomain(storeID uint64) string {
+	labels := c.GetLocationLabels()
+	s, ok := c.stores[storeID]
+	if len(labels) == 0 || !ok {
+		return ""
+	}
+	return s.GetLabelValue(labels[0])
+}
+
+// GetReplicaDiversityScore returns how well the peers of namespace regions
+// are spread over failure domains, which is in [0, 1] and higher is better.
+// The score of a region is the number of distinct domains of its peers
+// divided by the most it can have, which is limited by its peer count and the
+// domains of the up stores. Stores without a failure domain are domains on
+// their own. It returns the average score of the regions, or 1 if there is no
+// region.
+func (c *namespaceCluster) GetReplicaDiversityScore() float64 {
+	storeDomain := func(storeID uint64) string {
+		if domain := c.GetStoreDomain(storeID); domain != "" {
+			return domain
+		}
+		return fmt.Sprintf("store-%d", storeID)
+	}
+	available := make(map[string]struct{})
+	for id, s := range c.stores {
+		if s.IsUp() {
+			available[storeDomain(id)] = struct{}{}
+		}
+	}
+	regions := c.getRegions()
+	var total float64
+	var count int
+	for _, r := range regions {
+		peers := r.GetPeers()
+		if len(peers) == 0 {
+			continue
+		}
+		domains := make(map[string]struct{})
+		for _, p := range peers {
+			domains[storeDomain(p.GetStoreId())] = struct{}{}
+		}
+		most := len(peers)
+		if len(available) < most {
+			most = len(available)
+		}
+		score := 1.0
+		if len(domains) < most {
+			score = float64(len(domains)) / float64(most)
+		}
+		total += score
+		count++
+	}
+	if count == 0 {
+		return 1
+	}
+	return total / float64(count)
+}
+
+// GetDomainLoad returns the total size of namespace regions on the up stores
+// of each failure domain. The size of a region is counted once for every peer
+// in the domain. Stores without a failure domain are not counted.
+func (c *namespaceCluster) GetDomainLoad() map[string]int64 {
+	loads := make(map[string]int64)
+	for id, s := range c.stores {
+		if domain := c.GetStoreDomain(id); domain != "" && s.IsUp() {
+			loads[domain] = 0
+		}
+	}
+	for _, r := range c.getRegions() {
+		for _, p := range r.GetPeers() {
+			domain := c.GetStoreDomain(p.GetStoreId())
+			if _, ok := loads[domain]; ok && c.stores[p.GetStoreId()].IsUp() {
+				loads[domain] += r.GetApproximateSize()
+			}
+		}
+	}
+	return loads
+}
+
+// SuggestOptimalPlacement returns the IDs of the stores that the peers of the
+// region should be placed on, in ascending order. A store is picked for every
+// peer that the placement rules covering the region expect, or for every
+// replica if no rule covers it. Only the up and connected stores of the
+// namespace that match the region labels are considered. Each pick prefers the
+// store most distinct from the picked ones by location labels, then a store
+// the region is already on to avoid needless moves, then the store with the
+// lowest region score. A peer is left out if no store suits it.
+func (c *namespaceCluster) SuggestOptimalPlacement(region *core.RegionInfo) []uint64 {
+	var slots [][]placement.LabelConstraint
+	if rules := c.GetOpt().GetPlacementRules(c.namespace); len(rules) > 0 {
+		for _, r := range placement.GetAppliedRules(region, rules) {
+			for i := 0; i < r.Count; i++ {
+				slots = append(slots, r.LabelConstraints)
+			}
+		}
+	}
+	if len(slots) == 0 {
+		slots = make([][]placement.LabelConstraint, c.GetMaxReplicas())
+	}
+
+	regionLabels := c.getRegionLabels(region)
+	var candidates []*core.StoreInfo
+	for _, s := range c.stores {
+		if s.IsUp() && !s.IsDisconnected() && matchRegionLabels(s, regionLabels) {
+			candidates = append(candidates, s)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].GetID() < candidates[j].GetID() })
+
+	labels := c.GetLocationLabels()
+	highSpaceRatio, lowSpaceRatio := c.GetHighSpaceRatio(), c.GetLowSpaceRatio()
+	var picked []*core.StoreInfo
+	chosen := make(map[uint64]struct{})
+	for _, constraints := range slots {
+		var (
+			best                 *core.StoreInfo
+			bestDistinct, bestRS float64
+			bestCurrent          bool
+		)
+		for _, s := range candidates {
+			if _, ok := chosen[s.GetID()]; ok || !placement.MatchLabelConstraints(s, constraints) {
+				continue
+			}
+			distinct := core.DistinctScore(labels, picked, s)
+			current := region.GetStorePeer(s.GetID()) != nil
+			score := s.RegionScore(highSpaceRatio, lowSpaceRatio, 0)
+			if best == nil || distinct > bestDistinct ||
+				(distinct == bestDistinct && current && !bestCurrent) ||
+				(distinct == bestDistinct && current == bestCurrent && score < bestRS) {
+				best, bestDistinct, bestCurrent, bestRS = s, distinct, current, score
+			}
+		}
+		if best == nil {
+			continue
+		}
+		picked = append(picked, best)
+		chosen[best.GetID()] = struct{}{}
+	}
+
+	ids := make([]uint64, 0, len(picked))
+	for _, s := range picked {
+		ids = append(ids, s.GetID())
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// SelectMoveTarget selects the target store to move the peer of the region on
+// fromStore to among the candidates. The stores with the highest recovery
+// priority go first, so that the recovering stores which repair more critical
+// regions are refilled first. Among them the store with the lowest region
+// score wins, and ties are broken by the isolation delta so that the move
+// improving the isolation most is preferred.
+func (c *namespaceCluster) SelectMoveTarget(region *core.RegionInfo, fromStore uint64, candidates []*core.StoreInfo) *core.StoreInfo {
+	highSpaceRatio, lowSpaceRatio := c.GetHighSpaceRatio(), c.GetLowSpaceRatio()
+	var (
+		best         *core.StoreInfo
+		bestPriority int
+		bestScore    float64
+		bestDelta    int
+	)
+	for _, s := range candidates {
+		priority := c.GetRecoveryPriority(s.GetID())
+		score := s.RegionScore(highSpaceRatio, lowSpaceRatio, 0)
+		delta := c.GetIsolationDelta(region, fromStore, s.GetID())
+		switch {
+		case best == nil, priority > bestPriority:
+		case priority < bestPriority, score > bestScore:
+			continue
+		case score == bestScore && delta <= bestDelta:
+			continue
+		}
+		best, bestPriority, bestScore, bestDelta = s, priority, score, delta
+	}
+	return best
+}
+
+// DetectLabelDrift returns the IDs of namespace stores whose location label
+// values are different from the baseline, which are recorded when the stores
+// are first checked, in ascending order. The isolation of namespace regions
+// may be broken on these stores.
+func (c *namespaceCluster) DetectLabelDrift() []uint64 {
+	labels := c.GetLocationLabels()
+	var drifted []uint64
+	for id, s := range c.stores {
+		values := make([]string, 0, len(labels))
+		for _, label := range labels {
+			values = append(values, s.GetLabelValue(label))
+		}
+		if c.state.checkLabelDrift(id, values) {
+			drifted = append(drifted, id)
+		}
+	}
+	sort.Slice(drifted, func(i, j int) bool { return drifted[i] < drifted[j] })
+	return drifted
+}
+
+// GetRuleViolatingRegions returns the namespace regions whose placement does
+// not satisfy the placement rules of the namespace.
+func (c *namespaceCluster) GetRuleViolatingRegions() []*core.RegionInfo {
+	rules := c.GetOpt().GetPlacementRules(c.namespace)
+	if len(rules) == 0 {
+		return nil
+	}
+	var regions []*core.RegionInfo
+	for _, r := range c.getRegions() {
+		if r.GetLeader() != nil && !placement.FitRules(r, rules, c.Cluster.GetStore) {
+			regions = append(regions, r)
+		}
+	}
+	return regions
+}
+
+// GetAntiAffinityViolations returns the pairs of regions in the same
+// anti-affinity group of the namespace which have peers on the same namespace
+// store. The smaller region ID comes first in a pair, and the pairs are sorted.
+func (c *namespaceCluster) GetAntiAffinityViolations() [][2]uint64 {
+	seen := make(map[[2]uint64]struct{})
+	var violations [][2]uint64
+	for _, group := range c.GetOpt().GetAntiAffinityGroups(c.namespace) {
+		var regions []*core.RegionInfo
+		for _, id := range group {
+			if r := c.GetRegion(id); r != nil && c.checkRegion(r) {
+				regions = append(regions, r)
+			}
+		}
+		for i := range regions {
+			for j := i + 1; j < len(regions); j++ {
+				a, b := regions[i], regions[j]
+				if a.GetID() == b.GetID() || !shareStore(a, b) {
+					continue
+				}
+				pair := [2]uint64{a.GetID(), b.GetID()}
+				if pair[0] > pair[1] {
+					pair[0], pair[1] = pair[1], pair[0]
+				}
+				if _, ok := seen[pair]; !ok {
+					seen[pair] = struct{}{}
+					violations = append(violations, pair)
+				}
+			}
+		}
+	}
+	sort.Slice(violations, func(i, j int) bool {
+		return violations[i][0] < violations[j][0] ||
+			(violations[i][0] == violations[j][0] && violations[i][1] < violations[j][1])
+	})
+	return violations
+}
+
+// shareStore checks if the two regions have peers on the same store.
+func shareStore(a, b *core.RegionInfo) bool {
+	for _, p := range a.GetPeers() {
+		if b.GetStorePeer(p.GetStoreId()) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// GetConfigMismatchedRegions returns the namespace regions whose numbers of
+// voters or learners differ from the current config of the namespace in
+// ascending order of region IDs, e.g. the regions created before the max
+// replicas is changed. If placement rules cover a region, the numbers are
+// expected by the rules, otherwise a region is expected to have max replicas
+// voters and no learner.
+func (c *namespaceCluster) GetConfigMismatchedRegions() []*core.RegionInfo {
+	rules := c.GetOpt().GetPlacementRules(c.namespace)
+	var regions []*core.RegionInfo
+	for _, r := range c.getRegions() {
+		voters, learners := c.GetMaxReplicas(), 0
+		if applied := placement.GetAppliedRules(r, rules); len(applied) > 0 {
+			voters = 0
+			for _, rule := range applied {
+				if rule.Role == placement.Learner {
+					learners += rule.Count
+				} else {
+					voters += rule.Count
+				}
+			}
+		}
+		if len(r.GetVoters()) != voters || len(r.GetLearners()) != learners {
+			regions = append(regions, r)
+		}
+	}
+	sort.Slice(regions, func(i, j int) bool { return regions[i].GetID() < regions[j].GetID() })
+	return regions
+}
+
+// getRegionLabels returns the labels of the region configured for the
+// namespace.
+func (c *namespaceCluster) getRegionLabels(region *core.RegionInfo) []*placement.RegionLabel {
+	var labels []*placement.RegionLabel
+	for _, l := range c.GetOpt().GetRegionLabels(c.namespace) {
+		if l.CoverRegion(region) {
+			labels = append(labels, l)
+		}
+	}
+	return labels
+}
+
+// matchRegionLabels checks if the store has all the labels.
+func matchRegionLabels(store *core.StoreInfo, labels []*placement.RegionLabel) bool {
+	for _, l := range labels {
+		if !l.MatchStore(store) {
+			return false
+		}
+	}
+	return true
+}
+
+// EnforceRegionLabelPlacement creates an operator that moves a peer of the
+// labeled region from a store without the labels, e.g. a cold region on a hot
+// store, to a store of the namespace with the labels. The store with the
+// fewest regions is picked as the target. It returns nil if the region is
+// placed as its labels expect, or there is no store to move the peer to.
+func (c *namespaceCluster) EnforceRegionLabelPlacement(region *core.RegionInfo) *operator.Operator {
+	if !c.checkRegion(region) {
+		return nil
+	}
+	labels := c.getRegionLabels(region)
+	if len(labels) == 0 {
+		return nil
+	}
+	var source uint64
+	for _, p := range region.GetPeers() {
+		if !matchRegionLabels(c.Cluster.GetStore(p.GetStoreId()), labels) {
+			source = p.GetStoreId()
+			break
+		}
+	}
+	if source == 0 {
+		return nil
+	}
+
+	filters := []filter.Filter{
+		filter.NewExcludedFilter("namespace-cluster", nil, region.GetStoreIds()),
+		filter.NewStateFilter("namespace-cluster"),
+		filter.NewHealthFilter("namespace-cluster"),
+		filter.NewPendingPeerCountFilter("namespace-cluster"),
+		filter.NewSnapshotCountFilter("namespace-cluster"),
+	}
+	var target *core.StoreInfo
+	for _, s := range c.stores {
+		if !matchRegionLabels(s, labels) || filter.Target(c, s, filters) || c.IsStoreFlapping(s.GetID()) {
+			continue
+		}
+		if target == nil || s.GetRegionCount() < target.GetRegionCount() ||
+			(s.GetRegionCount() == target.GetRegionCount() && s.GetID() < target.GetID()) {
+			target = s
+		}
+	}
+	if target == nil {
+		log.Warn("no store matches the region labels", zap.Uint64("region-id", region.GetID()), zap.String("namespace", c.namespace))
+		return nil
+	}
+	newPeer, err := c.AllocPeer(target.GetID())
+	if err != nil {
+		log.Warn("failed to allocate peer", zap.Uint64("store-id", target.GetID()), zap.Error(err))
+		return nil
+	}
+	op, err := operator.CreateMovePeerOperator("enforce-region-label", c, region, operator.OpRegion, source, target.GetID(), newPeer.GetId())
+	if err != nil {
+		log.Warn("failed to create region label operator", zap.Uint64("region-id", region.GetID()), zap.Error(err))
+		return nil
+	}
+	return op
+}
+
+// CanMerge checks if it is safe to merge the two regions of the namespace. The
+// peers of a are moved to the stores of b before merging, so the regions must
+// be adjacent and have the same replica configuration, and the merged region
+// placed on the stores of b must satisfy the placement rules of the namespace.
+// A reason is returned if it is not safe.
+func (c *namespaceCluster) CanMerge(a, b *core.RegionInfo) (bool, string) {
+	for _, r := range []*core.RegionInfo{a, b} {
+		if !c.checkRegion(r) {
+			return false, fmt.Sprintf("region %d is not in namespace %s", r.GetID(), c.namespace)
+		}
+	}
+	var merged *core.RegionInfo
+	switch {
+	case bytes.Equal(a.GetEndKey(), b.GetStartKey()):
+		merged = b.Clone(core.WithStartKey(a.GetStartKey()))
+	case bytes.Equal(b.GetEndKey(), a.GetStartKey()):
+		merged = b.Clone(core.WithEndKey(a.GetEndKey()))
+	default:
+		return false, fmt.Sprintf("region %d and %d are not adjacent", a.GetID(), b.GetID())
+	}
+	if len(a.GetVoters()) != len(b.GetVoters()) || len(a.GetLearners()) != len(b.GetLearners()) {
+		return false, fmt.Sprintf("region %d has %d voters and %d learners but region %d has %d voters and %d learners",
+			a.GetID(), len(a.GetVoters()), len(a.GetLearners()), b.GetID(), len(b.GetVoters()), len(b.GetLearners()))
+	}
+	if rules := c.GetOpt().GetPlacementRules(c.namespace); len(rules) > 0 && !placement.FitRules(merged, rules, c.Cluster.GetStore) {
+		return false, fmt.Sprintf("merged region of %d and %d does not fit placement rules", a.GetID(), b.GetID())
+	}
+	return true, ""
+}
+
+// RegionWriteStats returns hot region's write stats.
+func (c *namespaceCluster) RegionWriteStats() map[uint64][]*statistics.HotPeerStat {
+	return c.Cluster.RegionWriteStats()
+}
+
+// GetStoreFlowStats returns the read and written bytes of namespace regions on
+// each store of the namespace. The flow of a region is counted on every store
+// which has a peer of it.
+func (c *namespaceCluster) GetStoreFlowStats() map[uint64]uint64 {
+	flows := make(map[uint64]uint64, len(c.stores))
+	for id := range c.stores {
+		flows[id] = 0
+	}
+	for _, r := range c.getRegions() {
+		flow := r.GetBytesRead() + r.GetBytesWritten()
+		for _, p := range r.GetPeers() {
+			if _, ok := flows[p.GetStoreId()]; ok {
+				flows[p.GetStoreId()] += flow
+			}
+		}
+	}
+	return flows
+}
+
+// getThermalWeights returns the weights of region size and flow in the
+// thermal scores of stores.
+func (c *namespaceCluster) getThermalWeights() (float64, float64) {
+	sizeWeight := c.GetOpt().GetThermalSizeWeight(c.namespace)
+	flowWeight := c.GetOpt().GetThermalFlowWeight(c.namespace)
+	if sizeWeight <= 0 && flowWeight <= 0 {
+		return 1, 1
+	}
+	return math.Max(sizeWeight, 0), math.Max(flowWeight, 0)
+}
+
+// GetThermalScores returns the thermal scores of the up stores of the
+// namespace, keyed by store ID, with a single scan of the regions. The scores
+// combine the size and flow of namespace regions on the stores by the weights
+// configured for the namespace. The size and flow are divided by the averages
+// of the up stores and then weighted, so that the scores average to the sum of
+// the weights. It also returns the factors that the region size and flow are
+// multiplied by to get the scores.
+func (c *namespaceCluster) GetThermalScores() (scores map[uint64]float64, sizeScale, flowScale float64) {
+	sizes := make(map[uint64]float64, len(c.stores))
+	flows := make(map[uint64]float64, len(c.stores))
+	for id, s := range c.stores {
+		if s.IsUp() {
+			sizes[id], flows[id] = 0, 0
+		}
+	}
+	var totalSize, totalFlow float64
+	for _, r := range c.getRegions() {
+		size, flow := float64(r.GetApproximateSize()), float64(r.GetBytesRead()+r.GetBytesWritten())
+		for _, p := range r.GetPeers() {
+			if _, ok := sizes[p.GetStoreId()]; ok {
+				sizes[p.GetStoreId()] += size
+				flows[p.GetStoreId()] += flow
+				totalSize += size
+				totalFlow += flow
+			}
+		}
+	}
+	sizeWeight, flowWeight := c.getThermalWeights()
+	if totalSize > 0 {
+		sizeScale = sizeWeight * float64(len(sizes)) / totalSize
+	}
+	if totalFlow > 0 {
+		flowScale = flowWeight * float64(len(flows)) / totalFlow
+	}
+	scores = make(map[uint64]float64, len(sizes))
+	for id := range sizes {
+		scores[id] = sizes[id]*sizeScale + flows[id]*flowScale
+	}
+	return scores, sizeScale, flowScale
+}
+
+// GetStoreThermalScore returns the thermal score of the store. A store hotter
+// than average has a score above the sum of the weights. It returns 0 if the
+// store is not an up store of the namespace.
+func (c *namespaceCluster) GetStoreThermalScore(storeID uint64) float64 {
+	scores, _, _ := c.GetThermalScores()
+	return scores[storeID]
+}
+
+// StoreHotPeerCount is the number of hot read and hot write peers of a store.
+type StoreHotPeerCount struct {
+	Read  int `json:"read"`
+	Write int `json:"write"`
+}
+
+// GetStoreHotPeerCounts returns the number of hot read and hot write peers of
+// namespace regions on each store of the namespace. Only peers which are hot
+// enough to be scheduled are counted.
+func (c *namespaceCluster) GetStoreHotPeerCounts() map[uint64]StoreHotPeerCount {
+	counts := make(map[uint64]StoreHotPeerCount, len(c.stores))
+	for id := range c.stores {
+		counts[id] = StoreHotPeerCount{}
+	}
+	for storeID, peers := range c.filterHotPeerStats(c.RegionReadStats()) {
+		count := counts[storeID]
+		count.Read += len(peers)
+		counts[storeID] = count
+	}
+	for storeID, peers := range c.filterHotPeerStats(c.RegionWriteStats()) {
+		count := counts[storeID]
+		count.Write += len(peers)
+		counts[storeID] = count
+	}
+	return counts
+}
+
+// CreateHotReadLeaderOperator creates an operator that transfers a hot read
+// leader from the namespace store with the highest read load to the coolest
+// leader eligible store among the followers of the region. The read load of a
+// store is the sum of read bytes rate of the hot leaders on it. The transfer
+// is created only if it does not make the target hotter than the source, and
+// the leader of the same region is not moved again within
+// hotLeaderMoveGuardWindow. It returns nil if there is no such transfer.
+func (c *namespaceCluster) CreateHotReadLeaderOperator() *operator.Operator {
+	loads := make(map[uint64]float64, len(c.stores))
+	hotLeaders := make(map[uint64][]*statistics.HotPeerStat)
+	for storeID, peers := range c.filterHotPeerStats(c.RegionReadStats()) {
+		for _, peer := range peers {
+			if c.GetRegion(peer.RegionID).GetLeader().GetStoreId() == storeID {
+				loads[storeID] += peer.BytesRate
+				hotLeaders[storeID] = append(hotLeaders[storeID], peer)
+			}
+		}
+	}
+	targets := make(map[uint64]struct{})
+	for _, s := range c.GetLeaderEligibleStores() {
+		if s.IsUp() && !s.IsBusy() && !c.state.isStoreBlacklisted(s.GetID()) && c.IsLeaseStable(s.GetID()) {
+			targets[s.GetID()] = struct{}{}
+		}
+	}
+
+	sources := make([]uint64, 0, len(hotLeaders))
+	for id := range hotLeaders {
+		sources = append(sources, id)
+	}
+	sort.Slice(sources, func(i, j int) bool { return loads[sources[i]] > loads[sources[j]] })
+	now := time.Now()
+	for _, source := range sources {
+		peers := hotLeaders[source]
+		sort.Slice(peers, func(i, j int) bool { return peers[i].BytesRate > peers[j].BytesRate })
+		for _, peer := range peers {
+			if c.state.isHotLeaderMovedRecently(peer.RegionID, now, hotLeaderMoveGuardWindow) {
+				continue
+			}
+			region := c.GetRegionWithLeader(peer.RegionID)
+			if region == nil {
+				continue
+			}
+			var target uint64
+			for id := range region.GetFollowers() {
+				if _, ok := targets[id]; !ok {
+					continue
+				}
+				if target == 0 || loads[id] < loads[target] || (loads[id] == loads[target] && id < target) {
+					target = id
+				}
+			}
+			if target == 0 || loads[target]+peer.BytesRate >= loads[source] {
+				continue
+			}
+			c.state.recordHotLeaderMove(region.GetID(), now)
+			return operator.CreateTransferLeaderOperator("namespace-hot-read-leader", region, source, target, operator.OpHotRegion)
+		}
+	}
+	return nil
+}
+
+// filterHotPeerStats filters the hot peer stats of namespace regions on the
+// stores of the namespace, which are hot enough to be scheduled.
+func (c *namespaceCluster) filterHotPeerStats(stats map[uint64][]*statistics.HotPeerStat) map[uint64][]*statistics.HotPeerStat {
+	res := make(map[uint64][]*statistics.HotPeerStat)
+	for storeID, peers := range stats {
+		if _, ok := c.stores[storeID]; !ok {
+			continue
+		}
+		for _, peer := range peers {
+			if peer.HotDegree >= c.GetHotRegionCacheHitsThreshold() && c.GetRegion(peer.RegionID) != nil {
+				res[storeID] = append(res[storeID], peer)
+			}
+		}
+	}
+	return res
+}
+
+// GetLeaderHeadroom returns how many more namespace leaders the store can take
+// before reaching its ideal leader count, which is the share of all leaders in
+// the namespace by leader weight among up stores. A negative value means the
+// store has more leaders than its ideal. It returns 0 if the store is not an
+// up store in the namespace.
+func (c *namespaceCluster) GetLeaderHeadroom(storeID uint64) float64 {
+	store, ok := c.stores[storeID]
+	if !ok || !store.IsUp() {
+		return 0
+	}
+	var totalWeight float64
+	for _, s := range c.stores {
+		if s.IsUp() {
+			totalWeight += s.GetLeaderWeight()
+		}
+	}
+	if totalWeight <= 0 {
+		return 0
+	}
+	var total, current int
+	for _, r := range c.getRegions() {
+		total++
+		if r.GetLeader().GetStoreId() == storeID {
+			current++
+		}
+	}
+	ideal := float64(total) * store.GetLeaderWeight() / totalWeight
+	return ideal - float64(current)
+}
+
+// EstimateRebalanceCost estimates the bytes of data that have to be moved to
+// make the region sizes of the up stores in the namespace equal. It is the sum
+// of sizes by which stores exceed the average.
+func (c *namespaceCluster) EstimateRebalanceCost() uint64 {
+	sizes := make(map[uint64]int64)
+	for id, s := range c.stores {
+		if s.IsUp() {
+			sizes[id] = 0
+		}
+	}
+	if len(sizes) == 0 {
+		return 0
+	}
+	var total int64
+	for _, r := range c.getRegions() {
+		for _, p := range r.GetPeers() {
+			if _, ok := sizes[p.GetStoreId()]; ok {
+				sizes[p.GetStoreId()] += r.GetApproximateSize()
+				total += r.GetApproximateSize()
+			}
+		}
+	}
+	avg := float64(total) / float64(len(sizes))
+	var cost float64
+	for _, size := range sizes {
+		if float64(size) > avg {
+			cost += float64(size) - avg
+		}
+	}
+	// The approximate size of regions is in MB.
+	return uint64(cost * (1 << 20))
+}
+
+// RecommendStoreCount returns the minimum number of stores the namespace needs
+// to hold all the replicas of its regions. The replicas of a region are on
+// distinct stores, each store holds at most MaxRegionsPerStore replicas if it
+// is set, and the total size of the replicas fits in the stores of the
+// average capacity of the up stores excluding the reserved capacity, if the
+// capacities are known.
+func (c *namespaceCluster) RecommendStoreCount() int {
+	maxReplicas := c.GetMaxReplicas()
+	regions := c.getRegions()
+	var size uint64
+	for _, r := range regions {
+		size += uint64(r.GetApproximateSize()) << 20
+	}
+	replicas := uint64(len(regions) * maxReplicas)
+	size *= uint64(maxReplicas)
+
+	count := uint64(maxReplicas)
+	if limit := c.GetOpt().GetMaxRegionsPerStore(c.namespace); limit > 0 {
+		if n := (replicas + limit - 1) / limit; n > count {
+			count = n
+		}
+	}
+	var capacity, stores uint64
+	for id, s := range c.stores {
+		if !s.IsUp() || s.GetCapacity() == 0 {
+			continue
+		}
+		if reserved := c.GetStoreReservedCapacity(id); reserved < s.GetCapacity() {
+			capacity += s.GetCapacity() - reserved
+		}
+		stores++
+	}
+	if capacity >= stores && stores > 0 {
+		avg := capacity / stores
+		if n := (size + avg - 1) / avg; n > count {
+			count = n
+		}
+	}
+	return int(count)
+}
+
+// GetStoresExceedingRegionCap returns the namespace stores which have more
+// namespace regions than regionCap, in ascending order of store ID.
+func (c *namespaceCluster) GetStoresExceedingRegionCap(regionCap int) []*core.StoreInfo {
+	counts := c.getStoreRegionCounts()
+	var stores []*core.StoreInfo
+	for id, s := range c.stores {
+		if counts[id] > regionCap {
+			stores = append(stores, s)
+		}
+	}
+	sort.Slice(stores, func(i, j int) bool { return stores[i].GetID() < stores[j].GetID() })
+	return stores
+}
+
+// GetUnderutilizedStores returns the up stores of the namespace which have
+// fewer namespace regions than threshold, in ascending order of store ID.
+// They are the candidates to be consolidated and decommissioned.
+func (c *namespaceCluster) GetUnderutilizedStores(threshold int) []*core.StoreInfo {
+	counts := c.getStoreRegionCounts()
+	var stores []*core.StoreInfo
+	for id, s := range c.stores {
+		if s.IsUp() && counts[id] < threshold {
+			stores = append(stores, s)
+		}
+	}
+	sort.Slice(stores, func(i, j int) bool { return stores[i].GetID() < stores[j].GetID() })
+	return stores
+}
+
+// GetLeaderTransferCandidates returns the namespace regions whose leaders are
+// on over-loaded stores and which have followers on under-loaded stores that
+// can receive the leaders, in ascending order of region ID. A store is over or
+// under loaded if it has more or fewer namespace leaders than the average of
+// the up stores. Blacklisted stores do not receive leaders.
+func (c *namespaceCluster) GetLeaderTransferCandidates() []*core.RegionInfo {
+	counts := make(map[uint64]int)
+	for id, s := range c.stores {
+		if s.IsUp() {
+			counts[id] = 0
+		}
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+	regions := c.getRegions()
+	var total int
+	for _, r := range regions {
+		if _, ok := counts[r.GetLeader().GetStoreId()]; ok {
+			counts[r.GetLeader().GetStoreId()]++
+			total++
+		}
+	}
+	average := float64(total) / float64(len(counts))
+	blacklist := c.GetBlacklistedStores()
+	filters := []filter.Filter{filter.StoreStateFilter{ActionScope: "namespace-cluster", TransferLeader: true}}
+	isViableTarget := func(storeID uint64) bool {
+		count, ok := counts[storeID]
+		return ok && float64(count) < average && !containsUint64(blacklist, storeID) &&
+			!filter.Target(c, c.stores[storeID], filters)
+	}
+
+	var candidates []*core.RegionInfo
+	for _, r := range regions {
+		leaderStore := r.GetLeader().GetStoreId()
+		if count, ok := counts[leaderStore]; !ok || float64(count) <= average {
+			continue
+		}
+		for _, p := range r.GetFollowers() {
+			if isViableTarget(p.GetStoreId()) {
+				candidates = append(candidates, r)
+				break
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].GetID() < candidates[j].GetID() })
+	return candidates
+}
+
+// GetDecommissionProgress returns the ratio of the namespace regions moved off
+// the offline store to the ones on it when it is first seen offline, which is
+// in [0, 1]. It returns 0 if the store is up, and 1 if it is tombstone or has
+// no region to move.
+func (c *namespaceCluster) GetDecommissionProgress(storeID uint64) float64 {
+	store, ok := c.stores[storeID]
+	if !ok || store.IsUp() {
+		return 0
+	}
+	if store.IsTombstone() {
+		return 1
+	}
+	remaining := c.getStoreRegionCounts()[storeID]
+	baseline := c.state.getDecommissionBaseline(storeID, remaining)
+	if baseline == 0 || remaining == 0 {
+		return 1
+	}
+	if remaining >= baseline {
+		return 0
+	}
+	return float64(baseline-remaining) / float64(baseline)
+}
+
+// clearDecommissionBaseline removes the number of namespace regions recorded on
+// the store when it is first seen offline, so that the progress starts over if
+// it goes offline again.
+func (c *namespaceCluster) clearDecommissionBaseline(storeID uint64) {
+	c.state.clearDecommissionBaseline(storeID)
+}
+
+// getStoreRegionCounts returns the number of namespace regions on each store.
+func (c *namespaceCluster) getStoreRegionCounts() map[uint64]int {
+	counts := make(map[uint64]int)
+	for _, r := range c.getRegions() {
+		for _, p := range r.GetPeers() {
+			counts[p.GetStoreId()]++
+		}
+	}
+	return counts
+}
+
+// GetRegionGrowthRate returns the growth rate of the region count of the
+// namespace in regions per hour, based on the region counts sampled by the
+// cluster.
+func (c *namespaceCluster) GetRegionGrowthRate() float64 {
+	return c.state.getRegionGrowthRate()
+}
+
+// GetSchedulingEfficiency returns the ratio of the useful operators produced
+// for the namespace in the last hour to all of them. Operators reverted by
+// later ones and the operators reverting them are wasted.
+func (c *namespaceCluster) GetSchedulingEfficiency() float64 {
+	return c.state.getSchedulingEfficiency(time.Now(), schedulingEfficiencyWindow)
+}
+
+// GetRegionSizeHistogram returns the number of namespace regions in each
+// bucket of approximate sizes. The buckets are the upper bounds in MB, and a
+// region is counted in the smallest bucket no less than its size. Regions
+// larger than all the buckets are counted under math.MaxInt64. Every bucket is
+// present in the result even if it has no region.
+func (c *namespaceCluster) GetRegionSizeHistogram(buckets []int64) map[int64]int {
+	bounds := append(make([]int64, 0, len(buckets)+1), buckets...)
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+	bounds = append(bounds, math.MaxInt64)
+	histogram := make(map[int64]int, len(bounds))
+	for _, b := range bounds {
+		histogram[b] = 0
+	}
+	for _, r := range c.getRegions() {
+		i := sort.Search(len(bounds), func(i int) bool { return bounds[i] >= r.GetApproximateSize() })
+		histogram[bounds[i]]++
+	}
+	return histogram
+}
+
+// GetPeerDistributionEntropy returns the Shannon entropy in bits of the peer
+// distribution over the up stores in the namespace. It reaches the max value
+// log2(n) when peers are spread evenly across n stores, and is 0 if all peers
+// are on one store or there is no peer.
+func (c *namespaceCluster) GetPeerDistributionEntropy() float64 {
+	counts := make(map[uint64]int)
+	for id, s := range c.stores {
+		if s.IsUp() {
+			counts[id] = 0
+		}
+	}
+	var total int
+	for _, r := range c.getRegions() {
+		for _, p := range r.GetPeers() {
+			if _, ok := counts[p.GetStoreId()]; ok {
+				counts[p.GetStoreId()]++
+				total++
+			}
+		}
+	}
+	var entropy float64
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(total)
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
+// StorePairColocation is the number of namespace regions which have replicas
+// on both stores of the pair. All these regions lose at least two replicas if
+// both stores fail.
+type StorePairColocation struct {
+	StoreIDs    [2]uint64 `json:"store_ids"`
+	RegionCount int       `json:"region_count"`
+}
+
+// GetColocationRisk returns the store pairs which share replicas of namespace
+// regions, in descending order of shared region counts, so that the riskiest
+// pairs come first. Ties are ordered by store IDs.
+func (c *namespaceCluster) GetColocationRisk() []StorePairColocation {
+	counts := make(map[[2]uint64]int)
+	for _, r := range c.getRegions() {
+		storeIDs := make([]uint64, 0, len(r.GetPeers()))
+		for id := range r.GetStoreIds() {
+			storeIDs = append(storeIDs, id)
+		}
+		sort.Slice(storeIDs, func(i, j int) bool { return storeIDs[i] < storeIDs[j] })
+		for i := range storeIDs {
+			for j := i + 1; j < len(storeIDs); j++ {
+				counts[[2]uint64{storeIDs[i], storeIDs[j]}]++
+			}
+		}
+	}
+	pairs := make([]StorePairColocation, 0, len(counts))
+	for ids, count := range counts {
+		pairs = append(pairs, StorePairColocation{StoreIDs: ids, RegionCount: count})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].RegionCount != pairs[j].RegionCount {
+			return pairs[i].RegionCount > pairs[j].RegionCount
+		}
+		if pairs[i].StoreIDs[0] != pairs[j].StoreIDs[0] {
+			return pairs[i].StoreIDs[0] < pairs[j].StoreIDs[0]
+		}
+		return pairs[i].StoreIDs[1] < pairs[j].StoreIDs[1]
+	})
+	return pairs
+}
+
+// NamespaceStats is the distribution of regions and leaders of a namespace
+// over its stores.
+type NamespaceStats struct {
+	RegionCount       int            `json:"region_count"`
+	StoreRegionCounts map[uint64]int `json:"store_region_counts"`
+	StoreLeaderCounts map[uint64]int `json:"store_leader_counts"`
+}
+
+// GetNamespaceStats returns the current distribution of regions and leaders
+// of the namespace.
+func (c *namespaceCluster) GetNamespaceStats() NamespaceStats {
+	return c.newNamespaceStats(c.getRegions())
+}
+
+func (c *namespaceCluster) newNamespaceStats(regions []*core.RegionInfo) NamespaceStats {
+	stats := NamespaceStats{
+		RegionCount:       len(regions),
+		StoreRegionCounts: make(map[uint64]int, len(c.stores)),
+		StoreLeaderCounts: make(map[uint64]int, len(c.stores)),
+	}
+	for id := range c.stores {
+		stats.StoreRegionCounts[id] = 0
+		stats.StoreLeaderCounts[id] = 0
+	}
+	for _, r := range regions {
+		for _, p := range r.GetPeers() {
+			stats.StoreRegionCounts[p.GetStoreId()]++
+		}
+		if leader := r.GetLeader(); leader != nil {
+			stats.StoreLeaderCounts[leader.GetStoreId()]++
+		}
+	}
+	return stats
+}
+
+// SimulatePlacement applies the operators to copies of the namespace regions
+// in order, and returns the projected distribution of regions and leaders.
+// The cluster is not changed. It returns an error if an operator is not for a
+// namespace region, or has a step that cannot be applied or simulated, e.g.
+// splitting regions.
+func (c *namespaceCluster) SimulatePlacement(ops []*operator.Operator) (NamespaceStats, error) {
+	regions := make(map[uint64]*core.RegionInfo)
+	for _, r := range c.getRegions() {
+		regions[r.GetID()] = r
+	}
+	for _, op := range ops {
+		region, ok := regions[op.RegionID()]
+		if !ok {
+			return NamespaceStats{}, errors.Errorf("region %d of operator %s is not in namespace %s", op.RegionID(), op.Desc(), c.namespace)
+		}
+		for i := 0; i < op.Len(); i++ {
+			var err error
+			if region, err = simulateStep(region, op.Step(i)); err != nil {
+				return NamespaceStats{}, errors.Wrapf(err, "operator %s", op.Desc())
+			}
+		}
+		if region == nil {
+			delete(regions, op.RegionID())
+		} else {
+			regions[op.RegionID()] = region
+		}
+	}
+	projected := make([]*core.RegionInfo, 0, len(regions))
+	for _, r := range regions {
+		projected = append(projected, r)
+	}
+	return c.newNamespaceStats(projected), nil
+}
+
+// simulateStep returns the copy of the region after the step is applied. It
+// returns nil if the region is merged into another one.
+func simulateStep(region *core.RegionInfo, step operator.OpStep) (*core.RegionInfo, error) {
+	addPeer := func(toStore, peerID uint64, isLearner bool) (*core.RegionInfo, error) {
+		if region.GetStorePeer(toStore) != nil {
+			return nil, errors.Errorf("region %d already has a peer on store %d", region.GetID(), toStore)
+		}
+		return region.Clone(core.WithAddPeer(&metapb.Peer{Id: peerID, StoreId: toStore, IsLearner: isLearner})), nil
+	}
+	switch s := step.(type) {
+	case operator.TransferLeader:
+		peer := region.GetStoreVoter(s.ToStore)
+		if peer == nil {
+			return nil, errors.Errorf("region %d has no voter on store %d", region.GetID(), s.ToStore)
+		}
+		return region.Clone(core.WithLeader(peer)), nil
+	case operator.AddPeer:
+		return addPeer(s.ToStore, s.PeerID, false)
+	case operator.AddLightPeer:
+		return addPeer(s.ToStore, s.PeerID, false)
+	case operator.AddLearner:
+		return addPeer(s.ToStore, s.PeerID, true)
+	case operator.AddLightLearner:
+		return addPeer(s.ToStore, s.PeerID, true)
+	case operator.PromoteLearner:
+		if region.GetStoreLearner(s.ToStore) == nil {
+			return nil, errors.Errorf("region %d has no learner on store %d", region.GetID(), s.ToStore)
+		}
+		return region.Clone(core.WithPromoteLearner(s.PeerID)), nil
+	case operator.RemovePeer:
+		if region.GetStorePeer(s.FromStore) == nil {
+			return nil, errors.Errorf("region %d has no peer on store %d", region.GetID(), s.FromStore)
+		}
+		if region.GetLeader().GetStoreId() == s.FromStore {
+			return nil, errors.Errorf("cannot remove the leader of region %d", region.GetID())
+		}
+		return region.Clone(core.WithRemoveStorePeer(s.FromStore)), nil
+	case operator.MergeRegion:
+		if s.IsPassive {
+			return region, nil
+		}
+		return nil, nil
+	default:
+		return nil, errors.Errorf("step %v cannot be simulated", step)
+	}
+}
+
+// SimulateStoreFailure returns the projected distribution of regions and
+// leaders of the namespace after the store fails and its regions are
+// redistributed. The peers on the failed store are replaced on the up stores
+// with the fewest regions that have no peer of the region yet, and the
+// leaders on it are transferred to the remaining voters with the fewest
+// leaders. Regions are handled in order of ID, and a peer is dropped if no
+// store can hold its replacement. The cluster is not changed.
+func (c *namespaceCluster) SimulateStoreFailure(storeID uint64) NamespaceStats {
+	regions := c.getRegions()
+	sort.Slice(regions, func(i, j int) bool { return regions[i].GetID() < regions[j].GetID() })
+	stats := c.newNamespaceStats(regions)
+	regionCounts, leaderCounts := stats.StoreRegionCounts, stats.StoreLeaderCounts
+	projected := make([]*core.RegionInfo, 0, len(regions))
+	for _, r := range regions {
+		peer := r.GetStorePeer(storeID)
+		if peer == nil {
+			projected = append(projected, r)
+			continue
+		}
+		region := r.Clone(core.WithRemoveStorePeer(storeID))
+		regionCounts[storeID]--
+		var target uint64
+		for id, s := range c.stores {
+			if id == storeID || !s.IsUp() || region.GetStorePeer(id) != nil {
+				continue
+			}
+			if target == 0 || regionCounts[id] < regionCounts[target] || (regionCounts[id] == regionCounts[target] && id < target) {
+				target = id
+			}
+		}
+		if target != 0 {
+			region = region.Clone(core.WithAddPeer(&metapb.Peer{StoreId: target, IsLearner: peer.GetIsLearner()}))
+			regionCounts[target]++
+		}
+		if r.GetLeader().GetStoreId() == storeID {
+			leaderCounts[storeID]--
+			var leader *metapb.Peer
+			for _, v := range region.GetVoters() {
+				id := v.GetStoreId()
+				if leader == nil || leaderCounts[id] < leaderCounts[leader.GetStoreId()] || (leaderCounts[id] == leaderCounts[leader.GetStoreId()] && id < leader.GetStoreId()) {
+					leader = v
+				}
+			}
+			region = region.Clone(core.WithLeader(leader))
+			if leader != nil {
+				leaderCounts[leader.GetStoreId()]++
+			}
+		}
+		projected = append(projected, region)
+	}
+	return c.newNamespaceStats(projected)
+}
+
+// EstimateConvergenceTicks estimates how many schedule ticks the namespace
+// takes to balance the regions and leaders of the up stores. The number of
+// moves needed is the sum of counts by which stores exceed the average, and at
+// most region-schedule-limit and leader-schedule-limit of them are done in a
+// tick. It returns 0 if the namespace is balanced, and -1 if it never
+// converges because a needed schedule limit is 0.
+func (c *namespaceCluster) EstimateConvergenceTicks() int {
+	regionCounts, leaderCounts := make(map[uint64]int), make(map[uint64]int)
+	for id, s := range c.stores {
+		if s.IsUp() {
+			regionCounts[id], leaderCounts[id] = 0, 0
+		}
+	}
+	if len(regionCounts) == 0 {
+		return 0
+	}
+	for _, r := range c.getRegions() {
+		for _, p := range r.GetPeers() {
+			if _, ok := regionCounts[p.GetStoreId()]; ok {
+				regionCounts[p.GetStoreId()]++
+			}
+		}
+		if _, ok := leaderCounts[r.GetLeader().GetStoreId()]; ok {
+			leaderCounts[r.GetLeader().GetStoreId()]++
+		}
+	}
+	regionTicks := convergenceTicks(regionCounts, c.GetRegionScheduleLimit())
+	leaderTicks := convergenceTicks(leaderCounts, c.GetLeaderScheduleLimit())
+	if regionTicks < 0 || leaderTicks < 0 {
+		return -1
+	}
+	if regionTicks > leaderTicks {
+		return regionTicks
+	}
+	return leaderTicks
+}
+
+func convergenceTicks(counts map[uint64]int, limit uint64) int {
+	var total int
+	for _, count := range counts {
+		total += count
+	}
+	expect := (total + len(counts) - 1) / len(counts)
+	var moves int
+	for _, count := range counts {
+		if count > expect {
+			moves += count - expect
+		}
+	}
+	if moves == 0 {
+		return 0
+	}
+	if limit == 0 {
+		return -1
+	}
+	return int((uint64(moves) + limit - 1) / limit)
+}
+
+// DrainToStores plans the operators that relocate all the regions of the
+// namespace to the target stores. For each region, the peers out of the
+// targets are moved to the targets with the fewest regions, including the
+// ones already planned. Regions that cannot be placed, e.g. there are not
+// enough target stores, are skipped. If moving all the peers of a region
+// needs more steps than max-operator-steps, only part of them are moved and
+// the rest are left to later calls. The operators are ordered by region ID.
+// The plan is kept within the region schedule limit of the namespace and the
+// store limits of the operator controller, and skips the regions which have
+// running operators, so the rest are left to later calls too.
+func (c *namespaceCluster) DrainToStores(oc *schedule.OperatorController, targetStores []uint64) []*operator.Operator {
+	running := oc.OperatorCount(operator.OpRegion)
+	limit := c.GetRegionScheduleLimit()
+	if running >= limit {
+		return nil
+	}
+	counts := make(map[uint64]int)
+	for _, id := range targetStores {
+		if s := c.GetStore(id); s != nil && s.IsUp() {
+			counts[id] = s.GetRegionCount()
+		}
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+
+	regions := c.getRegions()
+	sort.Slice(regions, func(i, j int) bool { return regions[i].GetID() < regions[j].GetID() })
+	var ops []*operator.Operator
+	for _, r := range regions {
+		if running+uint64(len(ops)) >= limit {
+			break
+		}
+		if oc.GetOperator(r.GetID()) != nil {
+			continue
+		}
+		kept := make(map[uint64]struct{})
+		var moved []*metapb.Peer
+		for _, p := range r.GetPeers() {
+			if _, ok := counts[p.GetStoreId()]; ok {
+				kept[p.GetStoreId()] = struct{}{}
+			} else {
+				moved = append(moved, p)
+			}
+		}
+		if len(moved) == 0 {
+			continue
+		}
+		// The leader is moved last, so that moving followers first fits in
+		// fewer steps.
+		leaderID := r.GetLeader().GetId()
+		sort.SliceStable(moved, func(i, j int) bool { return moved[i].GetId() != leaderID && moved[j].GetId() == leaderID })
+		var added []uint64
+		for range moved {
+			var target uint64
+			for id, count := range counts {
+				if _, ok := kept[id]; ok || containsUint64(added, id) {
+					continue
+				}
+				if target == 0 || count < counts[target] || (count == counts[target] && id < target) {
+					target = id
+				}
+			}
+			if target == 0 {
+				break
+			}
+			added = append(added, target)
+		}
+		if len(added) < len(moved) {
+			log.Warn("not enough target stores to drain region", zap.Uint64("region-id", r.GetID()), zap.String("namespace", c.namespace))
+			continue
+		}
+		op, n, err := c.createStepLimitedMoveOperator("drain-namespace", r, operator.OpRegion, kept, moved, added)
+		if err != nil {
+			log.Warn("failed to create drain operator", zap.Uint64("region-id", r.GetID()), zap.Error(err))
+			continue
+		}
+		if oc.ExceedStoreLimit(append(ops, op)...) {
+			continue
+		}
+		for _, id := range added[:n] {
+			counts[id]++
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// createStepLimitedMoveOperator creates an operator that moves the peers of
+// the region to the added stores one by one in order, while the peers on the
+// kept stores stay. As many peers as possible are moved within
+// max-operator-steps, and the number of moved peers is returned.
+func (c *namespaceCluster) createStepLimitedMoveOperator(desc string, region *core.RegionInfo, kind operator.OpKind, kept map[uint64]struct{}, moved []*metapb.Peer, added []uint64) (*operator.Operator, int, error) {
+	for n := len(added); n > 0; n-- {
+		stores := make(map[uint64]struct{}, len(region.GetPeers()))
+		for id := range kept {
+			stores[id] = struct{}{}
+		}
+		for _, id := range added[:n] {
+			stores[id] = struct{}{}
+		}
+		for _, p := range moved[n:] {
+			stores[p.GetStoreId()] = struct{}{}
+		}
+		op, err := operator.CreateMoveRegionOperator(desc, c, region, kind, stores)
+		if err == operator.ErrExceedMaxOperatorSteps {
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		return op, n, nil
+	}
+	return nil, 0, errors.Errorf("moving a peer of region %d needs more than %d steps", region.GetID(), c.GetMaxOperatorSteps())
+}
+
+func containsUint64(ids []uint64, id uint64) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRecoveryPriority returns how critical the namespace regions that the
+// store should reacquire are. Each region lacking healthy voters counts the
+// number of missing voters, unless the store already holds a healthy peer of
+// it, so stores which can repair more under-replicated regions get higher
+// priorities. It returns 0 if the store is not an up store in the namespace.
+func (c *namespaceCluster) GetRecoveryPriority(storeID uint64) int {
+	if s, ok := c.stores[storeID]; !ok || !s.IsUp() {
+		return 0
+	}
+	maxReplicas := c.GetMaxReplicas()
+	var priority int
+	for _, r := range c.getRegions() {
+		healthy := len(r.GetVoters())
+		var onStore bool
+		for _, p := range r.GetVoters() {
+			if r.GetDownPeer(p.GetId()) != nil {
+				healthy--
+			} else if p.GetStoreId() == storeID {
+				onStore = true
+			}
+		}
+		if missing := maxReplicas - healthy; missing > 0 && !onStore {
+			priority += missing
+		}
+	}
+	return priority
+}
+
+// GetStoresByRecoveryPriority returns the up stores of the namespace in
+// descending order of recovery priority, so that schedulers can process the
+// stores which repair more critical regions first. Ties are ordered by store
+// ID.
+func (c *namespaceCluster) GetStoresByRecoveryPriority() []*core.StoreInfo {
+	priorities := make(map[uint64]int)
+	var stores []*core.StoreInfo
+	for id, s := range c.stores {
+		if s.IsUp() {
+			priorities[id] = c.GetRecoveryPriority(id)
+			stores = append(stores, s)
+		}
+	}
+	sort.Slice(stores, func(i, j int) bool {
+		pi, pj := priorities[stores[i].GetID()], priorities[stores[j].GetID()]
+		return pi > pj || (pi == pj && stores[i].GetID() < stores[j].GetID())
+	})
+	return stores
+}
+
+// GetCapacityUtilizationSkew returns how much the fullest store of the
+// namespace exceeds the average, in terms of the used/total capacity ratios of
+// the up stores. Stores that have not reported their capacity are ignored. It
+// returns 0 if no store reports its capacity.
+func (c *namespaceCluster) GetCapacityUtilizationSkew() float64 {
+	var sum, max float64
+	var count int
+	for _, s := range c.stores {
+		if !s.IsUp() || s.GetCapacity() == 0 {
+			continue
+		}
+		used := 1 - s.AvailableRatio()
+		sum += used
+		max = math.Max(max, used)
+		count++
+	}
+	if count == 0 {
+		return 0
+	}
+	return max - sum/float64(count)
+}
+
+// GetPendingPeerCount returns the number of pending peers in the namespace.
+func (c *namespaceCluster) GetPendingPeerCount() int {
+	var count int
+	for _, s := range c.stores {
+		count += s.GetPendingPeerCount()
+	}
+	return count
+}
+
+// GetMaxReplicationLag returns the worst replication lag among the peers of
+// the namespace regions. The only progress info reported by the leaders is how
+// long a peer has been down, so the lag is the max down duration of the peers,
+// and it is 0 if no peer is down.
+func (c *namespaceCluster) GetMaxReplicationLag() time.Duration {
+	var lag time.Duration
+	for _, r := range c.getRegions() {
+		for _, stats := range r.GetDownPeers() {
+			if d := time.Duration(stats.GetDownSeconds()) * time.Second; d > lag {
+				lag = d
+			}
+		}
+	}
+	return lag
+}
+
+// GetEffectiveReplicationFactor returns the average number of healthy voters
+// of the namespace regions, i.e. voters which are not down. It is lower than
+// the max replicas when the durability of some regions is degraded, and it
+// returns 0 if the namespace has no region.
+func (c *namespaceCluster) GetEffectiveReplicationFactor() float64 {
+	regions := c.getRegions()
+	if len(regions) == 0 {
+		return 0
+	}
+	var healthy int
+	for _, r := range regions {
+		for _, p := range r.GetVoters() {
+			if r.GetDownPeer(p.GetId()) == nil {
+				healthy++
+			}
+		}
+	}
+	return float64(healthy) / float64(len(regions))
+}
+
+// GetSchedulingBacklog estimates the number of moves needed to bring the
+// namespace regions back to a healthy state. An under-replicated region needs
+// a peer added for each missing voter which is not down, an over-replicated
+// region needs its extra voters removed, and a region violating the placement
+// rules of the namespace needs at least one move. A region is counted by the
+// first of them that applies, since fixing the replicas may fit the rules too.
+func (c *namespaceCluster) GetSchedulingBacklog() int {
+	maxReplicas := c.GetMaxReplicas()
+	rules := c.GetOpt().GetPlacementRules(c.namespace)
+	var backlog int
+	for _, r := range c.getRegions() {
+		voters := r.GetVoters()
+		healthy := 0
+		for _, p := range voters {
+			if r.GetDownPeer(p.GetId()) == nil {
+				healthy++
+			}
+		}
+		switch {
+		case healthy < maxReplicas:
+			backlog += maxReplicas - healthy
+		case len(voters) > maxReplicas:
+			backlog += len(voters) - maxReplicas
+		case len(rules) > 0 && r.GetLeader() != nil && !placement.FitRules(r, rules, c.Cluster.GetStore):
+			backlog++
+		}
+	}
+	return backlog
+}
+
+// GetDataLossRisk returns the risk of losing data in the namespace, 0 means
+// safe and higher is riskier. A voter of a region is safe if it is neither
+// down nor pending, and its store is up, connected, not flapping and not slow.
+// Safe voters in the same failure domain count once since they may fail
+// together. Each region with fewer safe voters than the max replicas adds the
+// fraction of the replicas missing, or 1 if it has lost the quorum.
+func (c *namespaceCluster) GetDataLossRisk() float64 {
+	maxReplicas := c.GetMaxReplicas()
+	if maxReplicas <= 0 {
+		return 0
+	}
+	var risk float64
+	for _, r := range c.getRegions() {
+		domains := make(map[string]struct{})
+		for _, p := range r.GetVoters() {
+			if !c.isVoterSafe(r, p) {
+				continue
+			}
+			domain := c.GetStoreDomain(p.GetStoreId())
+			if domain == "" {
+				domain = fmt.Sprintf("store-%d", p.GetStoreId())
+			}
+			domains[domain] = struct{}{}
+		}
+		switch safe := len(domains); {
+		case safe >= maxReplicas:
+		case safe < maxReplicas/2+1:
+			risk++
+		default:
+			risk += float64(maxReplicas-safe) / float64(maxReplicas)
+		}
+	}
+	return risk
+}
+
+// GetSinglePointFailureStores returns the stores whose failure would make
+// some namespace regions lose the quorum, in ascending order of store ID. A
+// store fails together with the other stores in its failure domain, and the
+// voters which are not safe are considered failed already. Only the stores of
+// the voters of such regions are returned, e.g. all the stores of a region
+// whose voters are in the same rack. Regions which have lost the quorum are
+// skipped.
+func (c *namespaceCluster) GetSinglePointFailureStores() []uint64 {
+	spof := make(map[uint64]struct{})
+	for _, r := range c.getRegions() {
+		quorum := len(r.GetVoters())/2 + 1
+		domains := make(map[string][]uint64)
+		var safe int
+		for _, p := range r.GetVoters() {
+			if !c.isVoterSafe(r, p) {
+				continue
+			}
+			domain := c.GetStoreDomain(p.GetStoreId())
+			if domain == "" {
+				domain = fmt.Sprintf("store-%d", p.GetStoreId())
+			}
+			domains[domain] = append(domains[domain], p.GetStoreId())
+			safe++
+		}
+		if safe < quorum {
+			// The region is unavailable already.
+			continue
+		}
+		for _, storeIDs := range domains {
+			if safe-len(storeIDs) < quorum {
+				for _, id := range storeIDs {
+					spof[id] = struct{}{}
+				}
+			}
+		}
+	}
+	storeIDs := make([]uint64, 0, len(spof))
+	for id := range spof {
+		storeIDs = append(storeIDs, id)
+	}
+	sort.Slice(storeIDs, func(i, j int) bool { return storeIDs[i] < storeIDs[j] })
+	return storeIDs
+}
+
+// isVoterSafe checks if the voter of the region is healthy and on a healthy
+// store of the namespace.
+func (c *namespaceCluster) isVoterSafe(region *core.RegionInfo, peer *metapb.Peer) bool {
+	if region.GetDownPeer(peer.GetId()) != nil || region.GetPendingPeer(peer.GetId()) != nil {
+		return false
+	}
+	storeID := peer.GetStoreId()
+	store, ok := c.stores[storeID]
+	if !ok || !store.IsUp() || store.IsDisconnected() {
+		return false
+	}
+	return !c.IsStoreFlapping(storeID) && !c.IsSlowStore(storeID)
+}
+
+// GetStaleHeartbeatRegionCount returns the number of namespace regions whose
+// last heartbeat is older than threshold, which signals that the stores of the
+// namespace may be partitioned from PD. Regions which have not reported any
+// heartbeat since PD started are not counted.
+func (c *namespaceCluster) GetStaleHeartbeatRegionCount(threshold time.Duration) int {
+	now := time.Now()
+	var count int
+	for _, r := range c.getRegions() {
+		last, ok := c.states.regionHeartbeats.getLastHeartbeat(r.GetID())
+		if ok && now.Sub(last) > threshold {
+			count++
+		}
+	}
+	return count
+}
+
+// GetStoreHeartbeatIntervals returns the interval of the latest heartbeat of
+// each namespace store, keyed by store ID, which is the time range covered by
+// the statistics the store reports. Irregular or long intervals signal that
+// the store is overloaded. Stores which have not reported the interval are
+// not included.
+func (c *namespaceCluster) GetStoreHeartbeatIntervals() map[uint64]time.Duration {
+	intervals := make(map[uint64]time.Duration)
+	for id, s := range c.stores {
+		interval := s.GetStoreStats().GetInterval()
+		if interval.GetEndTimestamp() <= interval.GetStartTimestamp() {
+			continue
+		}
+		intervals[id] = time.Duration(interval.GetEndTimestamp()-interval.GetStartTimestamp()) * time.Second
+	}
+	return intervals
+}
+
+// GetNamespaceMaxPendingPeerCount returns the max number of pending peers in
+// the namespace. 0 means no limit. Unlike GetMaxPendingPeerCount, which limits
+// the pending peers of each store, it limits the pending peers of the whole
+// namespace.
+func (c *namespaceCluster) GetNamespaceMaxPendingPeerCount() uint64 {
+	return c.GetOpt().GetNamespaceMaxPendingPeerCount(c.namespace)
+}
+
+// isAddPeerThrottled returns true if the namespace has more pending peers than
+// its limit, so that no more peers should be added.
+func (c *namespaceCluster) isAddPeerThrottled() bool {
+	limit := c.GetNamespaceMaxPendingPeerCount()
+	return limit > 0 && uint64(c.GetPendingPeerCount()) > limit
+}
+
+// filterAddPeerOperators removes the operators that add peers unless they are
+// of high priority.
+func filterAddPeerOperators(ops []*operator.Operator) []*operator.Operator {
+	var res []*operator.Operator
+	for _, op := range ops {
+		if op.GetPriorityLevel() >= core.HighPriority || !isAddPeerOperator(op) {
+			res = append(res, op)
+		}
+	}
+	return res
+}
+
+func isAddPeerOperator(op *operator.Operator) bool {
+	for i := 0; i < op.Len(); i++ {
+		switch op.Step(i).(type) {
+		case operator.AddPeer, operator.AddLightPeer, operator.AddLearner, operator.AddLightLearner:
+			return true
+		}
+	}
+	return false
+}
+
+// addPeerStores returns the IDs of the stores which the operator adds peers
+// onto.
+func addPeerStores(op *operator.Operator) []uint64 {
+	var storeIDs []uint64
+	for i := 0; i < op.Len(); i++ {
+		switch step := op.Step(i).(type) {
+		case operator.AddPeer:
+			storeIDs = append(storeIDs, step.ToStore)
+		case operator.AddLightPeer:
+			storeIDs = append(storeIDs, step.ToStore)
+		case operator.AddLearner:
+			storeIDs = append(storeIDs, step.ToStore)
+		case operator.AddLightLearner:
+			storeIDs = append(storeIDs, step.ToStore)
+		}
+	}
+	return storeIDs
+}
+
+// GetJoinThrottleTicks returns the number of ramp-up ticks over which the
+// stores joining the namespace are ramped up.
+func (c *namespaceCluster) GetJoinThrottleTicks() uint64 {
+	return c.GetOpt().GetJoinThrottleTicks(c.namespace)
+}
+
+// IsJoinThrottled returns true if the store is ramping up after joining the
+// namespace and has received its share of peers. In the k-th of the n
+// throttle ticks since a store joins, it can receive at most
+// ceil(k * limit / n) peers in total, where limit is the region schedule
+// limit of the namespace, so that the regions migrate onto the stores joining
+// at once gradually.
+func (c *namespaceCluster) IsJoinThrottled(storeID uint64) bool {
+	rampTicks := c.GetJoinThrottleTicks()
+	if rampTicks == 0 {
+		return false
+	}
+	ticks, received, ok := c.state.getStoreJoin(storeID)
+	if !ok || ticks > rampTicks {
+		return false
+	}
+	quota := (ticks*c.GetRegionScheduleLimit() + rampTicks - 1) / rampTicks
+	return received >= quota
+}
+
+// isJoinThrottledOperator returns true if the operator adds a peer onto a
+// store throttled by IsJoinThrottled, unless it is of high priority.
+func (c *namespaceCluster) isJoinThrottledOperator(op *operator.Operator) bool {
+	if op.GetPriorityLevel() >= core.HighPriority {
+		return false
+	}
+	for _, storeID := range addPeerStores(op) {
+		if c.IsJoinThrottled(storeID) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetZoneLabel returns the location label of the availability zones of the
+// namespace.
+func (c *namespaceCluster) GetZoneLabel() string {
+	return c.GetOpt().GetZoneLabel(c.namespace)
+}
+
+// GetPreferredLeaderZone returns the availability zone in which the leaders of
+// the namespace regions are kept.
+func (c *namespaceCluster) GetPreferredLeaderZone() string {
+	return c.GetOpt().GetPreferredLeaderZone(c.namespace)
+}
+
+// GetSchedulePauseWindows returns the daily time windows in which scheduling of
+// the namespace is paused.
+func (c *namespaceCluster) GetSchedulePauseWindows() []typeutil.TimeWindow {
+	return c.GetOpt().GetSchedulePauseWindows(c.namespace)
+}
+
+func (c *namespaceCluster) isSchedulePaused(now time.Time) bool {
+	for _, w := range c.GetSchedulePauseWindows() {
+		if w.Contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMaxConcurrentOperators returns the max number of coexist operators of
+// regions in the namespace. 0 means no limit.
+func (c *namespaceCluster) GetMaxConcurrentOperators() uint64 {
+	return c.GetOpt().GetMaxConcurrentOperators(c.namespace)
+}
+
+// GetMaxMigrationBytesPerSec returns the max bytes per second of region data
+// moved by the operators in the namespace. 0 means no limit.
+func (c *namespaceCluster) GetMaxMigrationBytesPerSec() uint64 {
+	return c.GetOpt().GetMaxMigrationBytesPerSec(c.namespace)
+}
+
+// GetReadReplicaCount returns the number of read replicas, which are learners
+// on the read replica stores, of regions in the namespace.
+func (c *namespaceCluster) GetReadReplicaCount() uint64 {
+	return c.GetOpt().GetReadReplicaCount(c.namespace)
+}
+
+// GetReadReplicaStores returns the IDs of the stores designated to hold the
+// read replicas of regions in the namespace.
+func (c *namespaceCluster) GetReadReplicaStores() []uint64 {
+	return c.GetOpt().GetReadReplicaStores(c.namespace)
+}
+
+// GetMaxOperatorSteps returns the max number of steps of an operator of
+// regions in the namespace. 0 means no limit.
+func (c *namespaceCluster) GetMaxOperatorSteps() uint64 {
+	return c.GetOpt().GetMaxOperatorSteps(c.namespace)
+}
+
+// exceedMaxOperatorSteps returns true if the operator has more steps than the
+// limit of the namespace.
+func (c *namespaceCluster) exceedMaxOperatorSteps(op *operator.Operator) bool {
+	limit := c.GetMaxOperatorSteps()
+	return limit > 0 && uint64(op.Len()) > limit
+}
+
+const (
+	// defaultSnapshotSpeed is the speed in MB/s that stores send and apply
+	// snapshots if it is not configured for the namespace.
+	defaultSnapshotSpeed = 10.0
+	// snapshotTimeoutFactor leaves room for the snapshot speed to fluctuate
+	// when estimating operator timeouts.
+	snapshotTimeoutFactor = 2.0
+)
+
+// maxStoreWeightStep is the max change of a store's region weight in one round
+// of auto tuning, to avoid thrashing the balance.
+const maxStoreWeightStep = 0.1
+
+// IsStoreWeightAutoTuningEnabled returns if the region weights of stores in the
+// namespace are adjusted by their capacities automatically.
+func (c *namespaceCluster) IsStoreWeightAutoTuningEnabled() bool {
+	return c.GetOpt().IsStoreWeightAutoTuningEnabled(c.namespace)
+}
+
+// TuneStoreWeights moves the region weights of the up stores in the namespace
+// towards the ratios of their capacities to the average capacity, by at most
+// maxStoreWeightStep. The tuned weights are used by namespace clusters created
+// afterwards, so that stores with larger capacities are expected to hold more
+// regions.
+func (c *namespaceCluster) TuneStoreWeights() map[uint64]float64 {
+	var total uint64
+	var count int
+	for _, s := range c.stores {
+		if s.IsUp() {
+			total += s.GetCapacity()
+			count++
+		}
+	}
+	weights := make(map[uint64]float64, count)
+	if total == 0 {
+		return weights
+	}
+	avg := float64(total) / float64(count)
+	for id, s := range c.stores {
+		if s.IsUp() {
+			weights[id] = c.state.tuneStoreWeight(id, float64(s.GetCapacity())/avg, maxStoreWeightStep)
+		}
+	}
+	return weights
+}
+
+// GetSnapshotSpeed returns the speed in MB/s that stores send and apply
+// snapshots of regions in the namespace.
+func (c *namespaceCluster) GetSnapshotSpeed() float64 {
+	if speed := c.GetOpt().GetSnapshotSpeed(c.namespace); speed > 0 {
+		return speed
+	}
+	return defaultSnapshotSpeed
+}
+
+// EstimateOperatorTimeout estimates how long the step of an operator on the
+// region takes at most. Steps which add peers need to send and apply a
+// snapshot, so their timeouts grow with the region size. Other steps only need
+// to be proposed and applied by raft.
+func (c *namespaceCluster) EstimateOperatorTimeout(region *core.RegionInfo, step operator.OpStep) time.Duration {
+	switch step.(type) {
+	case operator.AddPeer, operator.AddLearner, operator.AddLightPeer, operator.AddLightLearner:
+		seconds := float64(region.GetApproximateSize()) / c.GetSnapshotSpeed() * snapshotTimeoutFactor
+		return operator.LeaderOperatorWaitTime + time.Duration(seconds*float64(time.Second))
+	default:
+		return operator.LeaderOperatorWaitTime
+	}
+}
+
+// GetSpareStores returns the IDs of the spare stores of the namespace.
+func (c *namespaceCluster) GetSpareStores() []uint64 {
+	return c.GetOpt().GetSpareStores(c.namespace)
+}
+
+// GetBlacklistedStores returns the stores which should not be the targets of
+// scheduling in the namespace, including the stores shutting down and the
+// stores under maintenance.
+func (c *namespaceCluster) GetBlacklistedStores() []uint64 {
+	storeIDs := c.state.getStoreBlacklist()
+	for _, id := range c.state.getShuttingDownStores() {
+		if !containsUint64(storeIDs, id) {
+			storeIDs = append(storeIDs, id)
+		}
+	}
+	now := time.Now()
+	for id := range c.stores {
+		if c.isStoreUnderMaintenance(id, now) && !containsUint64(storeIDs, id) {
+			storeIDs = append(storeIDs, id)
+		}
+	}
+	sort.Slice(storeIDs, func(i, j int) bool { return storeIDs[i] < storeIDs[j] })
+	return storeIDs
+}
+
+// GetStoreMaintenanceWindows returns the daily time windows in which the
+// namespace store is under maintenance.
+func (c *namespaceCluster) GetStoreMaintenanceWindows(storeID uint64) []typeutil.TimeWindow {
+	return c.GetOpt().GetStoreMaintenanceWindows(c.namespace, storeID)
+}
+
+func (c *namespaceCluster) isStoreUnderMaintenance(storeID uint64, now time.Time) bool {
+	for _, w := range c.GetStoreMaintenanceWindows(storeID) {
+		if w.Contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkNamespaceDecommissioning marks the namespace as decommissioning. Only
+// the leaders are drained from a decommissioning namespace, while its states
+// are kept for auditing.
+func (c *namespaceCluster) MarkNamespaceDecommissioning() {
+	c.state.markDecommissioning()
+}
+
+// IsDecommissioning returns if the namespace is decommissioning.
+func (c *namespaceCluster) IsDecommissioning() bool {
+	return c.state.isDecommissioning()
+}
+
+// slowStoreScoreRatio is how many times a store's slow score must exceed the
+// average of the other stores in the namespace for it to be slow.
+const slowStoreScoreRatio = 2
+
+// namespaceEvictLeaderName is the name recorded in the operator history for
+// the operators which evict the leaders out of the stores in upgrade mode or
+// shutting down.
+const namespaceEvictLeaderName = "namespace-evict-leader"
+
+// leaderDrainSchedulerTypes are the types of schedulers which move leaders out
+// of stores, they are allowed on decommissioning namespaces. Regions are not
+// moved, and the peers on the stores shutting down are kept for their restart.
+var leaderDrainSchedulerTypes = map[string]struct{}{
+	"evict-leader": {},
+}
+
+func isLeaderDrainScheduler(scheduler schedule.Scheduler) bool {
+	_, ok := leaderDrainSchedulerTypes[scheduler.GetType()]
+	return ok
+}
+
+// getOperatorTargetStores returns the stores which the operator moves replicas
+// or leader to.
+func getOperatorTargetStores(op *operator.Operator) []uint64 {
+	var stores []uint64
+	for i := 0; i < op.Len(); i++ {
+		switch step := op.Step(i).(type) {
+		case operator.TransferLeader:
+			stores = append(stores, step.ToStore)
+		case operator.AddPeer:
+			stores = append(stores, step.ToStore)
+		case operator.AddLightPeer:
+			stores = append(stores, step.ToStore)
+		case operator.AddLearner:
+			stores = append(stores, step.ToStore)
+		case operator.AddLightLearner:
+			stores = append(stores, step.ToStore)
+		case operator.PromoteLearner:
+			stores = append(stores, step.ToStore)
+		}
+	}
+	return stores
+}
+
+// checkOperator returns true if all stores which the operator moves replicas
+// or leader to belong to the namespace and are not blacklisted. For operators
+// that move replicas by adding and promoting learners, the stores must keep in
+// the namespace until the operators finish.
+func (c *namespaceCluster) checkOperator(op *operator.Operator) bool {
+	for _, id := range getOperatorTargetStores(op) {
+		if _, ok := c.stores[id]; !ok || c.state.isStoreBlacklisted(id) || c.state.isStoreShuttingDown(id) {
+			return false
+		}
+	}
+	for i := 0; i < op.Len(); i++ {
+		if step, ok := op.Step(i).(operator.TransferLeader); ok && (!c.IsLeaseStable(step.ToStore) || c.state.isStoreUpgrading(step.ToStore)) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateOperator checks if the operator respects the namespace isolation. It
+// returns an error if any step of the operator places a peer on a store out of
+// the namespace.
+func (c *namespaceCluster) ValidateOperator(op *operator.Operator) error {
+	for i := 0; i < op.Len(); i++ {
+		var storeID uint64
+		switch step := op.Step(i).(type) {
+		case operator.AddPeer:
+			storeID = step.ToStore
+		case operator.AddLightPeer:
+			storeID = step.ToStore
+		case operator.AddLearner:
+			storeID = step.ToStore
+		case operator.AddLightLearner:
+			storeID = step.ToStore
+		case operator.PromoteLearner:
+			storeID = step.ToStore
+		default:
+			continue
+		}
+		if _, ok := c.stores[storeID]; !ok {
+			return errors.Errorf("step %v of operator %v places peer on store %v out of namespace %v", op.Step(i), op.Desc(), storeID, c.namespace)
+		}
+	}
+	return nil
+}
+
+func (c *namespaceCluster) filterOperators(ops []*operator.Operator) []*operator.Operator {
+	var res []*operator.Operator
+	for _, op := range ops {
+		if c.checkOperator(op) && !c.exceedMaxOperatorSteps(op) && !c.isJoinThrottledOperator(op) {
+			res = append(res, op)
+		}
+	}
+	return res
+}
+
+// hasLeaderDrainScheduler checks if any of the schedulers drains leaders.
+func hasLeaderDrainScheduler(schedulers []schedule.Scheduler) bool {
+	for _, s := range schedulers {
+		if isLeaderDrainScheduler(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// evictNamespaceLeaders creates operators which transfer the leaders out of
+// the stores in upgrade mode or shutting down in each namespace. Unlike
+// scheduleByNamespace, it does not depend on the schedulers configured, and
+// it also runs on paused or decommissioning namespaces.
+func evictNamespaceLeaders(cluster opt.Cluster, classifier namespace.Classifier) []*operator.Operator {
+	states := getNamespaceStates(cluster)
+	var ops []*operator.Operator
+	for _, name := range classifier.GetAllNamespaces() {
+		state := states.get(name)
+		if len(state.getUpgradingStores()) == 0 && len(state.getShuttingDownStores()) == 0 {
+			continue
+		}
+		nc := newNamespaceCluster(cluster, classifier, name)
+		evicting := nc.filterOperators(append(nc.EvictShuttingDownLeaders(), nc.EvictUpgradingLeaders()...))
+		if len(evicting) == 0 {
+			continue
+		}
+		nc.state.recordOperators(namespaceEvictLeaderName, evicting)
+		nc.states.operators.put(nc.namespace, evicting)
+		ops = append(ops, evicting...)
+	}
+	return ops
+}
+
+func scheduleByNamespace(cluster opt.Cluster, classifier namespace.Classifier, scheduler schedule.Scheduler) []*operator.Operator {
+	namespaces := classifier.GetAllNamespaces()
+	perm := rand.Perm(len(namespaces))
+	for _, i := range perm {
+		nc := newNamespaceCluster(cluster, classifier, namespaces[i])
+		// The schedulers of a chain are tried in order within the namespace,
+		// so that the operators of a scheduler dropped by the filters do not
+		// stop the next one.
+		links := []schedule.Scheduler{scheduler}
+		if chain, ok := scheduler.(*schedulerChain); ok {
+			links = chain.schedulers
+		}
+		decommissioning := nc.IsDecommissioning()
+		if nc.isSchedulePaused(time.Now()) || (decommissioning && !hasLeaderDrainScheduler(links)) {
+			continue
+		}
+		nc.state.markVisited()
+		var (
+			op   []*operator.Operator
+			name string
+		)
+		for _, link := range links {
+			if len(links) > 1 && !link.IsScheduleAllowed(nc) {
+				continue
+			}
+			// Only the leaders are drained from decommissioning namespaces.
+			if decommissioning && !isLeaderDrainScheduler(link) {
+				op = nil
+				continue
+			}
+			op = link.Schedule(nc)
+			if nc.isAddPeerThrottled() {
+				op = filterAddPeerOperators(op)
+			}
+			if op = nc.filterOperators(op); len(op) > 0 {
+				name = link.GetName()
+				break
+			}
+		}
+		if len(op) > 0 {
+			nc.state.recordOperators(name, op)
+			nc.states.operators.put(nc.namespace, op)
+			return op
+		}
+	}
+	return nil
+}
+
+func (c *namespaceCluster) GetLeaderScheduleLimit() uint64 {
+	return c.GetOpt().GetLeaderScheduleLimit(c.namespace)
+}
+
+// GetRegionScheduleLimit returns the region schedule limit of the namespace.
+// If the adaptive schedule limit is enabled, the max bound is divided by 1
+// plus the average pending peers of the up stores, so that the limit drops as
+// pending peers pile up and recovers once they are applied. It is no less
+// than the min bound.
+func (c *namespaceCluster) GetRegionScheduleLimit() uint64 {
+	limit := c.GetOpt().GetRegionScheduleLimit(c.namespace)
+	if !c.GetOpt().IsAdaptiveScheduleLimitEnabled(c.namespace) {
+		return limit
+	}
+	maxLimit, minLimit := c.GetOpt().GetMaxRegionScheduleLimit(c.namespace), c.GetOpt().GetMinRegionScheduleLimit(c.namespace)
+	if maxLimit == 0 {
+		maxLimit = limit
+	}
+	if minLimit > maxLimit {
+		minLimit = maxLimit
+	}
+	var up, pending int
+	for _, s := range c.stores {
+		if s.IsUp() {
+			up++
+			pending += s.GetPendingPeerCount()
+		}
+	}
+	if up == 0 {
+		return maxLimit
+	}
+	adaptive := uint64(float64(maxLimit) / (1 + float64(pending)/float64(up)))
+	if adaptive < minLimit {
+		return minLimit
+	}
+	return adaptive
+}
+
+func (c *namespaceCluster) GetReplicaScheduleLimit() uint64 {
+	return c.GetOpt().GetReplicaScheduleLimit(c.namespace)
+}
+
+func (c *namespaceCluster) GetMergeScheduleLimit() uint64 {
+	return c.GetOpt().GetMergeScheduleLimit(c.namespace)
+}
+
+// GetMergeDirectionPreference returns the preferred direction to merge regions
+// in the namespace, which is "left", "right" or empty for no preference.
+func (c *namespaceCluster) GetMergeDirectionPreference() string {
+	return c.GetOpt().GetMergeDirectionPreference(c.namespace)
 }
 
 func (c *namespaceCluster) GetMaxReplicas() int {
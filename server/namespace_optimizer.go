@@ -0,0 +1,34 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+// GetStoreLoads returns the loads of the up stores of the namespace keyed by
+// store ID, where the load of a store is the total approximate size of the
+// namespace regions on it.
+func (c *namespaceCluster) GetStoreLoads() map[uint64]float64 {
+	loads := make(map[uint64]float64)
+	for id, s := range c.stores {
+		if s.IsUp() {
+			loads[id] = 0
+		}
+	}
+	for _, r := range c.getRegions() {
+		for _, p := range r.GetPeers() {
+			if _, ok := loads[p.GetStoreId()]; ok {
+				loads[p.GetStoreId()] += float64(r.GetApproximateSize())
+			}
+		}
+	}
+	return loads
+}
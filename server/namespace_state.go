@@ -0,0 +1,1153 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/cache"
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule"
+	"github.com/pingcap/pd/server/schedule/operator"
+)
+
+const (
+	// namespaceOpHistoryCapacity is the max number of operators kept in the
+	// history of a namespace.
+	namespaceOpHistoryCapacity = 256
+
+	// namespaceFairnessWindow is the number of latest scheduling rounds used
+	// to compute the scheduling fairness of a namespace.
+	namespaceFairnessWindow = 1024
+
+	// namespaceRegionCountHistoryCapacity is the max number of region count
+	// samples kept for a namespace, which covers a day if they are sampled
+	// every minute.
+	namespaceRegionCountHistoryCapacity = 1440
+
+	// A store is flapping if it comes back from disconnection for at least
+	// storeFlappingThreshold times within storeFlappingWindow.
+	storeFlappingWindow    = 10 * time.Minute
+	storeFlappingThreshold = 3
+
+	// A region is thrashing if its replica on a store is added or removed for
+	// at least replicaThrashingThreshold times within replicaThrashingWindow.
+	replicaThrashingWindow    = 10 * time.Minute
+	replicaThrashingThreshold = 4
+
+	// regionEventMaxRegions is the max number of regions whose replica
+	// changes or leader moves are recorded.
+	regionEventMaxRegions = 100000
+
+	// A region is oscillating if its leader moves back to the store it just
+	// left for at least leaderOscillationThreshold times within
+	// leaderOscillationWindow.
+	leaderOscillationWindow    = 10 * time.Minute
+	leaderOscillationThreshold = 2
+
+	// hotLeaderMoveGuardWindow is the time within which the leader of a hot
+	// region is not moved again after it is moved.
+	hotLeaderMoveGuardWindow = 10 * time.Minute
+
+	// incrementalScatterBatchSize is the max number of regions scattered in
+	// a tick by the incremental scatter of a namespace.
+	incrementalScatterBatchSize = 4
+
+	// freshStoreWindow is the time after a store is first seen in a namespace
+	// within which it is considered fresh.
+	freshStoreWindow = time.Hour
+
+	// schedulingEfficiencyWindow is the time window of the operator history
+	// used to compute the scheduling efficiency of a namespace.
+	schedulingEfficiencyWindow = time.Hour
+)
+
+// NamespaceOpRecord records an operator produced by a scheduler for a
+// namespace.
+type NamespaceOpRecord struct {
+	Time      time.Time
+	Scheduler string
+	Operator  *operator.Operator
+}
+
+// NamespaceFairnessStats records how many of the latest scheduling rounds
+// visited or skipped a namespace.
+type NamespaceFairnessStats struct {
+	Visited int `json:"visited"`
+	Skipped int `json:"skipped"`
+}
+
+// namespaceState holds the scheduling states of a namespace which need to be
+// kept across scheduling rounds.
+type namespaceState struct {
+	opHistory *cache.FIFO
+	ticks     *cache.FIFO
+	// visited is set to 1 once a scheduler visits the namespace in the
+	// current scheduling round.
+	visited int32
+	// regionCounts records the sampled region counts of the namespace, keyed
+	// by the sampling time in nanoseconds.
+	regionCounts *cache.FIFO
+	// decommissioning is set to 1 once the namespace is marked as
+	// decommissioning.
+	decommissioning int32
+
+	sync.Mutex
+	// labelBaseline records the location label values of stores when they
+	// are first seen in the namespace, keyed by store ID.
+	labelBaseline map[uint64][]string
+	// storeWeights records the auto tuned region weights of stores, keyed by
+	// store ID.
+	storeWeights map[uint64]float64
+	// storeBlacklist is the set of stores which should not be the targets of
+	// scheduling, it is persisted by the cluster.
+	storeBlacklist map[uint64]struct{}
+	// persistedStoreWeights records the store weights set by users, keyed by
+	// store ID, they are persisted by the cluster.
+	persistedStoreWeights map[uint64]core.StoreWeight
+	// hotLeaderMoves records the times when the leaders of hot regions are
+	// moved, keyed by region ID.
+	hotLeaderMoves map[uint64]time.Time
+	// upgradingStores is the set of stores in upgrade mode, their leaders
+	// are evicted and they do not receive new leaders.
+	upgradingStores map[uint64]struct{}
+	// shuttingDownStores is the set of stores shutting down gracefully, their
+	// leaders are evicted and they do not receive new peers or leaders.
+	shuttingDownStores map[uint64]struct{}
+	// scatterPending is the queue of regions waiting for the incremental
+	// scatter, scatterDone and scatterTotal are the numbers of regions
+	// scattered and queued since the queue was last empty.
+	scatterPending []uint64
+	scatterDone    int
+	scatterTotal   int
+	// decommissionBaselines records the numbers of namespace regions on the
+	// stores when they are first seen being decommissioned, keyed by store ID.
+	decommissionBaselines map[uint64]int
+	// storeJoins records the stores seen in the namespace and their ramp-up,
+	// keyed by store ID.
+	storeJoins map[uint64]*storeJoin
+	// underReplicatedSince records the times when regions are first detected
+	// under-replicated, keyed by region ID.
+	underReplicatedSince map[uint64]time.Time
+}
+
+// storeJoin records the time when a store is first seen in the namespace, the
+// number of ramp-up ticks since then, and the number of peers moved onto it.
+// The stores loaded when the cluster starts are recorded with the zero time
+// and are not ramped up since they may have joined long ago.
+type storeJoin struct {
+	firstSeen time.Time
+	ticks     uint64
+	received  uint64
+}
+
+func newNamespaceState() *namespaceState {
+	return &namespaceState{
+		opHistory:             cache.NewFIFO(namespaceOpHistoryCapacity),
+		ticks:                 cache.NewFIFO(namespaceFairnessWindow),
+		regionCounts:          cache.NewFIFO(namespaceRegionCountHistoryCapacity),
+		labelBaseline:         make(map[uint64][]string),
+		storeWeights:          make(map[uint64]float64),
+		storeBlacklist:        make(map[uint64]struct{}),
+		persistedStoreWeights: make(map[uint64]core.StoreWeight),
+		hotLeaderMoves:        make(map[uint64]time.Time),
+		upgradingStores:       make(map[uint64]struct{}),
+		shuttingDownStores:    make(map[uint64]struct{}),
+		decommissionBaselines: make(map[uint64]int),
+		storeJoins:            make(map[uint64]*storeJoin),
+		underReplicatedSince:  make(map[uint64]time.Time),
+	}
+}
+
+// markDecommissioning marks the namespace as decommissioning.
+func (s *namespaceState) markDecommissioning() {
+	atomic.StoreInt32(&s.decommissioning, 1)
+}
+
+// isDecommissioning returns if the namespace is decommissioning.
+func (s *namespaceState) isDecommissioning() bool {
+	return atomic.LoadInt32(&s.decommissioning) == 1
+}
+
+// checkLabelDrift compares the location label values of the store with its
+// baseline and returns true if they are different. The values are recorded as
+// the baseline if the store is not seen before or the location labels are
+// changed.
+func (s *namespaceState) checkLabelDrift(storeID uint64, values []string) bool {
+	s.Lock()
+	defer s.Unlock()
+	baseline, ok := s.labelBaseline[storeID]
+	if !ok || len(baseline) != len(values) {
+		s.labelBaseline[storeID] = values
+		return false
+	}
+	for i := range values {
+		if values[i] != baseline[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// recordStoresSeen records the stores not seen before as first seen at the
+// time.
+func (s *namespaceState) recordStoresSeen(storeIDs []uint64, t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	for _, id := range storeIDs {
+		if _, ok := s.storeJoins[id]; !ok {
+			s.storeJoins[id] = &storeJoin{firstSeen: t}
+		}
+	}
+}
+
+// getStoreFirstSeen returns the time when the store is first seen in the
+// namespace. It returns false if the store is not seen yet.
+func (s *namespaceState) getStoreFirstSeen(storeID uint64) (time.Time, bool) {
+	s.Lock()
+	defer s.Unlock()
+	join, ok := s.storeJoins[storeID]
+	if !ok {
+		return time.Time{}, false
+	}
+	return join.firstSeen, true
+}
+
+// getStoreWeight returns the auto tuned region weight of the store.
+func (s *namespaceState) getStoreWeight(storeID uint64) (float64, bool) {
+	s.Lock()
+	defer s.Unlock()
+	weight, ok := s.storeWeights[storeID]
+	return weight, ok
+}
+
+// tuneStoreWeight moves the region weight of the store towards the target by
+// at most maxStep, and returns the new weight. The weight starts from 1.
+func (s *namespaceState) tuneStoreWeight(storeID uint64, target, maxStep float64) float64 {
+	s.Lock()
+	defer s.Unlock()
+	weight, ok := s.storeWeights[storeID]
+	if !ok {
+		weight = 1
+	}
+	weight += math.Max(-maxStep, math.Min(maxStep, target-weight))
+	s.storeWeights[storeID] = weight
+	return weight
+}
+
+// setStoreBlacklist replaces the store blacklist of the namespace.
+func (s *namespaceState) setStoreBlacklist(storeIDs []uint64) {
+	s.Lock()
+	defer s.Unlock()
+	s.storeBlacklist = make(map[uint64]struct{}, len(storeIDs))
+	for _, id := range storeIDs {
+		s.storeBlacklist[id] = struct{}{}
+	}
+}
+
+// getStoreBlacklist returns the sorted store blacklist of the namespace.
+func (s *namespaceState) getStoreBlacklist() []uint64 {
+	s.Lock()
+	defer s.Unlock()
+	storeIDs := make([]uint64, 0, len(s.storeBlacklist))
+	for id := range s.storeBlacklist {
+		storeIDs = append(storeIDs, id)
+	}
+	sort.Slice(storeIDs, func(i, j int) bool { return storeIDs[i] < storeIDs[j] })
+	return storeIDs
+}
+
+// isStoreBlacklisted returns true if the store is in the blacklist.
+func (s *namespaceState) isStoreBlacklisted(storeID uint64) bool {
+	s.Lock()
+	defer s.Unlock()
+	_, ok := s.storeBlacklist[storeID]
+	return ok
+}
+
+// setPersistedStoreWeights replaces the store weights set by users.
+func (s *namespaceState) setPersistedStoreWeights(weights map[uint64]core.StoreWeight) {
+	s.Lock()
+	defer s.Unlock()
+	s.persistedStoreWeights = make(map[uint64]core.StoreWeight, len(weights))
+	for id, w := range weights {
+		s.persistedStoreWeights[id] = w
+	}
+}
+
+// getPersistedStoreWeights returns the store weights set by users.
+func (s *namespaceState) getPersistedStoreWeights() map[uint64]core.StoreWeight {
+	s.Lock()
+	defer s.Unlock()
+	weights := make(map[uint64]core.StoreWeight, len(s.persistedStoreWeights))
+	for id, w := range s.persistedStoreWeights {
+		weights[id] = w
+	}
+	return weights
+}
+
+// getPersistedStoreWeight returns the weights of the store set by users.
+func (s *namespaceState) getPersistedStoreWeight(storeID uint64) (core.StoreWeight, bool) {
+	s.Lock()
+	defer s.Unlock()
+	w, ok := s.persistedStoreWeights[storeID]
+	return w, ok
+}
+
+// setStoreUpgrading sets or clears the upgrade mode of the store.
+func (s *namespaceState) setStoreUpgrading(storeID uint64, upgrading bool) {
+	s.Lock()
+	defer s.Unlock()
+	if upgrading {
+		s.upgradingStores[storeID] = struct{}{}
+	} else {
+		delete(s.upgradingStores, storeID)
+	}
+}
+
+// isStoreUpgrading returns true if the store is in upgrade mode.
+func (s *namespaceState) isStoreUpgrading(storeID uint64) bool {
+	s.Lock()
+	defer s.Unlock()
+	_, ok := s.upgradingStores[storeID]
+	return ok
+}
+
+// getUpgradingStores returns the sorted stores in upgrade mode.
+func (s *namespaceState) getUpgradingStores() []uint64 {
+	s.Lock()
+	defer s.Unlock()
+	storeIDs := make([]uint64, 0, len(s.upgradingStores))
+	for id := range s.upgradingStores {
+		storeIDs = append(storeIDs, id)
+	}
+	sort.Slice(storeIDs, func(i, j int) bool { return storeIDs[i] < storeIDs[j] })
+	return storeIDs
+}
+
+// setStoreShuttingDown sets or clears the shutting down mark of the store.
+func (s *namespaceState) setStoreShuttingDown(storeID uint64, shuttingDown bool) {
+	s.Lock()
+	defer s.Unlock()
+	if shuttingDown {
+		s.shuttingDownStores[storeID] = struct{}{}
+	} else {
+		delete(s.shuttingDownStores, storeID)
+	}
+}
+
+// isStoreShuttingDown returns true if the store is shutting down.
+func (s *namespaceState) isStoreShuttingDown(storeID uint64) bool {
+	s.Lock()
+	defer s.Unlock()
+	_, ok := s.shuttingDownStores[storeID]
+	return ok
+}
+
+// getShuttingDownStores returns the sorted stores shutting down.
+func (s *namespaceState) getShuttingDownStores() []uint64 {
+	s.Lock()
+	defer s.Unlock()
+	storeIDs := make([]uint64, 0, len(s.shuttingDownStores))
+	for id := range s.shuttingDownStores {
+		storeIDs = append(storeIDs, id)
+	}
+	sort.Slice(storeIDs, func(i, j int) bool { return storeIDs[i] < storeIDs[j] })
+	return storeIDs
+}
+
+// addScatterRegions queues the regions for the incremental scatter. The
+// regions already queued are ignored. It returns the number of regions queued.
+func (s *namespaceState) addScatterRegions(regionIDs []uint64) int {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.scatterPending) == 0 {
+		s.scatterDone, s.scatterTotal = 0, 0
+	}
+	queued := make(map[uint64]struct{}, len(s.scatterPending))
+	for _, id := range s.scatterPending {
+		queued[id] = struct{}{}
+	}
+	var count int
+	for _, id := range regionIDs {
+		if _, ok := queued[id]; ok {
+			continue
+		}
+		queued[id] = struct{}{}
+		s.scatterPending = append(s.scatterPending, id)
+		count++
+	}
+	s.scatterTotal += count
+	return count
+}
+
+// popScatterRegions removes at most n regions from the head of the incremental
+// scatter queue and counts them as scattered.
+func (s *namespaceState) popScatterRegions(n int) []uint64 {
+	s.Lock()
+	defer s.Unlock()
+	if n > len(s.scatterPending) {
+		n = len(s.scatterPending)
+	}
+	regionIDs := append([]uint64(nil), s.scatterPending[:n]...)
+	s.scatterPending = s.scatterPending[n:]
+	s.scatterDone += n
+	return regionIDs
+}
+
+// hasScatterRegions returns if any region is waiting for the incremental
+// scatter.
+func (s *namespaceState) hasScatterRegions() bool {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.scatterPending) > 0
+}
+
+// getScatterProgress returns the numbers of regions scattered and queued by
+// the incremental scatter.
+func (s *namespaceState) getScatterProgress() (int, int) {
+	s.Lock()
+	defer s.Unlock()
+	return s.scatterDone, s.scatterTotal
+}
+
+// getDecommissionBaseline returns the number of namespace regions on the store
+// when it is first seen being decommissioned. The count is recorded as the
+// baseline if there is none.
+func (s *namespaceState) getDecommissionBaseline(storeID uint64, count int) int {
+	s.Lock()
+	defer s.Unlock()
+	if baseline, ok := s.decommissionBaselines[storeID]; ok {
+		return baseline
+	}
+	s.decommissionBaselines[storeID] = count
+	return count
+}
+
+// clearDecommissionBaseline removes the baseline of the store, e.g. its
+// decommission is canceled.
+func (s *namespaceState) clearDecommissionBaseline(storeID uint64) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.decommissionBaselines, storeID)
+}
+
+// recordUnderReplicated records the regions not recorded before as detected
+// under-replicated at the time. The regions not in the list are removed since
+// they are repaired.
+func (s *namespaceState) recordUnderReplicated(regionIDs []uint64, t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	detected := make(map[uint64]struct{}, len(regionIDs))
+	for _, id := range regionIDs {
+		if _, ok := s.underReplicatedSince[id]; !ok {
+			s.underReplicatedSince[id] = t
+		}
+		detected[id] = struct{}{}
+	}
+	for id := range s.underReplicatedSince {
+		if _, ok := detected[id]; !ok {
+			delete(s.underReplicatedSince, id)
+		}
+	}
+}
+
+// getUnderReplicatedSince returns the times when the under-replicated regions
+// are first detected, keyed by region ID.
+func (s *namespaceState) getUnderReplicatedSince() map[uint64]time.Time {
+	s.Lock()
+	defer s.Unlock()
+	since := make(map[uint64]time.Time, len(s.underReplicatedSince))
+	for id, t := range s.underReplicatedSince {
+		since[id] = t
+	}
+	return since
+}
+
+// tickStoreJoins records a ramp-up tick for the stores joining the namespace.
+func (s *namespaceState) tickStoreJoins() {
+	s.Lock()
+	defer s.Unlock()
+	for _, join := range s.storeJoins {
+		if !join.firstSeen.IsZero() {
+			join.ticks++
+		}
+	}
+}
+
+// getStoreJoin returns the number of ramp-up ticks since the store joins the
+// namespace and the number of peers moved onto it. It returns false if the
+// store is not seen yet or loaded when the cluster starts.
+func (s *namespaceState) getStoreJoin(storeID uint64) (uint64, uint64, bool) {
+	s.Lock()
+	defer s.Unlock()
+	join, ok := s.storeJoins[storeID]
+	if !ok || join.firstSeen.IsZero() {
+		return 0, 0, false
+	}
+	return join.ticks, join.received, true
+}
+
+// recordStoreJoinReceived records that a peer is moved onto the store if it is
+// joining the namespace.
+func (s *namespaceState) recordStoreJoinReceived(storeID uint64) {
+	s.Lock()
+	defer s.Unlock()
+	if join, ok := s.storeJoins[storeID]; ok && !join.firstSeen.IsZero() {
+		join.received++
+	}
+}
+
+// recordHotLeaderMove records that the leader of the hot region is moved at
+// the time.
+func (s *namespaceState) recordHotLeaderMove(regionID uint64, t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	s.hotLeaderMoves[regionID] = t
+}
+
+// isHotLeaderMovedRecently returns true if the leader of the hot region is
+// moved within the window. Expired records are removed.
+func (s *namespaceState) isHotLeaderMovedRecently(regionID uint64, now time.Time, window time.Duration) bool {
+	s.Lock()
+	defer s.Unlock()
+	for id, t := range s.hotLeaderMoves {
+		if now.Sub(t) >= window {
+			delete(s.hotLeaderMoves, id)
+		}
+	}
+	_, ok := s.hotLeaderMoves[regionID]
+	return ok
+}
+
+// recordRegionCount records the region count of the namespace sampled at the
+// time.
+func (s *namespaceState) recordRegionCount(t time.Time, count int) {
+	s.regionCounts.Put(uint64(t.UnixNano()), count)
+}
+
+// getRegionGrowthRate returns the growth rate of the region count in regions
+// per hour, which is the slope of the least squares line fitted to the
+// samples. It returns 0 if there are not enough samples.
+func (s *namespaceState) getRegionGrowthRate() float64 {
+	elems := s.regionCounts.Elems()
+	if len(elems) < 2 {
+		return 0
+	}
+	base := elems[0].Key
+	var sumX, sumY, sumXY, sumXX float64
+	for _, elem := range elems {
+		x := time.Duration(elem.Key - base).Hours()
+		y := float64(elem.Value.(int))
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	n := float64(len(elems))
+	d := n*sumXX - sumX*sumX
+	if d == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / d
+}
+
+// markVisited marks the namespace as visited by a scheduler in the current
+// scheduling round.
+func (s *namespaceState) markVisited() {
+	atomic.StoreInt32(&s.visited, 1)
+}
+
+// tickFairness ends the current scheduling round, recording whether any
+// scheduler visited the namespace in it.
+func (s *namespaceState) tickFairness() {
+	s.recordTick(atomic.SwapInt32(&s.visited, 0) == 1)
+}
+
+// recordTick records whether the namespace is visited in a scheduling round.
+func (s *namespaceState) recordTick(visited bool) {
+	s.ticks.Put(uint64(time.Now().UnixNano()), visited)
+}
+
+// getFairnessStats returns the visited and skipped counts of the namespace in
+// the latest scheduling rounds.
+func (s *namespaceState) getFairnessStats() *NamespaceFairnessStats {
+	stats := &NamespaceFairnessStats{}
+	for _, elem := range s.ticks.Elems() {
+		if elem.Value.(bool) {
+			stats.Visited++
+		} else {
+			stats.Skipped++
+		}
+	}
+	return stats
+}
+
+// recordOperators appends the operators produced by the scheduler to the
+// operator history.
+func (s *namespaceState) recordOperators(scheduler string, ops []*operator.Operator) {
+	now := time.Now()
+	for _, op := range ops {
+		s.opHistory.Put(op.RegionID(), &NamespaceOpRecord{
+			Time:      now,
+			Scheduler: scheduler,
+			Operator:  op,
+		})
+	}
+}
+
+// getOperatorHistory returns at most limit latest operator records, the
+// latest one comes first. limit <= 0 means no limit.
+func (s *namespaceState) getOperatorHistory(limit int) []*NamespaceOpRecord {
+	elems := s.opHistory.Elems()
+	if limit <= 0 || limit > len(elems) {
+		limit = len(elems)
+	}
+	records := make([]*NamespaceOpRecord, 0, limit)
+	for i := len(elems) - 1; i >= len(elems)-limit; i-- {
+		records = append(records, elems[i].Value.(*NamespaceOpRecord))
+	}
+	return records
+}
+
+// getSchedulingEfficiency returns the ratio of the useful operators to all
+// the operators recorded within the window before now. An operator and the
+// later one of the same region which reverts its placement change are both
+// wasted churn, e.g. a peer moved from store 1 to 2 and then moved back. It
+// returns 1 if no operator is recorded within the window.
+func (s *namespaceState) getSchedulingEfficiency(now time.Time, window time.Duration) float64 {
+	var records []*NamespaceOpRecord
+	for _, elem := range s.opHistory.Elems() {
+		if r := elem.Value.(*NamespaceOpRecord); now.Sub(r.Time) < window && !r.Time.After(now) {
+			records = append(records, r)
+		}
+	}
+	if len(records) == 0 {
+		return 1
+	}
+	effects := make([]opEffect, len(records))
+	for i, r := range records {
+		effects[i] = newOpEffect(r.Operator)
+	}
+	wasted := make([]bool, len(records))
+	var wastedCount int
+	for i := range records {
+		if wasted[i] || effects[i].isEmpty() {
+			continue
+		}
+		for j := i + 1; j < len(records); j++ {
+			if !wasted[j] && records[j].Operator.RegionID() == records[i].Operator.RegionID() && effects[j].reverts(effects[i]) {
+				wasted[i], wasted[j] = true, true
+				wastedCount += 2
+				break
+			}
+		}
+	}
+	return float64(len(records)-wastedCount) / float64(len(records))
+}
+
+// opEffect is the placement change of a region made by an operator. The
+// leader transfer only counts if the operator does not change peers, since
+// moving a peer may transfer the leader to any follower.
+type opEffect struct {
+	added      []uint64
+	removed    []uint64
+	leaderFrom uint64
+	leaderTo   uint64
+}
+
+func newOpEffect(op *operator.Operator) opEffect {
+	var e opEffect
+	for i := 0; i < op.Len(); i++ {
+		switch s := op.Step(i).(type) {
+		case operator.AddPeer:
+			e.added = append(e.added, s.ToStore)
+		case operator.AddLightPeer:
+			e.added = append(e.added, s.ToStore)
+		case operator.AddLearner:
+			e.added = append(e.added, s.ToStore)
+		case operator.AddLightLearner:
+			e.added = append(e.added, s.ToStore)
+		case operator.RemovePeer:
+			e.removed = append(e.removed, s.FromStore)
+		case operator.TransferLeader:
+			if e.leaderFrom == 0 {
+				e.leaderFrom = s.FromStore
+			}
+			e.leaderTo = s.ToStore
+		}
+	}
+	if len(e.added) > 0 || len(e.removed) > 0 {
+		e.leaderFrom, e.leaderTo = 0, 0
+	}
+	sort.Slice(e.added, func(i, j int) bool { return e.added[i] < e.added[j] })
+	sort.Slice(e.removed, func(i, j int) bool { return e.removed[i] < e.removed[j] })
+	return e
+}
+
+func (e opEffect) isEmpty() bool {
+	return len(e.added) == 0 && len(e.removed) == 0 && e.leaderFrom == e.leaderTo
+}
+
+// reverts checks if the effect undoes the other one.
+func (e opEffect) reverts(other opEffect) bool {
+	return equalStoreIDs(e.added, other.removed) && equalStoreIDs(e.removed, other.added) &&
+		e.leaderFrom == other.leaderTo && e.leaderTo == other.leaderFrom
+}
+
+func equalStoreIDs(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// namespaceStates is the collection of states of all namespaces, together with
+// the states of stores shared by namespaces.
+type namespaceStates struct {
+	sync.RWMutex
+	states map[string]*namespaceState
+
+	storeFlapping     *storeFlappingDetector
+	storeLeases       *storeLeaseTracker
+	operators         *namespaceOperators
+	replicaChurn      *replicaChurnDetector
+	leaderOscillation *leaderOscillationDetector
+	regionHeartbeats  *regionHeartbeatTracker
+}
+
+func newNamespaceStates() *namespaceStates {
+	return &namespaceStates{
+		states:            make(map[string]*namespaceState),
+		storeFlapping:     newStoreFlappingDetector(storeFlappingWindow, storeFlappingThreshold),
+		storeLeases:       newStoreLeaseTracker(),
+		operators:         newNamespaceOperators(),
+		replicaChurn:      newReplicaChurnDetector(replicaThrashingWindow, replicaThrashingThreshold, regionEventMaxRegions),
+		leaderOscillation: newLeaderOscillationDetector(leaderOscillationWindow, leaderOscillationThreshold, regionEventMaxRegions),
+		regionHeartbeats:  newRegionHeartbeatTracker(),
+	}
+}
+
+// get returns the state of the namespace, the state is created if it does not
+// exist yet.
+func (s *namespaceStates) get(name string) *namespaceState {
+	s.RLock()
+	state, ok := s.states[name]
+	s.RUnlock()
+	if ok {
+		return state
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if state, ok = s.states[name]; !ok {
+		state = newNamespaceState()
+		s.states[name] = state
+	}
+	return state
+}
+
+// namespaceStateProvider is implemented by clusters which keep the states of
+// namespaces across scheduling rounds.
+type namespaceStateProvider interface {
+	getNamespaceStates() *namespaceStates
+}
+
+// storeFlappingDetector records the times that stores come back after being
+// disconnected, to detect stores which go up and down repeatedly.
+type storeFlappingDetector struct {
+	sync.Mutex
+	window      time.Duration
+	threshold   int
+	transitions map[uint64][]time.Time
+}
+
+func newStoreFlappingDetector(window time.Duration, threshold int) *storeFlappingDetector {
+	return &storeFlappingDetector{
+		window:      window,
+		threshold:   threshold,
+		transitions: make(map[uint64][]time.Time),
+	}
+}
+
+// recordTransition records that the store comes back at the time.
+func (d *storeFlappingDetector) recordTransition(storeID uint64, t time.Time) {
+	d.Lock()
+	defer d.Unlock()
+	d.transitions[storeID] = append(d.prune(storeID, t), t)
+}
+
+// isFlapping returns true if the store has come back for too many times
+// within the window.
+func (d *storeFlappingDetector) isFlapping(storeID uint64, now time.Time) bool {
+	d.Lock()
+	defer d.Unlock()
+	return len(d.prune(storeID, now)) >= d.threshold
+}
+
+func (d *storeFlappingDetector) prune(storeID uint64, now time.Time) []time.Time {
+	transitions := d.transitions[storeID]
+	for len(transitions) > 0 && now.Sub(transitions[0]) > d.window {
+		transitions = transitions[1:]
+	}
+	if len(transitions) == 0 {
+		delete(d.transitions, storeID)
+		return nil
+	}
+	d.transitions[storeID] = transitions
+	return transitions
+}
+
+// storeLeaseTracker records the stores whose leader leases are about to
+// expire, leaders should not be transferred to them until the deadlines.
+type storeLeaseTracker struct {
+	sync.Mutex
+	deadlines map[uint64]time.Time
+}
+
+func newStoreLeaseTracker() *storeLeaseTracker {
+	return &storeLeaseTracker{
+		deadlines: make(map[uint64]time.Time),
+	}
+}
+
+// markUnstable marks the lease of the store as unstable until the deadline.
+func (t *storeLeaseTracker) markUnstable(storeID uint64, deadline time.Time) {
+	t.Lock()
+	defer t.Unlock()
+	t.deadlines[storeID] = deadline
+}
+
+// isStable returns false if the lease of the store is marked as unstable and
+// the mark does not expire yet. Expired marks are removed.
+func (t *storeLeaseTracker) isStable(storeID uint64, now time.Time) bool {
+	t.Lock()
+	defer t.Unlock()
+	deadline, ok := t.deadlines[storeID]
+	if !ok {
+		return true
+	}
+	if now.Before(deadline) {
+		return false
+	}
+	delete(t.deadlines, storeID)
+	return true
+}
+
+// regionHeartbeatTracker records the time of the last heartbeat of each region,
+// including the heartbeats which do not change the region.
+type regionHeartbeatTracker struct {
+	sync.Mutex
+	last map[uint64]time.Time
+}
+
+func newRegionHeartbeatTracker() *regionHeartbeatTracker {
+	return &regionHeartbeatTracker{
+		last: make(map[uint64]time.Time),
+	}
+}
+
+// record records the heartbeat of the region at the time.
+func (t *regionHeartbeatTracker) record(regionID uint64, now time.Time) {
+	t.Lock()
+	defer t.Unlock()
+	t.last[regionID] = now
+}
+
+// remove removes the region, e.g. when it is merged or dropped from the cache.
+func (t *regionHeartbeatTracker) remove(regionID uint64) {
+	t.Lock()
+	defer t.Unlock()
+	delete(t.last, regionID)
+}
+
+// getLastHeartbeat returns the time of the last heartbeat of the region, and
+// false if the region has not reported any heartbeat.
+func (t *regionHeartbeatTracker) getLastHeartbeat(regionID uint64) (time.Time, bool) {
+	t.Lock()
+	defer t.Unlock()
+	last, ok := t.last[regionID]
+	return last, ok
+}
+
+// namespaceOperators tracks the running operators produced by schedulers for
+// namespaces, so that they can be validated until they finish.
+type namespaceOperators struct {
+	sync.Mutex
+	ops map[uint64]namespaceOperator
+}
+
+type namespaceOperator struct {
+	op        *operator.Operator
+	namespace string
+}
+
+func newNamespaceOperators() *namespaceOperators {
+	return &namespaceOperators{
+		ops: make(map[uint64]namespaceOperator),
+	}
+}
+
+// put starts tracking the operators produced for the namespace.
+func (o *namespaceOperators) put(namespace string, ops []*operator.Operator) {
+	o.Lock()
+	defer o.Unlock()
+	for _, op := range ops {
+		o.ops[op.RegionID()] = namespaceOperator{op: op, namespace: namespace}
+	}
+}
+
+// getNamespace returns the namespace which the operator is produced for. The
+// tracked operator of the region is dropped if it is not the given one, as it
+// is already finished or replaced.
+func (o *namespaceOperators) getNamespace(regionID uint64, op *operator.Operator) (string, bool) {
+	o.Lock()
+	defer o.Unlock()
+	tracked, ok := o.ops[regionID]
+	if !ok {
+		return "", false
+	}
+	if tracked.op != op {
+		delete(o.ops, regionID)
+		return "", false
+	}
+	return tracked.namespace, true
+}
+
+// remove stops tracking the operator.
+func (o *namespaceOperators) remove(op *operator.Operator) {
+	o.Lock()
+	defer o.Unlock()
+	if tracked, ok := o.ops[op.RegionID()]; ok && tracked.op == op {
+		delete(o.ops, op.RegionID())
+	}
+}
+
+// prune stops tracking the operator of the region once it has ended, i.e. it
+// is finished or timed out, or the given status of the region records it as
+// canceled or replaced. The ones waiting to be promoted are kept.
+func (o *namespaceOperators) prune(regionID uint64, status *schedule.OperatorWithStatus) {
+	o.Lock()
+	defer o.Unlock()
+	tracked, ok := o.ops[regionID]
+	if !ok {
+		return
+	}
+	if tracked.op.IsFinish() || tracked.op.IsTimeout() ||
+		(status != nil && status.Op == tracked.op && status.Status != pdpb.OperatorStatus_RUNNING) {
+		delete(o.ops, regionID)
+	}
+}
+
+// regionEvent is an event of a region within a window, e.g. a replica of the
+// region removed from a store and added to another, or its leader moved
+// between stores. The stores are 0 if they are not involved.
+type regionEvent struct {
+	time     time.Time
+	from, to uint64
+}
+
+// regionEventWindow records the events of regions within a time window. The
+// expired events of a region are pruned whenever a new one of the region is
+// recorded. If more than maxRegions regions are recorded, the expired events
+// of all regions are pruned, and the regions whose latest events are the
+// oldest are evicted until three quarters of maxRegions are left, so that the
+// memory is bounded and the full pruning is amortized.
+type regionEventWindow struct {
+	sync.Mutex
+	window     time.Duration
+	maxRegions int
+	events     map[uint64][]regionEvent
+}
+
+func newRegionEventWindow(window time.Duration, maxRegions int) *regionEventWindow {
+	return &regionEventWindow{
+		window:     window,
+		maxRegions: maxRegions,
+		events:     make(map[uint64][]regionEvent),
+	}
+}
+
+// record records the events of the region which happen at now.
+func (w *regionEventWindow) record(regionID uint64, now time.Time, events ...regionEvent) {
+	if len(events) == 0 {
+		return
+	}
+	w.Lock()
+	defer w.Unlock()
+	w.events[regionID] = append(w.prune(regionID, now), events...)
+	if len(w.events) <= w.maxRegions {
+		return
+	}
+	for id := range w.events {
+		w.prune(id, now)
+	}
+	if len(w.events) <= w.maxRegions {
+		return
+	}
+	regionIDs := make([]uint64, 0, len(w.events))
+	for id := range w.events {
+		regionIDs = append(regionIDs, id)
+	}
+	latest := func(id uint64) time.Time {
+		events := w.events[id]
+		return events[len(events)-1].time
+	}
+	sort.Slice(regionIDs, func(i, j int) bool { return latest(regionIDs[i]).Before(latest(regionIDs[j])) })
+	for _, id := range regionIDs[:len(regionIDs)-w.maxRegions*3/4] {
+		delete(w.events, id)
+	}
+}
+
+// remove drops the events of the region, e.g. it is removed from the cluster.
+func (w *regionEventWindow) remove(regionID uint64) {
+	w.Lock()
+	defer w.Unlock()
+	delete(w.events, regionID)
+}
+
+// scan prunes the expired events of all regions, and returns the IDs of the
+// regions whose remaining events match, in ascending order.
+func (w *regionEventWindow) scan(now time.Time, match func(events []regionEvent) bool) []uint64 {
+	w.Lock()
+	defer w.Unlock()
+	var regionIDs []uint64
+	for id := range w.events {
+		if events := w.prune(id, now); len(events) > 0 && match(events) {
+			regionIDs = append(regionIDs, id)
+		}
+	}
+	sort.Slice(regionIDs, func(i, j int) bool { return regionIDs[i] < regionIDs[j] })
+	return regionIDs
+}
+
+func (w *regionEventWindow) prune(regionID uint64, now time.Time) []regionEvent {
+	events := w.events[regionID]
+	for len(events) > 0 && now.Sub(events[0].time) > w.window {
+		events = events[1:]
+	}
+	if len(events) == 0 {
+		delete(w.events, regionID)
+		return nil
+	}
+	w.events[regionID] = events
+	return events
+}
+
+// replicaChurnDetector records the times that replicas of regions are added to
+// or removed from stores, to detect regions whose replicas are moved back and
+// forth, e.g. by conflicting schedulers.
+type replicaChurnDetector struct {
+	threshold int
+	changes   *regionEventWindow
+}
+
+func newReplicaChurnDetector(window time.Duration, threshold, maxRegions int) *replicaChurnDetector {
+	return &replicaChurnDetector{
+		threshold: threshold,
+		changes:   newRegionEventWindow(window, maxRegions),
+	}
+}
+
+// recordPeerChanges records the stores whose replicas of the region are added
+// or removed from origin to region at the time.
+func (d *replicaChurnDetector) recordPeerChanges(origin, region *core.RegionInfo, t time.Time) {
+	var changes []regionEvent
+	for _, p := range region.GetPeers() {
+		if origin.GetStorePeer(p.GetStoreId()) == nil {
+			changes = append(changes, regionEvent{time: t, to: p.GetStoreId()})
+		}
+	}
+	for _, p := range origin.GetPeers() {
+		if region.GetStorePeer(p.GetStoreId()) == nil {
+			changes = append(changes, regionEvent{time: t, from: p.GetStoreId()})
+		}
+	}
+	d.changes.record(region.GetID(), t, changes...)
+}
+
+// removeRegion drops the records of the region.
+func (d *replicaChurnDetector) removeRegion(regionID uint64) {
+	d.changes.remove(regionID)
+}
+
+// getThrashingRegions returns the IDs of regions whose replicas on some store
+// are changed for too many times within the window, in ascending order.
+func (d *replicaChurnDetector) getThrashingRegions(now time.Time) []uint64 {
+	return d.changes.scan(now, func(changes []regionEvent) bool {
+		counts := make(map[uint64]int)
+		for _, e := range changes {
+			for _, storeID := range []uint64{e.from, e.to} {
+				if storeID == 0 {
+					continue
+				}
+				if counts[storeID]++; counts[storeID] >= d.threshold {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+// leaderOscillationDetector records the leader moves of regions, to detect
+// regions whose leaders are moved back and forth between stores, e.g. by
+// balance schedulers with conflicting goals.
+type leaderOscillationDetector struct {
+	threshold int
+	moves     *regionEventWindow
+}
+
+func newLeaderOscillationDetector(window time.Duration, threshold, maxRegions int) *leaderOscillationDetector {
+	return &leaderOscillationDetector{
+		threshold: threshold,
+		moves:     newRegionEventWindow(window, maxRegions),
+	}
+}
+
+// recordLeaderMove records that the leader of the region is moved from a store
+// to another at the time.
+func (d *leaderOscillationDetector) recordLeaderMove(regionID, from, to uint64, t time.Time) {
+	d.moves.record(regionID, t, regionEvent{time: t, from: from, to: to})
+}
+
+// removeRegion drops the records of the region.
+func (d *leaderOscillationDetector) removeRegion(regionID uint64) {
+	d.moves.remove(regionID)
+}
+
+// getOscillatingRegions returns the IDs of regions whose leaders are moved
+// back to the stores they just left for too many times within the window, in
+// ascending order.
+func (d *leaderOscillationDetector) getOscillatingRegions(now time.Time) []uint64 {
+	return d.moves.scan(now, func(moves []regionEvent) bool {
+		var bounces int
+		for i := 1; i < len(moves); i++ {
+			if moves[i].to == moves[i-1].from {
+				bounces++
+			}
+		}
+		return bounces >= d.threshold
+	})
+}
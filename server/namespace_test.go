@@ -14,10 +14,20 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/mock/mockhbstream"
+	"github.com/pingcap/pd/pkg/mock/mockid"
 	"github.com/pingcap/pd/pkg/testutil"
+	"github.com/pingcap/pd/pkg/typeutil"
 	"github.com/pingcap/pd/server/config"
 	"github.com/pingcap/pd/server/core"
 	"github.com/pingcap/pd/server/kv"
@@ -25,6 +35,10 @@ import (
 	"github.com/pingcap/pd/server/schedule"
 	"github.com/pingcap/pd/server/schedule/checker"
 	"github.com/pingcap/pd/server/schedule/operator"
+	"github.com/pingcap/pd/server/schedule/opt"
+	"github.com/pingcap/pd/server/schedule/placement"
+	"github.com/pingcap/pd/server/statistics"
+	"github.com/pingcap/pd/table"
 )
 
 var _ = Suite(&testNamespaceSuite{})
@@ -80,6 +94,184 @@ func (s *testNamespaceSuite) TestReplica(c *C) {
 	c.Assert(op, IsNil)
 }
 
+func (s *testNamespaceSuite) TestReadReplica(c *C) {
+	// store regionCount namespace
+	//  1,2,3          0       ns1
+	//     4          20       ns1
+	//     5          10       ns1
+	//     6           0       ns2
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addRegionStore(4, 20), IsNil)
+	c.Assert(s.tc.addRegionStore(5, 10), IsNil)
+	c.Assert(s.tc.addRegionStore(6, 0), IsNil)
+	s.classifier.setStore(4, "ns1")
+	s.classifier.setStore(5, "ns1")
+	s.classifier.setStore(6, "ns2")
+	nsCfg := &config.NamespaceConfig{ReadReplicaCount: 1, ReadReplicaStores: []uint64{4, 5, 6}}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns1").GetReadReplicaCount(), Equals, uint64(1))
+
+	rc := checker.NewReadReplicaChecker(s.tc, s.classifier)
+	lc := checker.NewLearnerChecker(s.tc, s.classifier)
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	s.classifier.setRegion(1, "ns1")
+
+	// The read replica is added to the read replica store of the namespace
+	// with the fewest regions.
+	op := rc.Check(s.tc.GetRegion(1))
+	c.Assert(op, NotNil)
+	c.Assert(op.Len(), Equals, 1)
+	c.Assert(op.Step(0).(operator.AddLearner).ToStore, Equals, uint64(5))
+	addLearner := func(storeID, peerID uint64) {
+		region := s.tc.GetRegion(1).Clone(core.WithAddPeer(&metapb.Peer{Id: peerID, StoreId: storeID, IsLearner: true}))
+		c.Assert(s.tc.putRegion(region), IsNil)
+	}
+	addLearner(5, 100)
+	c.Assert(rc.Check(s.tc.GetRegion(1)), IsNil)
+	// The read replica is not promoted.
+	c.Assert(lc.Check(s.tc.GetRegion(1)), IsNil)
+
+	// The extra read replica on the store with the most regions is removed.
+	addLearner(4, 101)
+	testutil.CheckRemovePeer(c, rc.Check(s.tc.GetRegion(1)), 4)
+
+	// Other learners are still promoted.
+	s.opt.SetNS("ns1", config.NewNamespaceOption(&config.NamespaceConfig{}))
+	op = lc.Check(s.tc.GetRegion(1))
+	c.Assert(op, NotNil)
+	c.Assert(op.Step(0).(operator.PromoteLearner).ToStore, Equals, uint64(5))
+}
+
+func (s *testNamespaceSuite) TestZonePlacement(c *C) {
+	// store zone regionCount
+	//     1   z1           10
+	//     2   z1            0
+	//     3   z2           10
+	//     4   z2            1
+	//     5   z3            5
+	//     6   z3            8
+	zones := []string{"z1", "z1", "z2", "z2", "z3", "z3"}
+	counts := []int{10, 0, 10, 1, 5, 8}
+	for i, zone := range zones {
+		id := uint64(i + 1)
+		c.Assert(s.tc.addLabelsStore(id, counts[i], map[string]string{"zone": zone}), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	rc := checker.NewReplicaChecker(s.tc, s.classifier)
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 1, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(3, 1, 3, 5), IsNil)
+	for id := uint64(1); id <= 3; id++ {
+		s.classifier.setRegion(id, "ns1")
+	}
+	// Without the zone label, the stores with the fewest regions are used.
+	c.Assert(rc.Check(s.tc.GetRegion(1)), IsNil)
+	testutil.CheckAddPeer(c, rc.Check(s.tc.GetRegion(2)), operator.OpReplica, 2)
+
+	nsCfg := &config.NamespaceConfig{ZoneLabel: "zone", PreferredLeaderZone: "z2"}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	// A follower in the zone with 2 replicas is moved to the missing zone.
+	testutil.CheckTransferPeer(c, rc.Check(s.tc.GetRegion(1)), operator.OpReplica, 2, 5)
+	// The missing replica is added to the missing zone.
+	testutil.CheckAddPeer(c, rc.Check(s.tc.GetRegion(2)), operator.OpReplica, 5)
+	// The replicas are spread across zones, and the leader is transferred to
+	// the preferred zone.
+	testutil.CheckTransferLeader(c, rc.Check(s.tc.GetRegion(3)), operator.OpLeader, 1, 3)
+	c.Assert(s.tc.addLeaderRegion(3, 3, 1, 5), IsNil)
+	c.Assert(rc.Check(s.tc.GetRegion(3)), IsNil)
+
+	// The leader is moved out of the zone after its followers.
+	c.Assert(s.tc.addLeaderRegion(1, 2, 1, 3), IsNil)
+	testutil.CheckTransferPeer(c, rc.Check(s.tc.GetRegion(1)), operator.OpReplica, 1, 5)
+	// The leader stays out of the preferred zone if no follower can take it.
+	c.Assert(s.tc.addLeaderRegion(3, 1, 4, 5), IsNil)
+	c.Assert(s.tc.setStoreDown(4), IsNil)
+	c.Assert(rc.Check(s.tc.GetRegion(3)), IsNil)
+}
+
+func (s *testNamespaceSuite) TestBalanceWithZonePlacement(c *C) {
+	// store zone regionCount leaderCount
+	//     1   z1          40          20
+	//     2   z2          30           5
+	//     3   z3          10           0
+	//     4   z2           0           0
+	zones := []string{"z1", "z2", "z3", "z2"}
+	regionCounts := []int{40, 30, 10, 0}
+	leaderCounts := []int{20, 5, 0, 0}
+	for i, zone := range zones {
+		id := uint64(i + 1)
+		c.Assert(s.tc.addLabelsStore(id, regionCounts[i], map[string]string{"zone": zone}), IsNil)
+		c.Assert(s.tc.updateStore(id, core.SetLeaderCount(leaderCounts[i])), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	nsCfg := &config.NamespaceConfig{ZoneLabel: "zone", PreferredLeaderZone: "z2"}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	storage := core.NewStorage(kv.NewMemoryKV())
+
+	// The peer on store 1 is not moved to store 4 since z2 already has a peer,
+	// but the one on store 2 can be.
+	br, err := schedule.CreateScheduler("balance-region", oc, storage, nil)
+	c.Assert(err, IsNil)
+	ops := scheduleByNamespace(s.tc, s.classifier, br)
+	c.Assert(ops, HasLen, 1)
+	testutil.CheckTransferPeer(c, ops[0], operator.OpBalance, 2, 4)
+
+	// The leader is transferred to store 2 in the preferred zone instead of
+	// store 3 with fewer leaders.
+	bl, err := schedule.CreateScheduler("balance-leader", oc, storage, nil)
+	c.Assert(err, IsNil)
+	ops = scheduleByNamespace(s.tc, s.classifier, bl)
+	c.Assert(ops, HasLen, 1)
+	testutil.CheckTransferLeader(c, ops[0], operator.OpBalance, 1, 2)
+}
+
+func (s *testNamespaceSuite) TestReplicaCheckerWithReadReplica(c *C) {
+	// store regionCount namespace
+	//  1,2,3          0       ns1
+	//     4          10       ns1
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addRegionStore(4, 10), IsNil)
+	s.classifier.setStore(4, "ns1")
+	nsCfg := &config.NamespaceConfig{ReadReplicaCount: 1, ReadReplicaStores: []uint64{4}}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+
+	rc := checker.NewReplicaChecker(s.tc, s.classifier)
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2), IsNil)
+	region := s.tc.GetRegion(1).Clone(core.WithAddPeer(&metapb.Peer{Id: 100, StoreId: 4, IsLearner: true}))
+	c.Assert(s.tc.putRegion(region), IsNil)
+	s.classifier.setRegion(1, "ns1")
+
+	// The read replica is not counted as a replica.
+	testutil.CheckAddPeer(c, rc.Check(s.tc.GetRegion(1)), operator.OpReplica, 3)
+
+	region = s.tc.GetRegion(1).Clone(core.WithAddPeer(&metapb.Peer{Id: 101, StoreId: 3}))
+	c.Assert(s.tc.putRegion(region), IsNil)
+	c.Assert(rc.Check(s.tc.GetRegion(1)), IsNil)
+
+	// The read replica on the offline store is not replaced by a voter.
+	c.Assert(s.tc.setStoreOffline(4), IsNil)
+	c.Assert(rc.Check(s.tc.GetRegion(1)), IsNil)
+
+	// The offline voter is still replaced.
+	c.Assert(s.tc.addRegionStore(5, 0), IsNil)
+	s.classifier.setStore(5, "ns1")
+	c.Assert(s.tc.setStoreOffline(3), IsNil)
+	testutil.CheckTransferPeer(c, rc.Check(s.tc.GetRegion(1)), operator.OpReplica, 3, 5)
+}
+
 func (s *testNamespaceSuite) TestNamespaceChecker(c *C) {
 	// store regionCount namespace
 	//     1           0       ns1
@@ -167,6 +359,39 @@ func (s *testNamespaceSuite) TestSchedulerBalanceRegion(c *C) {
 	c.Assert(op, IsNil)
 }
 
+func (s *testNamespaceSuite) TestSchedulerBalanceRegionWithReadReplica(c *C) {
+	// store regionCount namespace
+	//     1           0       ns1
+	//     2         100       ns1
+	//     3         200       ns1 (read replica store)
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 100), IsNil)
+	c.Assert(s.tc.addRegionStore(3, 200), IsNil)
+	for id := uint64(1); id <= 3; id++ {
+		s.classifier.setStore(id, "ns1")
+	}
+	s.opt.SetMaxReplicas(1)
+	nsCfg := &config.NamespaceConfig{ReadReplicaCount: 1, ReadReplicaStores: []uint64{3}}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, _ := schedule.CreateScheduler("balance-region", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+
+	c.Assert(s.tc.addLeaderRegion(1, 2), IsNil)
+	region := s.tc.GetRegion(1).Clone(core.WithAddPeer(&metapb.Peer{Id: 100, StoreId: 3, IsLearner: true}))
+	c.Assert(s.tc.putRegion(region), IsNil)
+	s.classifier.setRegion(1, "ns1")
+
+	// The region with a read replica is still balanced, while the read
+	// replica itself is not moved.
+	for i := 0; i < 10; i++ {
+		op := scheduleByNamespace(s.tc, s.classifier, sched)
+		c.Assert(op, HasLen, 1)
+		testutil.CheckTransferPeer(c, op[0], operator.OpBalance, 2, 1)
+	}
+}
+
 func (s *testNamespaceSuite) TestSchedulerBalanceLeader(c *C) {
 	// store regionCount namespace
 	//     1         100       ns1
@@ -198,6 +423,4016 @@ func (s *testNamespaceSuite) TestSchedulerBalanceLeader(c *C) {
 	c.Assert(op, IsNil)
 }
 
+func (s *testNamespaceSuite) TestStoreLeaderWeight(c *C) {
+	// store leaderCount namespace
+	//     1         100       ns1
+	//     2         200       ns1
+	c.Assert(s.tc.addLeaderStore(1, 100), IsNil)
+	c.Assert(s.tc.addLeaderStore(2, 200), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, _ := schedule.CreateScheduler("balance-leader", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+
+	// Store 1 is the least loaded store, the leader should stay there.
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetStoreLeaderWeight(2), Equals, 1.0)
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, sched), IsNil)
+
+	// Store 2 can hold 4 times leaders in ns1, so it becomes the target.
+	nsCfg := &config.NamespaceConfig{StoreLeaderWeights: map[uint64]float64{2: 4}}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetStoreLeaderWeight(1), Equals, 1.0)
+	c.Assert(nc.GetStoreLeaderWeight(2), Equals, 4.0)
+	c.Assert(nc.GetStore(2).GetLeaderWeight(), Equals, 4.0)
+	c.Assert(s.tc.GetStore(2).GetLeaderWeight(), Equals, 1.0)
+	op := scheduleByNamespace(s.tc, s.classifier, sched)
+	testutil.CheckTransferLeader(c, op[0], operator.OpBalance, 1, 2)
+}
+
+func (s *testNamespaceSuite) TestPersistedStoreWeight(c *C) {
+	// store leaderCount namespace
+	//     1         100       ns1
+	//     2         200       ns1
+	c.Assert(s.tc.addLeaderStore(1, 100), IsNil)
+	c.Assert(s.tc.addLeaderStore(2, 200), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2), IsNil)
+	s.classifier.setRegion(1, "ns1")
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, _ := schedule.CreateScheduler("balance-leader", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, sched), IsNil)
+
+	// The persisted weights take effect in the next scheduling round, and
+	// overwrite the configured ones.
+	nsCfg := &config.NamespaceConfig{StoreLeaderWeights: map[uint64]float64{2: 0.5}}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	weights := map[uint64]core.StoreWeight{2: {LeaderWeight: 4, RegionWeight: 2}}
+	c.Assert(s.tc.SetNamespaceStoreWeights("ns1", weights), IsNil)
+	c.Assert(s.tc.GetNamespaceStoreWeights("ns1"), DeepEquals, weights)
+	c.Assert(s.tc.GetNamespaceStoreWeights("ns2"), HasLen, 0)
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetStoreLeaderWeight(2), Equals, 4.0)
+	c.Assert(nc.GetStore(2).GetLeaderWeight(), Equals, 4.0)
+	c.Assert(nc.GetStore(2).GetRegionWeight(), Equals, 2.0)
+	c.Assert(nc.GetStore(1).GetLeaderWeight(), Equals, 1.0)
+	c.Assert(s.tc.GetStore(2).GetLeaderWeight(), Equals, 1.0)
+	op := scheduleByNamespace(s.tc, s.classifier, sched)
+	testutil.CheckTransferLeader(c, op[0], operator.OpBalance, 1, 2)
+
+	// The weights are restored after restart.
+	cluster := createTestRaftCluster(mockid.NewIDAllocator(), s.opt, s.tc.storage)
+	c.Assert(cluster.GetNamespaceStoreWeights("ns1"), HasLen, 0)
+	c.Assert(cluster.loadNamespaceStoreWeights(), IsNil)
+	c.Assert(cluster.GetNamespaceStoreWeights("ns1"), DeepEquals, weights)
+
+	// A zero weight is not set.
+	c.Assert(s.tc.SetNamespaceStoreWeights("ns1", map[uint64]core.StoreWeight{2: {RegionWeight: 2}}), IsNil)
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetStoreLeaderWeight(2), Equals, 0.5)
+	c.Assert(nc.GetStore(2).GetLeaderWeight(), Equals, 0.5)
+}
+
+func (s *testNamespaceSuite) TestMergeableRegionPairs(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 0), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns2")
+
+	// region size namespace
+	//      1   10       ns1
+	//      2   10       ns1
+	//      3  100       ns1
+	//      4   10       ns2
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	c.Assert(s.tc.putRegion(s.tc.GetRegion(3).Clone(core.SetApproximateSize(100))), IsNil)
+	c.Assert(s.tc.addLeaderRegion(4, 2), IsNil)
+	s.classifier.setRegion(4, "ns2")
+
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	pairs := nc.GetMergeableRegionPairs()
+	c.Assert(pairs, HasLen, 1)
+	c.Assert(pairs[0][0].GetID(), Equals, uint64(1))
+	c.Assert(pairs[0][1].GetID(), Equals, uint64(2))
+
+	// Region 4 has no neighbor in ns2.
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns2")
+	c.Assert(nc.GetMergeableRegionPairs(), HasLen, 0)
+}
+
+func (s *testNamespaceSuite) TestRegionWithLeader(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 0), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	// Region 2 is loaded without leader.
+	c.Assert(s.tc.LoadRegion(2, 1, 2), IsNil)
+	s.classifier.setRegion(2, "ns1")
+
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetRegion(1), NotNil)
+	c.Assert(nc.GetRegionWithLeader(1), NotNil)
+	c.Assert(nc.GetRegion(2), NotNil)
+	c.Assert(nc.GetRegionWithLeader(2), IsNil)
+	c.Assert(nc.GetRegionWithLeader(3), IsNil)
+}
+
+func (s *testNamespaceSuite) TestOperatorHistory(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 100), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+	s.opt.SetMaxReplicas(1)
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, _ := schedule.CreateScheduler("balance-region", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+
+	c.Assert(s.tc.addLeaderRegion(1, 2), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		c.Assert(scheduleByNamespace(s.tc, s.classifier, sched), HasLen, 1)
+	}
+
+	records := s.tc.GetOperatorHistory("ns1", 0)
+	c.Assert(records, HasLen, 3)
+	for _, r := range records {
+		c.Assert(r.Scheduler, Equals, sched.GetName())
+		c.Assert(r.Operator.RegionID(), Equals, uint64(1))
+		c.Assert(r.Time.Before(start), IsFalse)
+	}
+	c.Assert(s.tc.GetOperatorHistory("ns1", 2), HasLen, 2)
+	c.Assert(s.tc.GetOperatorHistory("ns2", 0), HasLen, 0)
+
+	// The history is bounded.
+	state := s.tc.getNamespaceState("ns1")
+	for i := 0; i < namespaceOpHistoryCapacity; i++ {
+		state.recordOperators("test", []*operator.Operator{newTestOperator(uint64(i), nil, operator.OpRegion)})
+	}
+	records = s.tc.GetOperatorHistory("ns1", 0)
+	c.Assert(records, HasLen, namespaceOpHistoryCapacity)
+	c.Assert(records[0].Operator.RegionID(), Equals, uint64(namespaceOpHistoryCapacity-1))
+}
+
+func (s *testNamespaceSuite) TestSchedulingEfficiency(c *C) {
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetSchedulingEfficiency(), Equals, 1.0)
+	move := func(regionID, from, to uint64) *operator.Operator {
+		return newTestOperator(regionID, nil, operator.OpRegion,
+			operator.AddLearner{ToStore: to}, operator.PromoteLearner{ToStore: to},
+			operator.TransferLeader{FromStore: from, ToStore: to}, operator.RemovePeer{FromStore: from})
+	}
+	transfer := func(regionID, from, to uint64) *operator.Operator {
+		return newTestOperator(regionID, nil, operator.OpLeader, operator.TransferLeader{FromStore: from, ToStore: to})
+	}
+
+	nc.state.recordOperators("test", []*operator.Operator{move(1, 1, 4), transfer(2, 1, 2)})
+	c.Assert(nc.GetSchedulingEfficiency(), Equals, 1.0)
+	// Moving the peer to another store does not revert the operator.
+	nc.state.recordOperators("test", []*operator.Operator{move(1, 4, 5)})
+	c.Assert(nc.GetSchedulingEfficiency(), Equals, 1.0)
+	// The reverted operators and the ones reverting them are wasted.
+	nc.state.recordOperators("test", []*operator.Operator{move(1, 5, 4), transfer(2, 2, 1)})
+	c.Assert(nc.GetSchedulingEfficiency(), Equals, 0.2)
+	// An operator is reverted only once.
+	nc.state.recordOperators("test", []*operator.Operator{transfer(2, 1, 2), transfer(3, 2, 1)})
+	c.Assert(nc.GetSchedulingEfficiency(), Equals, 3.0/7)
+
+	// The operators out of the window are not counted.
+	c.Assert(nc.state.getSchedulingEfficiency(time.Now().Add(2*schedulingEfficiencyWindow), schedulingEfficiencyWindow), Equals, 1.0)
+}
+
+func (s *testNamespaceSuite) TestAdaptiveScheduleLimit(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	nsCfg := &config.NamespaceConfig{RegionScheduleLimit: 8}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	setPendingPeers := func(counts ...int) {
+		for i, count := range counts {
+			c.Assert(s.tc.updateStore(uint64(i+1), core.SetPendingPeerCount(count)), IsNil)
+		}
+	}
+	getLimit := func() uint64 {
+		return newNamespaceCluster(s.tc, s.classifier, "ns1").GetRegionScheduleLimit()
+	}
+	setPendingPeers(20, 20, 20, 20)
+	c.Assert(getLimit(), Equals, uint64(8))
+
+	nsCfg.EnableAdaptiveScheduleLimit = true
+	nsCfg.MinRegionScheduleLimit = 2
+	nsCfg.MaxRegionScheduleLimit = 16
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	// The limit drops to the min bound under high pending peer load.
+	c.Assert(getLimit(), Equals, uint64(2))
+	// There are 2 pending peers per store on average.
+	setPendingPeers(4, 4, 0, 0)
+	c.Assert(getLimit(), Equals, uint64(5))
+	// The limit recovers to the max bound when the stores are idle.
+	setPendingPeers(0, 0, 0, 0)
+	c.Assert(getLimit(), Equals, uint64(16))
+
+	// The region schedule limit is the max bound if it is not set.
+	nsCfg.MaxRegionScheduleLimit = 0
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	c.Assert(getLimit(), Equals, uint64(8))
+}
+
+func (s *testNamespaceSuite) TestMaxPendingPeerCount(c *C) {
+	// store regionCount pendingPeerCount namespace
+	//     1           0                0       ns1
+	//     2         100                3       ns1
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 100), IsNil)
+	c.Assert(s.tc.updateStore(2, core.SetPendingPeerCount(3)), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+	s.opt.SetMaxReplicas(1)
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, _ := schedule.CreateScheduler("balance-region", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+	c.Assert(s.tc.addLeaderRegion(1, 2), IsNil)
+	s.classifier.setRegion(1, "ns1")
+
+	nsCfg := &config.NamespaceConfig{MaxPendingPeerCount: 2}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetPendingPeerCount(), Equals, 3)
+	c.Assert(nc.GetNamespaceMaxPendingPeerCount(), Equals, uint64(2))
+	// The per-store limit is not shadowed by the namespace limit.
+	c.Assert(nc.GetMaxPendingPeerCount(), Equals, s.opt.GetMaxPendingPeerCount())
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, sched), IsNil)
+
+	// Operators with high priority can still proceed.
+	op := newTestOperator(1, nil, operator.OpRegion, operator.AddPeer{ToStore: 1, PeerID: 1})
+	c.Assert(filterAddPeerOperators([]*operator.Operator{op}), HasLen, 0)
+	op.SetPriorityLevel(core.HighPriority)
+	c.Assert(filterAddPeerOperators([]*operator.Operator{op}), HasLen, 1)
+
+	nsCfg.MaxPendingPeerCount = 3
+	op = scheduleByNamespace(s.tc, s.classifier, sched)[0]
+	testutil.CheckTransferPeer(c, op, operator.OpBalance, 2, 1)
+
+	// The unset limit does not fall back to the per-store limit.
+	nsCfg.MaxPendingPeerCount = 0
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetNamespaceMaxPendingPeerCount(), Equals, uint64(0))
+	c.Assert(nc.isAddPeerThrottled(), IsFalse)
+}
+
+func (s *testNamespaceSuite) TestAchievedIsolationLevel(c *C) {
+	s.opt.GetReplication().Load().LocationLabels = []string{"rack", "host"}
+	// store rack host namespace
+	//     1   r1   h1       ns1
+	//     2   r1   h2       ns1
+	//     3   r2   h3       ns1
+	//     4   r3   h4       ns1
+	c.Assert(s.tc.addLabelsStore(1, 0, map[string]string{"rack": "r1", "host": "h1"}), IsNil)
+	c.Assert(s.tc.addLabelsStore(2, 0, map[string]string{"rack": "r1", "host": "h2"}), IsNil)
+	c.Assert(s.tc.addLabelsStore(3, 0, map[string]string{"rack": "r2", "host": "h3"}), IsNil)
+	c.Assert(s.tc.addLabelsStore(4, 0, map[string]string{"rack": "r3", "host": "h4"}), IsNil)
+	for id := uint64(1); id <= 4; id++ {
+		s.classifier.setStore(id, "ns1")
+	}
+
+	c.Assert(s.tc.addLeaderRegion(1, 1, 3, 4), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetAchievedIsolationLevel(), Equals, "rack")
+
+	// Region 2 has two peers in rack r1.
+	c.Assert(s.tc.addLeaderRegion(2, 1, 2, 3), IsNil)
+	s.classifier.setRegion(2, "ns1")
+	c.Assert(nc.GetAchievedIsolationLevel(), Equals, "host")
+}
+
+func (s *testNamespaceSuite) TestRandRegionByPeerState(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+
+	// region leader follower       namespace
+	//      1      1 2(pending), 3  ns1
+	//      2      1 2, 3(down)     ns1
+	//      3      1 2(pending), 3  ns2
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+	}
+	r := s.tc.GetRegion(1)
+	c.Assert(s.tc.putRegion(r.Clone(core.WithPendingPeers([]*metapb.Peer{r.GetStorePeer(2)}))), IsNil)
+	r = s.tc.GetRegion(2)
+	c.Assert(s.tc.putRegion(r.Clone(core.WithDownPeers([]*pdpb.PeerStats{{Peer: r.GetStorePeer(3)}}))), IsNil)
+	r = s.tc.GetRegion(3)
+	c.Assert(s.tc.putRegion(r.Clone(core.WithPendingPeers([]*metapb.Peer{r.GetStorePeer(2)}))), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	s.classifier.setRegion(2, "ns1")
+	s.classifier.setRegion(3, "ns2")
+
+	// Sampling may miss the expected region, so only the found ones are checked.
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	var pendingFound, downFound bool
+	for i := 0; i < 100; i++ {
+		if r := nc.RandRegionByPeerState(2, core.PeerPending); r != nil {
+			c.Assert(r.GetID(), Equals, uint64(1))
+			pendingFound = true
+		}
+		if r := nc.RandRegionByPeerState(3, core.PeerDown); r != nil {
+			c.Assert(r.GetID(), Equals, uint64(2))
+			downFound = true
+		}
+		c.Assert(nc.RandRegionByPeerState(3, core.PeerPending), IsNil)
+		c.Assert(nc.RandRegionByPeerState(2, core.PeerDown), IsNil)
+	}
+	c.Assert(pendingFound, IsTrue)
+	c.Assert(downFound, IsTrue)
+}
+
+func (s *testNamespaceSuite) TestStoreFlapping(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(3, 0), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+	s.classifier.setStore(3, "ns2")
+
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	for i := 0; i < storeFlappingThreshold; i++ {
+		c.Assert(nc.IsStoreFlapping(1), IsFalse)
+		for _, id := range []uint64{1, 3} {
+			// The store is disconnected and then comes back.
+			c.Assert(s.tc.updateStore(id, core.SetLastHeartbeatTS(time.Now().Add(-time.Minute))), IsNil)
+			c.Assert(s.tc.handleStoreHeartbeat(&pdpb.StoreStats{StoreId: id}), IsNil)
+		}
+		// Heartbeats in time are not transitions.
+		c.Assert(s.tc.handleStoreHeartbeat(&pdpb.StoreStats{StoreId: 2}), IsNil)
+	}
+	c.Assert(nc.IsStoreFlapping(1), IsTrue)
+	c.Assert(nc.IsStoreFlapping(2), IsFalse)
+	// Store 3 is not in the namespace.
+	c.Assert(nc.IsStoreFlapping(3), IsFalse)
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns2").IsStoreFlapping(3), IsTrue)
+
+	// Transitions out of the window are ignored.
+	d := newStoreFlappingDetector(time.Minute, 2)
+	d.recordTransition(1, time.Now().Add(-2*time.Minute))
+	d.recordTransition(1, time.Now())
+	c.Assert(d.isFlapping(1, time.Now()), IsFalse)
+	d.recordTransition(1, time.Now())
+	c.Assert(d.isFlapping(1, time.Now()), IsTrue)
+	c.Assert(d.isFlapping(1, time.Now().Add(2*time.Minute)), IsFalse)
+}
+
+func (s *testNamespaceSuite) TestOperatorStoreLeavesNamespace(c *C) {
+	// store regionCount namespace
+	//     1           0       ns1
+	//     2           0       ns1
+	//     3           0       ns1
+	//     4           0       ns1
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	s.classifier.setRegion(1, "ns1")
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	region := s.tc.GetRegion(1)
+
+	hbStreams := mockhbstream.NewHeartbeatStreams(s.tc.getClusterID())
+	oc := schedule.NewOperatorController(s.ctx, s.tc.RaftCluster, hbStreams)
+	newOp := func() *operator.Operator {
+		return newTestOperator(1, region.GetRegionEpoch(), operator.OpRegion,
+			operator.AddLearner{ToStore: 4, PeerID: 100},
+			operator.PromoteLearner{ToStore: 4, PeerID: 100},
+			operator.RemovePeer{FromStore: 1},
+		)
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	op := newOp()
+	c.Assert(nc.checkOperator(op), IsTrue)
+	s.tc.namespaceStates.operators.put("ns1", []*operator.Operator{op})
+	c.Assert(oc.AddOperator(op), IsTrue)
+
+	// The learner is added.
+	region = region.Clone(core.WithAddPeer(&metapb.Peer{Id: 100, StoreId: 4, IsLearner: true}))
+	s.tc.checkNamespaceOperator(oc, s.classifier, region)
+	c.Assert(oc.GetOperator(1), Equals, op)
+
+	// The store is reclassified before the learner is promoted.
+	s.classifier.setStore(4, "ns2")
+	s.tc.checkNamespaceOperator(oc, s.classifier, region)
+	c.Assert(oc.GetOperator(1), IsNil)
+	_, ok := s.tc.namespaceStates.operators.getNamespace(1, op)
+	c.Assert(ok, IsFalse)
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns1").checkOperator(newOp()), IsFalse)
+
+	// Operators not produced for namespaces are left alone.
+	op = newOp()
+	c.Assert(oc.AddOperator(op), IsTrue)
+	s.tc.checkNamespaceOperator(oc, s.classifier, region)
+	c.Assert(oc.GetOperator(1), Equals, op)
+}
+
+func (s *testNamespaceSuite) TestPruneNamespaceOperators(c *C) {
+	c.Assert(s.tc.addLeaderStore(1, 0), IsNil)
+	c.Assert(s.tc.addLeaderStore(2, 0), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	region := s.tc.GetRegion(1)
+
+	hbStreams := mockhbstream.NewHeartbeatStreams(s.tc.getClusterID())
+	oc := schedule.NewOperatorController(s.ctx, s.tc.RaftCluster, hbStreams)
+	tracked := s.tc.namespaceStates.operators
+	newOp := func() *operator.Operator {
+		return newTestOperator(1, region.GetRegionEpoch(), operator.OpLeader, operator.TransferLeader{FromStore: 1, ToStore: 2})
+	}
+
+	// The running operator is kept until it finishes.
+	op := newOp()
+	tracked.put("ns1", []*operator.Operator{op})
+	c.Assert(oc.AddOperator(op), IsTrue)
+	s.tc.checkNamespaceOperator(oc, s.classifier, region)
+	c.Assert(tracked.ops, HasLen, 1)
+	finished := region.Clone(core.WithLeader(region.GetStorePeer(2)))
+	oc.Dispatch(finished, schedule.DispatchFromHeartBeat)
+	c.Assert(oc.GetOperator(1), IsNil)
+	s.tc.checkNamespaceOperator(oc, s.classifier, finished)
+	c.Assert(tracked.ops, HasLen, 0)
+
+	// The canceled operator is dropped.
+	op = newOp()
+	tracked.put("ns1", []*operator.Operator{op})
+	c.Assert(oc.AddOperator(op), IsTrue)
+	c.Assert(oc.CancelOperatorsForRegion(1), Equals, 1)
+	s.tc.checkNamespaceOperator(oc, s.classifier, region)
+	c.Assert(tracked.ops, HasLen, 0)
+
+	// The operator not added yet is kept.
+	tracked.put("ns1", []*operator.Operator{newOp()})
+	s.tc.checkNamespaceOperator(oc, s.classifier, region)
+	c.Assert(tracked.ops, HasLen, 1)
+}
+
+func (s *testNamespaceSuite) TestSelectRegionToMoveOut(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addRegionStore(5, 0), IsNil)
+	s.classifier.setStore(5, "ns2")
+
+	// region size peers      note
+	//      1   10 1, 2, 3    too small to matter
+	//      2   50 1, 2, 3
+	//      3   80 1, 2, 3
+	//      4  300 1, 2, 3    too large to move
+	//      5   70 1, 2, 3, 4 no target store
+	//      6   60 1, 2, 3    has a pending peer
+	//      7   60 2, 3, 4    not on the store
+	sizes := []int64{10, 50, 80, 300}
+	for i, size := range sizes {
+		id := uint64(i + 1)
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		c.Assert(s.tc.putRegion(s.tc.GetRegion(id).Clone(core.SetApproximateSize(size))), IsNil)
+	}
+	c.Assert(s.tc.addLeaderRegion(5, 1, 2, 3, 4), IsNil)
+	c.Assert(s.tc.putRegion(s.tc.GetRegion(5).Clone(core.SetApproximateSize(70))), IsNil)
+	c.Assert(s.tc.addLeaderRegion(6, 1, 2, 3), IsNil)
+	region := s.tc.GetRegion(6)
+	c.Assert(s.tc.putRegion(region.Clone(core.SetApproximateSize(60), core.WithPendingPeers([]*metapb.Peer{region.GetStorePeer(2)}))), IsNil)
+	c.Assert(s.tc.addLeaderRegion(7, 2, 3, 4), IsNil)
+	c.Assert(s.tc.putRegion(s.tc.GetRegion(7).Clone(core.SetApproximateSize(60))), IsNil)
+	for id := uint64(1); id <= 7; id++ {
+		s.classifier.setRegion(id, "ns1")
+	}
+
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.SelectRegionToMoveOut(1).GetID(), Equals, uint64(3))
+	c.Assert(nc.SelectRegionToMoveOut(5), IsNil)
+
+	// No region can be moved if the only target store is down.
+	c.Assert(s.tc.setStoreDown(4), IsNil)
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.SelectRegionToMoveOut(1), IsNil)
+}
+
+func (s *testNamespaceSuite) TestSamplingStrategy(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 0), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+
+	// Region 1 is much larger than others, and region 2 has much more flow
+	// than others.
+	for id := uint64(1); id <= 10; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	c.Assert(s.tc.putRegion(s.tc.GetRegion(1).Clone(core.SetApproximateSize(1000))), IsNil)
+	c.Assert(s.tc.putRegion(s.tc.GetRegion(2).Clone(core.SetWrittenBytes(10000))), IsNil)
+
+	const trials = 1000
+	count := func(strategy string) map[uint64]int {
+		nsCfg := &config.NamespaceConfig{SamplingStrategy: strategy}
+		nsCfg.Adjust(s.opt)
+		s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+		nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+		c.Assert(nc.SamplingStrategy.String(), Equals, strategy)
+		counts := make(map[uint64]int)
+		for i := 0; i < trials; i++ {
+			counts[nc.RandLeaderRegion(1).GetID()]++
+			counts[nc.RandFollowerRegion(2).GetID()]++
+		}
+		return counts
+	}
+
+	// Each region is picked with probability 1/10 in theory.
+	counts := count("uniform")
+	c.Assert(counts[1], Less, trials/2)
+	c.Assert(counts[2], Less, trials/2)
+	// Region 1 is picked with probability about 0.6 in theory.
+	counts = count("size-weighted")
+	c.Assert(counts[1], Greater, trials)
+	// Region 2 is picked with probability about 0.65 in theory.
+	counts = count("flow-weighted")
+	c.Assert(counts[2], Greater, trials)
+}
+
+func (s *testNamespaceSuite) TestLeaderHeadroom(c *C) {
+	// store leaders weight namespace
+	//     1       6      1       ns1
+	//     2       2      1       ns1
+	//     3       0      2       ns1
+	//     4       1      1       ns2
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	s.classifier.setStore(4, "ns2")
+	c.Assert(s.tc.updateStore(3, core.SetLeaderWeight(2)), IsNil)
+	var regionID uint64
+	addRegions := func(storeID uint64, n int, ns string) {
+		for i := 0; i < n; i++ {
+			regionID++
+			c.Assert(s.tc.addLeaderRegion(regionID, storeID), IsNil)
+			s.classifier.setRegion(regionID, ns)
+		}
+	}
+	addRegions(1, 6, "ns1")
+	addRegions(2, 2, "ns1")
+	addRegions(4, 1, "ns2")
+
+	// 8 leaders in total, the ideal leader counts are 2, 2 and 4.
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetLeaderHeadroom(1), Equals, float64(-4))
+	c.Assert(nc.GetLeaderHeadroom(2), Equals, float64(0))
+	c.Assert(nc.GetLeaderHeadroom(3), Equals, float64(4))
+	c.Assert(nc.GetLeaderHeadroom(4), Equals, float64(0))
+}
+
+func (s *testNamespaceSuite) TestRegionsWithDownPeersByUrgency(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+
+	// region down     pending healthy
+	//      1 3        -             2
+	//      2 2, 3     -             1
+	//      3 -        -             3
+	//      4 3        2             1
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	downPeers := func(r *core.RegionInfo, storeIDs ...uint64) core.RegionCreateOption {
+		var stats []*pdpb.PeerStats
+		for _, id := range storeIDs {
+			stats = append(stats, &pdpb.PeerStats{Peer: r.GetStorePeer(id)})
+		}
+		return core.WithDownPeers(stats)
+	}
+	r := s.tc.GetRegion(1)
+	c.Assert(s.tc.putRegion(r.Clone(downPeers(r, 3))), IsNil)
+	r = s.tc.GetRegion(2)
+	c.Assert(s.tc.putRegion(r.Clone(downPeers(r, 2, 3))), IsNil)
+	r = s.tc.GetRegion(4)
+	c.Assert(s.tc.putRegion(r.Clone(downPeers(r, 3), core.WithPendingPeers([]*metapb.Peer{r.GetStorePeer(2)}))), IsNil)
+
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	regions := nc.GetRegionsWithDownPeersByUrgency()
+	c.Assert(regions, HasLen, 3)
+	c.Assert(regions[0].GetID(), Equals, uint64(2))
+	c.Assert(regions[1].GetID(), Equals, uint64(4))
+	c.Assert(regions[2].GetID(), Equals, uint64(1))
+}
+
+func (s *testNamespaceSuite) TestValidateOperator(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	s.classifier.setStore(4, "ns2")
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	epoch := s.tc.GetRegion(1).GetRegionEpoch()
+
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	op := newTestOperator(1, epoch, operator.OpRegion,
+		operator.AddLearner{ToStore: 3, PeerID: 100},
+		operator.PromoteLearner{ToStore: 3, PeerID: 100},
+		operator.TransferLeader{FromStore: 1, ToStore: 3},
+		operator.RemovePeer{FromStore: 1},
+	)
+	c.Assert(nc.ValidateOperator(op), IsNil)
+
+	// Adding a peer on a store of another namespace is rejected.
+	op = newTestOperator(1, epoch, operator.OpRegion,
+		operator.AddPeer{ToStore: 4, PeerID: 100},
+		operator.RemovePeer{FromStore: 1},
+	)
+	c.Assert(nc.ValidateOperator(op), NotNil)
+	op = newTestOperator(1, epoch, operator.OpRegion,
+		operator.AddLightLearner{ToStore: 4, PeerID: 100},
+	)
+	c.Assert(nc.ValidateOperator(op), NotNil)
+}
+
+func (s *testNamespaceSuite) TestSchedulePauseWindows(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 100), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+	s.opt.SetMaxReplicas(1)
+	c.Assert(s.tc.addLeaderRegion(1, 2), IsNil)
+	s.classifier.setRegion(1, "ns1")
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, _ := schedule.CreateScheduler("balance-region", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+	now := time.Now()
+	window := func(from, to time.Duration) typeutil.TimeWindow {
+		return typeutil.TimeWindow{
+			Start: now.Add(from).Format("15:04"),
+			End:   now.Add(to).Format("15:04"),
+		}
+	}
+	setWindows := func(windows ...typeutil.TimeWindow) {
+		nsCfg := &config.NamespaceConfig{SchedulePauseWindows: windows}
+		nsCfg.Adjust(s.opt)
+		s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	}
+
+	// The window covers now.
+	setWindows(window(-time.Hour, time.Hour))
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns1").GetSchedulePauseWindows(), HasLen, 1)
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, sched), IsNil)
+
+	// The windows are out of now.
+	setWindows(window(time.Hour, 2*time.Hour), window(-2*time.Hour, -time.Hour))
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, sched), HasLen, 1)
+}
+
+func (s *testNamespaceSuite) TestEstimateRebalanceCost(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.EstimateRebalanceCost(), Equals, uint64(0))
+
+	// Each store has 3 regions of 10MB.
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.EstimateRebalanceCost(), Equals, uint64(0))
+
+	// Store 1 has 60MB more than others, 40MB has to be moved out of it.
+	for id := uint64(4); id <= 9; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.EstimateRebalanceCost(), Equals, uint64(40<<20))
+}
+
+func (s *testNamespaceSuite) TestLearnerProgress(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	region := s.tc.GetRegion(1).Clone(
+		core.WithAddPeer(&metapb.Peer{Id: 100, StoreId: 3, IsLearner: true}),
+		core.WithAddPeer(&metapb.Peer{Id: 101, StoreId: 4, IsLearner: true}),
+	)
+	// The learner on store 3 is lagging behind.
+	region = region.Clone(core.WithPendingPeers([]*metapb.Peer{region.GetStorePeer(3)}))
+
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetLearnerProgress(region, 3), Equals, float64(0))
+	c.Assert(nc.GetLearnerProgress(region, 4), Equals, float64(1))
+	// The peer on store 2 is not a learner.
+	c.Assert(nc.GetLearnerProgress(region, 2), Equals, float64(0))
+
+	s.classifier.setStore(4, "ns2")
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetLearnerProgress(region, 4), Equals, float64(0))
+}
+
+func (s *testNamespaceSuite) TestLeaderEligibleStores(c *C) {
+	// store leaderCount labels         namespace
+	//     1         100 -              ns1
+	//     2           0 -              ns1
+	//     3           0 engine=tiflash ns1
+	//     4           0 noleader=true  ns1
+	//     5           0 -              ns2
+	s.opt.SetLabelProperty(opt.RejectLeader, "noleader", "true")
+	c.Assert(s.tc.addLeaderStore(1, 100), IsNil)
+	for id := uint64(2); id <= 5; id++ {
+		c.Assert(s.tc.addLeaderStore(id, 0), IsNil)
+	}
+	c.Assert(s.tc.updateStore(3, core.SetStoreLabels([]*metapb.StoreLabel{{Key: "engine", Value: "tiflash"}})), IsNil)
+	c.Assert(s.tc.updateStore(4, core.SetStoreLabels([]*metapb.StoreLabel{{Key: "noleader", Value: "true"}})), IsNil)
+	for id := uint64(1); id <= 4; id++ {
+		s.classifier.setStore(id, "ns1")
+	}
+	s.classifier.setStore(5, "ns2")
+
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	var ids []uint64
+	for _, store := range nc.GetLeaderEligibleStores() {
+		ids = append(ids, store.GetID())
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	c.Assert(ids, DeepEquals, []uint64{1, 2})
+
+	// Leaders are not transferred to stores which are not eligible.
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, _ := schedule.CreateScheduler("balance-leader", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+	c.Assert(s.tc.addLeaderRegion(1, 1, 3, 4), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, sched), IsNil)
+
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	op := scheduleByNamespace(s.tc, s.classifier, sched)
+	testutil.CheckTransferLeader(c, op[0], operator.OpBalance, 1, 2)
+}
+
+func (s *testNamespaceSuite) TestShuffleNamespaceLeader(c *C) {
+	// store regionCount namespace
+	//     1           0       ns1
+	//     2           0       ns1
+	//     3           0       ns1
+	//     4           0       ns2
+	//     5           0       ns2
+	for id := uint64(1); id <= 5; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	s.classifier.setStore(4, "ns2")
+	s.classifier.setStore(5, "ns2")
+	for id := uint64(1); id <= 10; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	for id := uint64(11); id <= 20; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 4, 5), IsNil)
+		s.classifier.setRegion(id, "ns2")
+	}
+	// Region 21 has a peer out of its namespace.
+	c.Assert(s.tc.addLeaderRegion(21, 1, 4), IsNil)
+	s.classifier.setRegion(21, "ns1")
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, err := schedule.CreateScheduler("shuffle-namespace-leader", oc, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder("shuffle-namespace-leader", []string{"3"}))
+	c.Assert(err, IsNil)
+	var count int
+	for i := 0; i < 20; i++ {
+		ops := scheduleByNamespace(s.tc, s.classifier, sched)
+		c.Assert(len(ops), LessEqual, 3)
+		count += len(ops)
+		for _, op := range ops {
+			region := s.tc.GetRegion(op.RegionID())
+			c.Assert(op.RegionID(), Not(Equals), uint64(21))
+			c.Assert(op.Len(), Equals, 1)
+			step, ok := op.Step(0).(operator.TransferLeader)
+			c.Assert(ok, IsTrue)
+			c.Assert(step.FromStore, Equals, region.GetLeader().GetStoreId())
+			ns := s.classifier.GetRegionNamespace(region)
+			c.Assert(s.classifier.GetStoreNamespace(s.tc.GetStore(step.ToStore)), Equals, ns)
+		}
+	}
+	c.Assert(count, Greater, 0)
+}
+
+func (s *testNamespaceSuite) TestShuffleNamespaceLeaderToIneligibleStore(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	c.Assert(s.tc.addLabelsStore(2, 0, map[string]string{engineLabel: tiflashEngine}), IsNil)
+	c.Assert(s.tc.addRegionStore(3, 0), IsNil)
+	for id := uint64(1); id <= 3; id++ {
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2), IsNil)
+	s.classifier.setRegion(1, "ns1")
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, err := schedule.CreateScheduler("shuffle-namespace-leader", oc, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder("shuffle-namespace-leader", nil))
+	c.Assert(err, IsNil)
+	// The only follower is on the TiFlash store.
+	for i := 0; i < 10; i++ {
+		c.Assert(scheduleByNamespace(s.tc, s.classifier, sched), IsNil)
+	}
+
+	// The only follower is on the store shutting down.
+	c.Assert(s.tc.addLeaderRegion(1, 1, 3), IsNil)
+	var count int
+	for i := 0; i < 50; i++ {
+		count += len(scheduleByNamespace(s.tc, s.classifier, sched))
+	}
+	c.Assert(count, Greater, 0)
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.BeginStoreShutdown(3), IsNil)
+	for i := 0; i < 10; i++ {
+		c.Assert(scheduleByNamespace(s.tc, s.classifier, sched), IsNil)
+	}
+}
+
+func (s *testNamespaceSuite) TestStoreWithMostDownRegions(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	s.classifier.setStore(4, "ns2")
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	storeID, count := nc.GetStoreWithMostDownRegions()
+	c.Assert(storeID, Equals, uint64(0))
+	c.Assert(count, Equals, 0)
+
+	// region down namespace
+	//      1    3       ns1
+	//      2    3       ns1
+	//      3 2, 3       ns1
+	//      4    2       ns1
+	//      5    4       ns2
+	//      6    4       ns2
+	//      7    4       ns2
+	downs := map[uint64][]uint64{1: {3}, 2: {3}, 3: {2, 3}, 4: {2}}
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	for id := uint64(5); id <= 7; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 4), IsNil)
+		s.classifier.setRegion(id, "ns2")
+		downs[id] = []uint64{4}
+	}
+	for id, stores := range downs {
+		r := s.tc.GetRegion(id)
+		var stats []*pdpb.PeerStats
+		for _, storeID := range stores {
+			stats = append(stats, &pdpb.PeerStats{Peer: r.GetStorePeer(storeID)})
+		}
+		c.Assert(s.tc.putRegion(r.Clone(core.WithDownPeers(stats))), IsNil)
+	}
+
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	storeID, count = nc.GetStoreWithMostDownRegions()
+	c.Assert(storeID, Equals, uint64(3))
+	c.Assert(count, Equals, 3)
+}
+
+func (s *testNamespaceSuite) TestOversizedRegions(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	s.classifier.setStore(1, "ns1")
+	// region size  keys     namespace
+	//      1   10  100      ns1
+	//      2  200  100      ns1
+	//      3   10  2000000  ns1
+	//      4  200  100      ns2
+	for id, stat := range map[uint64][2]int64{1: {10, 100}, 2: {200, 100}, 3: {10, 2000000}, 4: {200, 100}} {
+		c.Assert(s.tc.addLeaderRegion(id, 1), IsNil)
+		c.Assert(s.tc.putRegion(s.tc.GetRegion(id).Clone(core.SetApproximateSize(stat[0]), core.SetApproximateKeys(stat[1]))), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	s.classifier.setRegion(4, "ns2")
+	regionIDs := func(regions []*core.RegionInfo) []uint64 {
+		ids := make([]uint64, 0, len(regions))
+		for _, r := range regions {
+			ids = append(ids, r.GetID())
+		}
+		return ids
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetRegionMaxSize(), Equals, uint64(defaultRegionMaxSize))
+	c.Assert(nc.GetRegionMaxKeys(), Equals, uint64(defaultRegionMaxKeys))
+	c.Assert(regionIDs(nc.GetOversizedRegions()), DeepEquals, []uint64{2, 3})
+
+	nsCfg := &config.NamespaceConfig{RegionMaxSize: 300, RegionMaxKeys: 50}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	c.Assert(regionIDs(nc.GetOversizedRegions()), DeepEquals, []uint64{1, 2, 3})
+	nsCfg.RegionMaxKeys = 5000000
+	c.Assert(nc.GetOversizedRegions(), HasLen, 0)
+}
+
+func (s *testNamespaceSuite) TestSuggestSplitKeys(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	s.classifier.setStore(1, "ns1")
+	c.Assert(s.tc.addLeaderRegion(1, 1), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	region := s.tc.GetRegion(1).Clone(core.WithStartKey([]byte("a")), core.WithEndKey([]byte("c")))
+
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.SuggestSplitKeys(region, 2), DeepEquals, [][]byte{[]byte("b")})
+	keys := nc.SuggestSplitKeys(region, 4)
+	c.Assert(keys, HasLen, 3)
+	c.Assert(keys[1], DeepEquals, []byte("b"))
+	for i, key := range keys {
+		c.Assert(bytes.Compare(key, region.GetStartKey()), Greater, 0)
+		c.Assert(bytes.Compare(key, region.GetEndKey()), Less, 0)
+		if i > 0 {
+			c.Assert(bytes.Compare(key, keys[i-1]), Greater, 0)
+		}
+	}
+	c.Assert(nc.SuggestSplitKeys(region, 1), IsNil)
+
+	// The region is unbounded.
+	region = region.Clone(core.WithStartKey(nil), core.WithEndKey(nil))
+	c.Assert(nc.SuggestSplitKeys(region, 2), DeepEquals, [][]byte{{0x80}})
+
+	// The parts are capped by the approximate key count.
+	region = region.Clone(core.SetApproximateKeys(1))
+	c.Assert(nc.SuggestSplitKeys(region, 2), IsNil)
+}
+
+func (s *testNamespaceSuite) TestScheduleFairness(c *C) {
+	// ns1 can always be scheduled, while ns2 has nothing to schedule.
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 100), IsNil)
+	c.Assert(s.tc.addRegionStore(3, 0), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+	s.classifier.setStore(3, "ns2")
+	s.opt.SetMaxReplicas(1)
+	c.Assert(s.tc.addLeaderRegion(1, 2), IsNil)
+	s.classifier.setRegion(1, "ns1")
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, _ := schedule.CreateScheduler("balance-region", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+	state1, state2 := s.tc.getNamespaceState("ns1"), s.tc.getNamespaceState("ns2")
+	tick := func() {
+		state1.tickFairness()
+		state2.tickFairness()
+	}
+	// The retries within a round are counted once.
+	for i := 0; i < maxScheduleRetries; i++ {
+		c.Assert(scheduleByNamespace(s.tc, s.classifier, sched), HasLen, 1)
+	}
+	tick()
+	c.Assert(state1.getFairnessStats(), DeepEquals, &NamespaceFairnessStats{Visited: 1})
+	// ns2 is skipped if ns1 is always scheduled before it.
+	stats2 := state2.getFairnessStats()
+	c.Assert(stats2.Visited+stats2.Skipped, Equals, 1)
+
+	// The round without schedulers skips both.
+	tick()
+	c.Assert(state1.getFairnessStats(), DeepEquals, &NamespaceFairnessStats{Visited: 1, Skipped: 1})
+	c.Assert(state2.getFairnessStats(), DeepEquals, &NamespaceFairnessStats{Visited: stats2.Visited, Skipped: stats2.Skipped + 1})
+
+	// ns1 is skipped during its pause window.
+	nsCfg := &config.NamespaceConfig{SchedulePauseWindows: []typeutil.TimeWindow{{
+		Start: time.Now().Add(-time.Hour).Format("15:04"),
+		End:   time.Now().Add(time.Hour).Format("15:04"),
+	}}}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	for i := 0; i < maxScheduleRetries; i++ {
+		c.Assert(scheduleByNamespace(s.tc, s.classifier, sched), IsNil)
+	}
+	tick()
+	c.Assert(state1.getFairnessStats(), DeepEquals, &NamespaceFairnessStats{Visited: 1, Skipped: 2})
+	c.Assert(state2.getFairnessStats(), DeepEquals, &NamespaceFairnessStats{Visited: stats2.Visited + 1, Skipped: stats2.Skipped + 1})
+
+	// The stats are bounded by the window.
+	for i := 0; i < namespaceFairnessWindow; i++ {
+		state2.recordTick(true)
+	}
+	c.Assert(state2.getFairnessStats(), DeepEquals, &NamespaceFairnessStats{Visited: namespaceFairnessWindow})
+}
+
+func (s *testNamespaceSuite) TestDecommissioningNamespace(c *C) {
+	c.Assert(s.tc.addLeaderStore(1, 100), IsNil)
+	c.Assert(s.tc.addLeaderStore(2, 200), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+	c.Assert(s.tc.addLeaderRegion(1, 2, 1), IsNil)
+	s.classifier.setRegion(1, "ns1")
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	bls, err := schedule.CreateScheduler("balance-leader", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+	c.Assert(err, IsNil)
+	els, err := schedule.CreateScheduler("evict-leader", oc, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder("evict-leader", []string{"2"}))
+	c.Assert(err, IsNil)
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, bls), HasLen, 1)
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, els), HasLen, 1)
+
+	s.tc.MarkNamespaceDecommissioning("ns1")
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns1").IsDecommissioning(), IsTrue)
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, bls), IsNil)
+	ops := scheduleByNamespace(s.tc, s.classifier, els)
+	c.Assert(ops, HasLen, 1)
+	testutil.CheckTransferLeader(c, ops[0], operator.OpLeader, 2, 1)
+	// The states are kept for auditing.
+	c.Assert(s.tc.GetOperatorHistory("ns1", 0), HasLen, 3)
+}
+
+func (s *testNamespaceSuite) TestRegionsOnOfflineStores(c *C) {
+	for i := uint64(1); i <= 4; i++ {
+		c.Assert(s.tc.addRegionStore(i, 0), IsNil)
+		s.classifier.setStore(i, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(3, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(4, 4), IsNil)
+	for i := uint64(1); i <= 4; i++ {
+		s.classifier.setRegion(i, "ns1")
+	}
+
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetRegionsOnOfflineStores(), HasLen, 0)
+
+	c.Assert(s.tc.setStoreOffline(3), IsNil)
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	var ids []uint64
+	for _, r := range nc.GetRegionsOnOfflineStores() {
+		ids = append(ids, r.GetID())
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	c.Assert(ids, DeepEquals, []uint64{2, 3})
+
+	// Offline stores of other namespaces are not taken into account.
+	s.classifier.setStore(3, "ns2")
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetRegionsOnOfflineStores(), HasLen, 0)
+}
+
+func (s *testNamespaceSuite) TestStoreHotPeerCounts(c *C) {
+	for i := uint64(1); i <= 4; i++ {
+		c.Assert(s.tc.addRegionStore(i, 0), IsNil)
+	}
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+	s.classifier.setStore(3, "ns1")
+	s.classifier.setStore(4, "ns2")
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 2, 1, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(3, 4), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	s.classifier.setRegion(2, "ns1")
+	s.classifier.setRegion(3, "ns2")
+
+	hotDegree := s.opt.GetHotRegionCacheHitsThreshold()
+	for _, stat := range []*statistics.HotPeerStat{
+		{StoreID: 1, RegionID: 1, Kind: statistics.WriteFlow, HotDegree: hotDegree},
+		{StoreID: 2, RegionID: 1, Kind: statistics.WriteFlow, HotDegree: hotDegree},
+		{StoreID: 3, RegionID: 1, Kind: statistics.WriteFlow, HotDegree: hotDegree},
+		{StoreID: 1, RegionID: 2, Kind: statistics.WriteFlow, HotDegree: hotDegree},
+		{StoreID: 2, RegionID: 2, Kind: statistics.ReadFlow, HotDegree: hotDegree},
+		// Not hot enough.
+		{StoreID: 3, RegionID: 2, Kind: statistics.ReadFlow, HotDegree: hotDegree - 1},
+		// Regions and stores of other namespaces.
+		{StoreID: 1, RegionID: 3, Kind: statistics.ReadFlow, HotDegree: hotDegree},
+		{StoreID: 4, RegionID: 3, Kind: statistics.WriteFlow, HotDegree: hotDegree},
+	} {
+		s.tc.hotSpotCache.Update(stat)
+	}
+
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetStoreHotPeerCounts(), DeepEquals, map[uint64]StoreHotPeerCount{
+		1: {Read: 0, Write: 2},
+		2: {Read: 1, Write: 1},
+		3: {Read: 0, Write: 1},
+	})
+}
+
+func (s *testNamespaceSuite) TestMaxConcurrentOperators(c *C) {
+	c.Assert(s.tc.addLeaderStore(1, 0), IsNil)
+	c.Assert(s.tc.addLeaderStore(2, 0), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(5, 1, 2), IsNil)
+	s.classifier.setRegion(5, "ns2")
+
+	nsCfg := &config.NamespaceConfig{MaxConcurrentOperators: 2}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetMaxConcurrentOperators(), Equals, uint64(2))
+
+	hbStreams := mockhbstream.NewHeartbeatStreams(s.tc.getClusterID())
+	oc := schedule.NewOperatorController(s.ctx, s.tc.RaftCluster, hbStreams)
+	oc.SetNamespaceClassifier(s.classifier)
+	newOp := func(regionID uint64) *operator.Operator {
+		region := s.tc.GetRegion(regionID)
+		return newTestOperator(regionID, region.GetRegionEpoch(), operator.OpLeader, operator.TransferLeader{FromStore: 1, ToStore: 2})
+	}
+
+	c.Assert(oc.AddOperator(newOp(1)), IsTrue)
+	c.Assert(oc.AddOperator(newOp(2)), IsTrue)
+	// The excess operators are dropped.
+	c.Assert(oc.AddOperator(newOp(3)), IsFalse)
+	c.Assert(oc.AddWaitingOperator(newOp(4)), IsTrue)
+	c.Assert(oc.GetOperator(4), IsNil)
+	c.Assert(oc.GetOperators(), HasLen, 2)
+	// Replacing a running operator does not exceed the limit.
+	op := newOp(2)
+	op.SetPriorityLevel(core.HighPriority)
+	c.Assert(oc.AddOperator(op), IsTrue)
+	// Other namespaces are not limited.
+	c.Assert(oc.AddOperator(newOp(5)), IsTrue)
+
+	c.Assert(oc.RemoveOperator(oc.GetOperator(1)), IsTrue)
+	c.Assert(oc.AddOperator(newOp(3)), IsTrue)
+	c.Assert(oc.GetOperators(), HasLen, 3)
+	// The operators created by admins are not limited.
+	c.Assert(oc.AddOperator(newOp(4)), IsFalse)
+	region := s.tc.GetRegion(4)
+	c.Assert(oc.AddOperator(newTestOperator(4, region.GetRegionEpoch(), operator.OpAdmin, operator.TransferLeader{FromStore: 1, ToStore: 2})), IsTrue)
+	c.Assert(oc.GetOperators(), HasLen, 4)
+}
+
+func (s *testNamespaceSuite) TestNamespaceOperatorQueue(c *C) {
+	c.Assert(s.tc.addLeaderStore(1, 0), IsNil)
+	c.Assert(s.tc.addLeaderStore(2, 0), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+
+	nsCfg := &config.NamespaceConfig{MaxConcurrentOperators: 1}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+
+	hbStreams := mockhbstream.NewHeartbeatStreams(s.tc.getClusterID())
+	oc := schedule.NewOperatorController(s.ctx, s.tc.RaftCluster, hbStreams)
+	oc.SetNamespaceClassifier(s.classifier)
+	newOp := func(regionID uint64, kind operator.OpKind) *operator.Operator {
+		region := s.tc.GetRegion(regionID)
+		return newTestOperator(regionID, region.GetRegionEpoch(), kind, operator.TransferLeader{FromStore: 1, ToStore: 2})
+	}
+
+	c.Assert(oc.AddOperator(newOp(1, operator.OpLeader)), IsTrue)
+	// Operators exceeding the limit are queued.
+	c.Assert(oc.AddWaitingOperator(newOp(2, operator.OpBalance|operator.OpLeader)), IsTrue)
+	c.Assert(oc.AddWaitingOperator(newOp(3, operator.OpReplica|operator.OpLeader)), IsTrue)
+	c.Assert(oc.GetOperators(), HasLen, 1)
+
+	// The repair operator preempts the balance operator.
+	c.Assert(oc.RemoveOperator(oc.GetOperator(1)), IsTrue)
+	oc.PromoteWaitingOperator()
+	c.Assert(oc.GetOperator(3), NotNil)
+	c.Assert(oc.GetOperator(2), IsNil)
+	// Nothing is promoted at the limit.
+	oc.PromoteWaitingOperator()
+	c.Assert(oc.GetOperators(), HasLen, 1)
+
+	c.Assert(oc.RemoveOperator(oc.GetOperator(3)), IsTrue)
+	oc.PromoteWaitingOperator()
+	c.Assert(oc.GetOperator(2), NotNil)
+}
+
+// mockClock is a clock which only moves forward when it sleeps.
+type mockClock struct {
+	now time.Time
+}
+
+func (c *mockClock) Now() time.Time {
+	return c.now
+}
+
+func (c *mockClock) Sleep(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func (s *testNamespaceSuite) TestNamespaceMigrationLimit(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	// The regions are 10MB each.
+	nsCfg := &config.NamespaceConfig{MaxMigrationBytesPerSec: 20 << 20}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns1").GetMaxMigrationBytesPerSec(), Equals, uint64(20<<20))
+
+	hbStreams := mockhbstream.NewHeartbeatStreams(s.tc.getClusterID())
+	oc := schedule.NewOperatorController(s.ctx, s.tc.RaftCluster, hbStreams)
+	oc.SetNamespaceClassifier(s.classifier)
+	clock := &mockClock{now: time.Now()}
+	oc.SetMigrationClock(clock)
+	newMoveOp := func(regionID uint64) *operator.Operator {
+		region := s.tc.GetRegion(regionID)
+		return newTestOperator(regionID, region.GetRegionEpoch(), operator.OpRegion,
+			operator.AddLearner{ToStore: 3, PeerID: 100 + regionID},
+			operator.PromoteLearner{ToStore: 3, PeerID: 100 + regionID},
+			operator.RemovePeer{FromStore: 2})
+	}
+
+	c.Assert(oc.AddOperator(newMoveOp(1)), IsTrue)
+	c.Assert(oc.AddOperator(newMoveOp(2)), IsTrue)
+	c.Assert(oc.GetMigratingBytes("ns1"), Equals, int64(20<<20))
+	// The move operator is delayed when the budget is used up.
+	c.Assert(oc.AddOperator(newMoveOp(3)), IsFalse)
+	c.Assert(oc.AddWaitingOperator(newMoveOp(3)), IsTrue)
+	c.Assert(oc.GetOperator(3), IsNil)
+	// Operators moving no data are not limited.
+	region := s.tc.GetRegion(4)
+	c.Assert(oc.AddOperator(newTestOperator(4, region.GetRegionEpoch(), operator.OpLeader, operator.TransferLeader{FromStore: 1, ToStore: 2})), IsTrue)
+	c.Assert(oc.GetMigratingBytes("ns1"), Equals, int64(20<<20))
+
+	// The delayed operator is promoted once the budget is refilled.
+	oc.PromoteWaitingOperator()
+	c.Assert(oc.GetOperator(3), IsNil)
+	clock.Sleep(500 * time.Millisecond)
+	oc.PromoteWaitingOperator()
+	c.Assert(oc.GetOperator(3), NotNil)
+	c.Assert(oc.GetMigratingBytes("ns1"), Equals, int64(30<<20))
+	c.Assert(oc.RemoveOperator(oc.GetOperator(1)), IsTrue)
+	c.Assert(oc.GetMigratingBytes("ns1"), Equals, int64(20<<20))
+	c.Assert(oc.GetMigratingBytes("ns2"), Equals, int64(0))
+}
+
+func (s *testNamespaceSuite) TestCancelOperatorsForRegion(c *C) {
+	c.Assert(s.tc.addLeaderStore(1, 0), IsNil)
+	c.Assert(s.tc.addLeaderStore(2, 0), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	nsCfg := &config.NamespaceConfig{MaxConcurrentOperators: 1}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+
+	hbStreams := mockhbstream.NewHeartbeatStreams(s.tc.getClusterID())
+	oc := schedule.NewOperatorController(s.ctx, s.tc.RaftCluster, hbStreams)
+	oc.SetNamespaceClassifier(s.classifier)
+	newOp := func(regionID uint64, kind operator.OpKind) *operator.Operator {
+		region := s.tc.GetRegion(regionID)
+		return newTestOperator(regionID, region.GetRegionEpoch(), kind, operator.TransferLeader{FromStore: 1, ToStore: 2})
+	}
+	c.Assert(oc.AddOperator(newOp(1, operator.OpLeader)), IsTrue)
+	// The operator of region 2 is queued for the namespace.
+	c.Assert(oc.AddWaitingOperator(newOp(2, operator.OpLeader)), IsTrue)
+	c.Assert(oc.GetOperator(2), IsNil)
+
+	c.Assert(oc.CancelOperatorsForRegion(3), Equals, 0)
+	c.Assert(oc.CancelOperatorsForRegion(2), Equals, 1)
+	c.Assert(oc.GetOperatorStatus(2).Status, Equals, pdpb.OperatorStatus_CANCEL)
+	c.Assert(oc.CancelOperatorsForRegion(1), Equals, 1)
+	c.Assert(oc.GetOperator(1), IsNil)
+	c.Assert(oc.GetOperatorStatus(1).Status, Equals, pdpb.OperatorStatus_CANCEL)
+	// The queued operator is not promoted after being canceled.
+	oc.PromoteWaitingOperator()
+	c.Assert(oc.GetOperators(), HasLen, 0)
+
+	// The manual move replaces the operator of schedulers at once.
+	c.Assert(oc.AddOperator(newOp(3, operator.OpLeader)), IsTrue)
+	adminOp := newOp(3, operator.OpAdmin|operator.OpLeader)
+	c.Assert(oc.CancelAndAddOperator(adminOp), IsTrue)
+	c.Assert(oc.GetOperator(3), Equals, adminOp)
+	c.Assert(oc.GetOperators(), HasLen, 1)
+	// The admin operator is not replaced by another one of the same priority.
+	c.Assert(oc.CancelAndAddOperator(newOp(3, operator.OpAdmin|operator.OpLeader)), IsFalse)
+	c.Assert(oc.GetOperator(3), Equals, adminOp)
+	oc.RemoveOperator(adminOp)
+
+	// Admin operators are kept.
+	c.Assert(oc.AddOperator(newOp(1, operator.OpAdmin|operator.OpLeader)), IsTrue)
+	c.Assert(oc.CancelOperatorsForRegion(1), Equals, 0)
+	c.Assert(oc.GetOperator(1), NotNil)
+}
+
+func (s *testNamespaceSuite) TestDetectLabelDrift(c *C) {
+	s.opt.GetReplication().Load().LocationLabels = []string{"rack", "host"}
+	c.Assert(s.tc.addLabelsStore(1, 0, map[string]string{"rack": "r1", "host": "h1"}), IsNil)
+	c.Assert(s.tc.addLabelsStore(2, 0, map[string]string{"rack": "r2", "host": "h2"}), IsNil)
+	c.Assert(s.tc.addLabelsStore(3, 0, map[string]string{"rack": "r3", "host": "h3"}), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+	s.classifier.setStore(3, "ns2")
+
+	// The baseline is recorded on the first check.
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns1").DetectLabelDrift(), HasLen, 0)
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns2").DetectLabelDrift(), HasLen, 0)
+
+	// Store 2 is moved to the rack of store 1.
+	c.Assert(s.tc.addLabelsStore(2, 0, map[string]string{"rack": "r1", "host": "h2"}), IsNil)
+	// Labels which are not location labels are ignored.
+	c.Assert(s.tc.addLabelsStore(1, 0, map[string]string{"rack": "r1", "host": "h1", "disk": "ssd"}), IsNil)
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns1").DetectLabelDrift(), DeepEquals, []uint64{2})
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns2").DetectLabelDrift(), HasLen, 0)
+
+	// Drift is reported until the labels are restored.
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns1").DetectLabelDrift(), DeepEquals, []uint64{2})
+	c.Assert(s.tc.addLabelsStore(2, 0, map[string]string{"rack": "r2", "host": "h2"}), IsNil)
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns1").DetectLabelDrift(), HasLen, 0)
+}
+
+func (s *testNamespaceSuite) TestSpareStore(c *C) {
+	// store regionCount namespace
+	//     1           0       ns1
+	//     2         100       ns1
+	//     3          50       ns1
+	//     4           0       ns1 (spare)
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 100), IsNil)
+	c.Assert(s.tc.addRegionStore(3, 50), IsNil)
+	c.Assert(s.tc.addRegionStore(4, 0), IsNil)
+	for id := uint64(1); id <= 4; id++ {
+		s.classifier.setStore(id, "ns1")
+	}
+	nsCfg := &config.NamespaceConfig{MaxReplicas: 1, SpareStores: []uint64{4}}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns1").GetSpareStores(), DeepEquals, []uint64{4})
+
+	// Balance operators never target the spare store.
+	c.Assert(s.tc.addLeaderRegion(1, 2), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, _ := schedule.CreateScheduler("balance-region", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+	for i := 0; i < 10; i++ {
+		op := scheduleByNamespace(s.tc, s.classifier, sched)
+		c.Assert(op, HasLen, 1)
+		testutil.CheckTransferPeer(c, op[0], operator.OpBalance, 2, 1)
+	}
+
+	// Repairs prefer other stores to the spare store.
+	nsCfg.MaxReplicas = 3
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2), IsNil)
+	rc := checker.NewReplicaChecker(s.tc, s.classifier)
+	testutil.CheckAddPeer(c, rc.Check(s.tc.GetRegion(1)), operator.OpReplica, 3)
+
+	// The spare store is used if there is no other choice.
+	s.classifier.setStore(3, "ns2")
+	testutil.CheckAddPeer(c, rc.Check(s.tc.GetRegion(1)), operator.OpReplica, 4)
+}
+
+func (s *testNamespaceSuite) TestBalanceNamespaceFlow(c *C) {
+	// store regionCount flow namespace
+	//     1           3  1800      ns1
+	//     2           3    30      ns1
+	//     3           3    30      ns1
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 3), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	nsCfg := &config.NamespaceConfig{MaxReplicas: 1}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	putRegionWithFlow := func(regionID, storeID, flow uint64) {
+		c.Assert(s.tc.addLeaderRegion(regionID, storeID), IsNil)
+		c.Assert(s.tc.putRegion(s.tc.GetRegion(regionID).Clone(core.SetWrittenBytes(flow))), IsNil)
+		s.classifier.setRegion(regionID, "ns1")
+	}
+	for i := uint64(1); i <= 3; i++ {
+		putRegionWithFlow(i, 1, 600)
+		putRegionWithFlow(i+3, 2, 10)
+		putRegionWithFlow(i+6, 3, 10)
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetStoreFlowStats(), DeepEquals, map[uint64]uint64{1: 1800, 2: 30, 3: 30})
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	storage := core.NewStorage(kv.NewMemoryKV())
+	brs, err := schedule.CreateScheduler("balance-region", oc, storage, nil)
+	c.Assert(err, IsNil)
+	bfs, err := schedule.CreateScheduler("balance-namespace-flow", oc, storage, nil)
+	c.Assert(err, IsNil)
+	c.Assert(bfs.IsScheduleAllowed(nc), IsTrue)
+	// The size is balanced, but the flow is not.
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, brs), IsNil)
+	ops := scheduleByNamespace(s.tc, s.classifier, bfs)
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].Kind()&operator.OpBalance, Equals, operator.OpBalance)
+	c.Assert(ops[0].Step(0).(operator.AddLearner).ToStore, Not(Equals), uint64(1))
+	c.Assert(ops[0].Step(ops[0].Len()-1).(operator.RemovePeer).FromStore, Equals, uint64(1))
+
+	// Stores with flow in the tolerance are balanced.
+	for i := uint64(1); i <= 3; i++ {
+		putRegionWithFlow(i, 1, 105)
+		putRegionWithFlow(i+3, 2, 100)
+		putRegionWithFlow(i+6, 3, 100)
+	}
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, bfs), IsNil)
+
+	// Regions are not moved if moving them makes the target store hotter
+	// than the source store.
+	for i := uint64(1); i <= 3; i++ {
+		putRegionWithFlow(i, 1, 200)
+	}
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, bfs), IsNil)
+}
+
+func (s *testNamespaceSuite) TestStoreThermalScore(c *C) {
+	// store regionSize flow namespace
+	//     1         90    0      ns1
+	//     2         30  300      ns1
+	//     3         30    0      ns1
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 3), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	putRegion := func(regionID, storeID uint64, size int64, flow uint64) {
+		c.Assert(s.tc.addLeaderRegion(regionID, storeID), IsNil)
+		c.Assert(s.tc.putRegion(s.tc.GetRegion(regionID).Clone(core.SetApproximateSize(size), core.SetWrittenBytes(flow))), IsNil)
+		s.classifier.setRegion(regionID, "ns1")
+	}
+	for i := uint64(1); i <= 3; i++ {
+		putRegion(i, 1, 30, 0)
+		putRegion(i+3, 2, 10, 100)
+		putRegion(i+6, 3, 10, 0)
+	}
+	setWeights := func(size, flow float64) *namespaceCluster {
+		nsCfg := &config.NamespaceConfig{MaxReplicas: 1, ThermalSizeWeight: size, ThermalFlowWeight: flow}
+		nsCfg.Adjust(s.opt)
+		s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+		return newNamespaceCluster(s.tc, s.classifier, "ns1")
+	}
+
+	// Store 1 is the hottest by size only.
+	nc := setWeights(1, 0)
+	c.Assert(nc.GetStoreThermalScore(1), Greater, nc.GetStoreThermalScore(2))
+	c.Assert(nc.GetStoreThermalScore(2), Equals, nc.GetStoreThermalScore(3))
+	// Store 2 is the hottest if the flow dominates.
+	nc = setWeights(1, 4)
+	c.Assert(nc.GetStoreThermalScore(2), Greater, nc.GetStoreThermalScore(1))
+	c.Assert(nc.GetStoreThermalScore(1), Greater, nc.GetStoreThermalScore(3))
+	c.Assert(math.Abs(nc.GetStoreThermalScore(2)-12.6), Less, 1e-9)
+	c.Assert(nc.GetStoreThermalScore(4), Equals, 0.0)
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	bts, err := schedule.CreateScheduler("balance-namespace-thermal", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+	c.Assert(err, IsNil)
+	ops := scheduleByNamespace(s.tc, s.classifier, bts)
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].Kind()&operator.OpBalance, Equals, operator.OpBalance)
+	c.Assert(ops[0].Step(0).(operator.AddLearner).ToStore, Equals, uint64(3))
+	c.Assert(ops[0].Step(ops[0].Len()-1).(operator.RemovePeer).FromStore, Equals, uint64(2))
+}
+
+func (s *testNamespaceSuite) TestEstimateOperatorTimeout(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	s.classifier.setStore(1, "ns1")
+	c.Assert(s.tc.addLeaderRegion(1, 1), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	small := s.tc.GetRegion(1).Clone(core.SetApproximateSize(10))
+	large := s.tc.GetRegion(1).Clone(core.SetApproximateSize(100))
+	addLearner := operator.AddLearner{ToStore: 2, PeerID: 100}
+
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetSnapshotSpeed(), Equals, defaultSnapshotSpeed)
+	c.Assert(nc.EstimateOperatorTimeout(large, addLearner) > nc.EstimateOperatorTimeout(small, addLearner), IsTrue)
+	// Steps without snapshots do not depend on the region size.
+	transferLeader := operator.TransferLeader{FromStore: 1, ToStore: 2}
+	c.Assert(nc.EstimateOperatorTimeout(small, transferLeader), Equals, operator.LeaderOperatorWaitTime)
+	c.Assert(nc.EstimateOperatorTimeout(large, transferLeader), Equals, operator.LeaderOperatorWaitTime)
+
+	// 100MB at 50MB/s takes 2s, which is doubled for fluctuation.
+	nsCfg := &config.NamespaceConfig{SnapshotSpeed: 50}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	c.Assert(nc.GetSnapshotSpeed(), Equals, 50.0)
+	c.Assert(nc.EstimateOperatorTimeout(large, addLearner), Equals, operator.LeaderOperatorWaitTime+4*time.Second)
+}
+
+func (s *testNamespaceSuite) TestStoreWeightAutoTuning(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 0), IsNil)
+	// Store 2 is twice as large as store 1.
+	stats := &pdpb.StoreStats{Capacity: 2000 * (1 << 20), Available: 2000 * (1 << 20)}
+	c.Assert(s.tc.updateStore(2, core.SetStoreStats(stats)), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+
+	// The change of weights is bounded in each round.
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.TuneStoreWeights(), DeepEquals, map[uint64]float64{1: 1 - maxStoreWeightStep, 2: 1 + maxStoreWeightStep})
+	for i := 0; i < 10; i++ {
+		nc.TuneStoreWeights()
+	}
+	weights := nc.TuneStoreWeights()
+	c.Assert(math.Abs(weights[1]-2.0/3), Less, 1e-6)
+	c.Assert(math.Abs(weights[2]-4.0/3), Less, 1e-6)
+
+	// The tuned weights are used only if auto tuning is enabled.
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetStore(2).GetRegionWeight(), Equals, 1.0)
+	nsCfg := &config.NamespaceConfig{EnableStoreWeightAutoTuning: true}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.IsStoreWeightAutoTuningEnabled(), IsTrue)
+	c.Assert(nc.GetStore(1).GetRegionWeight(), Equals, weights[1])
+	c.Assert(nc.GetStore(2).GetRegionWeight(), Equals, weights[2])
+}
+
+func (s *testNamespaceSuite) TestStoreWeightAutoTuningRound(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 0), IsNil)
+	// Store 2 is twice as large as store 1.
+	stats := &pdpb.StoreStats{Capacity: 2000 * (1 << 20), Available: 2000 * (1 << 20)}
+	c.Assert(s.tc.updateStore(2, core.SetStoreStats(stats)), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+	c.Assert(s.tc.addLeaderRegion(1, 1), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	nsCfg := &config.NamespaceConfig{EnableStoreWeightAutoTuning: true}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+
+	// Several schedulers retry within a round, while the weights are tuned
+	// only once.
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	for _, typ := range []string{"balance-region", "balance-leader", "hot-region"} {
+		sched, err := schedule.CreateScheduler(typ, oc, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(typ, nil))
+		c.Assert(err, IsNil)
+		for i := 0; i < maxScheduleRetries; i++ {
+			scheduleByNamespace(s.tc, s.classifier, sched)
+		}
+	}
+	s.tc.tuneNamespaceStoreWeights(s.classifier)
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetStore(1).GetRegionWeight(), Equals, 1-maxStoreWeightStep)
+	c.Assert(nc.GetStore(2).GetRegionWeight(), Equals, 1+maxStoreWeightStep)
+
+	// The paused namespace is not tuned.
+	nsCfg = &config.NamespaceConfig{
+		EnableStoreWeightAutoTuning: true,
+		SchedulePauseWindows: []typeutil.TimeWindow{{
+			Start: time.Now().Add(-time.Hour).Format("15:04"),
+			End:   time.Now().Add(time.Hour).Format("15:04"),
+		}},
+	}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	s.tc.tuneNamespaceStoreWeights(s.classifier)
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetStore(2).GetRegionWeight(), Equals, 1+maxStoreWeightStep)
+}
+
+func (s *testNamespaceSuite) TestRuleViolatingRegions(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 1, 2, 3), IsNil)
+	region := s.tc.GetRegion(2)
+	learner := &metapb.Peer{Id: 100, StoreId: 4, IsLearner: true}
+	s.tc.putRegion(region.Clone(core.WithAddPeer(learner)))
+	s.classifier.setRegion(1, "ns1")
+	s.classifier.setRegion(2, "ns1")
+
+	// No rules, no violation.
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetRuleViolatingRegions(), HasLen, 0)
+
+	nsCfg := &config.NamespaceConfig{
+		PlacementRules: []*placement.Rule{
+			{GroupID: "pd", ID: "voters", Role: placement.Voter, Count: 3},
+			{GroupID: "pd", ID: "learner", Index: 1, Role: placement.Learner, Count: 1},
+		},
+	}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	regions := nc.GetRuleViolatingRegions()
+	c.Assert(regions, HasLen, 1)
+	c.Assert(regions[0].GetID(), Equals, uint64(1))
+}
+
+func (s *testNamespaceSuite) TestDrainToStores(c *C) {
+	for id := uint64(1); id <= 6; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 2, 3, 4), IsNil)
+	c.Assert(s.tc.addLeaderRegion(3, 4, 5, 6), IsNil)
+	for id := uint64(1); id <= 3; id++ {
+		s.classifier.setRegion(id, "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	hbStreams := mockhbstream.NewHeartbeatStreams(s.tc.getClusterID())
+	oc := schedule.NewOperatorController(s.ctx, s.tc.RaftCluster, hbStreams)
+
+	// Store 7 is not in the namespace.
+	ops := nc.DrainToStores(oc, []uint64{7})
+	c.Assert(ops, HasLen, 0)
+
+	// The plan is kept within the region schedule limit.
+	nsCfg := &config.NamespaceConfig{RegionScheduleLimit: 1}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	ops = nc.DrainToStores(oc, []uint64{4, 5, 6})
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].RegionID(), Equals, uint64(1))
+	// The regions with running operators are skipped.
+	c.Assert(oc.AddOperator(ops[0]), IsTrue)
+	c.Assert(nc.DrainToStores(oc, []uint64{4, 5, 6}), HasLen, 0)
+	nsCfg.RegionScheduleLimit = 2
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	ops = nc.DrainToStores(oc, []uint64{4, 5, 6})
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].RegionID(), Equals, uint64(2))
+	c.Assert(oc.RemoveOperator(oc.GetOperator(1)), IsTrue)
+
+	ops = nc.DrainToStores(oc, []uint64{4, 5, 6})
+	// Region 3 is already on the targets.
+	c.Assert(ops, HasLen, 2)
+	for i, op := range ops {
+		c.Assert(op.RegionID(), Equals, uint64(i+1))
+		region := s.tc.GetRegion(op.RegionID())
+		for !op.IsFinish() {
+			region = schedule.ApplyOperatorStep(region, op)
+		}
+		c.Assert(region.GetStoreIds(), DeepEquals, map[uint64]struct{}{4: {}, 5: {}, 6: {}})
+	}
+}
+
+func (s *testNamespaceSuite) TestCanMerge(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addRegionStore(4, 0), IsNil)
+	s.classifier.setStore(4, "ns2")
+	newTestRegion := func(id uint64, start, end []byte, stores ...uint64) *core.RegionInfo {
+		var peers []*metapb.Peer
+		for _, storeID := range stores {
+			peers = append(peers, &metapb.Peer{Id: id*10 + storeID, StoreId: storeID})
+		}
+		meta := &metapb.Region{Id: id, StartKey: start, EndKey: end, Peers: peers}
+		return core.NewRegionInfo(meta, peers[0])
+	}
+	regions := []*core.RegionInfo{
+		newTestRegion(1, []byte(""), []byte("a"), 1, 2, 3),
+		newTestRegion(2, []byte("a"), []byte("b"), 1, 2, 3),
+		newTestRegion(3, []byte("b"), []byte("c"), 1, 2),
+		newTestRegion(4, []byte("c"), []byte("d"), 1, 2, 4),
+	}
+	for _, r := range regions {
+		s.tc.putRegion(r)
+		s.classifier.setRegion(r.GetID(), "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+
+	ok, reason := nc.CanMerge(regions[0], regions[1])
+	c.Assert(ok, IsTrue)
+	c.Assert(reason, Equals, "")
+	ok, reason = nc.CanMerge(regions[0], regions[2])
+	c.Assert(ok, IsFalse)
+	c.Assert(reason, Equals, "region 1 and 3 are not adjacent")
+	ok, reason = nc.CanMerge(regions[1], regions[2])
+	c.Assert(ok, IsFalse)
+	c.Assert(reason, Equals, "region 2 has 3 voters and 0 learners but region 3 has 2 voters and 0 learners")
+	ok, reason = nc.CanMerge(regions[2], regions[3])
+	c.Assert(ok, IsFalse)
+	c.Assert(reason, Equals, "region 4 is not in namespace ns1")
+
+	// The merged region must fit the placement rules.
+	c.Assert(s.tc.addLabelsStore(5, 0, map[string]string{"zone": "z2"}), IsNil)
+	s.classifier.setStore(5, "ns1")
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	source, target := newTestRegion(5, []byte("d"), []byte("e"), 1, 2, 3), newTestRegion(6, []byte("e"), []byte("f"), 1, 2, 5)
+	s.classifier.setRegion(5, "ns1")
+	s.classifier.setRegion(6, "ns1")
+	nsCfg := &config.NamespaceConfig{
+		PlacementRules: []*placement.Rule{
+			{GroupID: "pd", ID: "default", Role: placement.Voter, Count: 3,
+				LabelConstraints: []placement.LabelConstraint{{Key: "zone", Op: placement.NotIn, Values: []string{"z2"}}}},
+		},
+	}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	ok, _ = nc.CanMerge(target, source)
+	c.Assert(ok, IsTrue)
+	ok, reason = nc.CanMerge(source, target)
+	c.Assert(ok, IsFalse)
+	c.Assert(reason, Equals, "merged region of 5 and 6 does not fit placement rules")
+}
+
+func (s *testNamespaceSuite) TestMergeWithReadReplica(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	nsCfg := &config.NamespaceConfig{ReadReplicaCount: 1, ReadReplicaStores: []uint64{4}}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	newTestRegion := func(id uint64, start, end []byte, size int64) *core.RegionInfo {
+		var peers []*metapb.Peer
+		for storeID := uint64(1); storeID <= 4; storeID++ {
+			peers = append(peers, &metapb.Peer{Id: id*10 + storeID, StoreId: storeID, IsLearner: storeID == 4})
+		}
+		meta := &metapb.Region{Id: id, StartKey: start, EndKey: end, Peers: peers}
+		return core.NewRegionInfo(meta, peers[0], core.SetApproximateSize(size), core.SetApproximateKeys(size))
+	}
+	regions := []*core.RegionInfo{
+		newTestRegion(1, []byte(""), []byte("a"), 18),
+		newTestRegion(2, []byte("a"), []byte("b"), 10),
+		newTestRegion(3, []byte("b"), []byte(""), 15),
+	}
+	for _, r := range regions {
+		c.Assert(s.tc.putRegion(r), IsNil)
+		s.classifier.setRegion(r.GetID(), "ns1")
+	}
+	s.opt.SetSplitMergeInterval(0)
+	mc := checker.NewMergeChecker(s.ctx, s.tc, s.classifier)
+
+	// The regions with read replicas on the same stores are merged directly.
+	ops := mc.Check(s.tc.GetRegion(2))
+	c.Assert(ops, HasLen, 2)
+	c.Assert(ops[0].Len(), Equals, 1)
+	c.Assert(ops[1].RegionID(), Equals, uint64(3))
+
+	// The region without the read replica cannot be the target.
+	c.Assert(s.tc.putRegion(s.tc.GetRegion(3).Clone(core.WithRemoveStorePeer(4))), IsNil)
+	ops = mc.Check(s.tc.GetRegion(2))
+	c.Assert(ops, HasLen, 2)
+	c.Assert(ops[1].RegionID(), Equals, uint64(1))
+	c.Assert(s.tc.putRegion(s.tc.GetRegion(1).Clone(core.WithRemoveStorePeer(4))), IsNil)
+	c.Assert(mc.Check(s.tc.GetRegion(2)), IsNil)
+
+	// Other learners are not merged.
+	nsCfg.ReadReplicaStores = nil
+	c.Assert(mc.Check(s.tc.GetRegion(2)), IsNil)
+}
+
+func (s *testNamespaceSuite) TestMergeDirectionPreference(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	newTestRegion := func(id uint64, start, end []byte, size int64) *core.RegionInfo {
+		var peers []*metapb.Peer
+		for storeID := uint64(1); storeID <= 3; storeID++ {
+			peers = append(peers, &metapb.Peer{Id: id*10 + storeID, StoreId: storeID})
+		}
+		meta := &metapb.Region{Id: id, StartKey: start, EndKey: end, Peers: peers}
+		return core.NewRegionInfo(meta, peers[0], core.SetApproximateSize(size), core.SetApproximateKeys(size))
+	}
+	// The next region is smaller, so it is the natural merge target.
+	regions := []*core.RegionInfo{
+		newTestRegion(1, []byte(""), []byte("a"), 18),
+		newTestRegion(2, []byte("a"), []byte("b"), 10),
+		newTestRegion(3, []byte("b"), []byte(""), 15),
+	}
+	for _, r := range regions {
+		c.Assert(s.tc.putRegion(r), IsNil)
+		s.classifier.setRegion(r.GetID(), "ns1")
+	}
+	s.opt.SetSplitMergeInterval(0)
+	mc := checker.NewMergeChecker(s.ctx, s.tc, s.classifier)
+	ops := mc.Check(s.tc.GetRegion(2))
+	c.Assert(ops, HasLen, 2)
+	c.Assert(ops[1].RegionID(), Equals, uint64(3))
+
+	// The region is merged into the left neighbor if it is preferred.
+	nsCfg := &config.NamespaceConfig{MergeDirection: "left"}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns1").GetMergeDirectionPreference(), Equals, "left")
+	ops = mc.Check(s.tc.GetRegion(2))
+	c.Assert(ops, HasLen, 2)
+	c.Assert(ops[1].RegionID(), Equals, uint64(1))
+	// The next region is the only target of the leftmost region.
+	ops = mc.Check(s.tc.GetRegion(1))
+	c.Assert(ops, HasLen, 2)
+	c.Assert(ops[1].RegionID(), Equals, uint64(2))
+
+	// The right neighbor is preferred even if it is larger.
+	c.Assert(s.tc.putRegion(s.tc.GetRegion(1).Clone(core.SetApproximateSize(5), core.SetApproximateKeys(5))), IsNil)
+	nsCfg.MergeDirection = "right"
+	ops = mc.Check(s.tc.GetRegion(2))
+	c.Assert(ops, HasLen, 2)
+	c.Assert(ops[1].RegionID(), Equals, uint64(3))
+	// The previous region is picked if the next one cannot be the target.
+	ops = mc.Check(s.tc.GetRegion(3))
+	c.Assert(ops, HasLen, 2)
+	c.Assert(ops[1].RegionID(), Equals, uint64(2))
+}
+
+func (s *testNamespaceSuite) TestMergeCapacity(c *C) {
+	for id := uint64(1); id <= 5; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	newTestRegion := func(id uint64, start, end []byte, size int64, stores ...uint64) *core.RegionInfo {
+		var peers []*metapb.Peer
+		for _, storeID := range stores {
+			peers = append(peers, &metapb.Peer{Id: id*10 + storeID, StoreId: storeID})
+		}
+		meta := &metapb.Region{Id: id, StartKey: start, EndKey: end, Peers: peers}
+		return core.NewRegionInfo(meta, peers[0], core.SetApproximateSize(size), core.SetApproximateKeys(size))
+	}
+	// The previous region is smaller, so it is the natural merge target.
+	regions := []*core.RegionInfo{
+		newTestRegion(1, []byte(""), []byte("a"), 15, 1, 2, 4),
+		newTestRegion(2, []byte("a"), []byte("b"), 10, 1, 2, 3),
+		newTestRegion(3, []byte("b"), []byte(""), 18, 1, 2, 5),
+	}
+	for _, r := range regions {
+		c.Assert(s.tc.putRegion(r), IsNil)
+		s.classifier.setRegion(r.GetID(), "ns1")
+	}
+	s.opt.SetSplitMergeInterval(0)
+	mc := checker.NewMergeChecker(s.ctx, s.tc, s.classifier)
+	ops := mc.Check(s.tc.GetRegion(2))
+	c.Assert(ops, HasLen, 2)
+	c.Assert(ops[1].RegionID(), Equals, uint64(1))
+
+	// Store 4 cannot hold the region once most of its capacity is reserved,
+	// so the region is merged into the next one.
+	nsCfg := &config.NamespaceConfig{StoreReservedCapacities: map[uint64]uint64{4: 995 << 20}}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	ops = mc.Check(s.tc.GetRegion(2))
+	c.Assert(ops, HasLen, 2)
+	c.Assert(ops[1].RegionID(), Equals, uint64(3))
+	for i := 0; i < ops[0].Len(); i++ {
+		if step, ok := ops[0].Step(i).(operator.AddLearner); ok {
+			c.Assert(step.ToStore, Equals, uint64(5))
+		}
+	}
+
+	// The merge is aborted if no adjacent region can hold it.
+	c.Assert(s.tc.updateStore(5, core.SetStoreStats(&pdpb.StoreStats{Capacity: 1000 << 20, Available: 5 << 20})), IsNil)
+	c.Assert(mc.Check(s.tc.GetRegion(2)), IsNil)
+}
+
+func (s *testNamespaceSuite) TestEstimateConvergenceTicks(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, id), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.EstimateConvergenceTicks(), Equals, 0)
+
+	// Store 1 has 11 regions and leaders, the others have 2 each, so 6 of
+	// them should be moved out of store 1.
+	for id := uint64(4); id <= 12; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	for id := uint64(13); id <= 14; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, id-11), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	nsCfg := &config.NamespaceConfig{LeaderScheduleLimit: 4, RegionScheduleLimit: 2}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	c.Assert(nc.EstimateConvergenceTicks(), Equals, 3)
+
+	nsCfg.RegionScheduleLimit = 0
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	c.Assert(nc.EstimateConvergenceTicks(), Equals, -1)
+}
+
+func (s *testNamespaceSuite) TestStoreBlacklist(c *C) {
+	// store regionCount namespace
+	//     1           0       ns1 (blacklisted)
+	//     2         100       ns1
+	//     3          50       ns1
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 100), IsNil)
+	c.Assert(s.tc.addRegionStore(3, 50), IsNil)
+	for id := uint64(1); id <= 3; id++ {
+		s.classifier.setStore(id, "ns1")
+	}
+	nsCfg := &config.NamespaceConfig{MaxReplicas: 1}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	c.Assert(s.tc.addLeaderRegion(1, 2), IsNil)
+	s.classifier.setRegion(1, "ns1")
+
+	c.Assert(s.tc.SetNamespaceStoreBlacklist("ns1", []uint64{1}), IsNil)
+	c.Assert(s.tc.GetNamespaceStoreBlacklist("ns1"), DeepEquals, []uint64{1})
+	c.Assert(s.tc.GetNamespaceStoreBlacklist("ns2"), HasLen, 0)
+
+	// The blacklist is restored after restart.
+	cluster := createTestRaftCluster(mockid.NewIDAllocator(), s.opt, s.tc.storage)
+	c.Assert(cluster.GetNamespaceStoreBlacklist("ns1"), HasLen, 0)
+	c.Assert(cluster.loadNamespaceStoreBlacklists(), IsNil)
+	c.Assert(cluster.GetNamespaceStoreBlacklist("ns1"), DeepEquals, []uint64{1})
+
+	// The blacklisted store is not used as target.
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, _ := schedule.CreateScheduler("balance-region", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+	for i := 0; i < 10; i++ {
+		op := scheduleByNamespace(s.tc, s.classifier, sched)
+		c.Assert(op, HasLen, 1)
+		testutil.CheckTransferPeer(c, op[0], operator.OpBalance, 2, 3)
+	}
+}
+
+func (s *testNamespaceSuite) TestStoreMaintenanceWindows(c *C) {
+	// store regionCount namespace
+	//     1           0       ns1 (under maintenance)
+	//     2         100       ns1
+	//     3          50       ns1
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 100), IsNil)
+	c.Assert(s.tc.addRegionStore(3, 50), IsNil)
+	for id := uint64(1); id <= 3; id++ {
+		s.classifier.setStore(id, "ns1")
+	}
+	now := time.Now()
+	window := func(from, to time.Duration) typeutil.TimeWindow {
+		return typeutil.TimeWindow{Start: now.Add(from).Format("15:04"), End: now.Add(to).Format("15:04")}
+	}
+	setWindows := func(windows ...typeutil.TimeWindow) {
+		nsCfg := &config.NamespaceConfig{MaxReplicas: 1, StoreMaintenanceWindows: map[uint64][]typeutil.TimeWindow{1: windows}}
+		nsCfg.Adjust(s.opt)
+		s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	}
+	c.Assert(s.tc.addLeaderRegion(1, 2), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, _ := schedule.CreateScheduler("balance-region", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+
+	// The store is avoided in its maintenance window.
+	setWindows(window(2*time.Hour, 3*time.Hour), window(-time.Hour, time.Hour))
+	c.Assert(nc.GetStoreMaintenanceWindows(1), HasLen, 2)
+	c.Assert(nc.GetBlacklistedStores(), DeepEquals, []uint64{1})
+	for i := 0; i < 10; i++ {
+		op := scheduleByNamespace(s.tc, s.classifier, sched)
+		c.Assert(op, HasLen, 1)
+		testutil.CheckTransferPeer(c, op[0], operator.OpBalance, 2, 3)
+	}
+
+	// The store is eligible outside its maintenance window.
+	setWindows(window(2*time.Hour, 3*time.Hour))
+	c.Assert(nc.GetBlacklistedStores(), HasLen, 0)
+	op := scheduleByNamespace(s.tc, s.classifier, sched)
+	c.Assert(op, HasLen, 1)
+	testutil.CheckTransferPeer(c, op[0], operator.OpBalance, 2, 1)
+}
+
+func (s *testNamespaceSuite) TestSuggestLeader(c *C) {
+	// store leaderCount namespace
+	//     1          30       ns1
+	//     2          10       ns1
+	//     3          20       ns1
+	//     4           0       ns2
+	c.Assert(s.tc.addLeaderStore(1, 30), IsNil)
+	c.Assert(s.tc.addLeaderStore(2, 10), IsNil)
+	c.Assert(s.tc.addLeaderStore(3, 20), IsNil)
+	c.Assert(s.tc.addLeaderStore(4, 0), IsNil)
+	for id := uint64(1); id <= 3; id++ {
+		s.classifier.setStore(id, "ns1")
+	}
+	s.classifier.setStore(4, "ns2")
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3, 4), IsNil)
+	region := s.tc.GetRegion(1)
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.SuggestLeader(region).GetStoreId(), Equals, uint64(2))
+
+	// Pending peers are skipped.
+	region = region.Clone(core.WithPendingPeers([]*metapb.Peer{region.GetStorePeer(2)}))
+	c.Assert(nc.SuggestLeader(region).GetStoreId(), Equals, uint64(3))
+
+	// Stores which are not up are skipped.
+	s.tc.setStoreOffline(3)
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.SuggestLeader(region).GetStoreId(), Equals, uint64(1))
+	s.tc.setStoreOffline(1)
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.SuggestLeader(region), IsNil)
+}
+
+func (s *testNamespaceSuite) TestHotSplit(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	s.classifier.setStore(1, "ns1")
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1), IsNil)
+		c.Assert(s.tc.putRegion(s.tc.GetRegion(id).Clone(core.SetWrittenBytes(100), core.SetReadBytes(100))), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.CreateHotSplitOperator(1000), IsNil)
+
+	// Region 2 is 3.5 times hotter than the threshold, it is split into 4 parts.
+	c.Assert(s.tc.putRegion(s.tc.GetRegion(2).Clone(core.SetWrittenBytes(2500), core.SetReadBytes(1000))), IsNil)
+	op := nc.CreateHotSplitOperator(1000)
+	c.Assert(op, NotNil)
+	c.Assert(op.RegionID(), Equals, uint64(2))
+	c.Assert(op.Kind()&operator.OpHotRegion, Equals, operator.OpHotRegion)
+	split, ok := op.Step(0).(operator.SplitRegion)
+	c.Assert(ok, IsTrue)
+	c.Assert(split.Policy, Equals, pdpb.CheckPolicy_USEKEY)
+	c.Assert(split.SplitKeys, HasLen, 3)
+	region := s.tc.GetRegion(2)
+	for _, key := range split.SplitKeys {
+		c.Assert(bytes.Compare(key, region.GetStartKey()), Equals, 1)
+		c.Assert(bytes.Compare(key, region.GetEndKey()), Equals, -1)
+	}
+}
+
+func (s *testNamespaceSuite) TestPeerDistributionEntropy(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetPeerDistributionEntropy(), Equals, 0.0)
+
+	// Peers are spread evenly, the entropy is log2(4).
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, id), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	even := nc.GetPeerDistributionEntropy()
+	c.Assert(math.Abs(even-2), Less, 1e-9)
+
+	// Most peers are on store 1.
+	for id := uint64(2); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1), IsNil)
+	}
+	for id := uint64(5); id <= 10; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	concentrated := nc.GetPeerDistributionEntropy()
+	c.Assert(concentrated, Greater, 0.0)
+	c.Assert(concentrated, Less, even)
+
+	for id := uint64(1); id <= 10; id++ {
+		s.classifier.setRegion(id, "ns2")
+	}
+	c.Assert(s.tc.addLeaderRegion(11, 1), IsNil)
+	s.classifier.setRegion(11, "ns1")
+	c.Assert(nc.GetPeerDistributionEntropy(), Equals, 0.0)
+}
+
+func (s *testNamespaceSuite) TestIsolationDelta(c *C) {
+	s.opt.GetReplication().Load().LocationLabels = []string{"rack", "host"}
+	// store rack host namespace
+	//     1   r1   h1       ns1
+	//     2   r2   h2       ns1
+	//     3   r2   h3       ns1
+	//     4   r2   h4       ns1
+	//     5   r3   h5       ns1
+	racks := []string{"", "r1", "r2", "r2", "r2", "r3"}
+	for id := uint64(1); id <= 5; id++ {
+		c.Assert(s.tc.addLabelsStore(id, 10, map[string]string{"rack": racks[id], "host": fmt.Sprintf("h%d", id)}), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	region := s.tc.GetRegion(1)
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetIsolationDelta(region, 3, 4), Equals, 0)
+	c.Assert(nc.GetIsolationDelta(region, 3, 5), Equals, 1)
+	c.Assert(nc.GetIsolationDelta(region, 1, 4), Equals, -1)
+
+	// Stores 4 and 5 have the same load, store 5 improves the isolation.
+	candidates := []*core.StoreInfo{nc.GetStore(4), nc.GetStore(5)}
+	c.Assert(nc.SelectMoveTarget(region, 3, candidates).GetID(), Equals, uint64(5))
+
+	// The load goes first.
+	c.Assert(s.tc.updateStore(4, core.SetRegionCount(5), core.SetRegionSize(50)), IsNil)
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	candidates = []*core.StoreInfo{nc.GetStore(4), nc.GetStore(5)}
+	c.Assert(nc.SelectMoveTarget(region, 3, candidates).GetID(), Equals, uint64(4))
+}
+
+func (s *testNamespaceSuite) TestSchedulerBalanceRegionByIsolation(c *C) {
+	s.opt.GetReplication().Load().LocationLabels = []string{"rack", "host"}
+	// store rack host regionCount namespace
+	//     1   r1   h1          50       ns1
+	//     2   r2   h2          50       ns1
+	//     3   r2   h3         100       ns1
+	//     4   r2   h4           0       ns1
+	//     5   r3   h5           0       ns1
+	racks := []string{"", "r1", "r2", "r2", "r2", "r3"}
+	counts := []int{0, 50, 50, 100, 0, 0}
+	for id := uint64(1); id <= 5; id++ {
+		c.Assert(s.tc.addLabelsStore(id, counts[id], map[string]string{"rack": racks[id], "host": fmt.Sprintf("h%d", id)}), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	s.classifier.setRegion(1, "ns1")
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, _ := schedule.CreateScheduler("balance-region", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+
+	// Stores 4 and 5 have the same load, store 5 improves the isolation.
+	op := scheduleByNamespace(s.tc, s.classifier, sched)
+	c.Assert(op, NotNil)
+	testutil.CheckTransferPeer(c, op[0], operator.OpBalance, 3, 5)
+}
+
+func (s *testNamespaceSuite) TestStoresExceedingRegionCap(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 100), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	// region leader follower namespace
+	//      1      1        2  ns1
+	//      2      1        3  ns1
+	//      3      1        2  ns1
+	//      4      2        3  ns2
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 1, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(3, 1, 2), IsNil)
+	c.Assert(s.tc.addLeaderRegion(4, 2, 3), IsNil)
+	for id := uint64(1); id <= 3; id++ {
+		s.classifier.setRegion(id, "ns1")
+	}
+	s.classifier.setRegion(4, "ns2")
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+
+	stores := nc.GetStoresExceedingRegionCap(2)
+	c.Assert(stores, HasLen, 1)
+	c.Assert(stores[0].GetID(), Equals, uint64(1))
+	c.Assert(nc.GetStoresExceedingRegionCap(1), HasLen, 2)
+	c.Assert(nc.GetStoresExceedingRegionCap(3), HasLen, 0)
+}
+
+func (s *testNamespaceSuite) TestHotReadLeader(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	// region leader follower read bytes rate
+	//      1      1     2, 3            1000
+	//      2      1     2, 3             500
+	//      3      2     1, 3             300
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(3, 2, 1, 3), IsNil)
+	for id := uint64(1); id <= 3; id++ {
+		s.classifier.setRegion(id, "ns1")
+	}
+	hotDegree := s.opt.GetHotRegionCacheHitsThreshold()
+	for _, stat := range []*statistics.HotPeerStat{
+		{StoreID: 1, RegionID: 1, Kind: statistics.ReadFlow, HotDegree: hotDegree, BytesRate: 1000},
+		{StoreID: 1, RegionID: 2, Kind: statistics.ReadFlow, HotDegree: hotDegree, BytesRate: 500},
+		{StoreID: 2, RegionID: 3, Kind: statistics.ReadFlow, HotDegree: hotDegree, BytesRate: 300},
+	} {
+		s.tc.hotSpotCache.Update(stat)
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+
+	// The hottest leader moves to the coolest store.
+	op := nc.CreateHotReadLeaderOperator()
+	c.Assert(op, NotNil)
+	c.Assert(op.RegionID(), Equals, uint64(1))
+	testutil.CheckTransferLeader(c, op, operator.OpHotRegion, 1, 3)
+
+	// Region 1 is not moved again.
+	op = nc.CreateHotReadLeaderOperator()
+	c.Assert(op, NotNil)
+	c.Assert(op.RegionID(), Equals, uint64(2))
+	testutil.CheckTransferLeader(c, op, operator.OpHotRegion, 1, 3)
+
+	// Moving region 3 does not make the read load more balanced.
+	c.Assert(nc.CreateHotReadLeaderOperator(), IsNil)
+}
+
+func (s *testNamespaceSuite) TestSchedulerHotReadLeader(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(3, 2, 1, 3), IsNil)
+	for id := uint64(1); id <= 3; id++ {
+		s.classifier.setRegion(id, "ns1")
+	}
+	hotDegree := s.opt.GetHotRegionCacheHitsThreshold()
+	for _, stat := range []*statistics.HotPeerStat{
+		{StoreID: 1, RegionID: 1, Kind: statistics.ReadFlow, HotDegree: hotDegree, BytesRate: 1000},
+		{StoreID: 1, RegionID: 2, Kind: statistics.ReadFlow, HotDegree: hotDegree, BytesRate: 500},
+		{StoreID: 2, RegionID: 3, Kind: statistics.ReadFlow, HotDegree: hotDegree, BytesRate: 300},
+	} {
+		s.tc.hotSpotCache.Update(stat)
+	}
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, err := schedule.CreateScheduler("hot-read-region", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+	c.Assert(err, IsNil)
+
+	// The hot read leader moves to the coolest store of the namespace.
+	op := scheduleByNamespace(s.tc, s.classifier, sched)
+	c.Assert(op, NotNil)
+	c.Assert(op[0].RegionID(), Equals, uint64(1))
+	testutil.CheckTransferLeader(c, op[0], operator.OpHotRegion, 1, 3)
+}
+
+func (s *testNamespaceSuite) TestRegionGrowthRate(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	s.classifier.setStore(1, "ns1")
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetRegionGrowthRate(), Equals, 0.0)
+
+	// 2 regions are added every half an hour.
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 2; j++ {
+			id := uint64(i*2 + j + 1)
+			c.Assert(s.tc.addLeaderRegion(id, 1), IsNil)
+			s.classifier.setRegion(id, "ns1")
+		}
+		s.tc.recordNamespaceRegionCounts(s.classifier, start.Add(time.Duration(i)*30*time.Minute))
+	}
+	c.Assert(math.Abs(nc.GetRegionGrowthRate()-4), Less, 1e-9)
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns2").GetRegionGrowthRate(), Equals, 0.0)
+}
+
+func (s *testNamespaceSuite) TestMaxOperatorSteps(c *C) {
+	for id := uint64(1); id <= 6; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	nsCfg := &config.NamespaceConfig{MaxReplicas: 3, MaxOperatorSteps: 1}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	oc := schedule.NewOperatorController(s.ctx, s.tc.RaftCluster, nil)
+	c.Assert(nc.GetMaxOperatorSteps(), Equals, uint64(1))
+	// Moving a peer needs at least 3 steps.
+	c.Assert(nc.DrainToStores(oc, []uint64{4, 5, 6}), HasLen, 0)
+
+	// One peer is moved in each round.
+	nsCfg.MaxOperatorSteps = 4
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	var rounds int
+	for {
+		ops := nc.DrainToStores(oc, []uint64{4, 5, 6})
+		if len(ops) == 0 {
+			break
+		}
+		rounds++
+		c.Assert(ops, HasLen, 1)
+		c.Assert(ops[0].Len(), LessEqual, 4)
+		region := s.tc.GetRegion(1)
+		for !ops[0].IsFinish() {
+			region = schedule.ApplyOperatorStep(region, ops[0])
+		}
+		c.Assert(s.tc.putRegion(region), IsNil)
+	}
+	c.Assert(rounds, Equals, 3)
+	c.Assert(s.tc.GetRegion(1).GetStoreIds(), DeepEquals, map[uint64]struct{}{4: {}, 5: {}, 6: {}})
+}
+
+func (s *testNamespaceSuite) TestUnderutilizedStores(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 100), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	// Store 3 has only one region of ns1, store 4 has none but is offline.
+	for id := uint64(1); id <= 5; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(6, 1, 3), IsNil)
+	s.classifier.setRegion(6, "ns1")
+	c.Assert(s.tc.addLeaderRegion(7, 3, 1), IsNil)
+	s.classifier.setRegion(7, "ns2")
+	s.tc.setStoreOffline(4)
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+
+	stores := nc.GetUnderutilizedStores(3)
+	c.Assert(stores, HasLen, 1)
+	c.Assert(stores[0].GetID(), Equals, uint64(3))
+	c.Assert(nc.GetUnderutilizedStores(1), HasLen, 0)
+	c.Assert(nc.GetUnderutilizedStores(10), HasLen, 3)
+}
+
+func (s *testNamespaceSuite) TestLeaderTransferCandidates(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	// store leaderCount
+	//     1           4
+	//     2           1
+	//     3           2
+	//     4           1
+	regions := [][]uint64{
+		{1, 2, 3},
+		{1, 3},
+		{1, 4},
+		{1, 2, 4},
+		{2, 1},
+		{3, 1},
+		{3, 1},
+		{4, 1},
+	}
+	for i, stores := range regions {
+		id := uint64(i + 1)
+		c.Assert(s.tc.addLeaderRegion(id, stores[0], stores[1:]...), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	getIDs := func() []uint64 {
+		var ids []uint64
+		for _, r := range nc.GetLeaderTransferCandidates() {
+			ids = append(ids, r.GetID())
+		}
+		return ids
+	}
+	// Region 2 only has a follower on store 3, which has the average leaders.
+	c.Assert(getIDs(), DeepEquals, []uint64{1, 3, 4})
+
+	// Blacklisted stores do not receive leaders.
+	nc.state.setStoreBlacklist([]uint64{4})
+	c.Assert(getIDs(), DeepEquals, []uint64{1, 4})
+}
+
+func (s *testNamespaceSuite) TestDecommissionProgress(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetDecommissionProgress(1), Equals, 0.0)
+
+	// The baseline is recorded when the store is first seen offline.
+	c.Assert(s.tc.setStoreOffline(1), IsNil)
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetDecommissionProgress(1), Equals, 0.0)
+	c.Assert(s.tc.addLeaderRegion(1, 2, 3, 4), IsNil)
+	c.Assert(nc.GetDecommissionProgress(1), Equals, 0.25)
+	c.Assert(s.tc.addLeaderRegion(2, 2, 3, 4), IsNil)
+	c.Assert(nc.GetDecommissionProgress(1), Equals, 0.5)
+	c.Assert(s.tc.addLeaderRegion(3, 2, 3, 4), IsNil)
+	c.Assert(s.tc.addLeaderRegion(4, 2, 3, 4), IsNil)
+	c.Assert(nc.GetDecommissionProgress(1), Equals, 1.0)
+
+	// The baseline is cleared once the store is seen up again.
+	c.Assert(s.tc.updateStore(1, core.SetStoreState(metapb.StoreState_Up)), IsNil)
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetDecommissionProgress(1), Equals, 0.0)
+	s.tc.collectNamespaceMetrics(s.classifier)
+	c.Assert(s.tc.setStoreOffline(1), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 1, 2, 3), IsNil)
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetDecommissionProgress(1), Equals, 0.0)
+	c.Assert(s.tc.addLeaderRegion(2, 2, 3, 4), IsNil)
+	c.Assert(nc.GetDecommissionProgress(1), Equals, 0.5)
+}
+
+func (s *testNamespaceSuite) TestOptimizeNamespaceBalance(c *C) {
+	s.opt.SetMaxReplicas(1)
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	// store regionSizes load
+	//     1      50, 5   55
+	//     2     25, 20   45
+	//     3               0
+	regions := []struct {
+		store uint64
+		size  int64
+	}{{1, 50}, {1, 5}, {2, 25}, {2, 20}}
+	for i, r := range regions {
+		id := uint64(i + 1)
+		c.Assert(s.tc.addLeaderRegion(id, r.store), IsNil)
+		c.Assert(s.tc.putRegion(s.tc.GetRegion(id).Clone(core.SetApproximateSize(r.size))), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetStoreLoads(), DeepEquals, map[uint64]float64{1: 55, 2: 45, 3: 0})
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	storage := core.NewStorage(kv.NewMemoryKV())
+	maxLoad, err := schedule.CreateScheduler("optimize-namespace-balance", oc, storage, schedule.ConfigSliceDecoder("optimize-namespace-balance", []string{"max-load"}))
+	c.Assert(err, IsNil)
+	loadVariance, err := schedule.CreateScheduler("optimize-namespace-balance", oc, storage, schedule.ConfigSliceDecoder("optimize-namespace-balance", nil))
+	c.Assert(err, IsNil)
+	_, err = schedule.CreateScheduler("optimize-namespace-balance", oc, storage, schedule.ConfigSliceDecoder("optimize-namespace-balance", []string{"foo"}))
+	c.Assert(err, NotNil)
+
+	// Only moving a region off store 1 reduces the max load.
+	ops := scheduleByNamespace(s.tc, s.classifier, maxLoad)
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].RegionID(), Equals, uint64(1))
+	testutil.CheckTransferPeer(c, ops[0], operator.OpBalance, 1, 3)
+	// Moving a region off store 2 leaves the loads 55, 20 and 25, which have
+	// a lower variance than 5, 45 and 50.
+	ops = scheduleByNamespace(s.tc, s.classifier, loadVariance)
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].RegionID(), Equals, uint64(3))
+	testutil.CheckTransferPeer(c, ops[0], operator.OpBalance, 2, 3)
+
+	// The move off store 4 is preferred, but store 6 does not keep the
+	// distinct score for it.
+	s.opt.SetMaxReplicas(3)
+	s.opt.GetReplication().Load().LocationLabels = []string{"zone"}
+	c.Assert(s.tc.addLabelsStore(4, 0, map[string]string{"zone": "z1"}), IsNil)
+	c.Assert(s.tc.addLabelsStore(5, 0, map[string]string{"zone": "z2"}), IsNil)
+	c.Assert(s.tc.addLabelsStore(6, 0, map[string]string{"zone": "z2"}), IsNil)
+	c.Assert(s.tc.addLabelsStore(7, 0, map[string]string{"zone": "z3"}), IsNil)
+	for id := uint64(1); id <= 3; id++ {
+		s.classifier.setStore(id, "ns2")
+	}
+	for id := uint64(4); id <= 7; id++ {
+		s.classifier.setStore(id, "ns1")
+	}
+	for id := uint64(6); id <= 7; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 4, 5, 7), IsNil)
+		c.Assert(s.tc.putRegion(s.tc.GetRegion(id).Clone(core.SetApproximateSize(10))), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	for id := uint64(1); id <= 4; id++ {
+		s.classifier.setRegion(id, "ns2")
+	}
+	ops = scheduleByNamespace(s.tc, s.classifier, loadVariance)
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].RegionID(), Equals, uint64(6))
+	testutil.CheckTransferPeer(c, ops[0], operator.OpBalance, 5, 6)
+
+	// No move improves the loads 20, 10, 10 and 20 keeping the distinct score.
+	c.Assert(s.tc.addLeaderRegion(6, 4, 6, 7), IsNil)
+	c.Assert(s.tc.putRegion(s.tc.GetRegion(6).Clone(core.SetApproximateSize(10))), IsNil)
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, loadVariance), IsNil)
+}
+
+func (s *testNamespaceSuite) TestSinglePointFailureStores(c *C) {
+	s.opt.GetReplication().Load().LocationLabels = []string{"rack", "host"}
+	// store rack host
+	//  1,2,3   r1 h1,h2,h3
+	//      4   r2 h4
+	//    5,6   r3 h5,h6
+	racks := []string{"r1", "r1", "r1", "r2", "r3", "r3"}
+	for i, rack := range racks {
+		id := uint64(i + 1)
+		c.Assert(s.tc.addLabelsStore(id, 0, map[string]string{"rack": rack, "host": fmt.Sprintf("h%d", id)}), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	// All the replicas of region 1 are in rack r1, and most of region 2 are
+	// in rack r3.
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 4, 5, 6), IsNil)
+	c.Assert(s.tc.addLeaderRegion(3, 1, 4, 5), IsNil)
+	for id := uint64(1); id <= 3; id++ {
+		s.classifier.setRegion(id, "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetSinglePointFailureStores(), DeepEquals, []uint64{1, 2, 3, 5, 6})
+
+	c.Assert(s.tc.addLeaderRegion(1, 1, 4, 5), IsNil)
+	c.Assert(nc.GetSinglePointFailureStores(), DeepEquals, []uint64{5, 6})
+
+	// The regions with a voter on the down store lose the quorum if either
+	// of the other voters fails.
+	c.Assert(s.tc.setStoreDown(4), IsNil)
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetSinglePointFailureStores(), DeepEquals, []uint64{1, 5, 6})
+}
+
+func (s *testNamespaceSuite) TestReplicaDiversityScore(c *C) {
+	s.opt.GetReplication().Load().LocationLabels = []string{"rack", "host"}
+	// store rack
+	//  1,2,3   r1
+	//      4   r2
+	//      5   r3
+	racks := []string{"r1", "r1", "r1", "r2", "r3"}
+	for i, rack := range racks {
+		id := uint64(i + 1)
+		c.Assert(s.tc.addLabelsStore(id, 0, map[string]string{"rack": rack, "host": fmt.Sprintf("h%d", id)}), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetReplicaDiversityScore(), Equals, 1.0)
+
+	// Well diversified placements.
+	c.Assert(s.tc.addLeaderRegion(1, 1, 4, 5), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 2, 4, 5), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	s.classifier.setRegion(2, "ns1")
+	c.Assert(nc.GetReplicaDiversityScore(), Equals, 1.0)
+
+	// Concentrated placements lower the score.
+	c.Assert(s.tc.addLeaderRegion(2, 1, 2, 4), IsNil)
+	c.Assert(math.Abs(nc.GetReplicaDiversityScore()-(1.0+2.0/3)/2), Less, 1e-9)
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(math.Abs(nc.GetReplicaDiversityScore()-(1.0/3+2.0/3)/2), Less, 1e-9)
+
+	// The score is limited by the domains of the up stores.
+	c.Assert(s.tc.setStoreOffline(5), IsNil)
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(math.Abs(nc.GetReplicaDiversityScore()-(1.0/2+1.0)/2), Less, 1e-9)
+}
+
+func (s *testNamespaceSuite) TestSimulatePlacement(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 1, 2, 3), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	s.classifier.setRegion(2, "ns1")
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	current := nc.GetNamespaceStats()
+	c.Assert(current, DeepEquals, NamespaceStats{
+		RegionCount:       2,
+		StoreRegionCounts: map[uint64]int{1: 2, 2: 2, 3: 2, 4: 0},
+		StoreLeaderCounts: map[uint64]int{1: 2, 2: 0, 3: 0, 4: 0},
+	})
+
+	region1, region2 := s.tc.GetRegion(1), s.tc.GetRegion(2)
+	// The leader of region 1 is transferred to store 2 before the peer on
+	// store 1 is removed.
+	steps := append(operator.CreateAddPeerSteps(4, 100),
+		operator.TransferLeader{FromStore: 1, ToStore: 2},
+		operator.RemovePeer{FromStore: 1},
+	)
+	move := operator.NewOperator("move", "mv peer: store 1 to 4", region1.GetID(), region1.GetRegionEpoch(), operator.OpRegion|operator.OpLeader, steps...)
+	transfer := operator.CreateTransferLeaderOperator("transfer", region2, 1, 3, operator.OpLeader)
+	stats, err := nc.SimulatePlacement([]*operator.Operator{move, transfer})
+	c.Assert(err, IsNil)
+	c.Assert(stats, DeepEquals, NamespaceStats{
+		RegionCount:       2,
+		StoreRegionCounts: map[uint64]int{1: 1, 2: 2, 3: 2, 4: 1},
+		StoreLeaderCounts: map[uint64]int{1: 0, 2: 1, 3: 1, 4: 0},
+	})
+	// The cluster is not changed.
+	c.Assert(nc.GetNamespaceStats(), DeepEquals, current)
+	c.Assert(s.tc.GetRegion(1).GetStoreIds(), DeepEquals, map[uint64]struct{}{1: {}, 2: {}, 3: {}})
+
+	split := operator.CreateSplitRegionOperator("split", region1, operator.OpAdmin, pdpb.CheckPolicy_APPROXIMATE, nil)
+	_, err = nc.SimulatePlacement([]*operator.Operator{split})
+	c.Assert(err, NotNil)
+}
+
+func (s *testNamespaceSuite) TestSimulateStoreFailure(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(4, 2, 3, 4), IsNil)
+	s.classifier.setRegion(4, "ns1")
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	current := nc.GetNamespaceStats()
+
+	// The peers on store 1 are all replaced on store 4, and the leaders are
+	// spread over the remaining voters.
+	c.Assert(nc.SimulateStoreFailure(1), DeepEquals, NamespaceStats{
+		RegionCount:       4,
+		StoreRegionCounts: map[uint64]int{1: 0, 2: 4, 3: 4, 4: 4},
+		StoreLeaderCounts: map[uint64]int{1: 0, 2: 2, 3: 1, 4: 1},
+	})
+	// The cluster is not changed.
+	c.Assert(nc.GetNamespaceStats(), DeepEquals, current)
+
+	// The peer of region 4 on store 4 is replaced on store 1, and the leaders
+	// are not moved.
+	stats := nc.SimulateStoreFailure(4)
+	c.Assert(stats.StoreRegionCounts, DeepEquals, map[uint64]int{1: 4, 2: 4, 3: 4, 4: 0})
+	c.Assert(stats.StoreLeaderCounts, DeepEquals, current.StoreLeaderCounts)
+
+	// Peers are dropped if no store can hold their replacements.
+	s.tc.setStoreOffline(4)
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	stats = nc.SimulateStoreFailure(1)
+	c.Assert(stats.StoreRegionCounts, DeepEquals, map[uint64]int{1: 0, 2: 4, 3: 4, 4: 1})
+	c.Assert(stats.StoreLeaderCounts, DeepEquals, map[uint64]int{1: 0, 2: 2, 3: 2, 4: 0})
+}
+
+func (s *testNamespaceSuite) TestRecoveryPriority(c *C) {
+	for id := uint64(1); id <= 5; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	nsCfg := &config.NamespaceConfig{MaxReplicas: 3}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	// region leader follower namespace
+	//      1      1     2, 3       ns1
+	//      2      1        2       ns1
+	//      3      1        4       ns1
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 1, 2), IsNil)
+	c.Assert(s.tc.addLeaderRegion(3, 1, 4), IsNil)
+	for id := uint64(1); id <= 3; id++ {
+		s.classifier.setRegion(id, "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetRecoveryPriority(1), Equals, 0)
+	c.Assert(nc.GetRecoveryPriority(2), Equals, 1)
+	c.Assert(nc.GetRecoveryPriority(3), Equals, 2)
+	c.Assert(nc.GetRecoveryPriority(5), Equals, 2)
+	c.Assert(nc.GetRecoveryPriority(6), Equals, 0)
+
+	// Region 1 loses two voters.
+	region := s.tc.GetRegion(1)
+	c.Assert(s.tc.putRegion(region.Clone(core.WithDownPeers([]*pdpb.PeerStats{
+		{Peer: region.GetStorePeer(2), DownSeconds: 3600},
+		{Peer: region.GetStorePeer(3), DownSeconds: 3600},
+	}))), IsNil)
+	c.Assert(nc.GetRecoveryPriority(3), Equals, 4)
+	c.Assert(nc.GetRecoveryPriority(5), Equals, 4)
+	c.Assert(nc.GetRecoveryPriority(4), Equals, 3)
+
+	s.tc.setStoreOffline(3)
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	var ids []uint64
+	for _, s := range nc.GetStoresByRecoveryPriority() {
+		ids = append(ids, s.GetID())
+	}
+	c.Assert(ids, DeepEquals, []uint64{5, 2, 4, 1})
+}
+
+func (s *testNamespaceSuite) TestSchedulerBalanceRegionByRecoveryPriority(c *C) {
+	// store regionCount namespace
+	//     1          50       ns1
+	//     2          50       ns1
+	//     3         100       ns1
+	//     4          10       ns1
+	//     5           0       ns1
+	counts := []int{0, 50, 50, 100, 10, 0}
+	for id := uint64(1); id <= 5; id++ {
+		c.Assert(s.tc.addRegionStore(id, counts[id]), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	// Region 2 lacks a voter, which store 4 can repair but store 5 cannot.
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 1, 5), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	s.classifier.setRegion(2, "ns1")
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, _ := schedule.CreateScheduler("balance-region", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+
+	// Store 4 goes first though store 5 has less load.
+	op := scheduleByNamespace(s.tc, s.classifier, sched)
+	c.Assert(op, NotNil)
+	testutil.CheckTransferPeer(c, op[0], operator.OpBalance, 3, 4)
+}
+
+func (s *testNamespaceSuite) TestMaxReplicationLag(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(3, 1, 2, 3), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	s.classifier.setRegion(2, "ns1")
+	s.classifier.setRegion(3, "ns2")
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetMaxReplicationLag(), Equals, time.Duration(0))
+
+	region := s.tc.GetRegion(1)
+	c.Assert(s.tc.putRegion(region.Clone(core.WithDownPeers([]*pdpb.PeerStats{
+		{Peer: region.GetStorePeer(2), DownSeconds: 30},
+	}))), IsNil)
+	region = s.tc.GetRegion(2)
+	c.Assert(s.tc.putRegion(region.Clone(core.WithDownPeers([]*pdpb.PeerStats{
+		{Peer: region.GetStorePeer(3), DownSeconds: 90},
+	}))), IsNil)
+	// Region 3 belongs to another namespace.
+	region = s.tc.GetRegion(3)
+	c.Assert(s.tc.putRegion(region.Clone(core.WithDownPeers([]*pdpb.PeerStats{
+		{Peer: region.GetStorePeer(3), DownSeconds: 600},
+	}))), IsNil)
+	c.Assert(nc.GetMaxReplicationLag(), Equals, 90*time.Second)
+}
+
+func (s *testNamespaceSuite) TestCapacityUtilizationSkew(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetCapacityUtilizationSkew(), Equals, 0.0)
+
+	// used ratios: 0.1, 0.1, 0.1, 0.9
+	for id := uint64(1); id <= 4; id++ {
+		available := uint64(900)
+		if id == 4 {
+			available = 100
+		}
+		c.Assert(s.tc.updateStore(id, core.SetStoreStats(&pdpb.StoreStats{Capacity: 1000, Available: available})), IsNil)
+	}
+	// A store which does not report its capacity is ignored.
+	c.Assert(s.tc.addLeaderStore(5, 0), IsNil)
+	s.classifier.setStore(5, "ns1")
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(math.Abs(nc.GetCapacityUtilizationSkew()-0.6) < 1e-9, IsTrue)
+}
+
+func (s *testNamespaceSuite) TestEffectiveReplicationFactor(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetEffectiveReplicationFactor(), Equals, 0.0)
+
+	// region voters down peers
+	//      1   1, 2, 3          -
+	//      2      1, 2          -
+	//      3   1, 2, 3          3
+	//      4      1, 2          -   learner on 3
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 1, 2), IsNil)
+	c.Assert(s.tc.addLeaderRegion(3, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(4, 1, 2), IsNil)
+	region := s.tc.GetRegion(3)
+	c.Assert(s.tc.putRegion(region.Clone(core.WithDownPeers([]*pdpb.PeerStats{
+		{Peer: region.GetStorePeer(3), DownSeconds: 3600},
+	}))), IsNil)
+	region = s.tc.GetRegion(4)
+	c.Assert(s.tc.putRegion(region.Clone(core.WithAddPeer(&metapb.Peer{Id: 100, StoreId: 3, IsLearner: true}))), IsNil)
+	for id := uint64(1); id <= 4; id++ {
+		s.classifier.setRegion(id, "ns1")
+	}
+	c.Assert(nc.GetEffectiveReplicationFactor(), Equals, 2.25)
+	c.Assert(nc.GetEffectiveReplicationFactor() < float64(nc.GetMaxReplicas()), IsTrue)
+}
+
+func (s *testNamespaceSuite) TestSchedulingBacklog(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLabelsStore(id, 0, map[string]string{"zone": "z1"}), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addLabelsStore(4, 0, map[string]string{"zone": "z2"}), IsNil)
+	s.classifier.setStore(4, "ns1")
+	nsCfg := &config.NamespaceConfig{MaxReplicas: 3, PlacementRules: []*placement.Rule{
+		{
+			GroupID:          "pd",
+			ID:               "z1",
+			Role:             placement.Voter,
+			Count:            3,
+			LabelConstraints: []placement.LabelConstraint{{Key: "zone", Op: placement.In, Values: []string{"z1"}}},
+			StartKey:         []byte(fmt.Sprintf("%20d", 5)),
+			EndKey:           []byte(fmt.Sprintf("%20d", 6)),
+		},
+	}}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetSchedulingBacklog(), Equals, 0)
+
+	// region    voters down peers backlog
+	//      1   1, 2, 3          -       0
+	//      2      1, 2          -       1
+	//      3   1, 2, 3          3       1
+	//      4 1, 2, 3, 4         -       1
+	//      5   1, 2, 4          -       1 violates the rule
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 1, 2), IsNil)
+	c.Assert(s.tc.addLeaderRegion(3, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(4, 1, 2, 3, 4), IsNil)
+	c.Assert(s.tc.addLeaderRegion(5, 1, 2, 4), IsNil)
+	region := s.tc.GetRegion(3)
+	c.Assert(s.tc.putRegion(region.Clone(core.WithDownPeers([]*pdpb.PeerStats{
+		{Peer: region.GetStorePeer(3), DownSeconds: 3600},
+	}))), IsNil)
+	for id := uint64(1); id <= 5; id++ {
+		s.classifier.setRegion(id, "ns1")
+	}
+	c.Assert(nc.GetSchedulingBacklog(), Equals, 4)
+}
+
+func (s *testNamespaceSuite) TestLeaseStable(c *C) {
+	// store leaderCount namespace
+	//     1         300       ns1
+	//     2         100       ns1
+	//     3         200       ns1
+	c.Assert(s.tc.addLeaderStore(1, 300), IsNil)
+	c.Assert(s.tc.addLeaderStore(2, 100), IsNil)
+	c.Assert(s.tc.addLeaderStore(3, 200), IsNil)
+	for id := uint64(1); id <= 3; id++ {
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	s.classifier.setRegion(1, "ns1")
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, _ := schedule.CreateScheduler("balance-leader", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+	op := scheduleByNamespace(s.tc, s.classifier, sched)
+	testutil.CheckTransferLeader(c, op[0], operator.OpBalance, 1, 2)
+
+	// Store 2 is avoided as the target while its lease is unstable.
+	c.Assert(s.tc.MarkStoreLeaseUnstable(2, time.Hour), IsNil)
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.IsLeaseStable(1), IsTrue)
+	c.Assert(nc.IsLeaseStable(2), IsFalse)
+	c.Assert(nc.checkOperator(op[0]), IsFalse)
+	op = scheduleByNamespace(s.tc, s.classifier, sched)
+	testutil.CheckTransferLeader(c, op[0], operator.OpBalance, 1, 3)
+
+	c.Assert(s.tc.MarkStoreLeaseUnstable(3, time.Hour), IsNil)
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, sched), IsNil)
+
+	// The lease becomes stable again once the mark expires.
+	c.Assert(s.tc.MarkStoreLeaseUnstable(2, -time.Second), IsNil)
+	c.Assert(nc.IsLeaseStable(2), IsTrue)
+	op = scheduleByNamespace(s.tc, s.classifier, sched)
+	testutil.CheckTransferLeader(c, op[0], operator.OpBalance, 1, 2)
+}
+
+func (s *testNamespaceSuite) TestBoundaryRegions(c *C) {
+	classifier, err := table.NewTableNamespaceClassifier(core.NewStorage(kv.NewMemoryKV()), mockid.NewIDAllocator())
+	c.Assert(err, IsNil)
+	tableClassifier := classifier.(interface {
+		CreateNamespace(name string) error
+		AddNamespaceTableID(name string, tableID int64) error
+	})
+	c.Assert(tableClassifier.CreateNamespace("ns1"), IsNil)
+	c.Assert(tableClassifier.AddNamespaceTableID("ns1", 1), IsNil)
+	c.Assert(tableClassifier.AddNamespaceTableID("ns1", 2), IsNil)
+
+	tableKey := func(tableID int64) []byte { return table.EncodeBytes(table.GenerateTableKey(tableID)) }
+	rowKey := func(tableID, rowID int64) []byte { return table.EncodeBytes(table.GenerateRowKey(tableID, rowID)) }
+	// region          range namespace
+	//      1   [t1, t2_r10)       ns1
+	//      2 [t2_r10, t2_r20)     ns1
+	//      3  [t2_r20, t3_r5)     ns1, global
+	//      4      [t3_r5, "")     global
+	keys := [][]byte{tableKey(1), rowKey(2, 10), rowKey(2, 20), rowKey(3, 5), nil}
+	for i := 0; i < 4; i++ {
+		id := uint64(i + 1)
+		region := core.NewRegionInfo(&metapb.Region{Id: id, StartKey: keys[i], EndKey: keys[i+1], Peers: []*metapb.Peer{{Id: id + 100, StoreId: 1}}}, nil)
+		c.Assert(s.tc.putRegion(region), IsNil)
+	}
+
+	nc := newNamespaceCluster(s.tc, classifier, "ns1")
+	regions := nc.GetBoundaryRegions()
+	c.Assert(regions, HasLen, 1)
+	c.Assert(regions[0].GetID(), Equals, uint64(3))
+	c.Assert(newNamespaceCluster(s.tc, classifier, namespace.DefaultNamespace).GetBoundaryRegions(), HasLen, 0)
+
+	// Once table 3 joins ns1, region 3 is within ns1, but region 4 covers
+	// table 3 and the following global tables.
+	c.Assert(tableClassifier.AddNamespaceTableID("ns1", 3), IsNil)
+	regions = nc.GetBoundaryRegions()
+	c.Assert(regions, HasLen, 1)
+	c.Assert(regions[0].GetID(), Equals, uint64(4))
+
+	// Classifiers which do not define namespaces by key range report nothing.
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns1").GetBoundaryRegions(), IsNil)
+}
+
+func (s *testNamespaceSuite) TestStoreUpgrading(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	syncLeaderCounts := func() {
+		counts := make(map[uint64]int)
+		for id := uint64(1); id <= 3; id++ {
+			counts[s.tc.GetRegion(id).GetLeader().GetStoreId()]++
+		}
+		for id := uint64(1); id <= 3; id++ {
+			c.Assert(s.tc.updateStore(id, core.SetLeaderCount(counts[id])), IsNil)
+		}
+	}
+	syncLeaderCounts()
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, _ := schedule.CreateScheduler("balance-leader", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+
+	// The leaders are drained from store 1 in upgrade mode.
+	s.tc.SetNamespaceStoreUpgrading("ns1", 1, true)
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.IsStoreUpgrading(1), IsTrue)
+	c.Assert(nc.IsStoreUpgrading(2), IsFalse)
+	for i := 0; i < 3; i++ {
+		ops := evictNamespaceLeaders(s.tc, s.classifier)
+		c.Assert(ops, HasLen, 1)
+		c.Assert(ops[0].Desc(), Equals, "namespace-upgrade-evict-leader")
+		c.Assert(ops[0].GetPriorityLevel(), Equals, core.HighPriority)
+		step := ops[0].Step(0).(operator.TransferLeader)
+		c.Assert(step.FromStore, Equals, uint64(1))
+		c.Assert(step.ToStore, Not(Equals), uint64(1))
+		region := s.tc.GetRegion(ops[0].RegionID())
+		c.Assert(s.tc.putRegion(region.Clone(core.WithLeader(region.GetStorePeer(step.ToStore)))), IsNil)
+		syncLeaderCounts()
+	}
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.GetRegion(id).GetLeader().GetStoreId(), Not(Equals), uint64(1))
+	}
+
+	// No leader returns to store 1 until the mode is cleared.
+	c.Assert(s.tc.updateStore(2, core.SetLeaderCount(100)), IsNil)
+	c.Assert(s.tc.updateStore(3, core.SetLeaderCount(100)), IsNil)
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, sched), IsNil)
+	s.tc.SetNamespaceStoreUpgrading("ns1", 1, false)
+	ops := scheduleByNamespace(s.tc, s.classifier, sched)
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].Step(0).(operator.TransferLeader).ToStore, Equals, uint64(1))
+}
+
+func (s *testNamespaceSuite) TestStoreShutdown(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderStore(4, 0), IsNil)
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(4, 2, 3), IsNil)
+	s.classifier.setRegion(4, "ns1")
+	syncLeaderCounts := func() {
+		counts := make(map[uint64]int)
+		for id := uint64(1); id <= 4; id++ {
+			counts[s.tc.GetRegion(id).GetLeader().GetStoreId()]++
+		}
+		for id := uint64(1); id <= 3; id++ {
+			c.Assert(s.tc.updateStore(id, core.SetLeaderCount(counts[id])), IsNil)
+		}
+	}
+	syncLeaderCounts()
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.BeginStoreShutdown(4), NotNil)
+	region := s.tc.GetRegion(4)
+	addPeer := newTestOperator(4, region.GetRegionEpoch(), operator.OpRegion, operator.AddPeer{ToStore: 1, PeerID: 100})
+	c.Assert(nc.checkOperator(addPeer), IsTrue)
+
+	// The leaders are drained from store 1 once it begins shutting down.
+	c.Assert(nc.BeginStoreShutdown(1), IsNil)
+	c.Assert(nc.IsStoreShuttingDown(1), IsTrue)
+	c.Assert(nc.IsStoreShuttingDown(2), IsFalse)
+	for i := 0; i < 3; i++ {
+		ops := evictNamespaceLeaders(s.tc, s.classifier)
+		c.Assert(ops, HasLen, 1)
+		c.Assert(ops[0].Desc(), Equals, "namespace-shutdown-evict-leader")
+		step := ops[0].Step(0).(operator.TransferLeader)
+		c.Assert(step.FromStore, Equals, uint64(1))
+		c.Assert(step.ToStore, Not(Equals), uint64(1))
+		region := s.tc.GetRegion(ops[0].RegionID())
+		c.Assert(s.tc.putRegion(region.Clone(core.WithLeader(region.GetStorePeer(step.ToStore)))), IsNil)
+		syncLeaderCounts()
+	}
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.GetRegion(id).GetLeader().GetStoreId(), Not(Equals), uint64(1))
+	}
+	// No peer is added to store 1.
+	c.Assert(nc.GetBlacklistedStores(), DeepEquals, []uint64{1})
+	c.Assert(nc.checkOperator(addPeer), IsFalse)
+	c.Assert(nc.filterOperators([]*operator.Operator{addPeer}), HasLen, 0)
+
+	nc.EndStoreShutdown(1)
+	c.Assert(nc.IsStoreShuttingDown(1), IsFalse)
+	c.Assert(nc.GetBlacklistedStores(), HasLen, 0)
+	c.Assert(nc.checkOperator(addPeer), IsTrue)
+}
+
+func (s *testNamespaceSuite) TestStoreShutdownInDecommissioningNamespace(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 100), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addRegionStore(4, 0), IsNil)
+	s.classifier.setStore(4, "ns1")
+	for id := uint64(1); id <= 2; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	c.Assert(s.tc.updateLeaderCount(1, 2), IsNil)
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	bls, err := schedule.CreateScheduler("balance-leader", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+	c.Assert(err, IsNil)
+	brs, err := schedule.CreateScheduler("balance-region", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+	c.Assert(err, IsNil)
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, brs), NotNil)
+
+	s.tc.MarkNamespaceDecommissioning("ns1")
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.BeginStoreShutdown(1), IsNil)
+	// The leaders are drained from the store shutting down, while its regions
+	// are not moved.
+	for i := 0; i < 2; i++ {
+		c.Assert(scheduleByNamespace(s.tc, s.classifier, brs), IsNil)
+		c.Assert(scheduleByNamespace(s.tc, s.classifier, bls), IsNil)
+		ops := evictNamespaceLeaders(s.tc, s.classifier)
+		c.Assert(ops, HasLen, 1)
+		c.Assert(ops[0].Desc(), Equals, "namespace-shutdown-evict-leader")
+		step := ops[0].Step(0).(operator.TransferLeader)
+		c.Assert(step.FromStore, Equals, uint64(1))
+		region := s.tc.GetRegion(ops[0].RegionID())
+		c.Assert(s.tc.putRegion(region.Clone(core.WithLeader(region.GetStorePeer(step.ToStore)))), IsNil)
+		c.Assert(s.tc.updateLeaderCount(1, 1-i), IsNil)
+	}
+	// Leaders are not balanced once drained.
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, bls), IsNil)
+	for id := uint64(1); id <= 2; id++ {
+		c.Assert(s.tc.GetRegion(id).GetLeader().GetStoreId(), Not(Equals), uint64(1))
+		c.Assert(s.tc.GetRegion(id).GetStorePeer(1), NotNil)
+	}
+}
+
+func (s *testNamespaceSuite) TestColocationRisk(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetColocationRisk(), HasLen, 0)
+
+	// region peers namespace
+	//      1  1, 2       ns1
+	//      2  2, 1       ns1
+	//      3  1, 2       ns1
+	//      4  3, 4       ns1
+	//      5  1, 2       ns2
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 2, 1), IsNil)
+	c.Assert(s.tc.addLeaderRegion(3, 1, 2), IsNil)
+	c.Assert(s.tc.addLeaderRegion(4, 3, 4), IsNil)
+	c.Assert(s.tc.addLeaderRegion(5, 1, 2), IsNil)
+	for id := uint64(1); id <= 4; id++ {
+		s.classifier.setRegion(id, "ns1")
+	}
+	s.classifier.setRegion(5, "ns2")
+	c.Assert(nc.GetColocationRisk(), DeepEquals, []StorePairColocation{
+		{StoreIDs: [2]uint64{1, 2}, RegionCount: 3},
+		{StoreIDs: [2]uint64{3, 4}, RegionCount: 1},
+	})
+}
+
+func (s *testNamespaceSuite) TestSlowStore(c *C) {
+	// store regionCount namespace
+	//     1         100       ns1
+	//     2           0       ns1
+	//     3           0       ns1
+	c.Assert(s.tc.addRegionStore(1, 100), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(3, 0), IsNil)
+	for id := uint64(1); id <= 3; id++ {
+		s.classifier.setStore(id, "ns1")
+	}
+	s.opt.SetMaxReplicas(1)
+	c.Assert(s.tc.addLeaderRegion(1, 1), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	setLatency := func(storeID uint64, latency uint64) {
+		stats := *s.tc.GetStore(storeID).GetStoreStats()
+		stats.OpLatencies = []*pdpb.RecordPair{{Key: "apply", Value: latency}}
+		c.Assert(s.tc.updateStore(storeID, core.SetStoreStats(&stats)), IsNil)
+	}
+	setLatency(1, 10)
+	setLatency(2, 1000)
+	setLatency(3, 20)
+
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.IsSlowStore(1), IsFalse)
+	c.Assert(nc.IsSlowStore(2), IsTrue)
+	c.Assert(nc.IsSlowStore(3), IsFalse)
+
+	// The slow store is avoided as the target to add peer.
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, _ := schedule.CreateScheduler("balance-region", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+	for i := 0; i < 10; i++ {
+		op := scheduleByNamespace(s.tc, s.classifier, sched)
+		testutil.CheckTransferPeer(c, op[0], operator.OpBalance, 1, 3)
+	}
+
+	// It is not slow any more once other stores are as slow as it.
+	setLatency(1, 800)
+	setLatency(3, 800)
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns1").IsSlowStore(2), IsFalse)
+}
+
+func (s *testNamespaceSuite) TestPostGCMergeCandidates(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 0), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns2")
+
+	// region size namespace
+	//      1    1       ns1
+	//      2    1       ns1
+	//      3  100       ns1
+	//      4    1       ns1
+	//      5    1       ns1
+	//      6    1       ns1
+	//      7    1       ns2
+	//      8    1       ns1
+	for id := uint64(1); id <= 8; id++ {
+		storeID, ns := uint64(1), "ns1"
+		if id == 7 {
+			storeID, ns = 2, "ns2"
+		}
+		c.Assert(s.tc.addLeaderRegion(id, storeID), IsNil)
+		s.classifier.setRegion(id, ns)
+		size := int64(core.EmptyRegionApproximateSize)
+		if id == 3 {
+			size = 100
+		}
+		c.Assert(s.tc.putRegion(s.tc.GetRegion(id).Clone(core.SetApproximateSize(size))), IsNil)
+	}
+
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	var ids []uint64
+	for _, r := range nc.GetPostGCMergeCandidates() {
+		ids = append(ids, r.GetID())
+	}
+	// Region 8 is not adjacent to another empty region in ns1.
+	c.Assert(ids, DeepEquals, []uint64{1, 2, 4, 5, 6})
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns2").GetPostGCMergeCandidates(), HasLen, 0)
+}
+
+func (s *testNamespaceSuite) TestEnforceRegionLabelPlacement(c *C) {
+	c.Assert(s.tc.addLabelsStore(1, 1, map[string]string{"tier": "hot"}), IsNil)
+	c.Assert(s.tc.addLabelsStore(2, 1, map[string]string{"tier": "hot"}), IsNil)
+	c.Assert(s.tc.addLabelsStore(3, 3, map[string]string{"tier": "cold"}), IsNil)
+	c.Assert(s.tc.addLabelsStore(4, 1, map[string]string{"tier": "cold"}), IsNil)
+	c.Assert(s.tc.addLabelsStore(5, 0, map[string]string{"tier": "cold"}), IsNil)
+	for id := uint64(1); id <= 4; id++ {
+		s.classifier.setStore(id, "ns1")
+	}
+	s.classifier.setStore(5, "ns2")
+	c.Assert(s.tc.addLeaderRegion(1, 1, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 1, 2), IsNil)
+	c.Assert(s.tc.addLeaderRegion(3, 3, 4), IsNil)
+	for id := uint64(1); id <= 3; id++ {
+		s.classifier.setRegion(id, "ns1")
+	}
+	nsCfg := &config.NamespaceConfig{MaxReplicas: 2, RegionLabels: []*placement.RegionLabel{
+		{Key: "tier", Value: "cold", StartKey: []byte(fmt.Sprintf("%20d", 1)), EndKey: []byte(fmt.Sprintf("%20d", 2))},
+		{Key: "tier", Value: "cold", StartKey: []byte(fmt.Sprintf("%20d", 3)), EndKey: []byte(fmt.Sprintf("%20d", 4))},
+	}}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+
+	// The cold region on the hot store 1 is moved to the cold store 4 of the
+	// namespace.
+	op := nc.EnforceRegionLabelPlacement(s.tc.GetRegion(1))
+	c.Assert(op, NotNil)
+	c.Assert(op.Desc(), Equals, "enforce-region-label")
+	c.Assert(op.Step(0).(operator.AddLearner).ToStore, Equals, uint64(4))
+	c.Assert(op.Step(op.Len()-1).(operator.RemovePeer).FromStore, Equals, uint64(1))
+	// Regions without labels are not moved.
+	c.Assert(nc.EnforceRegionLabelPlacement(s.tc.GetRegion(2)), IsNil)
+	// Regions placed as their labels expect are not moved.
+	c.Assert(nc.EnforceRegionLabelPlacement(s.tc.GetRegion(3)), IsNil)
+
+	// No cold store to move to.
+	c.Assert(s.tc.addLeaderRegion(1, 1, 3, 4), IsNil)
+	c.Assert(nc.EnforceRegionLabelPlacement(s.tc.GetRegion(1)), IsNil)
+}
+
+func (s *testNamespaceSuite) TestBalanceNamespaceDomain(c *C) {
+	// store regionSize zone namespace
+	//     1         20   z1       ns1
+	//     2         20   z1       ns1
+	//     3         20   z2       ns1
+	//     4         20    -       ns1
+	s.opt.GetReplication().Load().LocationLabels = []string{"zone", "host"}
+	c.Assert(s.tc.addLabelsStore(1, 2, map[string]string{"zone": "z1", "host": "h1"}), IsNil)
+	c.Assert(s.tc.addLabelsStore(2, 2, map[string]string{"zone": "z1", "host": "h2"}), IsNil)
+	c.Assert(s.tc.addLabelsStore(3, 2, map[string]string{"zone": "z2", "host": "h3"}), IsNil)
+	c.Assert(s.tc.addRegionStore(4, 2), IsNil)
+	for id := uint64(1); id <= 4; id++ {
+		s.classifier.setStore(id, "ns1")
+	}
+	nsCfg := &config.NamespaceConfig{MaxReplicas: 1}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	for i := uint64(0); i < 8; i++ {
+		c.Assert(s.tc.addLeaderRegion(i+1, i/2+1), IsNil)
+		s.classifier.setRegion(i+1, "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetStoreDomain(1), Equals, "z1")
+	c.Assert(nc.GetStoreDomain(4), Equals, "")
+	c.Assert(nc.GetDomainLoad(), DeepEquals, map[string]int64{"z1": 40, "z2": 20})
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	storage := core.NewStorage(kv.NewMemoryKV())
+	brs, err := schedule.CreateScheduler("balance-region", oc, storage, nil)
+	c.Assert(err, IsNil)
+	bds, err := schedule.CreateScheduler("balance-namespace-domain", oc, storage, nil)
+	c.Assert(err, IsNil)
+	// The stores are balanced, but the domains are not.
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, brs), IsNil)
+	ops := scheduleByNamespace(s.tc, s.classifier, bds)
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].Kind()&operator.OpBalance, Equals, operator.OpBalance)
+	c.Assert(ops[0].Step(0).(operator.AddLearner).ToStore, Equals, uint64(3))
+	c.Assert(nc.GetStoreDomain(ops[0].Step(ops[0].Len()-1).(operator.RemovePeer).FromStore), Equals, "z1")
+
+	// The domains are balanced after a region is moved.
+	c.Assert(s.tc.addLeaderRegion(1, 3), IsNil)
+	c.Assert(nc.GetDomainLoad(), DeepEquals, map[string]int64{"z1": 30, "z2": 30})
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, bds), IsNil)
+}
+
+func (s *testNamespaceSuite) TestBalanceNamespaceLeaderRatio(c *C) {
+	// Store 1 holds all the leaders of the namespace regions.
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 100), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	for id := uint64(1); id <= 6; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetLeaderPeerRatio(1), Equals, 1.0)
+	c.Assert(nc.GetLeaderPeerRatio(2), Equals, 0.0)
+	c.Assert(nc.GetLeaderPeerRatio(4), Equals, 0.0)
+	// Only the peers of the namespace regions are counted.
+	leaders, peers := nc.GetLeaderPeerCounts()
+	c.Assert(leaders, DeepEquals, map[uint64]int{1: 6})
+	c.Assert(peers, DeepEquals, map[uint64]int{1: 6, 2: 6, 3: 6})
+
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	bls, err := schedule.CreateScheduler("balance-namespace-leader-ratio", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+	c.Assert(err, IsNil)
+	ops := scheduleByNamespace(s.tc, s.classifier, bls)
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].Kind()&operator.OpLeader, Equals, operator.OpLeader)
+	step := ops[0].Step(0).(operator.TransferLeader)
+	c.Assert(step.FromStore, Equals, uint64(1))
+
+	// The ratio of the source store is reduced after the transfer.
+	region := s.tc.GetRegion(ops[0].RegionID())
+	c.Assert(s.tc.putRegion(region.Clone(core.WithLeader(region.GetStorePeer(step.ToStore)))), IsNil)
+	c.Assert(nc.GetLeaderPeerRatio(1), Less, 1.0)
+	c.Assert(nc.GetLeaderPeerRatio(step.ToStore), Greater, 0.0)
+
+	// The ratios are balanced.
+	for id := uint64(1); id <= 6; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, (id-1)%3+1, id%3+1, (id+1)%3+1), IsNil)
+	}
+	c.Assert(nc.GetLeaderPeerRatio(1), Equals, nc.GetLeaderPeerRatio(2))
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, bls), IsNil)
+}
+
+func (s *testNamespaceSuite) TestPopulateNamespaceStore(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 10), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	for id := uint64(1); id <= 10; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	// The stores loaded when the cluster starts are not fresh.
+	s.tc.recordNamespaceStoresSeen(s.classifier, time.Time{})
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetFreshStores(), HasLen, 0)
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, err := schedule.CreateScheduler("populate-namespace-store", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+	c.Assert(err, IsNil)
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, sched), IsNil)
+
+	// Store 4 joins the namespace, and it is not fresh until seen.
+	c.Assert(s.tc.addRegionStore(4, 0), IsNil)
+	s.classifier.setStore(4, "ns1")
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetFreshStores(), HasLen, 0)
+	s.tc.recordNamespaceStoresSeen(s.classifier, time.Now())
+	c.Assert(nc.GetFreshStores(), DeepEquals, []uint64{4})
+
+	// The regions migrate peers onto the store one by one.
+	var steps int
+	for ; steps < 20; steps++ {
+		// Regions are sampled randomly, so it tries several times.
+		var ops []*operator.Operator
+		for i := 0; i < 10 && len(ops) == 0; i++ {
+			ops = scheduleByNamespace(s.tc, s.classifier, sched)
+		}
+		if len(ops) == 0 {
+			break
+		}
+		c.Assert(ops, HasLen, 1)
+		c.Assert(ops[0].Step(0).(operator.AddLearner).ToStore, Equals, uint64(4))
+		source := ops[0].Step(ops[0].Len() - 1).(operator.RemovePeer).FromStore
+		region := s.tc.GetRegion(ops[0].RegionID())
+		var storeIDs []uint64
+		for _, p := range region.GetPeers() {
+			if p.GetStoreId() != source {
+				storeIDs = append(storeIDs, p.GetStoreId())
+			}
+		}
+		c.Assert(s.tc.addLeaderRegion(region.GetID(), 4, storeIDs...), IsNil)
+		for id, delta := range map[uint64]int{source: -1, 4: 1} {
+			store := s.tc.GetStore(id)
+			c.Assert(s.tc.updateStore(id, core.SetRegionCount(store.GetRegionCount()+delta)), IsNil)
+		}
+	}
+	// The region counts of the stores differ by at most 1.
+	c.Assert(steps, Equals, 7)
+	c.Assert(s.tc.GetStore(4).GetRegionCount(), Equals, 7)
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.GetStore(id).GetRegionCount(), LessEqual, 8)
+	}
+
+	// A store is no longer fresh after the window.
+	c.Assert(s.tc.addRegionStore(5, 0), IsNil)
+	s.classifier.setStore(5, "ns1")
+	s.tc.recordNamespaceStoresSeen(s.classifier, time.Now().Add(-freshStoreWindow))
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetFreshStores(), HasLen, 0)
+}
+
+func (s *testNamespaceSuite) TestJoinThrottle(c *C) {
+	nsCfg := &config.NamespaceConfig{RegionScheduleLimit: 1, JoinThrottleTicks: 4}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 10), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	for id := uint64(1); id <= 10; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	sched, err := schedule.CreateScheduler("populate-namespace-store", oc, core.NewStorage(kv.NewMemoryKV()), nil)
+	c.Assert(err, IsNil)
+	s.tc.recordNamespaceStoresSeen(s.classifier, time.Time{})
+	c.Assert(scheduleByNamespace(s.tc, s.classifier, sched), IsNil)
+	// The stores seen first are not throttled.
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(nc.IsJoinThrottled(id), IsFalse)
+	}
+
+	// Stores 4 and 5 join the namespace at once. The operators are not
+	// applied, so that the stores keep being populated.
+	for id := uint64(4); id <= 5; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	s.tc.recordNamespaceStoresSeen(s.classifier, time.Now())
+	state := s.tc.getNamespaceState("ns1")
+	var targets []uint64
+	for tick := 1; tick <= 6; tick++ {
+		state.tickStoreJoins()
+		// The scheduler runs several times in a tick, but the peers are
+		// charged only when the operators are added.
+		ops := scheduleByNamespace(s.tc, s.classifier, sched)
+		c.Assert(scheduleByNamespace(s.tc, s.classifier, sched), HasLen, len(ops))
+		if len(ops) == 0 {
+			targets = append(targets, 0)
+			continue
+		}
+		c.Assert(ops, HasLen, 1)
+		s.tc.recordNamespaceStoreJoinReceived(s.classifier, ops)
+		targets = append(targets, ops[0].Step(0).(operator.AddLearner).ToStore)
+	}
+	// Each store receives 1 peer in the 4 throttle ticks, and the peers are
+	// moved freely after them.
+	c.Assert(targets[0]+targets[1], Equals, uint64(9))
+	c.Assert(targets[2:4], DeepEquals, []uint64{0, 0})
+	c.Assert(targets[4], Not(Equals), uint64(0))
+	c.Assert(targets[5], Not(Equals), uint64(0))
+
+	// A store joining later is throttled on its own.
+	for id := uint64(4); id <= 5; id++ {
+		c.Assert(s.tc.updateStore(id, core.SetRegionCount(5)), IsNil)
+	}
+	c.Assert(s.tc.addRegionStore(6, 0), IsNil)
+	s.classifier.setStore(6, "ns1")
+	s.tc.recordNamespaceStoresSeen(s.classifier, time.Now())
+	state.tickStoreJoins()
+	ops := scheduleByNamespace(s.tc, s.classifier, sched)
+	c.Assert(ops[0].Step(0).(operator.AddLearner).ToStore, Equals, uint64(6))
+	s.tc.recordNamespaceStoreJoinReceived(s.classifier, ops)
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.IsJoinThrottled(6), IsTrue)
+	c.Assert(nc.IsJoinThrottled(4), IsFalse)
+
+	// The throttling is disabled with 0 ticks.
+	nsCfg.JoinThrottleTicks = 0
+	c.Assert(nc.IsJoinThrottled(6), IsFalse)
+}
+
+func (s *testNamespaceSuite) TestDetectReplicaThrashing(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	s.classifier.setRegion(2, "ns2")
+	// churn adds a peer on store 4 and removes it for the times.
+	churn := func(regionID uint64, times int) {
+		for i := 0; i < times; i++ {
+			region := s.tc.GetRegion(regionID)
+			peer, _ := s.tc.AllocPeer(4)
+			added := region.Clone(core.WithAddPeer(peer), core.WithIncConfVer())
+			c.Assert(s.tc.processRegionHeartbeat(added), IsNil)
+			removed := added.Clone(core.WithRemoveStorePeer(4), core.WithIncConfVer())
+			c.Assert(s.tc.processRegionHeartbeat(removed), IsNil)
+		}
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.DetectReplicaThrashing(), HasLen, 0)
+
+	churn(1, 2)
+	churn(2, 2)
+	churn(3, 1)
+	// Regions of other namespaces are not reported.
+	c.Assert(nc.DetectReplicaThrashing(), DeepEquals, []uint64{1})
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns2").DetectReplicaThrashing(), DeepEquals, []uint64{2})
+	// Conf changes without peer changes are not counted.
+	c.Assert(s.tc.processRegionHeartbeat(s.tc.GetRegion(3).Clone(core.WithIncConfVer())), IsNil)
+	c.Assert(nc.DetectReplicaThrashing(), DeepEquals, []uint64{1})
+	churn(3, 1)
+	c.Assert(nc.DetectReplicaThrashing(), DeepEquals, []uint64{1, 3})
+
+	// The records are dropped once the region is removed.
+	s.tc.DropCacheRegion(3)
+	c.Assert(s.tc.namespaceStates.replicaChurn.changes.events, Not(HasKey), uint64(3))
+	c.Assert(nc.DetectReplicaThrashing(), DeepEquals, []uint64{1})
+}
+
+func (s *testNamespaceSuite) TestRegionEventWindow(c *C) {
+	now := time.Now()
+	w := newRegionEventWindow(time.Minute, 4)
+	// The expired events of a region are pruned when it is recorded again.
+	w.record(1, now.Add(-2*time.Minute), regionEvent{time: now.Add(-2 * time.Minute), to: 1})
+	w.record(1, now, regionEvent{time: now, to: 2})
+	c.Assert(w.events[1], DeepEquals, []regionEvent{{time: now, to: 2}})
+
+	// Beyond the capacity, the regions with the oldest events are evicted.
+	for id := uint64(2); id <= 5; id++ {
+		t := now.Add(time.Duration(id) * time.Second)
+		w.record(id, t, regionEvent{time: t, from: 1, to: 2})
+	}
+	c.Assert(w.events, HasLen, 3)
+	c.Assert(w.events, Not(HasKey), uint64(1))
+	c.Assert(w.events, Not(HasKey), uint64(2))
+
+	all := func([]regionEvent) bool { return true }
+	c.Assert(w.scan(now.Add(10*time.Second), all), DeepEquals, []uint64{3, 4, 5})
+	c.Assert(w.scan(now.Add(time.Hour), all), HasLen, 0)
+	c.Assert(w.events, HasLen, 0)
+}
+
+func (s *testNamespaceSuite) TestDetectLeaderOscillation(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	s.classifier.setRegion(2, "ns2")
+	// transfer moves the leader of the region to the stores in turn.
+	transfer := func(regionID uint64, storeIDs ...uint64) {
+		for _, storeID := range storeIDs {
+			region := s.tc.GetRegion(regionID)
+			c.Assert(s.tc.processRegionHeartbeat(region.Clone(core.WithLeader(region.GetStorePeer(storeID)))), IsNil)
+		}
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.DetectLeaderOscillation(), HasLen, 0)
+
+	// The leaders of regions 1 and 2 bounce between stores 1 and 2.
+	transfer(1, 2, 1, 2)
+	transfer(2, 2, 1, 2)
+	// The leader of region 3 moves around without going back.
+	transfer(3, 2, 3, 1)
+	// The leader of region 4 goes back once.
+	transfer(4, 2, 1)
+	// Regions of other namespaces are not reported.
+	c.Assert(nc.DetectLeaderOscillation(), DeepEquals, []uint64{1})
+	c.Assert(newNamespaceCluster(s.tc, s.classifier, "ns2").DetectLeaderOscillation(), DeepEquals, []uint64{2})
+	transfer(4, 2)
+	c.Assert(nc.DetectLeaderOscillation(), DeepEquals, []uint64{1, 4})
+
+	// The moves expire after the window.
+	detector := s.tc.namespaceStates.leaderOscillation
+	c.Assert(detector.getOscillatingRegions(time.Now().Add(leaderOscillationWindow+time.Second)), HasLen, 0)
+	c.Assert(nc.DetectLeaderOscillation(), HasLen, 0)
+
+	// The expired moves are pruned when the leader moves again, and the moves
+	// are dropped once the region is removed.
+	detector.recordLeaderMove(1, 1, 2, time.Now().Add(-leaderOscillationWindow-time.Second))
+	transfer(1, 1)
+	c.Assert(detector.moves.events[1], HasLen, 1)
+	s.tc.DropCacheRegion(1)
+	c.Assert(detector.moves.events, Not(HasKey), uint64(1))
+}
+
+func (s *testNamespaceSuite) TestRepairSLABreaches(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 1, 2), IsNil)
+	c.Assert(s.tc.addLeaderRegion(3, 1, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(4, 1), IsNil)
+	for id := uint64(1); id <= 4; id++ {
+		s.classifier.setRegion(id, "ns1")
+	}
+	region := s.tc.GetRegion(3)
+	s.tc.putRegion(region.Clone(core.WithDownPeers([]*pdpb.PeerStats{{Peer: region.GetStorePeer(3), DownSeconds: 60}})))
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	// No region is detected before recorded.
+	c.Assert(nc.GetRepairSLABreaches(0), HasLen, 0)
+
+	// Regions 2, 3 and 4 are detected under-replicated an hour ago, and
+	// recording them again keeps the time.
+	s.tc.recordNamespaceUnderReplicated(s.classifier, time.Now().Add(-time.Hour))
+	s.tc.recordNamespaceUnderReplicated(s.classifier, time.Now())
+	c.Assert(nc.GetRepairSLABreaches(time.Minute), DeepEquals, []uint64{2, 3, 4})
+	c.Assert(nc.GetRepairSLABreaches(2*time.Hour), HasLen, 0)
+
+	// The time is reset once the region is repaired.
+	c.Assert(s.tc.addLeaderRegion(2, 1, 2, 3), IsNil)
+	s.tc.recordNamespaceUnderReplicated(s.classifier, time.Now())
+	c.Assert(nc.GetRepairSLABreaches(time.Minute), DeepEquals, []uint64{3, 4})
+	c.Assert(s.tc.addLeaderRegion(2, 1, 2), IsNil)
+	s.tc.recordNamespaceUnderReplicated(s.classifier, time.Now())
+	c.Assert(nc.GetRepairSLABreaches(time.Minute), DeepEquals, []uint64{3, 4})
+}
+
+func (s *testNamespaceSuite) TestRecommendStoreCount(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	for id := uint64(1); id <= 10; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	recommend := func(nsCfg *config.NamespaceConfig) int {
+		nsCfg.Adjust(s.opt)
+		s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+		return newNamespaceCluster(s.tc, s.classifier, "ns1").RecommendStoreCount()
+	}
+	// The replicas of a region need distinct stores.
+	c.Assert(recommend(&config.NamespaceConfig{MaxReplicas: 3}), Equals, 3)
+	c.Assert(recommend(&config.NamespaceConfig{MaxReplicas: 5}), Equals, 5)
+
+	// Each store holds at most 6 replicas.
+	c.Assert(recommend(&config.NamespaceConfig{MaxReplicas: 3, MaxRegionsPerStore: 6}), Equals, 5)
+	c.Assert(recommend(&config.NamespaceConfig{MaxReplicas: 5, MaxRegionsPerStore: 6}), Equals, 9)
+
+	// Each store has 50MB capacity left for the namespace, and each region is
+	// 10MB.
+	reserved := make(map[uint64]uint64)
+	for id := uint64(1); id <= 4; id++ {
+		reserved[id] = 950 << 20
+	}
+	c.Assert(recommend(&config.NamespaceConfig{MaxReplicas: 3, StoreReservedCapacities: reserved}), Equals, 6)
+	c.Assert(recommend(&config.NamespaceConfig{MaxReplicas: 5, StoreReservedCapacities: reserved}), Equals, 10)
+}
+
+func (s *testNamespaceSuite) TestRegionSizeHistogram(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	s.classifier.setStore(1, "ns1")
+	for i, size := range []int64{1, 10, 11, 64, 96, 200} {
+		id := uint64(i + 1)
+		c.Assert(s.tc.addLeaderRegion(id, 1), IsNil)
+		c.Assert(s.tc.putRegion(s.tc.GetRegion(id).Clone(core.SetApproximateSize(size))), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(7, 1), IsNil)
+	s.classifier.setRegion(7, "ns2")
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+
+	c.Assert(nc.GetRegionSizeHistogram([]int64{96, 10, 64, 32}), DeepEquals, map[int64]int{
+		10:            2,
+		32:            1,
+		64:            1,
+		96:            1,
+		math.MaxInt64: 1,
+	})
+	c.Assert(nc.GetRegionSizeHistogram(nil), DeepEquals, map[int64]int{math.MaxInt64: 6})
+}
+
+func (s *testNamespaceSuite) TestStoresBySlowScore(c *C) {
+	for id := uint64(1); id <= 5; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	s.classifier.setStore(5, "ns2")
+	setLatency := func(storeID uint64, latencies ...uint64) {
+		stats := *s.tc.GetStore(storeID).GetStoreStats()
+		stats.OpLatencies = nil
+		for _, l := range latencies {
+			stats.OpLatencies = append(stats.OpLatencies, &pdpb.RecordPair{Key: "apply", Value: l})
+		}
+		c.Assert(s.tc.updateStore(storeID, core.SetStoreStats(&stats)), IsNil)
+	}
+	// The slow score is the max latency.
+	setLatency(1, 10, 300)
+	setLatency(2, 500)
+	setLatency(3, 300)
+	setLatency(5, 1000)
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	var ids []uint64
+	for _, store := range nc.GetStoresBySlowScore() {
+		ids = append(ids, store.GetID())
+	}
+	c.Assert(ids, DeepEquals, []uint64{2, 1, 3, 4})
+}
+
+func (s *testNamespaceSuite) TestDataLossRisk(c *C) {
+	s.opt.GetReplication().Load().LocationLabels = []string{"zone", "host"}
+	zones := map[uint64]string{1: "z1", 2: "z2", 3: "z3", 4: "z3", 5: "z1"}
+	for id := uint64(1); id <= 5; id++ {
+		c.Assert(s.tc.addLabelsStore(id, 0, map[string]string{"zone": zones[id], "host": fmt.Sprintf("h%d", id)}), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	// Store 5 is flapping.
+	for i := 0; i < storeFlappingThreshold; i++ {
+		c.Assert(s.tc.updateStore(5, core.SetLastHeartbeatTS(time.Now().Add(-time.Minute))), IsNil)
+		c.Assert(s.tc.handleStoreHeartbeat(&pdpb.StoreStats{StoreId: 5}), IsNil)
+	}
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.IsStoreFlapping(5), IsTrue)
+	c.Assert(nc.GetDataLossRisk(), Equals, 0.0)
+
+	// Two peers of region 2 are co-located in zone z3.
+	c.Assert(s.tc.addLeaderRegion(2, 2, 3, 4), IsNil)
+	s.classifier.setRegion(2, "ns1")
+	c.Assert(nc.GetDataLossRisk(), Equals, 1.0/3)
+
+	// Region 3 is under-replicated and one of its peers is on the flapping
+	// store, so it has lost the quorum of safe replicas.
+	c.Assert(s.tc.addLeaderRegion(3, 1, 5), IsNil)
+	s.classifier.setRegion(3, "ns1")
+	c.Assert(nc.GetDataLossRisk(), Equals, 1.0/3+1)
+}
+
+func (s *testNamespaceSuite) TestStoreHeartbeatIntervals(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	s.classifier.setStore(4, "ns2")
+	now := uint64(time.Now().Unix())
+	// Store 2 heartbeats slowly, and store 3 has not reported the interval.
+	for id, interval := range map[uint64]uint64{1: 10, 2: 60, 4: 10} {
+		stats := &pdpb.StoreStats{Interval: &pdpb.TimeInterval{StartTimestamp: now - interval, EndTimestamp: now}}
+		c.Assert(s.tc.updateStore(id, core.SetStoreStats(stats)), IsNil)
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetStoreHeartbeatIntervals(), DeepEquals, map[uint64]time.Duration{
+		1: 10 * time.Second,
+		2: time.Minute,
+	})
+}
+
+func (s *testNamespaceSuite) TestStaleHeartbeatRegionCount(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 0), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 0), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns2")
+	now := time.Now()
+	heartbeats := s.tc.namespaceStates.regionHeartbeats
+	reportAt := func(regionID, storeID uint64, t time.Time) {
+		c.Assert(s.tc.addLeaderRegion(regionID, storeID), IsNil)
+		if !t.IsZero() {
+			heartbeats.record(regionID, t)
+		}
+		s.classifier.setRegion(regionID, s.classifier.GetStoreNamespace(s.tc.GetStore(storeID)))
+	}
+	reportAt(1, 1, now)
+	reportAt(2, 1, now.Add(-10*time.Minute))
+	reportAt(3, 1, now.Add(-20*time.Minute))
+	// Never reported since PD started.
+	reportAt(4, 1, time.Time{})
+	// Stale, but in another namespace.
+	reportAt(5, 2, now.Add(-20*time.Minute))
+
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetStaleHeartbeatRegionCount(5*time.Minute), Equals, 2)
+	c.Assert(nc.GetStaleHeartbeatRegionCount(15*time.Minute), Equals, 1)
+	c.Assert(nc.GetStaleHeartbeatRegionCount(time.Hour), Equals, 0)
+
+	// A heartbeat which does not change the region still refreshes it.
+	c.Assert(s.tc.processRegionHeartbeat(s.tc.GetRegion(3)), IsNil)
+	c.Assert(nc.GetStaleHeartbeatRegionCount(5*time.Minute), Equals, 1)
+
+	// The region dropped from the cache is no longer tracked.
+	s.tc.DropCacheRegion(2)
+	_, ok := heartbeats.getLastHeartbeat(2)
+	c.Assert(ok, IsFalse)
+}
+
+func (s *testNamespaceSuite) TestSuggestOptimalPlacement(c *C) {
+	s.opt.GetReplication().Load().LocationLabels = []string{"zone", "host"}
+	zones := map[uint64]string{1: "z1", 2: "z1", 3: "z2", 4: "z3", 5: "z3"}
+	for id := uint64(1); id <= 5; id++ {
+		c.Assert(s.tc.addLabelsStore(id, int(id)*10, map[string]string{"zone": zones[id], "host": fmt.Sprintf("h%d", id)}), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	// Two peers of the region are in zone z1.
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	region := s.tc.GetRegion(1)
+
+	// Store 1 and 3 are kept, and store 4 in zone z3 has fewer regions than
+	// store 5.
+	suggested := nc.SuggestOptimalPlacement(region)
+	c.Assert(suggested, DeepEquals, []uint64{1, 3, 4})
+	var before, after []*core.StoreInfo
+	for _, id := range []uint64{1, 2, 3} {
+		before = append(before, s.tc.GetStore(id))
+	}
+	for _, id := range suggested {
+		after = append(after, s.tc.GetStore(id))
+	}
+	labels := nc.GetLocationLabels()
+	c.Assert(locationIsolation(after, labels), Greater, locationIsolation(before, labels))
+
+	// The region is placed well already.
+	c.Assert(s.tc.addLeaderRegion(1, 1, 3, 5), IsNil)
+	c.Assert(nc.SuggestOptimalPlacement(s.tc.GetRegion(1)), DeepEquals, []uint64{1, 3, 5})
+
+	// Store 4 and 5 are offline.
+	for _, id := range []uint64{4, 5} {
+		c.Assert(s.tc.updateStore(id, core.SetStoreState(metapb.StoreState_Offline)), IsNil)
+	}
+	nc = newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.SuggestOptimalPlacement(s.tc.GetRegion(1)), DeepEquals, []uint64{1, 2, 3})
+}
+
+func (s *testNamespaceSuite) TestStoreReservedCapacity(c *C) {
+	c.Assert(s.tc.addRegionStore(1, 10), IsNil)
+	c.Assert(s.tc.addRegionStore(2, 20), IsNil)
+	s.classifier.setStore(1, "ns1")
+	s.classifier.setStore(2, "ns1")
+	nsCfg := &config.NamespaceConfig{StoreReservedCapacities: map[uint64]uint64{1: 900 * (1 << 20)}}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetStoreReservedCapacity(1), Equals, uint64(900*(1<<20)))
+	c.Assert(nc.GetStoreReservedCapacity(2), Equals, uint64(0))
+
+	highSpaceRatio, lowSpaceRatio := nc.GetHighSpaceRatio(), nc.GetLowSpaceRatio()
+	raw1, raw2 := s.tc.GetStore(1), s.tc.GetStore(2)
+	c.Assert(raw1.RegionScore(highSpaceRatio, lowSpaceRatio, 0), Less, raw2.RegionScore(highSpaceRatio, lowSpaceRatio, 0))
+	store1, store2 := nc.GetStore(1), nc.GetStore(2)
+	c.Assert(store1.GetAvailable(), Equals, raw1.GetAvailable()-900*(1<<20))
+	c.Assert(store2.GetAvailable(), Equals, raw2.GetAvailable())
+	c.Assert(store1.IsLowSpace(lowSpaceRatio), IsTrue)
+	c.Assert(store1.RegionScore(highSpaceRatio, lowSpaceRatio, 0), Greater, store2.RegionScore(highSpaceRatio, lowSpaceRatio, 0))
+}
+
+func (s *testNamespaceSuite) TestConfigMismatchedRegions(c *C) {
+	for id := uint64(1); id <= 5; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		s.classifier.setRegion(id, "ns1")
+	}
+	nsCfg := &config.NamespaceConfig{MaxReplicas: 3}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetConfigMismatchedRegions(), HasLen, 0)
+
+	// The max replicas is changed to 5, and only region 3 is reconfigured.
+	nsCfg = &config.NamespaceConfig{MaxReplicas: 5}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	c.Assert(s.tc.addLeaderRegion(3, 1, 2, 3, 4, 5), IsNil)
+	regionIDs := func() []uint64 {
+		var ids []uint64
+		for _, r := range nc.GetConfigMismatchedRegions() {
+			ids = append(ids, r.GetID())
+		}
+		return ids
+	}
+	c.Assert(regionIDs(), DeepEquals, []uint64{1, 2})
+
+	// Region 3 is expected to have a learner by the rules.
+	nsCfg.PlacementRules = []*placement.Rule{
+		{GroupID: "pd", ID: "voter", Role: placement.Voter, Count: 3, StartKey: []byte(fmt.Sprintf("%20d", 3)), EndKey: []byte(fmt.Sprintf("%20d", 4))},
+		{GroupID: "pd", ID: "learner", Role: placement.Learner, Count: 1, StartKey: []byte(fmt.Sprintf("%20d", 3)), EndKey: []byte(fmt.Sprintf("%20d", 4))},
+	}
+	c.Assert(regionIDs(), DeepEquals, []uint64{1, 2, 3})
+	c.Assert(s.tc.addLeaderRegion(3, 1, 2, 3), IsNil)
+	c.Assert(s.tc.putRegion(s.tc.GetRegion(3).Clone(core.WithAddPeer(&metapb.Peer{Id: 100, StoreId: 4, IsLearner: true}))), IsNil)
+	c.Assert(regionIDs(), DeepEquals, []uint64{1, 2})
+}
+
+func (s *testNamespaceSuite) TestAntiAffinityViolations(c *C) {
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	// region stores
+	//      1 1, 2
+	//      2 2, 3
+	//      3 3, 4
+	//      4 1, 4
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2), IsNil)
+	c.Assert(s.tc.addLeaderRegion(2, 2, 3), IsNil)
+	c.Assert(s.tc.addLeaderRegion(3, 3, 4), IsNil)
+	c.Assert(s.tc.addLeaderRegion(4, 1, 4), IsNil)
+	for id := uint64(1); id <= 4; id++ {
+		s.classifier.setRegion(id, "ns1")
+	}
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.GetAntiAffinityViolations(), HasLen, 0)
+
+	nsCfg := &config.NamespaceConfig{AntiAffinityGroups: [][]uint64{{2, 1}, {1, 3}, {3, 4, 2}, {1, 2}}}
+	nsCfg.Adjust(s.opt)
+	s.opt.SetNS("ns1", config.NewNamespaceOption(nsCfg))
+	c.Assert(nc.GetAntiAffinityViolations(), DeepEquals, [][2]uint64{{1, 2}, {2, 3}, {3, 4}})
+
+	// Region 2 is moved away from store 2.
+	c.Assert(s.tc.addLeaderRegion(2, 4, 3), IsNil)
+	c.Assert(nc.GetAntiAffinityViolations(), DeepEquals, [][2]uint64{{2, 3}, {2, 4}, {3, 4}})
+}
+
+func (s *testNamespaceSuite) TestIncrementalScatter(c *C) {
+	for id := uint64(1); id <= 6; id++ {
+		c.Assert(s.tc.addRegionStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addRegionStore(7, 0), IsNil)
+	s.classifier.setStore(7, "ns2")
+	regionIDs := []uint64{11}
+	for id := uint64(1); id <= 10; id++ {
+		c.Assert(s.tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		s.classifier.setRegion(id, "ns1")
+		regionIDs = append(regionIDs, id)
+	}
+	// Region 11 is not in the namespace.
+	c.Assert(s.tc.addLeaderRegion(11, 7), IsNil)
+	s.classifier.setRegion(11, "ns2")
+
+	nc := newNamespaceCluster(s.tc, s.classifier, "ns1")
+	c.Assert(nc.StartIncrementalScatter(regionIDs), Equals, 10)
+	// Queued regions are not queued again.
+	c.Assert(nc.StartIncrementalScatter([]uint64{1, 2}), Equals, 0)
+	scattered, total := nc.GetScatterProgress()
+	c.Assert(scattered, Equals, 0)
+	c.Assert(total, Equals, 10)
+
+	scatterer := schedule.NewRegionScatterer(s.tc, s.classifier)
+	var ticks int
+	for scattered < total {
+		ops := nc.ScatterTick(scatterer)
+		c.Assert(len(ops), LessEqual, incrementalScatterBatchSize)
+		last := scattered
+		scattered, _ = nc.GetScatterProgress()
+		c.Assert(scattered-last, LessEqual, incrementalScatterBatchSize)
+		c.Assert(scattered, Greater, last)
+		ticks++
+	}
+	c.Assert(ticks, Equals, 3)
+	c.Assert(nc.ScatterTick(scatterer), HasLen, 0)
+
+	// A new scatter starts over once the queue is empty.
+	c.Assert(nc.StartIncrementalScatter([]uint64{1}), Equals, 1)
+	scattered, total = nc.GetScatterProgress()
+	c.Assert(scattered, Equals, 0)
+	c.Assert(total, Equals, 1)
+}
+
+type mockChainScheduler struct {
+	schedule.Scheduler
+	name  string
+	ops   []*operator.Operator
+	calls *[]string
+}
+
+func (s *mockChainScheduler) GetName() string { return s.name }
+
+func (s *mockChainScheduler) GetType() string { return s.name }
+
+func (s *mockChainScheduler) IsScheduleAllowed(cluster opt.Cluster) bool { return true }
+
+func (s *mockChainScheduler) Schedule(cluster opt.Cluster) []*operator.Operator {
+	*s.calls = append(*s.calls, s.name)
+	return s.ops
+}
+
+func (s *testNamespaceSuite) TestSchedulerChain(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(s.tc.addLeaderStore(id, 0), IsNil)
+		s.classifier.setStore(id, "ns1")
+	}
+	c.Assert(s.tc.addLeaderRegion(1, 1, 2), IsNil)
+	s.classifier.setRegion(1, "ns1")
+	region := s.tc.GetRegion(1)
+	leaderOp := operator.CreateTransferLeaderOperator("transfer-leader", region, 1, 2, operator.OpLeader)
+	regionOp, err := operator.CreateMovePeerOperator("move-peer", s.tc, region, operator.OpRegion, 2, 1, 3)
+	c.Assert(err, IsNil)
+
+	var calls []string
+	first := &mockChainScheduler{name: "first", ops: []*operator.Operator{leaderOp}, calls: &calls}
+	second := &mockChainScheduler{name: "second", ops: []*operator.Operator{regionOp}, calls: &calls}
+	chain := newSchedulerChain(first, second)
+	c.Assert(chain.GetName(), Equals, "scheduler-chain(first,second)")
+
+	// The second scheduler does not run if the first one yields operators.
+	ops := scheduleByNamespace(s.tc, s.classifier, chain)
+	c.Assert(ops, DeepEquals, []*operator.Operator{leaderOp})
+	c.Assert(calls, DeepEquals, []string{"first"})
+
+	// The second scheduler runs if the first one yields nothing.
+	calls, first.ops = nil, nil
+	ops = scheduleByNamespace(s.tc, s.classifier, chain)
+	c.Assert(ops, DeepEquals, []*operator.Operator{regionOp})
+	c.Assert(calls, DeepEquals, []string{"first", "second"})
+	c.Assert(s.tc.GetOperatorHistory("ns1", 1)[0].Scheduler, Equals, "second")
+
+	// The chain order is honored.
+	calls = nil
+	ops = scheduleByNamespace(s.tc, s.classifier, newSchedulerChain(second, first))
+	c.Assert(ops, DeepEquals, []*operator.Operator{regionOp})
+	c.Assert(calls, DeepEquals, []string{"second"})
+
+	// The chain is created by the types of its schedulers, and recreated by
+	// its encoded config.
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	storage := core.NewStorage(kv.NewMemoryKV())
+	sched, err := schedule.CreateScheduler(schedulerChainType, oc, storage, schedule.ConfigSliceDecoder(schedulerChainType, []string{"balance-leader", "balance-region"}))
+	c.Assert(err, IsNil)
+	c.Assert(sched.GetName(), Equals, "scheduler-chain(balance-leader-scheduler,balance-region-scheduler)")
+	data, err := sched.EncodeConfig()
+	c.Assert(err, IsNil)
+	sched, err = schedule.CreateScheduler(schedulerChainType, oc, storage, schedule.ConfigJSONDecoder(data))
+	c.Assert(err, IsNil)
+	c.Assert(sched.GetName(), Equals, "scheduler-chain(balance-leader-scheduler,balance-region-scheduler)")
+	// A chain has at least one scheduler and does not contain chains.
+	_, err = schedule.CreateScheduler(schedulerChainType, oc, storage, schedule.ConfigSliceDecoder(schedulerChainType, nil))
+	c.Assert(err, NotNil)
+	_, err = schedule.CreateScheduler(schedulerChainType, oc, storage, schedule.ConfigSliceDecoder(schedulerChainType, []string{schedulerChainType}))
+	c.Assert(err, NotNil)
+}
+
 type mapClassifer struct {
 	stores  map[uint64]string
 	regions map[uint64]string
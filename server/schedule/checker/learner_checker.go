@@ -14,24 +14,46 @@
 package checker
 
 import (
+	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/namespace"
 	"github.com/pingcap/pd/server/schedule/operator"
+	"github.com/pingcap/pd/server/schedule/opt"
 )
 
-// LearnerChecker ensures region has a learner will be promoted.
-type LearnerChecker struct{}
+// LearnerChecker ensures region has a learner will be promoted. The read
+// replicas of namespaces are kept as learners.
+type LearnerChecker struct {
+	cluster    opt.Cluster
+	classifier namespace.Classifier
+}
 
 // NewLearnerChecker creates a learner checker.
-func NewLearnerChecker() *LearnerChecker { return &LearnerChecker{} }
+func NewLearnerChecker(cluster opt.Cluster, classifier namespace.Classifier) *LearnerChecker {
+	return &LearnerChecker{cluster: cluster, classifier: classifier}
+}
 
 // Check verifies a region's namespace, creating an Operator if need.
 func (l *LearnerChecker) Check(region *core.RegionInfo) *operator.Operator {
+	readReplicas := getReadReplicas(l.cluster, l.classifier, region)
 	for _, p := range region.GetLearners() {
 		if region.GetPendingLearner(p.GetId()) != nil {
 			continue
 		}
+		if containsPeer(readReplicas, p) {
+			continue
+		}
 		op := operator.CreatePromoteLearnerOperator("promote-learner", region, p)
 		return op
 	}
 	return nil
 }
+
+func containsPeer(peers []*metapb.Peer, peer *metapb.Peer) bool {
+	for _, p := range peers {
+		if p.GetId() == peer.GetId() {
+			return true
+		}
+	}
+	return false
+}
@@ -26,6 +26,14 @@ import (
 	"go.uber.org/zap"
 )
 
+// The directions to merge regions preferred by namespaces. A region is merged
+// into the previous region if the left is preferred, or the next one if the
+// right is preferred, as long as the adjacent region can be the target.
+const (
+	mergeDirectionLeft  = "left"
+	mergeDirectionRight = "right"
+)
+
 // MergeChecker ensures region to merge with adjacent region when size is small
 type MergeChecker struct {
 	cluster    opt.Cluster
@@ -84,13 +92,15 @@ func (m *MergeChecker) Check(region *core.RegionInfo) []*operator.Operator {
 		return nil
 	}
 
-	// skip region has down peers or pending peers or learner peers
-	if len(region.GetDownPeers()) > 0 || len(region.GetPendingPeers()) > 0 || len(region.GetLearners()) > 0 {
+	// skip region has down peers or pending peers or learner peers, except
+	// the read replicas of its namespace
+	if len(region.GetDownPeers()) > 0 || len(region.GetPendingPeers()) > 0 ||
+		len(region.GetLearners()) > len(getReadReplicas(m.cluster, m.classifier, region)) {
 		checkerCounter.WithLabelValues("merge_checker", "special-peer").Inc()
 		return nil
 	}
 
-	if len(region.GetPeers()) != m.cluster.GetMaxReplicas() {
+	if len(region.GetVoters()) != m.cluster.GetMaxReplicas() {
 		checkerCounter.WithLabelValues("merge_checker", "abnormal-replica").Inc()
 		return nil
 	}
@@ -108,8 +118,17 @@ func (m *MergeChecker) Check(region *core.RegionInfo) []*operator.Operator {
 		target = next
 	}
 	if !m.cluster.IsOneWayMergeEnabled() && m.checkTarget(region, prev) { // allow a region can be merged by two ways.
-		if target == nil || prev.GetApproximateSize() < next.GetApproximateSize() { // pick smaller
+		switch m.cluster.GetOpt().GetMergeDirectionPreference(m.classifier.GetRegionNamespace(region)) {
+		case mergeDirectionLeft:
 			target = prev
+		case mergeDirectionRight:
+			if target == nil {
+				target = prev
+			}
+		default:
+			if target == nil || prev.GetApproximateSize() < next.GetApproximateSize() { // pick smaller
+				target = prev
+			}
 		}
 	}
 
@@ -135,6 +154,53 @@ func (m *MergeChecker) Check(region *core.RegionInfo) []*operator.Operator {
 func (m *MergeChecker) checkTarget(region, adjacent *core.RegionInfo) bool {
 	return adjacent != nil && !m.cluster.IsRegionHot(adjacent) &&
 		m.classifier.AllowMerge(region, adjacent) &&
-		len(adjacent.GetDownPeers()) == 0 && len(adjacent.GetPendingPeers()) == 0 && len(adjacent.GetLearners()) == 0 && // no special peer
-		len(adjacent.GetPeers()) == m.cluster.GetMaxReplicas() // peer count should equal
+		len(adjacent.GetDownPeers()) == 0 && len(adjacent.GetPendingPeers()) == 0 && // no special peer
+		len(adjacent.GetVoters()) == m.cluster.GetMaxReplicas() && // peer count should equal
+		m.hasSameReadReplicas(region, adjacent) &&
+		m.hasMergeCapacity(region, adjacent)
+}
+
+// hasSameReadReplicas checks if the learners of the regions are all read
+// replicas on the same stores, so that they are kept as learners after the
+// peers of the region are moved to the stores of the adjacent region.
+func (m *MergeChecker) hasSameReadReplicas(region, adjacent *core.RegionInfo) bool {
+	replicas := getReadReplicas(m.cluster, m.classifier, adjacent)
+	if len(adjacent.GetLearners()) != len(replicas) || len(region.GetLearners()) != len(replicas) {
+		return false
+	}
+	for _, p := range replicas {
+		if region.GetStoreLearner(p.GetStoreId()) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// hasMergeCapacity checks if the stores of the adjacent region can hold the
+// region, whose peers are moved to them before merging. The capacity reserved
+// by the namespace of the region is not available. Stores which have not
+// reported their capacities are not checked.
+func (m *MergeChecker) hasMergeCapacity(region, adjacent *core.RegionInfo) bool {
+	size := uint64(region.GetApproximateSize()) << 20
+	ns := m.classifier.GetRegionNamespace(region)
+	for _, p := range adjacent.GetPeers() {
+		if region.GetStorePeer(p.GetStoreId()) != nil {
+			continue
+		}
+		store := m.cluster.GetStore(p.GetStoreId())
+		if store == nil || store.GetCapacity() == 0 {
+			continue
+		}
+		available := store.GetAvailable()
+		if reserved := m.cluster.GetOpt().GetStoreReservedCapacity(ns, store.GetID()); reserved < available {
+			available -= reserved
+		} else {
+			available = 0
+		}
+		if available < size {
+			checkerCounter.WithLabelValues("merge_checker", "no-capacity").Inc()
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,140 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/namespace"
+	"github.com/pingcap/pd/server/schedule/filter"
+	"github.com/pingcap/pd/server/schedule/operator"
+	"github.com/pingcap/pd/server/schedule/opt"
+	"go.uber.org/zap"
+)
+
+const readReplicaCheckerName = "read-replica-checker"
+
+// ReadReplicaChecker ensures regions have the read replicas configured for
+// their namespaces. Read replicas are learners on the read replica stores of
+// the namespace.
+type ReadReplicaChecker struct {
+	cluster    opt.Cluster
+	classifier namespace.Classifier
+	filters    []filter.Filter
+}
+
+// NewReadReplicaChecker creates a read replica checker.
+func NewReadReplicaChecker(cluster opt.Cluster, classifier namespace.Classifier) *ReadReplicaChecker {
+	return &ReadReplicaChecker{
+		cluster:    cluster,
+		classifier: classifier,
+		filters: []filter.Filter{
+			filter.NewStateFilter(readReplicaCheckerName),
+			filter.NewStoreLimitFilter(readReplicaCheckerName),
+			filter.NewHealthFilter(readReplicaCheckerName),
+			filter.NewSnapshotCountFilter(readReplicaCheckerName),
+			filter.NewPendingPeerCountFilter(readReplicaCheckerName),
+		},
+	}
+}
+
+// Check adds a read replica to the region if it has fewer than the configured
+// count, or removes one if it has more.
+func (r *ReadReplicaChecker) Check(region *core.RegionInfo) *operator.Operator {
+	if r.classifier == nil {
+		return nil
+	}
+	ns := r.classifier.GetRegionNamespace(region)
+	count := int(r.cluster.GetOpt().GetReadReplicaCount(ns))
+	replicas := getReadReplicas(r.cluster, r.classifier, region)
+	if len(replicas) < count {
+		return r.addReadReplica(region, ns)
+	}
+	if len(replicas) > count {
+		return r.removeReadReplica(region, replicas)
+	}
+	return nil
+}
+
+// addReadReplica creates an operator that adds a learner to the read replica
+// store with the fewest regions.
+func (r *ReadReplicaChecker) addReadReplica(region *core.RegionInfo, ns string) *operator.Operator {
+	filters := append([]filter.Filter{
+		filter.NewExcludedFilter(readReplicaCheckerName, nil, region.GetStoreIds()),
+		filter.NewNamespaceFilter(readReplicaCheckerName, r.classifier, ns),
+	}, r.filters...)
+	var target *core.StoreInfo
+	for _, id := range r.cluster.GetOpt().GetReadReplicaStores(ns) {
+		store := r.cluster.GetStore(id)
+		if store == nil || filter.Target(r.cluster, store, filters) {
+			continue
+		}
+		if target == nil || store.GetRegionCount() < target.GetRegionCount() {
+			target = store
+		}
+	}
+	if target == nil {
+		checkerCounter.WithLabelValues("read_replica_checker", "no-target-store").Inc()
+		return nil
+	}
+	newPeer, err := r.cluster.AllocPeer(target.GetID())
+	if err != nil {
+		log.Warn("failed to allocate peer", zap.Uint64("store-id", target.GetID()), zap.Error(err))
+		return nil
+	}
+	checkerCounter.WithLabelValues("read_replica_checker", "new-operator").Inc()
+	return operator.CreateAddLearnerOperator("add-read-replica", region, newPeer.GetId(), target.GetID(), operator.OpReplica)
+}
+
+// removeReadReplica creates an operator that removes the read replica on the
+// store with the most regions.
+func (r *ReadReplicaChecker) removeReadReplica(region *core.RegionInfo, replicas []*metapb.Peer) *operator.Operator {
+	var source *core.StoreInfo
+	for _, p := range replicas {
+		store := r.cluster.GetStore(p.GetStoreId())
+		if store == nil {
+			continue
+		}
+		if source == nil || store.GetRegionCount() > source.GetRegionCount() {
+			source = store
+		}
+	}
+	if source == nil {
+		return nil
+	}
+	op, err := operator.CreateRemovePeerOperator("remove-read-replica", r.cluster, operator.OpReplica, region, source.GetID())
+	if err != nil {
+		checkerCounter.WithLabelValues("read_replica_checker", "create-operator-fail").Inc()
+		return nil
+	}
+	checkerCounter.WithLabelValues("read_replica_checker", "new-operator").Inc()
+	return op
+}
+
+// getReadReplicas returns the learners of the region on the read replica
+// stores of its namespace.
+func getReadReplicas(cluster opt.Cluster, classifier namespace.Classifier, region *core.RegionInfo) []*metapb.Peer {
+	if classifier == nil {
+		return nil
+	}
+	stores := cluster.GetOpt().GetReadReplicaStores(classifier.GetRegionNamespace(region))
+	var replicas []*metapb.Peer
+	for _, id := range stores {
+		if p := region.GetStoreLearner(id); p != nil {
+			replicas = append(replicas, p)
+		}
+	}
+	return replicas
+}
@@ -81,9 +81,16 @@ func (r *ReplicaChecker) Check(region *core.RegionInfo) *operator.Operator {
 		return op
 	}
 
-	if len(region.GetPeers()) < r.cluster.GetMaxReplicas() && r.cluster.IsMakeUpReplicaEnabled() {
-		log.Debug("region has fewer than max replicas", zap.Uint64("region-id", region.GetID()), zap.Int("peers", len(region.GetPeers())))
-		newPeer, _ := r.selectBestPeerToAddReplica(region, filter.NewStorageThresholdFilter(r.name))
+	if r.replicaCount(region) < r.cluster.GetMaxReplicas() && r.cluster.IsMakeUpReplicaEnabled() {
+		log.Debug("region has fewer than max replicas", zap.Uint64("region-id", region.GetID()), zap.Int("peers", r.replicaCount(region)))
+		newPeer, _ := r.selectBestPeerToAddReplica(region, filter.NewStorageThresholdFilter(r.name), r.newSpareStoreFilter(region), r.newZoneFilter(region))
+		if newPeer == nil {
+			newPeer, _ = r.selectBestPeerToAddReplica(region, filter.NewStorageThresholdFilter(r.name), r.newSpareStoreFilter(region))
+		}
+		if newPeer == nil {
+			// Spare stores are used only if there is no other choice.
+			newPeer, _ = r.selectBestPeerToAddReplica(region, filter.NewStorageThresholdFilter(r.name))
+		}
 		if newPeer == nil {
 			checkerCounter.WithLabelValues("replica_checker", "no-target-store").Inc()
 			return nil
@@ -110,7 +117,14 @@ func (r *ReplicaChecker) Check(region *core.RegionInfo) *operator.Operator {
 		return op
 	}
 
-	return r.checkBestReplacement(region)
+	if op := r.checkZonePlacement(region); op != nil {
+		checkerCounter.WithLabelValues("replica_checker", "new-operator").Inc()
+		return op
+	}
+	if op := r.checkBestReplacement(region); op != nil {
+		return op
+	}
+	return r.checkLeaderZone(region)
 }
 
 // SelectBestReplacementStore returns a store id that to be used to replace the old peer and distinct score.
@@ -120,6 +134,57 @@ func (r *ReplicaChecker) SelectBestReplacementStore(region *core.RegionInfo, old
 	return r.selectBestStoreToAddReplica(newRegion, filters...)
 }
 
+// replicaCount returns the number of peers of the region, excluding the read
+// replicas of its namespace.
+func (r *ReplicaChecker) replicaCount(region *core.RegionInfo) int {
+	return len(region.GetPeers()) - len(getReadReplicas(r.cluster, r.classifier, region))
+}
+
+// isReadReplica checks if the peer is a read replica of the region's namespace.
+func (r *ReplicaChecker) isReadReplica(region *core.RegionInfo, peer *metapb.Peer) bool {
+	return containsPeer(getReadReplicas(r.cluster, r.classifier, region), peer)
+}
+
+// newSpareStoreFilter creates a filter that filters the spare stores of the
+// region's namespace out as targets.
+func (r *ReplicaChecker) newSpareStoreFilter(region *core.RegionInfo) filter.Filter {
+	spares := make(map[uint64]struct{})
+	if r.classifier != nil {
+		for _, id := range r.cluster.GetOpt().GetSpareStores(r.classifier.GetRegionNamespace(region)) {
+			spares[id] = struct{}{}
+		}
+	}
+	return filter.NewExcludedFilter(r.name, nil, spares)
+}
+
+// getZoneLabel returns the location label of the availability zones of the
+// region's namespace.
+func (r *ReplicaChecker) getZoneLabel(region *core.RegionInfo) string {
+	if r.classifier == nil {
+		return ""
+	}
+	return r.cluster.GetOpt().GetZoneLabel(r.classifier.GetRegionNamespace(region))
+}
+
+// newZoneFilter creates a filter that filters the stores in the zones which
+// already have peers of the region out as targets, if the region's namespace
+// sets the zone label.
+func (r *ReplicaChecker) newZoneFilter(region *core.RegionInfo) filter.Filter {
+	excluded := make(map[uint64]struct{})
+	if label := r.getZoneLabel(region); label != "" {
+		zones := make(map[string]struct{})
+		for _, s := range r.cluster.GetRegionStores(region) {
+			zones[s.GetLabelValue(label)] = struct{}{}
+		}
+		for _, s := range r.cluster.GetStores() {
+			if _, ok := zones[s.GetLabelValue(label)]; ok {
+				excluded[s.GetID()] = struct{}{}
+			}
+		}
+	}
+	return filter.NewExcludedFilter(r.name, nil, excluded)
+}
+
 // selectBestPeerToAddReplica returns a new peer that to be used to add a replica and distinct score.
 func (r *ReplicaChecker) selectBestPeerToAddReplica(region *core.RegionInfo, filters ...filter.Filter) (*metapb.Peer, float64) {
 	storeID, score := r.selectBestStoreToAddReplica(region, filters...)
@@ -155,11 +220,18 @@ func (r *ReplicaChecker) selectBestStoreToAddReplica(region *core.RegionInfo, fi
 	return target.GetID(), core.DistinctScore(r.cluster.GetLocationLabels(), regionStores, target)
 }
 
-// selectWorstPeer returns the worst peer in the region.
+// selectWorstPeer returns the worst peer in the region. The read replicas are
+// never selected.
 func (r *ReplicaChecker) selectWorstPeer(region *core.RegionInfo) (*metapb.Peer, float64) {
 	regionStores := r.cluster.GetRegionStores(region)
+	candidates := make([]*core.StoreInfo, 0, len(regionStores))
+	for _, store := range regionStores {
+		if !r.isReadReplica(region, region.GetStorePeer(store.GetID())) {
+			candidates = append(candidates, store)
+		}
+	}
 	s := selector.NewReplicaSelector(regionStores, r.cluster.GetLocationLabels(), r.filters...)
-	worstStore := s.SelectSource(r.cluster, regionStores)
+	worstStore := s.SelectSource(r.cluster, candidates)
 	if worstStore == nil {
 		log.Debug("no worst store", zap.Uint64("region-id", region.GetID()))
 		return nil, 0
@@ -174,7 +246,8 @@ func (r *ReplicaChecker) checkDownPeer(region *core.RegionInfo) *operator.Operat
 
 	for _, stats := range region.GetDownPeers() {
 		peer := stats.GetPeer()
-		if peer == nil {
+		// The read replicas are maintained by the read replica checker.
+		if peer == nil || r.isReadReplica(region, peer) {
 			continue
 		}
 		storeID := peer.GetStoreId()
@@ -200,12 +273,15 @@ func (r *ReplicaChecker) checkOfflinePeer(region *core.RegionInfo) *operator.Ope
 		return nil
 	}
 
-	// just skip learner
-	if len(region.GetLearners()) != 0 {
+	// just skip learner, except the read replicas.
+	if len(region.GetLearners()) != len(getReadReplicas(r.cluster, r.classifier, region)) {
 		return nil
 	}
 
 	for _, peer := range region.GetPeers() {
+		if r.isReadReplica(region, peer) {
+			continue
+		}
 		storeID := peer.GetStoreId()
 		store := r.cluster.GetStore(storeID)
 		if store == nil {
@@ -222,6 +298,87 @@ func (r *ReplicaChecker) checkOfflinePeer(region *core.RegionInfo) *operator.Ope
 	return nil
 }
 
+// checkZonePlacement moves a voter of the region out of a zone which has more
+// than one voter of the region, to a zone without any peer of it. Followers
+// are moved before the leader.
+func (r *ReplicaChecker) checkZonePlacement(region *core.RegionInfo) *operator.Operator {
+	label := r.getZoneLabel(region)
+	if label == "" {
+		return nil
+	}
+	voters := region.GetVoters()
+	counts := make(map[string]int)
+	for _, p := range voters {
+		if s := r.cluster.GetStore(p.GetStoreId()); s != nil {
+			counts[s.GetLabelValue(label)]++
+		}
+	}
+	var oldPeer *metapb.Peer
+	for _, p := range voters {
+		s := r.cluster.GetStore(p.GetStoreId())
+		if s == nil || counts[s.GetLabelValue(label)] < 2 {
+			continue
+		}
+		if oldPeer == nil || oldPeer.GetId() == region.GetLeader().GetId() {
+			oldPeer = p
+		}
+	}
+	if oldPeer == nil {
+		return nil
+	}
+	storeID, _ := r.SelectBestReplacementStore(region, oldPeer, filter.NewStorageThresholdFilter(r.name), r.newSpareStoreFilter(region), r.newZoneFilter(region))
+	if storeID == 0 {
+		checkerCounter.WithLabelValues("replica_checker", "no-zone-store").Inc()
+		return nil
+	}
+	newPeer, err := r.cluster.AllocPeer(storeID)
+	if err != nil {
+		return nil
+	}
+	op, err := operator.CreateMovePeerOperator("move-to-missing-zone", r.cluster, region, operator.OpReplica, oldPeer.GetStoreId(), newPeer.GetStoreId(), newPeer.GetId())
+	if err != nil {
+		checkerCounter.WithLabelValues("replica_checker", "create-operator-fail").Inc()
+		return nil
+	}
+	return op
+}
+
+// checkLeaderZone transfers the leader of the region to a follower in the
+// preferred leader zone of its namespace, if the leader is out of the zone.
+// The follower on the store with the fewest leaders is preferred.
+func (r *ReplicaChecker) checkLeaderZone(region *core.RegionInfo) *operator.Operator {
+	label := r.getZoneLabel(region)
+	if label == "" {
+		return nil
+	}
+	zone := r.cluster.GetOpt().GetPreferredLeaderZone(r.classifier.GetRegionNamespace(region))
+	leaderStore := r.cluster.GetStore(region.GetLeader().GetStoreId())
+	if zone == "" || leaderStore == nil || leaderStore.GetLabelValue(label) == zone {
+		return nil
+	}
+	filters := []filter.Filter{filter.StoreStateFilter{ActionScope: r.name, TransferLeader: true}}
+	var target *core.StoreInfo
+	for _, s := range r.cluster.GetFollowerStores(region) {
+		if s.GetLabelValue(label) != zone || filter.Target(r.cluster, s, filters) {
+			continue
+		}
+		p := region.GetStorePeer(s.GetID())
+		if region.GetDownPeer(p.GetId()) != nil || region.GetPendingPeer(p.GetId()) != nil {
+			continue
+		}
+		if target == nil || s.GetLeaderCount() < target.GetLeaderCount() ||
+			(s.GetLeaderCount() == target.GetLeaderCount() && s.GetID() < target.GetID()) {
+			target = s
+		}
+	}
+	if target == nil {
+		checkerCounter.WithLabelValues("replica_checker", "no-zone-leader").Inc()
+		return nil
+	}
+	checkerCounter.WithLabelValues("replica_checker", "new-operator").Inc()
+	return operator.CreateTransferLeaderOperator("transfer-leader-to-preferred-zone", region, leaderStore.GetID(), target.GetID(), operator.OpLeader)
+}
+
 func (r *ReplicaChecker) checkBestReplacement(region *core.RegionInfo) *operator.Operator {
 	if !r.cluster.IsLocationReplacementEnabled() {
 		return nil
@@ -232,7 +389,7 @@ func (r *ReplicaChecker) checkBestReplacement(region *core.RegionInfo) *operator
 		checkerCounter.WithLabelValues("replica_checker", "all-right").Inc()
 		return nil
 	}
-	storeID, newScore := r.SelectBestReplacementStore(region, oldPeer, filter.NewStorageThresholdFilter(r.name))
+	storeID, newScore := r.SelectBestReplacementStore(region, oldPeer, filter.NewStorageThresholdFilter(r.name), r.newSpareStoreFilter(region))
 	if storeID == 0 {
 		checkerCounter.WithLabelValues("replica_checker", "no-replacement-store").Inc()
 		return nil
@@ -259,7 +416,7 @@ func (r *ReplicaChecker) checkBestReplacement(region *core.RegionInfo) *operator
 func (r *ReplicaChecker) fixPeer(region *core.RegionInfo, peer *metapb.Peer, status string) *operator.Operator {
 	removeExtra := fmt.Sprintf("remove-extra-%s-replica", status)
 	// Check the number of replicas first.
-	if len(region.GetPeers()) > r.cluster.GetMaxReplicas() {
+	if r.replicaCount(region) > r.cluster.GetMaxReplicas() {
 		op, err := operator.CreateRemovePeerOperator(removeExtra, r.cluster, operator.OpReplica, region, peer.GetStoreId())
 		if err != nil {
 			reason := fmt.Sprintf("%s-fail", removeExtra)
@@ -269,7 +426,15 @@ func (r *ReplicaChecker) fixPeer(region *core.RegionInfo, peer *metapb.Peer, sta
 		return op
 	}
 
-	storeID, _ := r.SelectBestReplacementStore(region, peer, filter.NewStorageThresholdFilter(r.name))
+	storeID, _ := r.SelectBestReplacementStore(region, peer, filter.NewStorageThresholdFilter(r.name), r.newSpareStoreFilter(region),
+		r.newZoneFilter(region.Clone(core.WithRemoveStorePeer(peer.GetStoreId()))))
+	if storeID == 0 {
+		storeID, _ = r.SelectBestReplacementStore(region, peer, filter.NewStorageThresholdFilter(r.name), r.newSpareStoreFilter(region))
+	}
+	if storeID == 0 {
+		// Spare stores are used only if there is no other choice.
+		storeID, _ = r.SelectBestReplacementStore(region, peer, filter.NewStorageThresholdFilter(r.name))
+	}
 	if storeID == 0 {
 		reason := fmt.Sprintf("no-store-%s", status)
 		checkerCounter.WithLabelValues("replica_checker", reason).Inc()
@@ -25,24 +25,26 @@ import (
 
 // CheckerController is used to manage all checkers.
 type CheckerController struct {
-	cluster          opt.Cluster
-	opController     *OperatorController
-	learnerChecker   *checker.LearnerChecker
-	replicaChecker   *checker.ReplicaChecker
-	namespaceChecker *checker.NamespaceChecker
-	mergeChecker     *checker.MergeChecker
+	cluster            opt.Cluster
+	opController       *OperatorController
+	learnerChecker     *checker.LearnerChecker
+	replicaChecker     *checker.ReplicaChecker
+	readReplicaChecker *checker.ReadReplicaChecker
+	namespaceChecker   *checker.NamespaceChecker
+	mergeChecker       *checker.MergeChecker
 }
 
 // NewCheckerController create a new CheckerController.
 // TODO: isSupportMerge should be removed.
 func NewCheckerController(ctx context.Context, cluster opt.Cluster, classifier namespace.Classifier, opController *OperatorController) *CheckerController {
 	return &CheckerController{
-		cluster:          cluster,
-		opController:     opController,
-		learnerChecker:   checker.NewLearnerChecker(),
-		replicaChecker:   checker.NewReplicaChecker(cluster, classifier),
-		namespaceChecker: checker.NewNamespaceChecker(cluster, classifier),
-		mergeChecker:     checker.NewMergeChecker(ctx, cluster, classifier),
+		cluster:            cluster,
+		opController:       opController,
+		learnerChecker:     checker.NewLearnerChecker(cluster, classifier),
+		replicaChecker:     checker.NewReplicaChecker(cluster, classifier),
+		readReplicaChecker: checker.NewReadReplicaChecker(cluster, classifier),
+		namespaceChecker:   checker.NewNamespaceChecker(cluster, classifier),
+		mergeChecker:       checker.NewMergeChecker(ctx, cluster, classifier),
 	}
 }
 
@@ -70,6 +72,9 @@ func (c *CheckerController) CheckRegion(region *core.RegionInfo) (bool, []*opera
 		if op := c.replicaChecker.Check(region); op != nil {
 			return checkerIsBusy, []*operator.Operator{op}
 		}
+		if op := c.readReplicaChecker.Check(region); op != nil {
+			return checkerIsBusy, []*operator.Operator{op}
+		}
 	}
 	if c.mergeChecker != nil && opController.OperatorCount(operator.OpMerge) < c.cluster.GetMergeScheduleLimit() {
 		checkerIsBusy = false
@@ -0,0 +1,105 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"sort"
+
+	"github.com/pingcap/pd/server/schedule/operator"
+)
+
+// namespaceQueueCapacity is the max number of operator groups queued for a
+// namespace. The least urgent ones are dropped if a queue is full.
+const namespaceQueueCapacity = 16
+
+// operatorUrgency ranks how urgent the operator is. The priority level is
+// compared first, then repair operators come before balance operators, and
+// merge operators come last.
+func operatorUrgency(op *operator.Operator) int {
+	rank := 1
+	if op.Kind()&operator.OpReplica != 0 {
+		rank = 2
+	} else if op.Kind()&operator.OpMerge != 0 {
+		rank = 0
+	}
+	return int(op.GetPriorityLevel())*3 + rank
+}
+
+// namespaceQueue holds the operator groups which exceed the max concurrent
+// operators of a namespace, in descending order of urgency. A group has more
+// than one operator only for merging, and they must be added together.
+type namespaceQueue struct {
+	groups [][]*operator.Operator
+}
+
+// push puts the operator group into the queue. A queued group of the same
+// region is replaced. It returns the group dropped since the queue is full,
+// which may be the pushed one.
+func (q *namespaceQueue) push(ops []*operator.Operator) []*operator.Operator {
+	for i, g := range q.groups {
+		if g[0].RegionID() == ops[0].RegionID() {
+			q.groups = append(q.groups[:i], q.groups[i+1:]...)
+			break
+		}
+	}
+	q.groups = append(q.groups, ops)
+	sort.SliceStable(q.groups, func(i, j int) bool {
+		return operatorUrgency(q.groups[i][0]) > operatorUrgency(q.groups[j][0])
+	})
+	if len(q.groups) <= namespaceQueueCapacity {
+		return nil
+	}
+	dropped := q.groups[len(q.groups)-1]
+	q.groups = q.groups[:len(q.groups)-1]
+	return dropped
+}
+
+// peek returns the most urgent group, or nil if the queue is empty.
+func (q *namespaceQueue) peek() []*operator.Operator {
+	if len(q.groups) == 0 {
+		return nil
+	}
+	return q.groups[0]
+}
+
+// pop removes the most urgent group.
+func (q *namespaceQueue) pop() {
+	q.groups = q.groups[1:]
+}
+
+// remove removes the groups which have an operator of the region, and
+// returns the removed operators.
+func (q *namespaceQueue) remove(regionID uint64) []*operator.Operator {
+	var removed []*operator.Operator
+	groups := q.groups[:0]
+	for _, g := range q.groups {
+		hit := false
+		for _, op := range g {
+			if op.RegionID() == regionID {
+				hit = true
+			}
+		}
+		if hit {
+			removed = append(removed, g...)
+		} else {
+			groups = append(groups, g)
+		}
+	}
+	q.groups = groups
+	return removed
+}
+
+func (q *namespaceQueue) len() int {
+	return len(q.groups)
+}
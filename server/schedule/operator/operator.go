@@ -55,6 +55,27 @@ type Cluster interface {
 	AllocPeer(storeID uint64) (*metapb.Peer, error)
 }
 
+// ErrExceedMaxOperatorSteps is returned when the operator to create has more
+// steps than the cluster allows.
+var ErrExceedMaxOperatorSteps = errors.New("the operator exceeds the max operator steps")
+
+// stepLimitCluster is implemented by clusters which limit the number of steps
+// of an operator, e.g. the ones of a namespace. 0 means no limit.
+type stepLimitCluster interface {
+	GetMaxOperatorSteps() uint64
+}
+
+// checkMaxOperatorSteps returns ErrExceedMaxOperatorSteps if the cluster
+// limits the number of steps of an operator and the steps exceed it.
+func checkMaxOperatorSteps(cluster Cluster, steps []OpStep) error {
+	if c, ok := cluster.(stepLimitCluster); ok {
+		if limit := c.GetMaxOperatorSteps(); limit > 0 && uint64(len(steps)) > limit {
+			return ErrExceedMaxOperatorSteps
+		}
+	}
+	return nil
+}
+
 // OpInfluence records the influence of the cluster.
 type OpInfluence struct {
 	StoresInfluence map[uint64]*StoreInfluence
@@ -765,6 +786,9 @@ func CreateMoveRegionOperator(desc string, cluster Cluster, region *core.RegionI
 	if err != nil {
 		return nil, err
 	}
+	if err := checkMaxOperatorSteps(cluster, steps); err != nil {
+		return nil, err
+	}
 	kind |= mvkind
 	brief := fmt.Sprintf("mv region: stores %v to %v", u64Set(region.GetStoreIds()), u64Set(storeIDs))
 	return NewOperator(desc, brief, region.GetID(), region.GetRegionEpoch(), kind, steps...), nil
@@ -893,6 +917,9 @@ func CreateMovePeerOperator(desc string, cluster Cluster, region *core.RegionInf
 	}
 	st := CreateAddPeerSteps(newStore, peerID)
 	steps = append(st, steps...)
+	if err := checkMaxOperatorSteps(cluster, steps); err != nil {
+		return nil, err
+	}
 	brief := fmt.Sprintf("mv peer: store %v to %v", oldStore, newStore)
 	return NewOperator(desc, brief, region.GetID(), region.GetRegionEpoch(), removeKind|kind|OpRegion, steps...), nil
 }
@@ -907,6 +934,9 @@ func CreateOfflinePeerOperator(desc string, cluster Cluster, region *core.Region
 	st := CreateAddPeerSteps(newStore, peerID)
 	steps = append(steps, st...)
 	steps = append(steps, RemovePeer{FromStore: oldStore})
+	if err := checkMaxOperatorSteps(cluster, steps); err != nil {
+		return nil, err
+	}
 	brief := fmt.Sprintf("mv peer: store %v to %v", oldStore, newStore)
 	return NewOperator(desc, brief, region.GetID(), region.GetRegionEpoch(), kind|OpRegion, steps...), nil
 }
@@ -920,6 +950,9 @@ func CreateMoveLeaderOperator(desc string, cluster Cluster, region *core.RegionI
 	st := CreateAddPeerSteps(newStore, peerID)
 	st = append(st, TransferLeader{ToStore: newStore, FromStore: oldStore})
 	steps = append(st, steps...)
+	if err := checkMaxOperatorSteps(cluster, steps); err != nil {
+		return nil, err
+	}
 	brief := fmt.Sprintf("mv leader: store %v to %v", oldStore, newStore)
 	return NewOperator(desc, brief, region.GetID(), region.GetRegionEpoch(), removeKind|kind|OpLeader|OpRegion, steps...), nil
 }
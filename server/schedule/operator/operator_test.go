@@ -267,3 +267,32 @@ func (s *testOperatorSuite) TestOperatorKind(c *C) {
 	_, err = ParseOperatorKind("foobar")
 	c.Assert(err, NotNil)
 }
+
+type stepLimitedCluster struct {
+	*mockcluster.Cluster
+	maxSteps uint64
+}
+
+func (c *stepLimitedCluster) GetMaxOperatorSteps() uint64 {
+	return c.maxSteps
+}
+
+func (s *testOperatorSuite) TestMaxOperatorSteps(c *C) {
+	region := s.newTestRegion(1, 1, [2]uint64{1, 1}, [2]uint64{2, 2})
+	cluster := &stepLimitedCluster{Cluster: s.cluster}
+
+	// 0 means no limit.
+	op, err := CreateMovePeerOperator("test", cluster, region, OpAdmin, 1, 3, 3)
+	c.Assert(err, IsNil)
+	steps := uint64(op.Len())
+
+	cluster.maxSteps = steps
+	_, err = CreateMovePeerOperator("test", cluster, region, OpAdmin, 1, 3, 3)
+	c.Assert(err, IsNil)
+
+	cluster.maxSteps = steps - 1
+	_, err = CreateMovePeerOperator("test", cluster, region, OpAdmin, 1, 3, 3)
+	c.Assert(err, Equals, ErrExceedMaxOperatorSteps)
+	_, err = CreateMoveRegionOperator("test", cluster, region, OpAdmin, map[uint64]struct{}{3: {}, 4: {}})
+	c.Assert(err, Equals, ErrExceedMaxOperatorSteps)
+}
@@ -18,6 +18,7 @@ import (
 	"container/list"
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -30,6 +31,7 @@ import (
 	"github.com/pingcap/log"
 	"github.com/pingcap/pd/pkg/cache"
 	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/namespace"
 	"github.com/pingcap/pd/server/schedule/operator"
 	"github.com/pingcap/pd/server/schedule/opt"
 	"go.uber.org/zap"
@@ -69,9 +71,36 @@ type OperatorController struct {
 	opRecords *OperatorRecords
 	// TODO: Need to clean up the unused store ID.
 	storesLimit     map[uint64]*ratelimit.Bucket
+	classifier      namespace.Classifier
 	wop             WaitingOperator
 	wopStatus       *WaitingOperatorStatus
 	opNotifierQueue operatorQueue
+	// nsQueues holds the waiting operators which exceed the max concurrent
+	// operators of their namespaces, keyed by namespace.
+	nsQueues map[string]*namespaceQueue
+	// migrationLimits limits the bytes per second of region data moved in
+	// each namespace, keyed by namespace.
+	migrationLimits map[string]migrationLimit
+	// migrationClock is the clock the migration limits are refilled by. The
+	// real clock is used if it is nil.
+	migrationClock ratelimit.Clock
+	// migratingBytes records the bytes of region data being moved by the
+	// running operators, keyed by region ID.
+	migratingBytes map[uint64]namespaceMigration
+}
+
+// migrationLimit limits the bytes per second of region data moved in a
+// namespace.
+type migrationLimit struct {
+	rate   uint64
+	bucket *ratelimit.Bucket
+}
+
+// namespaceMigration is the bytes of region data moved by an operator in a
+// namespace.
+type namespaceMigration struct {
+	namespace string
+	bytes     int64
 }
 
 // NewOperatorController creates a OperatorController.
@@ -88,9 +117,20 @@ func NewOperatorController(ctx context.Context, cluster opt.Cluster, hbStreams H
 		wop:             NewRandBuckets(),
 		wopStatus:       NewWaitingOperatorStatus(),
 		opNotifierQueue: make(operatorQueue, 0),
+		nsQueues:        make(map[string]*namespaceQueue),
+		migrationLimits: make(map[string]migrationLimit),
+		migratingBytes:  make(map[uint64]namespaceMigration),
 	}
 }
 
+// SetNamespaceClassifier sets the classifier used to limit the number of
+// operators of each namespace. The limits are not checked if it is not set.
+func (oc *OperatorController) SetNamespaceClassifier(classifier namespace.Classifier) {
+	oc.Lock()
+	defer oc.Unlock()
+	oc.classifier = classifier
+}
+
 // Ctx returns a context which will be canceled once RaftCluster is stopped.
 // For now, it is only used to control the lifetime of TTL cache in schedulers.
 func (oc *OperatorController) Ctx() context.Context {
@@ -250,7 +290,7 @@ func (oc *OperatorController) AddOperator(ops ...*operator.Operator) bool {
 	oc.Lock()
 	defer oc.Unlock()
 
-	if oc.exceedStoreLimit(ops...) || !oc.checkAddOperator(ops...) {
+	if oc.exceedStoreLimit(ops...) || oc.exceedNamespaceLimit(ops...) || !oc.checkAddOperator(ops...) {
 		for _, op := range ops {
 			operatorCounter.WithLabelValues(op.Desc(), "cancel").Inc()
 			oc.opRecords.Put(op, pdpb.OperatorStatus_CANCEL)
@@ -263,10 +303,19 @@ func (oc *OperatorController) AddOperator(ops ...*operator.Operator) bool {
 	return true
 }
 
-// PromoteWaitingOperator promotes operators from waiting operators.
+// PromoteWaitingOperator promotes operators from waiting operators. The
+// operators queued for namespaces are promoted first, the most urgent ones
+// go first once their namespaces are below the limits. Waiting operators which
+// exceed the limits of their namespaces are queued instead of being canceled.
 func (oc *OperatorController) PromoteWaitingOperator() {
 	oc.Lock()
 	defer oc.Unlock()
+	if ops := oc.popNamespaceQueueLocked(); ops != nil {
+		for _, op := range ops {
+			oc.addOperatorLocked(op)
+		}
+		return
+	}
 	var ops []*operator.Operator
 	for {
 		ops = oc.wop.GetOperator()
@@ -275,7 +324,12 @@ func (oc *OperatorController) PromoteWaitingOperator() {
 		}
 		operatorWaitCounter.WithLabelValues(ops[0].Desc(), "get").Inc()
 
-		if oc.exceedStoreLimit(ops...) || !oc.checkAddOperator(ops...) {
+		if !oc.exceedStoreLimit(ops...) && oc.exceedNamespaceLimit(ops...) && oc.checkAddOperator(ops...) {
+			oc.wopStatus.ops[ops[0].Desc()]--
+			oc.pushNamespaceQueueLocked(ops)
+			continue
+		}
+		if oc.exceedStoreLimit(ops...) || oc.exceedNamespaceLimit(ops...) || !oc.checkAddOperator(ops...) {
 			for _, op := range ops {
 				operatorWaitCounter.WithLabelValues(op.Desc(), "promote_canceled").Inc()
 				oc.opRecords.Put(op, pdpb.OperatorStatus_CANCEL)
@@ -292,6 +346,58 @@ func (oc *OperatorController) PromoteWaitingOperator() {
 	}
 }
 
+// pushNamespaceQueueLocked queues the operators for their namespace. The
+// operators dropped from the full queue are canceled.
+func (oc *OperatorController) pushNamespaceQueueLocked(ops []*operator.Operator) {
+	ns := oc.classifier.GetRegionNamespace(oc.cluster.GetRegion(ops[0].RegionID()))
+	q, ok := oc.nsQueues[ns]
+	if !ok {
+		q = &namespaceQueue{}
+		oc.nsQueues[ns] = q
+	}
+	operatorWaitCounter.WithLabelValues(ops[0].Desc(), "namespace_queue").Inc()
+	for _, op := range q.push(ops) {
+		operatorWaitCounter.WithLabelValues(op.Desc(), "namespace_queue_canceled").Inc()
+		oc.opRecords.Put(op, pdpb.OperatorStatus_CANCEL)
+	}
+}
+
+// popNamespaceQueueLocked removes and returns the most urgent operators queued
+// for a namespace which can be added now. Queued operators which cannot be
+// added anymore, e.g. the regions are changed, are canceled.
+func (oc *OperatorController) popNamespaceQueueLocked() []*operator.Operator {
+	names := make([]string, 0, len(oc.nsQueues))
+	for ns := range oc.nsQueues {
+		names = append(names, ns)
+	}
+	sort.Strings(names)
+	for _, ns := range names {
+		q := oc.nsQueues[ns]
+		for ops := q.peek(); ops != nil; ops = q.peek() {
+			if !oc.checkAddOperator(ops...) {
+				q.pop()
+				for _, op := range ops {
+					operatorWaitCounter.WithLabelValues(op.Desc(), "namespace_queue_canceled").Inc()
+					oc.opRecords.Put(op, pdpb.OperatorStatus_CANCEL)
+				}
+				continue
+			}
+			if oc.exceedStoreLimit(ops...) || oc.exceedNamespaceLimit(ops...) {
+				break
+			}
+			q.pop()
+			if q.len() == 0 {
+				delete(oc.nsQueues, ns)
+			}
+			return ops
+		}
+		if q.len() == 0 {
+			delete(oc.nsQueues, ns)
+		}
+	}
+	return nil
+}
+
 // checkAddOperator checks if the operator can be added.
 // There are several situations that cannot be added:
 // - There is no such region in the cluster
@@ -347,6 +453,7 @@ func (oc *OperatorController) addOperatorLocked(op *operator.Operator) bool {
 		storeLimitGauge.WithLabelValues(strconv.FormatUint(storeID, 10), "take").Set(float64(stepCost) / float64(operator.RegionInfluence))
 		oc.storesLimit[storeID].Take(stepCost)
 	}
+	oc.takeMigrationLocked(op)
 	oc.updateCounts(oc.operators)
 
 	var step operator.OpStep
@@ -368,6 +475,51 @@ func (oc *OperatorController) RemoveOperator(op *operator.Operator) (found bool)
 	return oc.removeOperatorLocked(op)
 }
 
+// CancelOperatorsForRegion cancels the pending operators of the region
+// produced by schedulers, i.e. the running one and the ones queued for its
+// namespace, so that the region can be moved manually. Admin operators are
+// kept. It returns the number of canceled operators.
+func (oc *OperatorController) CancelOperatorsForRegion(regionID uint64) int {
+	oc.Lock()
+	defer oc.Unlock()
+	return oc.cancelOperatorsForRegionLocked(regionID)
+}
+
+// CancelAndAddOperator cancels the pending operators of the region produced
+// by schedulers and adds the operator under the same lock, so that no
+// scheduler operator of the region can be added in between. The pending
+// operators are canceled even if the operator is not added.
+func (oc *OperatorController) CancelAndAddOperator(op *operator.Operator) bool {
+	oc.Lock()
+	defer oc.Unlock()
+	oc.cancelOperatorsForRegionLocked(op.RegionID())
+	if oc.exceedStoreLimit(op) || oc.exceedNamespaceLimit(op) || !oc.checkAddOperator(op) {
+		operatorCounter.WithLabelValues(op.Desc(), "cancel").Inc()
+		oc.opRecords.Put(op, pdpb.OperatorStatus_CANCEL)
+		return false
+	}
+	return oc.addOperatorLocked(op)
+}
+
+func (oc *OperatorController) cancelOperatorsForRegionLocked(regionID uint64) int {
+	var canceled []*operator.Operator
+	if op, ok := oc.operators[regionID]; ok && op.Kind()&operator.OpAdmin == 0 && oc.removeOperatorLocked(op) {
+		canceled = append(canceled, op)
+	}
+	for ns, q := range oc.nsQueues {
+		canceled = append(canceled, q.remove(regionID)...)
+		if q.len() == 0 {
+			delete(oc.nsQueues, ns)
+		}
+	}
+	for _, op := range canceled {
+		log.Info("cancel operator for region", zap.Uint64("region-id", regionID), zap.Reflect("operator", op))
+		operatorCounter.WithLabelValues(op.Desc(), "cancel").Inc()
+		oc.opRecords.Put(op, pdpb.OperatorStatus_CANCEL)
+	}
+	return len(canceled)
+}
+
 // GetOperatorStatus gets the operator and its status with the specify id.
 func (oc *OperatorController) GetOperatorStatus(id uint64) *OperatorWithStatus {
 	oc.Lock()
@@ -385,6 +537,7 @@ func (oc *OperatorController) removeOperatorLocked(op *operator.Operator) bool {
 	regionID := op.RegionID()
 	if cur := oc.operators[regionID]; cur == op {
 		delete(oc.operators, regionID)
+		delete(oc.migratingBytes, regionID)
 		oc.updateCounts(oc.operators)
 		operatorCounter.WithLabelValues(op.Desc(), "remove").Inc()
 		return true
@@ -695,6 +848,14 @@ func (o *OperatorRecords) Put(op *operator.Operator, status pdpb.OperatorStatus)
 	o.ttl.Put(id, record)
 }
 
+// ExceedStoreLimit returns true if any store exceeds the cost limit after
+// adding the operators, e.g. when planning a batch of operators.
+func (oc *OperatorController) ExceedStoreLimit(ops ...*operator.Operator) bool {
+	oc.Lock()
+	defer oc.Unlock()
+	return oc.exceedStoreLimit(ops...)
+}
+
 // exceedStoreLimit returns true if the store exceeds the cost limit after adding the operator. Otherwise, returns false.
 func (oc *OperatorController) exceedStoreLimit(ops ...*operator.Operator) bool {
 	opInfluence := NewTotalOpInfluence(ops, oc.cluster)
@@ -713,6 +874,159 @@ func (oc *OperatorController) exceedStoreLimit(ops ...*operator.Operator) bool {
 	return false
 }
 
+// exceedNamespaceLimit returns true if the number of operators of a namespace
+// exceeds its max concurrent operators after adding the operators, or the
+// namespace moves more region data than its limit. Otherwise, returns false.
+// The operators created by admins through the API are not limited.
+func (oc *OperatorController) exceedNamespaceLimit(ops ...*operator.Operator) bool {
+	if oc.classifier == nil {
+		return false
+	}
+	for _, op := range ops {
+		if op.Kind()&operator.OpAdmin != 0 {
+			return false
+		}
+	}
+	if oc.exceedMigrationLimit(ops...) {
+		return true
+	}
+	adding := make(map[uint64]struct{}, len(ops))
+	for _, op := range ops {
+		adding[op.RegionID()] = struct{}{}
+	}
+	counts := make(map[string]uint64)
+	for _, op := range ops {
+		region := oc.cluster.GetRegion(op.RegionID())
+		if region == nil {
+			continue
+		}
+		ns := oc.classifier.GetRegionNamespace(region)
+		limit := oc.cluster.GetOpt().GetMaxConcurrentOperators(ns)
+		if limit == 0 {
+			continue
+		}
+		count, ok := counts[ns]
+		if !ok {
+			// Operators of the regions being added are replaced, so they are
+			// not counted.
+			for regionID := range oc.operators {
+				if _, ok := adding[regionID]; ok {
+					continue
+				}
+				if r := oc.cluster.GetRegion(regionID); r != nil && oc.classifier.GetRegionNamespace(r) == ns {
+					count++
+				}
+			}
+		}
+		count++
+		counts[ns] = count
+		if count > limit {
+			log.Debug("exceed namespace max concurrent operators, cancel add operator",
+				zap.Uint64("region-id", op.RegionID()),
+				zap.String("namespace", ns),
+				zap.Uint64("limit", limit))
+			return true
+		}
+	}
+	return false
+}
+
+// migration returns the namespace of the region of the operator and the bytes
+// of region data moved by the operator, which is the size of the region for
+// each peer added.
+func (oc *OperatorController) migration(op *operator.Operator) (string, int64) {
+	region := oc.cluster.GetRegion(op.RegionID())
+	if region == nil || oc.classifier == nil {
+		return "", 0
+	}
+	var bytes int64
+	for i := 0; i < op.Len(); i++ {
+		switch op.Step(i).(type) {
+		case operator.AddPeer, operator.AddLightPeer, operator.AddLearner, operator.AddLightLearner:
+			bytes += region.GetApproximateSize() << 20
+		}
+	}
+	return oc.classifier.GetRegionNamespace(region), bytes
+}
+
+// getMigrationLimit returns the limit of the bytes per second of region data
+// moved in the namespace, or nil if it has no limit. The limit is recreated
+// if the rate is changed.
+func (oc *OperatorController) getMigrationLimit(ns string) *ratelimit.Bucket {
+	rate := oc.cluster.GetOpt().GetMaxMigrationBytesPerSec(ns)
+	if rate == 0 {
+		delete(oc.migrationLimits, ns)
+		return nil
+	}
+	if limit, ok := oc.migrationLimits[ns]; ok && limit.rate == rate {
+		return limit.bucket
+	}
+	bucket := ratelimit.NewBucketWithRateAndClock(float64(rate), int64(rate), oc.migrationClock)
+	oc.migrationLimits[ns] = migrationLimit{rate: rate, bucket: bucket}
+	return bucket
+}
+
+// exceedMigrationLimit returns true if the limit of a namespace does not have
+// enough bytes left for the region data moved by the operators, so that they
+// should be delayed. An operator moving more bytes than the limit can hold is
+// allowed once the limit is full.
+func (oc *OperatorController) exceedMigrationLimit(ops ...*operator.Operator) bool {
+	for _, op := range ops {
+		ns, bytes := oc.migration(op)
+		if bytes == 0 {
+			continue
+		}
+		limit := oc.getMigrationLimit(ns)
+		if limit == nil {
+			continue
+		}
+		if available := limit.Available(); available >= bytes || available >= limit.Capacity() {
+			continue
+		}
+		log.Debug("exceed namespace max migration bytes, cancel add operator",
+			zap.Uint64("region-id", op.RegionID()),
+			zap.String("namespace", ns))
+		return true
+	}
+	return false
+}
+
+// takeMigrationLocked records the region data moved by the operator, and
+// takes it from the limit of the namespace.
+func (oc *OperatorController) takeMigrationLocked(op *operator.Operator) {
+	ns, bytes := oc.migration(op)
+	if bytes == 0 {
+		return
+	}
+	oc.migratingBytes[op.RegionID()] = namespaceMigration{namespace: ns, bytes: bytes}
+	if limit := oc.getMigrationLimit(ns); limit != nil {
+		limit.Take(bytes)
+	}
+}
+
+// SetMigrationClock sets the clock the migration limits of namespaces are
+// refilled by, e.g. a fake one in tests. The existing limits are recreated.
+func (oc *OperatorController) SetMigrationClock(clock ratelimit.Clock) {
+	oc.Lock()
+	defer oc.Unlock()
+	oc.migrationClock = clock
+	oc.migrationLimits = make(map[string]migrationLimit)
+}
+
+// GetMigratingBytes returns the bytes of region data being moved by the
+// running operators in the namespace.
+func (oc *OperatorController) GetMigratingBytes(ns string) int64 {
+	oc.RLock()
+	defer oc.RUnlock()
+	var bytes int64
+	for _, m := range oc.migratingBytes {
+		if m.namespace == ns {
+			bytes += m.bytes
+		}
+	}
+	return bytes
+}
+
 // SetAllStoresLimit is used to set limit of all stores.
 func (oc *OperatorController) SetAllStoresLimit(rate float64) {
 	oc.Lock()
@@ -0,0 +1,113 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package placement
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/server/core"
+)
+
+// decodeKeyRange returns the raw keys of a key range. The hex format keys are
+// used if the raw keys are not set, e.g. the range is unmarshaled from JSON.
+func decodeKeyRange(startKey []byte, startKeyHex string, endKey []byte, endKeyHex string) ([]byte, []byte) {
+	if len(startKey) == 0 && startKeyHex != "" {
+		startKey, _ = hex.DecodeString(startKeyHex)
+	}
+	if len(endKey) == 0 && endKeyHex != "" {
+		endKey, _ = hex.DecodeString(endKeyHex)
+	}
+	return startKey, endKey
+}
+
+// coverRegion checks if the key range covers the region. An empty end key
+// means the range is unbounded.
+func coverRegion(startKey, endKey []byte, region *core.RegionInfo) bool {
+	if bytes.Compare(region.GetStartKey(), startKey) < 0 {
+		return false
+	}
+	if len(endKey) == 0 {
+		return true
+	}
+	return len(region.GetEndKey()) > 0 && bytes.Compare(region.GetEndKey(), endKey) <= 0
+}
+
+// CoverRegion checks if the key range of the rule covers the region.
+func (r *Rule) CoverRegion(region *core.RegionInfo) bool {
+	startKey, endKey := decodeKeyRange(r.StartKey, r.StartKeyHex, r.EndKey, r.EndKeyHex)
+	return coverRegion(startKey, endKey, region)
+}
+
+// matchPeer checks if the peer of the region matches the role of the rule.
+func (r *Rule) matchPeer(region *core.RegionInfo, peer *metapb.Peer) bool {
+	switch r.Role {
+	case Voter:
+		return !peer.GetIsLearner()
+	case Leader:
+		return region.GetLeader().GetId() == peer.GetId()
+	case Follower:
+		return !peer.GetIsLearner() && region.GetLeader().GetId() != peer.GetId()
+	case Learner:
+		return peer.GetIsLearner()
+	}
+	return false
+}
+
+// GetAppliedRules returns the rules which cover the region and take effect, in
+// the order that they are applied.
+func GetAppliedRules(region *core.RegionInfo, rules []*Rule) []*Rule {
+	var applied []*Rule
+	for _, r := range rules {
+		if r.CoverRegion(region) {
+			applied = append(applied, r)
+		}
+	}
+	return prepareRulesForApply(applied)
+}
+
+// FitRules checks if the placement of the region satisfies the rules which
+// cover it. Rules are applied in order, and each one takes the expected count
+// of the peers which are not taken yet and match its role and label
+// constraints. The region fits if all the rules get enough peers and no peer
+// is left. getStore is used to get the stores of the peers. Location labels
+// of the rules are not checked.
+func FitRules(region *core.RegionInfo, rules []*Rule, getStore func(storeID uint64) *core.StoreInfo) bool {
+	applied := GetAppliedRules(region, rules)
+	if len(applied) == 0 {
+		return true
+	}
+
+	taken := make(map[uint64]struct{})
+	for _, r := range applied {
+		count := 0
+		for _, p := range region.GetPeers() {
+			if count >= r.Count {
+				break
+			}
+			if _, ok := taken[p.GetId()]; ok {
+				continue
+			}
+			if r.matchPeer(region, p) && MatchLabelConstraints(getStore(p.GetStoreId()), r.LabelConstraints) {
+				taken[p.GetId()] = struct{}{}
+				count++
+			}
+		}
+		if count < r.Count {
+			return false
+		}
+	}
+	return len(taken) == len(region.GetPeers())
+}
@@ -0,0 +1,60 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package placement
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/server/core"
+)
+
+var _ = Suite(&testFitSuite{})
+
+type testFitSuite struct{}
+
+func (s *testFitSuite) TestFitRules(c *C) {
+	stores := map[uint64]*core.StoreInfo{
+		1: core.NewStoreInfoWithLabel(1, 0, map[string]string{"zone": "z1"}),
+		2: core.NewStoreInfoWithLabel(2, 0, map[string]string{"zone": "z1"}),
+		3: core.NewStoreInfoWithLabel(3, 0, map[string]string{"zone": "z2"}),
+		4: core.NewStoreInfoWithLabel(4, 0, map[string]string{"zone": "z2"}),
+	}
+	getStore := func(id uint64) *core.StoreInfo { return stores[id] }
+	peers := []*metapb.Peer{
+		{Id: 11, StoreId: 1},
+		{Id: 12, StoreId: 2},
+		{Id: 13, StoreId: 3},
+		{Id: 14, StoreId: 4, IsLearner: true},
+	}
+	region := core.NewRegionInfo(&metapb.Region{Id: 1, StartKey: []byte("a"), EndKey: []byte("c"), Peers: peers}, peers[0])
+
+	voters := &Rule{GroupID: "pd", ID: "voters", Role: Voter, Count: 3}
+	learner := &Rule{GroupID: "pd", ID: "learner", Index: 1, Role: Learner, Count: 1,
+		LabelConstraints: []LabelConstraint{{Key: "zone", Op: In, Values: []string{"z2"}}}}
+	c.Assert(FitRules(region, nil, getStore), IsTrue)
+	c.Assert(FitRules(region, []*Rule{voters, learner}, getStore), IsTrue)
+	// The learner is left.
+	c.Assert(FitRules(region, []*Rule{voters}, getStore), IsFalse)
+
+	leader := &Rule{GroupID: "pd", ID: "leader", Role: Leader, Count: 1,
+		LabelConstraints: []LabelConstraint{{Key: "zone", Op: In, Values: []string{"z2"}}}}
+	c.Assert(FitRules(region, []*Rule{leader, learner}, getStore), IsFalse)
+
+	// Rules not covering the region are ignored.
+	voters.StartKeyHex, voters.EndKeyHex = "63", "64" // ["c", "d")
+	c.Assert(voters.CoverRegion(region), IsFalse)
+	c.Assert(FitRules(region, []*Rule{voters}, getStore), IsTrue)
+	voters.StartKeyHex, voters.EndKeyHex = "", "63" // ["", "c")
+	c.Assert(voters.CoverRegion(region), IsTrue)
+}
@@ -0,0 +1,39 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package placement
+
+import "github.com/pingcap/pd/server/core"
+
+// RegionLabel attaches a label to the regions within a key range, e.g.
+// tier=cold. The peers of a labeled region are expected to be placed on the
+// stores that have the same label.
+type RegionLabel struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	StartKey    []byte `json:"-"`         // range start key
+	StartKeyHex string `json:"start_key"` // hex format start key, for marshal/unmarshal
+	EndKey      []byte `json:"-"`         // range end key
+	EndKeyHex   string `json:"end_key"`   // hex format end key, for marshal/unmarshal
+}
+
+// CoverRegion checks if the key range of the label covers the region.
+func (l *RegionLabel) CoverRegion(region *core.RegionInfo) bool {
+	startKey, endKey := decodeKeyRange(l.StartKey, l.StartKeyHex, l.EndKey, l.EndKeyHex)
+	return coverRegion(startKey, endKey, region)
+}
+
+// MatchStore checks if the store has the label.
+func (l *RegionLabel) MatchStore(store *core.StoreInfo) bool {
+	return store != nil && store.GetLabelValue(l.Key) == l.Value
+}
@@ -0,0 +1,37 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package placement
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/server/core"
+)
+
+var _ = Suite(&testRegionLabelSuite{})
+
+type testRegionLabelSuite struct{}
+
+func (s *testRegionLabelSuite) TestRegionLabel(c *C) {
+	region := core.NewRegionInfo(&metapb.Region{Id: 1, StartKey: []byte("a"), EndKey: []byte("c")}, nil)
+	label := &RegionLabel{Key: "tier", Value: "cold", StartKeyHex: "61"} // ["a", "")
+	c.Assert(label.CoverRegion(region), IsTrue)
+	label.StartKeyHex, label.EndKeyHex = "62", "" // ["b", "")
+	c.Assert(label.CoverRegion(region), IsFalse)
+
+	c.Assert(label.MatchStore(core.NewStoreInfoWithLabel(1, 0, map[string]string{"tier": "cold"})), IsTrue)
+	c.Assert(label.MatchStore(core.NewStoreInfoWithLabel(2, 0, map[string]string{"tier": "hot"})), IsFalse)
+	c.Assert(label.MatchStore(core.NewStoreInfoWithLabel(3, 0, nil)), IsFalse)
+	c.Assert(label.MatchStore(nil), IsFalse)
+}
@@ -0,0 +1,168 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule"
+	"github.com/pingcap/pd/server/schedule/operator"
+	"github.com/pingcap/pd/server/schedule/opt"
+	"github.com/pingcap/pd/server/schedulers"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	// args: the types of the schedulers in the chain, in order.
+	schedule.RegisterSliceDecoderBuilder(schedulerChainType, func(args []string) schedule.ConfigDecoder {
+		return func(v interface{}) error {
+			conf, ok := v.(*schedulerChainConfig)
+			if !ok {
+				return schedulers.ErrScheduleConfigNotExist
+			}
+			if len(args) == 0 {
+				return errors.New("should specify the schedulers of the chain")
+			}
+			conf.Types = args
+			return nil
+		}
+	})
+	schedule.RegisterScheduler(schedulerChainType, func(opController *schedule.OperatorController, storage *core.Storage, decoder schedule.ConfigDecoder) (schedule.Scheduler, error) {
+		conf := &schedulerChainConfig{}
+		if err := decoder(conf); err != nil {
+			return nil, err
+		}
+		schedulers := make([]schedule.Scheduler, 0, len(conf.Types))
+		for _, typ := range conf.Types {
+			if typ == schedulerChainType {
+				return nil, errors.New("scheduler chains cannot be chained")
+			}
+			// The schedulers in the chain are created with their default
+			// configs.
+			s, err := schedule.CreateScheduler(typ, opController, storage, schedule.ConfigSliceDecoder(typ, nil))
+			if err != nil {
+				return nil, err
+			}
+			schedulers = append(schedulers, s)
+		}
+		return newSchedulerChain(schedulers...), nil
+	})
+}
+
+const schedulerChainType = "scheduler-chain"
+
+type schedulerChainConfig struct {
+	Types []string `json:"types"`
+}
+
+// schedulerChain tries its schedulers in order and returns the operators of
+// the first one that produces any, e.g. balance-region only runs when
+// balance-leader has nothing to do. When it is run by scheduleByNamespace,
+// the chain is tried within each namespace, so a scheduler only runs when the
+// previous ones yield nothing in the same namespace.
+type schedulerChain struct {
+	schedulers []schedule.Scheduler
+}
+
+// newSchedulerChain creates a scheduler chain which tries the schedulers in
+// the given order.
+func newSchedulerChain(schedulers ...schedule.Scheduler) *schedulerChain {
+	return &schedulerChain{schedulers: schedulers}
+}
+
+func (s *schedulerChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "scheduler chain does not support http", http.StatusNotFound)
+}
+
+func (s *schedulerChain) GetName() string {
+	names := make([]string, 0, len(s.schedulers))
+	for _, sched := range s.schedulers {
+		names = append(names, sched.GetName())
+	}
+	return schedulerChainType + "(" + strings.Join(names, ",") + ")"
+}
+
+func (s *schedulerChain) GetType() string {
+	return schedulerChainType
+}
+
+func (s *schedulerChain) EncodeConfig() ([]byte, error) {
+	conf := &schedulerChainConfig{Types: make([]string, 0, len(s.schedulers))}
+	for _, sched := range s.schedulers {
+		conf.Types = append(conf.Types, sched.GetType())
+	}
+	return schedule.EncodeConfig(conf)
+}
+
+func (s *schedulerChain) GetMinInterval() time.Duration {
+	var interval time.Duration
+	for i, sched := range s.schedulers {
+		if d := sched.GetMinInterval(); i == 0 || d < interval {
+			interval = d
+		}
+	}
+	return interval
+}
+
+func (s *schedulerChain) GetNextInterval(interval time.Duration) time.Duration {
+	var next time.Duration
+	for i, sched := range s.schedulers {
+		if d := sched.GetNextInterval(interval); i == 0 || d < next {
+			next = d
+		}
+	}
+	return next
+}
+
+func (s *schedulerChain) Prepare(cluster opt.Cluster) error {
+	for i, sched := range s.schedulers {
+		if err := sched.Prepare(cluster); err != nil {
+			for _, prepared := range s.schedulers[:i] {
+				prepared.Cleanup(cluster)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *schedulerChain) Cleanup(cluster opt.Cluster) {
+	for _, sched := range s.schedulers {
+		sched.Cleanup(cluster)
+	}
+}
+
+func (s *schedulerChain) Schedule(cluster opt.Cluster) []*operator.Operator {
+	for _, sched := range s.schedulers {
+		if !sched.IsScheduleAllowed(cluster) {
+			continue
+		}
+		if ops := sched.Schedule(cluster); len(ops) > 0 {
+			return ops
+		}
+	}
+	return nil
+}
+
+func (s *schedulerChain) IsScheduleAllowed(cluster opt.Cluster) bool {
+	for _, sched := range s.schedulers {
+		if sched.IsScheduleAllowed(cluster) {
+			return true
+		}
+	}
+	return false
+}
@@ -105,7 +105,8 @@ func (l *balanceLeaderScheduler) Schedule(cluster opt.Cluster) []*operator.Opera
 	leaderScheduleStrategy := l.opController.GetLeaderScheduleStrategy()
 	stores := cluster.GetStores()
 	sources := filter.SelectSourceStores(stores, l.filters, cluster)
-	targets := filter.SelectTargetStores(stores, l.filters, cluster)
+	targetFilters := append([]filter.Filter{newStoreBlacklistFilter(l.GetName(), cluster), newLeaseStableFilter(l.GetName(), cluster), newLeaderZoneFilter(l.GetName(), cluster)}, l.filters...)
+	targets := filter.SelectTargetStores(filterLeaderEligibleStores(cluster, stores), targetFilters, cluster)
 	sort.Slice(sources, func(i, j int) bool {
 		return sources[i].LeaderScore(leaderScheduleStrategy, 0) > sources[j].LeaderScore(leaderScheduleStrategy, 0)
 	})
@@ -160,8 +161,9 @@ func (l *balanceLeaderScheduler) transferLeaderOut(cluster opt.Cluster, source *
 		schedulerCounter.WithLabelValues(l.GetName(), "no-leader-region").Inc()
 		return nil
 	}
-	targets := cluster.GetFollowerStores(region)
-	targets = filter.SelectTargetStores(targets, l.filters, cluster)
+	targets := filterLeaderEligibleStores(cluster, cluster.GetFollowerStores(region))
+	targetFilters := append([]filter.Filter{newStoreBlacklistFilter(l.GetName(), cluster), newLeaseStableFilter(l.GetName(), cluster), newLeaderZoneFilter(l.GetName(), cluster)}, l.filters...)
+	targets = filter.SelectTargetStores(targets, targetFilters, cluster)
 	leaderScheduleStrategy := l.opController.GetLeaderScheduleStrategy()
 	sort.Slice(targets, func(i, j int) bool {
 		return targets[i].LeaderScore(leaderScheduleStrategy, 0) < targets[j].LeaderScore(leaderScheduleStrategy, 0)
@@ -0,0 +1,154 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"sort"
+
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule"
+	"github.com/pingcap/pd/server/schedule/filter"
+	"github.com/pingcap/pd/server/schedule/operator"
+	"github.com/pingcap/pd/server/schedule/opt"
+)
+
+func init() {
+	schedule.RegisterSliceDecoderBuilder("balance-namespace-domain", func(args []string) schedule.ConfigDecoder {
+		return func(v interface{}) error {
+			return nil
+		}
+	})
+	schedule.RegisterScheduler("balance-namespace-domain", func(opController *schedule.OperatorController, storage *core.Storage, decoder schedule.ConfigDecoder) (schedule.Scheduler, error) {
+		return newBalanceNamespaceDomainScheduler(opController), nil
+	})
+}
+
+const (
+	balanceNamespaceDomainName = "balance-namespace-domain-scheduler"
+	// balanceDomainTolerantRatio is the ratio of the average domain load,
+	// domains whose loads differ less than it are considered as balanced.
+	balanceDomainTolerantRatio = 0.1
+)
+
+// domainLoadCluster is implemented by clusters which provide the load of
+// each failure domain defined by location labels.
+type domainLoadCluster interface {
+	GetStoreDomain(storeID uint64) string
+	GetDomainLoad() map[string]int64
+}
+
+type balanceNamespaceDomainScheduler struct {
+	*baseScheduler
+	filters []filter.Filter
+}
+
+// newBalanceNamespaceDomainScheduler creates a scheduler that tends to keep
+// the region size of each failure domain balanced by moving regions from the
+// most loaded domain to the least loaded one. It schedules only if the
+// cluster provides the domain loads, e.g. when it is run by namespace.
+func newBalanceNamespaceDomainScheduler(opController *schedule.OperatorController) schedule.Scheduler {
+	base := newBaseScheduler(opController)
+	return &balanceNamespaceDomainScheduler{
+		baseScheduler: base,
+		filters:       []filter.Filter{filter.StoreStateFilter{ActionScope: balanceNamespaceDomainName, MoveRegion: true}},
+	}
+}
+
+func (s *balanceNamespaceDomainScheduler) GetName() string {
+	return balanceNamespaceDomainName
+}
+
+func (s *balanceNamespaceDomainScheduler) GetType() string {
+	return "balance-namespace-domain"
+}
+
+func (s *balanceNamespaceDomainScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
+	return s.opController.OperatorCount(operator.OpRegion) < cluster.GetRegionScheduleLimit()
+}
+
+func (s *balanceNamespaceDomainScheduler) Schedule(cluster opt.Cluster) []*operator.Operator {
+	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
+	c, ok := cluster.(domainLoadCluster)
+	if !ok {
+		return nil
+	}
+	loads := c.GetDomainLoad()
+	if len(loads) < 2 {
+		return nil
+	}
+	var total int64
+	for _, load := range loads {
+		total += load
+	}
+	tolerance := int64(float64(total) / float64(len(loads)) * balanceDomainTolerantRatio)
+
+	stores := cluster.GetStores()
+	sources := filter.SelectSourceStores(stores, s.filters, cluster)
+	sort.Slice(sources, func(i, j int) bool {
+		return s.less(c, loads, sources[j], sources[i])
+	})
+	targetFilters := append([]filter.Filter{newSpareStoreFilter(s.GetName(), cluster), newStoreBlacklistFilter(s.GetName(), cluster)}, s.filters...)
+	targets := filter.SelectTargetStores(stores, targetFilters, cluster)
+	sort.Slice(targets, func(i, j int) bool {
+		return s.less(c, loads, targets[i], targets[j])
+	})
+
+	for _, source := range sources {
+		sourceDomain := c.GetStoreDomain(source.GetID())
+		if sourceDomain == "" {
+			continue
+		}
+		for _, target := range targets {
+			targetDomain := c.GetStoreDomain(target.GetID())
+			if targetDomain == "" || targetDomain == sourceDomain {
+				continue
+			}
+			sourceLoad, targetLoad := loads[sourceDomain], loads[targetDomain]
+			if sourceLoad <= targetLoad+tolerance {
+				// The following targets are in more loaded domains.
+				break
+			}
+			if op := s.moveRegion(cluster, source, target, sourceLoad-targetLoad); op != nil {
+				schedulerCounter.WithLabelValues(s.GetName(), "new-operator").Inc()
+				return []*operator.Operator{op}
+			}
+		}
+	}
+	schedulerCounter.WithLabelValues(s.GetName(), "balanced").Inc()
+	return nil
+}
+
+// less orders the stores by the loads of their domains, and then by their
+// region sizes within the same domain.
+func (s *balanceNamespaceDomainScheduler) less(c domainLoadCluster, loads map[string]int64, a, b *core.StoreInfo) bool {
+	loadA, loadB := loads[c.GetStoreDomain(a.GetID())], loads[c.GetStoreDomain(b.GetID())]
+	if loadA != loadB {
+		return loadA < loadB
+	}
+	return a.GetRegionSize() < b.GetRegionSize()
+}
+
+// moveRegion tries to move a region from the source store to the target store
+// in another domain. To avoid oscillation, the region size must be no more
+// than half of the difference between the domains, so that the source domain
+// is still no less loaded than the target domain after moving.
+func (s *balanceNamespaceDomainScheduler) moveRegion(cluster opt.Cluster, source, target *core.StoreInfo, diff int64) *operator.Operator {
+	return moveRegionToStore(cluster, s.GetName(), "balance-namespace-domain", source, target, func(region *core.RegionInfo) bool {
+		if size := region.GetApproximateSize(); size <= 0 || size*2 > diff {
+			schedulerCounter.WithLabelValues(s.GetName(), "unsuitable-size").Inc()
+			return false
+		}
+		return true
+	})
+}
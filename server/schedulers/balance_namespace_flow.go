@@ -0,0 +1,131 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"sort"
+
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule"
+	"github.com/pingcap/pd/server/schedule/filter"
+	"github.com/pingcap/pd/server/schedule/operator"
+	"github.com/pingcap/pd/server/schedule/opt"
+)
+
+func init() {
+	schedule.RegisterSliceDecoderBuilder("balance-namespace-flow", func(args []string) schedule.ConfigDecoder {
+		return func(v interface{}) error {
+			return nil
+		}
+	})
+	schedule.RegisterScheduler("balance-namespace-flow", func(opController *schedule.OperatorController, storage *core.Storage, decoder schedule.ConfigDecoder) (schedule.Scheduler, error) {
+		return newBalanceNamespaceFlowScheduler(opController), nil
+	})
+}
+
+const (
+	balanceNamespaceFlowName = "balance-namespace-flow-scheduler"
+	// balanceFlowTolerantRatio is the ratio of the average store flow, stores
+	// whose flow differ less than it are considered as balanced.
+	balanceFlowTolerantRatio = 0.1
+)
+
+// flowStatsCluster is implemented by clusters which provide the flow of
+// regions on each store.
+type flowStatsCluster interface {
+	GetStoreFlowStats() map[uint64]uint64
+}
+
+type balanceNamespaceFlowScheduler struct {
+	*baseScheduler
+	filters []filter.Filter
+}
+
+// newBalanceNamespaceFlowScheduler creates a scheduler that tends to keep the
+// read and written bytes of regions on each store balanced. It is used for
+// namespaces which are bound by IO rather than size, and schedules only if the
+// cluster provides the store flow, e.g. when it is run by namespace.
+func newBalanceNamespaceFlowScheduler(opController *schedule.OperatorController) schedule.Scheduler {
+	base := newBaseScheduler(opController)
+	return &balanceNamespaceFlowScheduler{
+		baseScheduler: base,
+		filters:       []filter.Filter{filter.StoreStateFilter{ActionScope: balanceNamespaceFlowName, MoveRegion: true}},
+	}
+}
+
+func (s *balanceNamespaceFlowScheduler) GetName() string {
+	return balanceNamespaceFlowName
+}
+
+func (s *balanceNamespaceFlowScheduler) GetType() string {
+	return "balance-namespace-flow"
+}
+
+func (s *balanceNamespaceFlowScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
+	return s.opController.OperatorCount(operator.OpRegion) < cluster.GetRegionScheduleLimit()
+}
+
+func (s *balanceNamespaceFlowScheduler) Schedule(cluster opt.Cluster) []*operator.Operator {
+	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
+	c, ok := cluster.(flowStatsCluster)
+	if !ok {
+		return nil
+	}
+	flows := c.GetStoreFlowStats()
+	stores := cluster.GetStores()
+	if len(stores) == 0 {
+		return nil
+	}
+	var total uint64
+	for _, store := range stores {
+		total += flows[store.GetID()]
+	}
+	tolerance := uint64(float64(total) / float64(len(stores)) * balanceFlowTolerantRatio)
+
+	sources := filter.SelectSourceStores(stores, s.filters, cluster)
+	sort.Slice(sources, func(i, j int) bool { return flows[sources[i].GetID()] > flows[sources[j].GetID()] })
+	targetFilters := append([]filter.Filter{newSpareStoreFilter(s.GetName(), cluster), newStoreBlacklistFilter(s.GetName(), cluster)}, s.filters...)
+	targets := filter.SelectTargetStores(stores, targetFilters, cluster)
+	sort.Slice(targets, func(i, j int) bool { return flows[targets[i].GetID()] < flows[targets[j].GetID()] })
+
+	for _, source := range sources {
+		for _, target := range targets {
+			sourceFlow, targetFlow := flows[source.GetID()], flows[target.GetID()]
+			if sourceFlow <= targetFlow+tolerance {
+				// The following targets have more flow.
+				break
+			}
+			if op := s.moveRegion(cluster, source, target, sourceFlow-targetFlow); op != nil {
+				schedulerCounter.WithLabelValues(s.GetName(), "new-operator").Inc()
+				return []*operator.Operator{op}
+			}
+		}
+	}
+	schedulerCounter.WithLabelValues(s.GetName(), "balanced").Inc()
+	return nil
+}
+
+// moveRegion tries to move a region from the source store to the target store.
+// To avoid oscillation, the flow of the region must be positive and no more
+// than half of the difference between the stores, so that the source store
+// still has no less flow than the target store after moving.
+func (s *balanceNamespaceFlowScheduler) moveRegion(cluster opt.Cluster, source, target *core.StoreInfo, diff uint64) *operator.Operator {
+	return moveRegionToStore(cluster, s.GetName(), "balance-namespace-flow", source, target, func(region *core.RegionInfo) bool {
+		if flow := region.GetBytesRead() + region.GetBytesWritten(); flow == 0 || flow*2 > diff {
+			schedulerCounter.WithLabelValues(s.GetName(), "unsuitable-flow").Inc()
+			return false
+		}
+		return true
+	})
+}
@@ -0,0 +1,154 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"sort"
+
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule"
+	"github.com/pingcap/pd/server/schedule/filter"
+	"github.com/pingcap/pd/server/schedule/operator"
+	"github.com/pingcap/pd/server/schedule/opt"
+)
+
+func init() {
+	schedule.RegisterSliceDecoderBuilder("balance-namespace-leader-ratio", func(args []string) schedule.ConfigDecoder {
+		return func(v interface{}) error {
+			return nil
+		}
+	})
+	schedule.RegisterScheduler("balance-namespace-leader-ratio", func(opController *schedule.OperatorController, storage *core.Storage, decoder schedule.ConfigDecoder) (schedule.Scheduler, error) {
+		return newBalanceNamespaceLeaderRatioScheduler(opController), nil
+	})
+}
+
+const balanceNamespaceLeaderRatioName = "balance-namespace-leader-ratio-scheduler"
+
+// leaderPeerRatioCluster is implemented by clusters which provide the
+// numbers of leaders and peers of each store.
+type leaderPeerRatioCluster interface {
+	GetLeaderPeerCounts() (leaders, peers map[uint64]int)
+}
+
+// leaderPeerCounts is the numbers of leaders and peers of each store, keyed by
+// store ID.
+type leaderPeerCounts struct {
+	leaders map[uint64]int
+	peers   map[uint64]int
+}
+
+// ratio returns the ratio of the leaders to the peers on the store. It returns
+// 0 if the store has no peer.
+func (c *leaderPeerCounts) ratio(storeID uint64) float64 {
+	if c.peers[storeID] == 0 {
+		return 0
+	}
+	return float64(c.leaders[storeID]) / float64(c.peers[storeID])
+}
+
+// peerCount returns the number of peers on the store, which is at least 1.
+func (c *leaderPeerCounts) peerCount(storeID uint64) int {
+	if count := c.peers[storeID]; count > 0 {
+		return count
+	}
+	return 1
+}
+
+type balanceNamespaceLeaderRatioScheduler struct {
+	*baseScheduler
+	filters []filter.Filter
+}
+
+// newBalanceNamespaceLeaderRatioScheduler creates a scheduler that tends to
+// keep the leader-to-peer ratio of each store the same by transferring leaders
+// from the store with the highest ratio, which is a leader hotspot, to the
+// followers on the stores with lower ratios. It schedules only if the cluster
+// provides the ratios, e.g. when it is run by namespace.
+func newBalanceNamespaceLeaderRatioScheduler(opController *schedule.OperatorController) schedule.Scheduler {
+	base := newBaseScheduler(opController)
+	return &balanceNamespaceLeaderRatioScheduler{
+		baseScheduler: base,
+		filters:       []filter.Filter{filter.StoreStateFilter{ActionScope: balanceNamespaceLeaderRatioName, TransferLeader: true}},
+	}
+}
+
+func (s *balanceNamespaceLeaderRatioScheduler) GetName() string {
+	return balanceNamespaceLeaderRatioName
+}
+
+func (s *balanceNamespaceLeaderRatioScheduler) GetType() string {
+	return "balance-namespace-leader-ratio"
+}
+
+func (s *balanceNamespaceLeaderRatioScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
+	return s.opController.OperatorCount(operator.OpLeader) < cluster.GetLeaderScheduleLimit()
+}
+
+func (s *balanceNamespaceLeaderRatioScheduler) Schedule(cluster opt.Cluster) []*operator.Operator {
+	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
+	c, ok := cluster.(leaderPeerRatioCluster)
+	if !ok {
+		return nil
+	}
+	counts := &leaderPeerCounts{}
+	counts.leaders, counts.peers = c.GetLeaderPeerCounts()
+	sources := filter.SelectSourceStores(cluster.GetStores(), s.filters, cluster)
+	sort.Slice(sources, func(i, j int) bool {
+		return counts.ratio(sources[i].GetID()) > counts.ratio(sources[j].GetID())
+	})
+	for _, source := range sources {
+		for i := 0; i < balanceLeaderRetryLimit; i++ {
+			if op := s.transferLeaderOut(cluster, counts, source); op != nil {
+				schedulerCounter.WithLabelValues(s.GetName(), "new-operator").Inc()
+				return []*operator.Operator{op}
+			}
+		}
+	}
+	schedulerCounter.WithLabelValues(s.GetName(), "balanced").Inc()
+	return nil
+}
+
+// transferLeaderOut tries to transfer the leader of a region on the source
+// store to the follower whose store has the lowest ratio. To avoid
+// oscillation, the ratio of the source store must be still no less than the
+// ratio of the target store after the transfer.
+func (s *balanceNamespaceLeaderRatioScheduler) transferLeaderOut(cluster opt.Cluster, counts *leaderPeerCounts, source *core.StoreInfo) *operator.Operator {
+	region := cluster.RandLeaderRegion(source.GetID(), core.HealthRegion())
+	if region == nil {
+		schedulerCounter.WithLabelValues(s.GetName(), "no-leader-region").Inc()
+		return nil
+	}
+	if cluster.IsRegionHot(region) {
+		schedulerCounter.WithLabelValues(s.GetName(), "region-hot").Inc()
+		return nil
+	}
+	targetFilters := append([]filter.Filter{newStoreBlacklistFilter(s.GetName(), cluster), newLeaseStableFilter(s.GetName(), cluster)}, s.filters...)
+	targets := filter.SelectTargetStores(filterLeaderEligibleStores(cluster, cluster.GetFollowerStores(region)), targetFilters, cluster)
+	sort.Slice(targets, func(i, j int) bool {
+		return counts.ratio(targets[i].GetID()) < counts.ratio(targets[j].GetID())
+	})
+	if len(targets) == 0 {
+		schedulerCounter.WithLabelValues(s.GetName(), "no-target-store").Inc()
+		return nil
+	}
+	target := targets[0]
+	sourceRatio := counts.ratio(source.GetID()) - 1/float64(counts.peerCount(source.GetID()))
+	targetRatio := counts.ratio(target.GetID()) + 1/float64(counts.peerCount(target.GetID()))
+	if sourceRatio < targetRatio {
+		schedulerCounter.WithLabelValues(s.GetName(), "skip").Inc()
+		return nil
+	}
+	return operator.CreateTransferLeaderOperator("balance-namespace-leader-ratio", region, source.GetID(), target.GetID(), operator.OpBalance)
+}
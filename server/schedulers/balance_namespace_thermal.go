@@ -0,0 +1,148 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"sort"
+
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule"
+	"github.com/pingcap/pd/server/schedule/filter"
+	"github.com/pingcap/pd/server/schedule/operator"
+	"github.com/pingcap/pd/server/schedule/opt"
+)
+
+func init() {
+	schedule.RegisterSliceDecoderBuilder("balance-namespace-thermal", func(args []string) schedule.ConfigDecoder {
+		return func(v interface{}) error {
+			return nil
+		}
+	})
+	schedule.RegisterScheduler("balance-namespace-thermal", func(opController *schedule.OperatorController, storage *core.Storage, decoder schedule.ConfigDecoder) (schedule.Scheduler, error) {
+		return newBalanceNamespaceThermalScheduler(opController), nil
+	})
+}
+
+const (
+	balanceNamespaceThermalName = "balance-namespace-thermal-scheduler"
+	// balanceThermalTolerantRatio is the ratio of the average thermal score,
+	// stores whose scores differ less than it are considered as balanced.
+	balanceThermalTolerantRatio = 0.1
+)
+
+// thermalScoreCluster is implemented by clusters which provide the thermal
+// scores of stores, which combine the size and flow of regions on them, and
+// the factors that the region size and flow are multiplied by to get the
+// thermal scores.
+type thermalScoreCluster interface {
+	GetThermalScores() (stores map[uint64]float64, sizeScale, flowScale float64)
+}
+
+// thermalScores is the thermal scores of stores and the factors to get the
+// thermal scores of regions.
+type thermalScores struct {
+	stores               map[uint64]float64
+	sizeScale, flowScale float64
+}
+
+// region returns the thermal score that the region adds to each store which
+// has a peer of it.
+func (s *thermalScores) region(region *core.RegionInfo) float64 {
+	return float64(region.GetApproximateSize())*s.sizeScale + float64(region.GetBytesRead()+region.GetBytesWritten())*s.flowScale
+}
+
+type balanceNamespaceThermalScheduler struct {
+	*baseScheduler
+	filters []filter.Filter
+}
+
+// newBalanceNamespaceThermalScheduler creates a scheduler that moves regions
+// from the hottest stores to the coldest stores by thermal scores, so that
+// both the size and flow are balanced by one scheduler. It schedules only if
+// the cluster provides the thermal scores, e.g. when it is run by namespace.
+func newBalanceNamespaceThermalScheduler(opController *schedule.OperatorController) schedule.Scheduler {
+	base := newBaseScheduler(opController)
+	return &balanceNamespaceThermalScheduler{
+		baseScheduler: base,
+		filters:       []filter.Filter{filter.StoreStateFilter{ActionScope: balanceNamespaceThermalName, MoveRegion: true}},
+	}
+}
+
+func (s *balanceNamespaceThermalScheduler) GetName() string {
+	return balanceNamespaceThermalName
+}
+
+func (s *balanceNamespaceThermalScheduler) GetType() string {
+	return "balance-namespace-thermal"
+}
+
+func (s *balanceNamespaceThermalScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
+	return s.opController.OperatorCount(operator.OpRegion) < cluster.GetRegionScheduleLimit()
+}
+
+func (s *balanceNamespaceThermalScheduler) Schedule(cluster opt.Cluster) []*operator.Operator {
+	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
+	c, ok := cluster.(thermalScoreCluster)
+	if !ok {
+		return nil
+	}
+	stores := cluster.GetStores()
+	if len(stores) == 0 {
+		return nil
+	}
+	thermal := &thermalScores{}
+	thermal.stores, thermal.sizeScale, thermal.flowScale = c.GetThermalScores()
+	scores := thermal.stores
+	var total float64
+	for _, store := range stores {
+		total += scores[store.GetID()]
+	}
+	tolerance := total / float64(len(stores)) * balanceThermalTolerantRatio
+
+	sources := filter.SelectSourceStores(stores, s.filters, cluster)
+	sort.Slice(sources, func(i, j int) bool { return scores[sources[i].GetID()] > scores[sources[j].GetID()] })
+	targetFilters := append([]filter.Filter{newSpareStoreFilter(s.GetName(), cluster), newStoreBlacklistFilter(s.GetName(), cluster)}, s.filters...)
+	targets := filter.SelectTargetStores(stores, targetFilters, cluster)
+	sort.Slice(targets, func(i, j int) bool { return scores[targets[i].GetID()] < scores[targets[j].GetID()] })
+
+	for _, source := range sources {
+		for _, target := range targets {
+			sourceScore, targetScore := scores[source.GetID()], scores[target.GetID()]
+			if sourceScore <= targetScore+tolerance {
+				// The following targets are hotter.
+				break
+			}
+			if op := s.moveRegion(cluster, thermal, source, target, sourceScore-targetScore); op != nil {
+				schedulerCounter.WithLabelValues(s.GetName(), "new-operator").Inc()
+				return []*operator.Operator{op}
+			}
+		}
+	}
+	schedulerCounter.WithLabelValues(s.GetName(), "balanced").Inc()
+	return nil
+}
+
+// moveRegion tries to move a region from the source store to the target store.
+// To avoid oscillation, the thermal score of the region must be positive and
+// no more than half of the difference between the stores, so that the source
+// store is still no colder than the target store after moving.
+func (s *balanceNamespaceThermalScheduler) moveRegion(cluster opt.Cluster, scores *thermalScores, source, target *core.StoreInfo, diff float64) *operator.Operator {
+	return moveRegionToStore(cluster, s.GetName(), "balance-namespace-thermal", source, target, func(region *core.RegionInfo) bool {
+		if score := scores.region(region); score <= 0 || score*2 > diff {
+			schedulerCounter.WithLabelValues(s.GetName(), "unsuitable-score").Inc()
+			return false
+		}
+		return true
+	})
+}
@@ -115,14 +115,14 @@ func (s *balanceRegionScheduler) Schedule(cluster opt.Cluster) []*operator.Opera
 		for i := 0; i < balanceRegionRetryLimit; i++ {
 			// Priority picks the region that has a pending peer.
 			// Pending region may means the disk is overload, remove the pending region firstly.
-			region := cluster.RandPendingRegion(sourceID, core.HealthRegionAllowPending())
+			region := cluster.RandPendingRegion(sourceID, healthRegionAllowPending(cluster))
 			if region == nil {
 				// Then picks the region that has a follower in the source store.
-				region = cluster.RandFollowerRegion(sourceID, core.HealthRegion())
+				region = cluster.RandFollowerRegion(sourceID, healthRegion(cluster))
 			}
 			if region == nil {
 				// Last, picks the region has the leader in the source store.
-				region = cluster.RandLeaderRegion(sourceID, core.HealthRegion())
+				region = cluster.RandLeaderRegion(sourceID, healthRegion(cluster))
 			}
 			if region == nil {
 				schedulerCounter.WithLabelValues(s.GetName(), "no-region").Inc()
@@ -131,7 +131,7 @@ func (s *balanceRegionScheduler) Schedule(cluster opt.Cluster) []*operator.Opera
 			log.Debug("select region", zap.String("scheduler", s.GetName()), zap.Uint64("region-id", region.GetID()))
 
 			// We don't schedule region with abnormal number of replicas.
-			if len(region.GetPeers()) != cluster.GetMaxReplicas() {
+			if isAbnormalReplicaCount(cluster, region) {
 				log.Debug("region has abnormal replica count", zap.String("scheduler", s.GetName()), zap.Uint64("region-id", region.GetID()))
 				schedulerCounter.WithLabelValues(s.GetName(), "abnormal-replica").Inc()
 				continue
@@ -167,8 +167,14 @@ func (s *balanceRegionScheduler) transferPeer(cluster opt.Cluster, region *core.
 	checker := checker.NewReplicaChecker(cluster, nil, s.GetName())
 	exclude := make(map[uint64]struct{})
 	excludeFilter := filter.NewExcludedFilter(s.name, nil, exclude)
+	spareFilter := newSpareStoreFilter(s.GetName(), cluster)
+	blacklistFilter := newStoreBlacklistFilter(s.GetName(), cluster)
+	slowFilter := newSlowStoreFilter(s.GetName(), cluster)
+	joinFilter := newJoinThrottleFilter(s.GetName(), cluster)
+	zoneFilter := newRegionZoneFilter(s.GetName(), cluster, region, sourceStoreID)
+	filters := []filter.Filter{scoreGuard, excludeFilter, spareFilter, blacklistFilter, slowFilter, joinFilter, zoneFilter}
 	for {
-		storeID, _ := checker.SelectBestReplacementStore(region, oldPeer, scoreGuard, excludeFilter)
+		storeID := s.selectReplacementStore(cluster, checker, region, oldPeer, filters)
 		if storeID == 0 {
 			schedulerCounter.WithLabelValues(s.GetName(), "no-replacement").Inc()
 			return nil
@@ -210,3 +216,33 @@ func (s *balanceRegionScheduler) transferPeer(cluster opt.Cluster, region *core.
 		return op
 	}
 }
+
+// moveTargetCluster is implemented by clusters which select the targets of
+// moving peers by themselves, e.g. to prefer the moves improving isolation.
+type moveTargetCluster interface {
+	SelectMoveTarget(region *core.RegionInfo, fromStore uint64, candidates []*core.StoreInfo) *core.StoreInfo
+}
+
+// selectReplacementStore selects the store to replace the old peer of the
+// region with, which passes the filters. It returns 0 if there is none.
+func (s *balanceRegionScheduler) selectReplacementStore(cluster opt.Cluster, checker *checker.ReplicaChecker, region *core.RegionInfo, oldPeer *metapb.Peer, filters []filter.Filter) uint64 {
+	c, ok := cluster.(moveTargetCluster)
+	if !ok {
+		storeID, _ := checker.SelectBestReplacementStore(region, oldPeer, filters...)
+		return storeID
+	}
+	// The same filters as the replica checker applies to the targets.
+	filters = append(filters,
+		filter.NewStoreLimitFilter(s.GetName()),
+		filter.NewHealthFilter(s.GetName()),
+		filter.NewSnapshotCountFilter(s.GetName()),
+		filter.NewPendingPeerCountFilter(s.GetName()),
+		filter.NewStateFilter(s.GetName()),
+		filter.NewExcludedFilter(s.GetName(), nil, region.GetStoreIds()),
+	)
+	target := c.SelectMoveTarget(region, oldPeer.GetStoreId(), filter.SelectTargetStores(cluster.GetStores(), filters, cluster))
+	if target == nil {
+		return 0
+	}
+	return target.GetID()
+}
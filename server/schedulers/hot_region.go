@@ -169,10 +169,21 @@ func (h *balanceHotRegionsScheduler) dispatch(typ BalanceType, cluster opt.Clust
 	return nil
 }
 
+// hotReadLeaderCluster is implemented by clusters which balance the hot read
+// leaders by themselves, e.g. to balance them within a namespace.
+type hotReadLeaderCluster interface {
+	CreateHotReadLeaderOperator() *operator.Operator
+}
+
 func (h *balanceHotRegionsScheduler) balanceHotReadRegions(cluster opt.Cluster) []*operator.Operator {
 	// balance by leader
-	srcRegion, newLeader := h.balanceByLeader(cluster, h.stats.readStatAsLeader)
-	if srcRegion != nil {
+	if c, ok := cluster.(hotReadLeaderCluster); ok {
+		if op := c.CreateHotReadLeaderOperator(); op != nil {
+			schedulerCounter.WithLabelValues(h.GetName(), "move-leader").Inc()
+			op.SetPriorityLevel(core.HighPriority)
+			return []*operator.Operator{op}
+		}
+	} else if srcRegion, newLeader := h.balanceByLeader(cluster, h.stats.readStatAsLeader); srcRegion != nil {
 		schedulerCounter.WithLabelValues(h.GetName(), "move-leader").Inc()
 		op := operator.CreateTransferLeaderOperator("transfer-hot-read-leader", srcRegion, srcRegion.GetLeader().GetStoreId(), newLeader.GetStoreId(), operator.OpHotRegion)
 		op.SetPriorityLevel(core.HighPriority)
@@ -299,17 +310,22 @@ func (h *balanceHotRegionsScheduler) balanceByPeer(cluster opt.Cluster, storesSt
 			continue
 		}
 
-		if isRegionUnhealthy(srcRegion) {
+		if isRegionUnhealthyWithReadReplicas(cluster, srcRegion) {
 			schedulerCounter.WithLabelValues(h.GetName(), "unhealthy-replica").Inc()
 			continue
 		}
 
-		if len(srcRegion.GetPeers()) != cluster.GetMaxReplicas() {
+		if isAbnormalReplicaCount(cluster, srcRegion) {
 			log.Debug("region has abnormal replica count", zap.String("scheduler", h.GetName()), zap.Uint64("region-id", srcRegion.GetID()))
 			schedulerCounter.WithLabelValues(h.GetName(), "abnormal-replica").Inc()
 			continue
 		}
 
+		if isReadReplica(cluster, srcRegion, srcStoreID) {
+			schedulerCounter.WithLabelValues(h.GetName(), "read-replica").Inc()
+			continue
+		}
+
 		srcStore := cluster.GetStore(srcStoreID)
 		if srcStore == nil {
 			log.Error("failed to get the source store", zap.Uint64("store-id", srcStoreID))
@@ -371,7 +387,7 @@ func (h *balanceHotRegionsScheduler) balanceByLeader(cluster opt.Cluster, stores
 			continue
 		}
 
-		if isRegionUnhealthy(srcRegion) {
+		if isRegionUnhealthyWithReadReplicas(cluster, srcRegion) {
 			schedulerCounter.WithLabelValues(h.GetName(), "unhealthy-replica").Inc()
 			continue
 		}
@@ -0,0 +1,148 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule"
+	"github.com/pingcap/pd/server/schedule/filter"
+	"github.com/pingcap/pd/server/schedule/operator"
+	"github.com/pingcap/pd/server/schedule/opt"
+	"github.com/pingcap/pd/server/schedule/selector"
+)
+
+func init() {
+	schedule.RegisterSliceDecoderBuilder("namespace-adjacent-region", func(args []string) schedule.ConfigDecoder {
+		return func(v interface{}) error {
+			conf, ok := v.(*namespaceAdjacentRegionSchedulerConfig)
+			if !ok {
+				return ErrScheduleConfigNotExist
+			}
+			conf.Limit = uint64(1)
+			if len(args) == 1 {
+				limit, err := strconv.ParseUint(args[0], 10, 64)
+				if err != nil {
+					return err
+				}
+				conf.Limit = limit
+			}
+			return nil
+		}
+	})
+
+	schedule.RegisterScheduler("namespace-adjacent-region", func(opController *schedule.OperatorController, storage *core.Storage, decoder schedule.ConfigDecoder) (schedule.Scheduler, error) {
+		conf := &namespaceAdjacentRegionSchedulerConfig{Limit: uint64(1)}
+		decoder(conf)
+		return newNamespaceAdjacentRegionScheduler(opController, conf), nil
+	})
+}
+
+const namespaceAdjacentRegionName = "namespace-adjacent-region-scheduler"
+
+type namespaceAdjacentRegionSchedulerConfig struct {
+	// Limit is the max number of leaders transferred in one schedule.
+	Limit uint64 `json:"limit"`
+}
+
+type namespaceAdjacentRegionScheduler struct {
+	*baseScheduler
+	conf     *namespaceAdjacentRegionSchedulerConfig
+	selector *selector.RandomSelector
+	// lastKey is the key the next scan starts from, so that the regions are
+	// scanned part by part. It is reset once the scan reaches the end.
+	lastKey []byte
+}
+
+// newNamespaceAdjacentRegionScheduler creates a scheduler that separates the
+// leaders of adjacent hot regions which are located in the same store. Only
+// the regions and stores of the cluster passed to Schedule are considered, so
+// it works within a namespace when the scheduler is run by namespace.
+func newNamespaceAdjacentRegionScheduler(opController *schedule.OperatorController, conf *namespaceAdjacentRegionSchedulerConfig) schedule.Scheduler {
+	filters := []filter.Filter{
+		filter.StoreStateFilter{ActionScope: namespaceAdjacentRegionName, TransferLeader: true},
+	}
+	base := newBaseScheduler(opController)
+	return &namespaceAdjacentRegionScheduler{
+		baseScheduler: base,
+		conf:          conf,
+		selector:      selector.NewRandomSelector(filters),
+	}
+}
+
+func (s *namespaceAdjacentRegionScheduler) GetName() string {
+	return namespaceAdjacentRegionName
+}
+
+func (s *namespaceAdjacentRegionScheduler) GetType() string {
+	return "namespace-adjacent-region"
+}
+
+func (s *namespaceAdjacentRegionScheduler) EncodeConfig() ([]byte, error) {
+	return schedule.EncodeConfig(s.conf)
+}
+
+func (s *namespaceAdjacentRegionScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
+	return s.opController.OperatorCount(operator.OpAdjacent|operator.OpLeader) < cluster.GetLeaderScheduleLimit()
+}
+
+func (s *namespaceAdjacentRegionScheduler) Schedule(cluster opt.Cluster) []*operator.Operator {
+	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
+	var ops []*operator.Operator
+	var last *core.RegionInfo
+	regions := cluster.ScanRegions(s.lastKey, nil, scanLimit)
+	blacklistFilter := newStoreBlacklistFilter(s.GetName(), cluster)
+	for _, r := range regions {
+		if uint64(len(ops)) >= s.conf.Limit {
+			// The next scan resumes after the region of the last operator.
+			return ops
+		}
+		// The next scan starts from the last region checked, so that it is
+		// still compared with the one after it.
+		s.lastKey = r.GetStartKey()
+		// Regions out of the cluster, e.g. regions of other namespaces, are
+		// not seen by GetRegion.
+		if cluster.GetRegion(r.GetID()) == nil || r.GetLeader() == nil || !cluster.IsRegionHot(r) {
+			last = nil
+			continue
+		}
+		if last == nil || !bytes.Equal(last.GetEndKey(), r.GetStartKey()) ||
+			last.GetLeader().GetStoreId() != r.GetLeader().GetStoreId() {
+			last = r
+			continue
+		}
+		target := s.selector.SelectTarget(cluster, filterLeaderEligibleStores(cluster, cluster.GetFollowerStores(r)), blacklistFilter)
+		if target == nil {
+			schedulerCounter.WithLabelValues(s.GetName(), "no-target-store").Inc()
+			last = r
+			continue
+		}
+		schedulerCounter.WithLabelValues(s.GetName(), "new-operator").Inc()
+		op := operator.CreateTransferLeaderOperator("namespace-adjacent-leader", r, r.GetLeader().GetStoreId(), target.GetID(), operator.OpAdjacent)
+		op.SetPriorityLevel(core.LowPriority)
+		ops = append(ops, op)
+		// The leader of r is moved away, so the next region is not co-located
+		// with it anymore.
+		last = nil
+		s.lastKey = r.GetEndKey()
+	}
+	// The scan starts over from the first region next time if it reaches the
+	// end.
+	if len(regions) < scanLimit {
+		s.lastKey = nil
+	}
+	return ops
+}
@@ -0,0 +1,251 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule"
+	"github.com/pingcap/pd/server/schedule/filter"
+	"github.com/pingcap/pd/server/schedule/operator"
+	"github.com/pingcap/pd/server/schedule/opt"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	// args: [objective], where objective is max-load or load-variance.
+	schedule.RegisterSliceDecoderBuilder("optimize-namespace-balance", func(args []string) schedule.ConfigDecoder {
+		return func(v interface{}) error {
+			conf, ok := v.(*optimizeNamespaceBalanceSchedulerConfig)
+			if !ok {
+				return ErrScheduleConfigNotExist
+			}
+			if len(args) > 1 {
+				return errors.New("should specify at most one objective")
+			}
+			if len(args) == 1 {
+				if _, ok := balanceObjectives[args[0]]; !ok {
+					return errors.Errorf("unknown objective %s", args[0])
+				}
+				conf.Objective = args[0]
+			}
+			return nil
+		}
+	})
+	schedule.RegisterScheduler("optimize-namespace-balance", func(opController *schedule.OperatorController, storage *core.Storage, decoder schedule.ConfigDecoder) (schedule.Scheduler, error) {
+		conf := &optimizeNamespaceBalanceSchedulerConfig{Objective: loadVarianceObjective}
+		if err := decoder(conf); err != nil {
+			return nil, err
+		}
+		return newOptimizeNamespaceBalanceScheduler(opController, conf), nil
+	})
+}
+
+const (
+	optimizeNamespaceBalanceName = "optimize-namespace-balance-scheduler"
+	// optimizeBalanceSampleLimit is the number of regions sampled from each
+	// source store as the candidates to move.
+	optimizeBalanceSampleLimit = 16
+)
+
+const (
+	maxLoadObjective      = "max-load"
+	loadVarianceObjective = "load-variance"
+)
+
+// objectiveFunction evaluates the loads of stores keyed by store ID. The lower
+// the value is, the better the loads are balanced.
+type objectiveFunction func(loads map[uint64]float64) float64
+
+// balanceObjectives is the objectives the scheduler can optimize, keyed by
+// name.
+var balanceObjectives = map[string]objectiveFunction{
+	maxLoadObjective:      maxLoad,
+	loadVarianceObjective: loadVariance,
+}
+
+// maxLoad is the objective to minimize the max load of stores.
+func maxLoad(loads map[uint64]float64) float64 {
+	var max float64
+	for _, load := range loads {
+		if load > max {
+			max = load
+		}
+	}
+	return max
+}
+
+// loadVariance is the objective to minimize the variance of the loads of
+// stores.
+func loadVariance(loads map[uint64]float64) float64 {
+	if len(loads) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, load := range loads {
+		sum += load
+	}
+	mean := sum / float64(len(loads))
+	var variance float64
+	for _, load := range loads {
+		variance += (load - mean) * (load - mean)
+	}
+	return variance / float64(len(loads))
+}
+
+// storeLoadCluster is implemented by clusters which provide the loads of
+// stores.
+type storeLoadCluster interface {
+	GetStoreLoads() map[uint64]float64
+}
+
+type optimizeNamespaceBalanceSchedulerConfig struct {
+	Objective string `json:"objective"`
+}
+
+type optimizeNamespaceBalanceScheduler struct {
+	*baseScheduler
+	conf    *optimizeNamespaceBalanceSchedulerConfig
+	filters []filter.Filter
+}
+
+// newOptimizeNamespaceBalanceScheduler creates a scheduler that moves the peer
+// which improves the objective of the store loads the most. The candidate
+// moves are the ones of the regions sampled from each source store to the
+// target stores which keep the distinct score. It schedules only if the
+// cluster provides the loads, e.g. when it is run by namespace.
+func newOptimizeNamespaceBalanceScheduler(opController *schedule.OperatorController, conf *optimizeNamespaceBalanceSchedulerConfig) schedule.Scheduler {
+	base := newBaseScheduler(opController)
+	return &optimizeNamespaceBalanceScheduler{
+		baseScheduler: base,
+		conf:          conf,
+		filters:       []filter.Filter{filter.StoreStateFilter{ActionScope: optimizeNamespaceBalanceName, MoveRegion: true}},
+	}
+}
+
+func (s *optimizeNamespaceBalanceScheduler) GetName() string {
+	return optimizeNamespaceBalanceName
+}
+
+func (s *optimizeNamespaceBalanceScheduler) GetType() string {
+	return "optimize-namespace-balance"
+}
+
+func (s *optimizeNamespaceBalanceScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
+	return s.opController.OperatorCount(operator.OpRegion) < cluster.GetRegionScheduleLimit()
+}
+
+// balanceMove is a candidate move of a region peer.
+type balanceMove struct {
+	region         *core.RegionInfo
+	source, target uint64
+	improvement    float64
+}
+
+// better returns true if the move improves the objective more than the other,
+// and the ones with the same improvement are preferred in ascending order of
+// region, source and target store IDs.
+func (m *balanceMove) better(other *balanceMove) bool {
+	switch {
+	case other == nil:
+		return true
+	case m.improvement != other.improvement:
+		return m.improvement > other.improvement
+	case m.region.GetID() != other.region.GetID():
+		return m.region.GetID() < other.region.GetID()
+	case m.source != other.source:
+		return m.source < other.source
+	default:
+		return m.target < other.target
+	}
+}
+
+func (s *optimizeNamespaceBalanceScheduler) Schedule(cluster opt.Cluster) []*operator.Operator {
+	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
+	c, ok := cluster.(storeLoadCluster)
+	if !ok {
+		return nil
+	}
+	objective := balanceObjectives[s.conf.Objective]
+	loads := c.GetStoreLoads()
+	current := objective(loads)
+	sources := filter.SelectSourceStores(cluster.GetStores(), s.filters, cluster)
+	targetFilters := append([]filter.Filter{newStoreBlacklistFilter(s.GetName(), cluster), newJoinThrottleFilter(s.GetName(), cluster)}, s.filters...)
+	targets := filter.SelectTargetStores(cluster.GetStores(), targetFilters, cluster)
+
+	var best *balanceMove
+	for _, source := range sources {
+		if _, ok := loads[source.GetID()]; !ok {
+			continue
+		}
+		for _, region := range s.sampleRegions(cluster, source.GetID()) {
+			size := float64(region.GetApproximateSize())
+			guards := []filter.Filter{
+				filter.NewDistinctScoreFilter(s.GetName(), cluster.GetLocationLabels(), cluster.GetRegionStores(region), source),
+				newRegionZoneFilter(s.GetName(), cluster, region, source.GetID()),
+			}
+			for _, target := range targets {
+				if _, ok := loads[target.GetID()]; !ok || region.GetStorePeer(target.GetID()) != nil {
+					continue
+				}
+				if filter.Target(cluster, target, guards) {
+					continue
+				}
+				loads[source.GetID()] -= size
+				loads[target.GetID()] += size
+				move := &balanceMove{region: region, source: source.GetID(), target: target.GetID(), improvement: current - objective(loads)}
+				loads[source.GetID()] += size
+				loads[target.GetID()] -= size
+				if move.improvement > 0 && move.better(best) {
+					best = move
+				}
+			}
+		}
+	}
+	if best == nil {
+		schedulerCounter.WithLabelValues(s.GetName(), "balanced").Inc()
+		return nil
+	}
+	newPeer, err := cluster.AllocPeer(best.target)
+	if err != nil {
+		schedulerCounter.WithLabelValues(s.GetName(), "no-peer").Inc()
+		return nil
+	}
+	op, err := operator.CreateMovePeerOperator("optimize-namespace-balance", cluster, best.region, operator.OpBalance, best.source, best.target, newPeer.GetId())
+	if err != nil {
+		schedulerCounter.WithLabelValues(s.GetName(), "create-operator-fail").Inc()
+		return nil
+	}
+	schedulerCounter.WithLabelValues(s.GetName(), "new-operator").Inc()
+	return []*operator.Operator{op}
+}
+
+// sampleRegions samples up to optimizeBalanceSampleLimit distinct healthy
+// regions which have a peer on the store, alternating between the ones with a
+// follower and a leader on it.
+func (s *optimizeNamespaceBalanceScheduler) sampleRegions(cluster opt.Cluster, storeID uint64) []*core.RegionInfo {
+	sampled := make(map[uint64]*core.RegionInfo)
+	randRegions := []func(uint64, ...core.RegionOption) *core.RegionInfo{cluster.RandFollowerRegion, cluster.RandLeaderRegion}
+	for i := 0; i < 2*optimizeBalanceSampleLimit && len(sampled) < optimizeBalanceSampleLimit; i++ {
+		region := randRegions[i%2](storeID, healthRegion(cluster))
+		if region == nil || isAbnormalReplicaCount(cluster, region) {
+			continue
+		}
+		sampled[region.GetID()] = region
+	}
+	regions := make([]*core.RegionInfo, 0, len(sampled))
+	for _, r := range sampled {
+		regions = append(regions, r)
+	}
+	return regions
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"sort"
+
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule"
+	"github.com/pingcap/pd/server/schedule/filter"
+	"github.com/pingcap/pd/server/schedule/operator"
+	"github.com/pingcap/pd/server/schedule/opt"
+)
+
+func init() {
+	schedule.RegisterSliceDecoderBuilder("populate-namespace-store", func(args []string) schedule.ConfigDecoder {
+		return func(v interface{}) error {
+			return nil
+		}
+	})
+	schedule.RegisterScheduler("populate-namespace-store", func(opController *schedule.OperatorController, storage *core.Storage, decoder schedule.ConfigDecoder) (schedule.Scheduler, error) {
+		return newPopulateNamespaceStoreScheduler(opController), nil
+	})
+}
+
+const populateNamespaceStoreName = "populate-namespace-store-scheduler"
+
+// freshStoreCluster is implemented by clusters which detect the stores just
+// joined and not populated yet.
+type freshStoreCluster interface {
+	GetFreshStores() []uint64
+}
+
+type populateNamespaceStoreScheduler struct {
+	*baseScheduler
+	filters []filter.Filter
+}
+
+// newPopulateNamespaceStoreScheduler creates a scheduler that populates the
+// fresh stores by moving peers from the stores with the most regions onto
+// them, one region at a time within the region schedule limit, so that the
+// regions migrate gradually. It schedules only if the cluster detects fresh
+// stores, e.g. when it is run by namespace.
+func newPopulateNamespaceStoreScheduler(opController *schedule.OperatorController) schedule.Scheduler {
+	base := newBaseScheduler(opController)
+	return &populateNamespaceStoreScheduler{
+		baseScheduler: base,
+		filters:       []filter.Filter{filter.StoreStateFilter{ActionScope: populateNamespaceStoreName, MoveRegion: true}},
+	}
+}
+
+func (s *populateNamespaceStoreScheduler) GetName() string {
+	return populateNamespaceStoreName
+}
+
+func (s *populateNamespaceStoreScheduler) GetType() string {
+	return "populate-namespace-store"
+}
+
+func (s *populateNamespaceStoreScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
+	return s.opController.OperatorCount(operator.OpRegion) < cluster.GetRegionScheduleLimit()
+}
+
+func (s *populateNamespaceStoreScheduler) Schedule(cluster opt.Cluster) []*operator.Operator {
+	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
+	c, ok := cluster.(freshStoreCluster)
+	if !ok {
+		return nil
+	}
+	freshIDs := c.GetFreshStores()
+	if len(freshIDs) == 0 {
+		return nil
+	}
+	fresh := make(map[uint64]*core.StoreInfo, len(freshIDs))
+	for _, id := range freshIDs {
+		if store := cluster.GetStore(id); store != nil {
+			fresh[id] = store
+		}
+	}
+
+	stores := cluster.GetStores()
+	var sources []*core.StoreInfo
+	for _, store := range filter.SelectSourceStores(stores, s.filters, cluster) {
+		if _, ok := fresh[store.GetID()]; !ok {
+			sources = append(sources, store)
+		}
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].GetRegionCount() > sources[j].GetRegionCount()
+	})
+	targetFilters := append([]filter.Filter{newSpareStoreFilter(s.GetName(), cluster), newStoreBlacklistFilter(s.GetName(), cluster), newJoinThrottleFilter(s.GetName(), cluster)}, s.filters...)
+	var targets []*core.StoreInfo
+	for _, store := range filter.SelectTargetStores(stores, targetFilters, cluster) {
+		if _, ok := fresh[store.GetID()]; ok {
+			targets = append(targets, store)
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].GetRegionCount() < targets[j].GetRegionCount()
+	})
+
+	for _, target := range targets {
+		for _, source := range sources {
+			if source.GetRegionCount() <= target.GetRegionCount()+1 {
+				// The following sources have fewer regions.
+				break
+			}
+			if op := s.moveRegion(cluster, source, target); op != nil {
+				schedulerCounter.WithLabelValues(s.GetName(), "new-operator").Inc()
+				return []*operator.Operator{op}
+			}
+		}
+	}
+	schedulerCounter.WithLabelValues(s.GetName(), "no-region").Inc()
+	return nil
+}
+
+// moveRegion tries to move a region from the source store to the fresh
+// target store without breaking the isolation of the region.
+func (s *populateNamespaceStoreScheduler) moveRegion(cluster opt.Cluster, source, target *core.StoreInfo) *operator.Operator {
+	return moveRegionToStore(cluster, s.GetName(), "populate-namespace-store", source, target, nil)
+}
@@ -441,6 +441,71 @@ func (s *testEvictLeaderSuite) TestEvictLeader(c *C) {
 	testutil.CheckTransferLeader(c, op[0], operator.OpLeader, 1, 2)
 }
 
+var _ = Suite(&testNamespaceAdjacentRegionSuite{})
+
+type testNamespaceAdjacentRegionSuite struct{}
+
+func (s *testNamespaceAdjacentRegionSuite) TestSeparate(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opt := mockoption.NewScheduleOptions()
+	opt.HotRegionCacheHitsThreshold = 0
+	tc := mockcluster.NewCluster(opt)
+	sc, err := schedule.CreateScheduler("namespace-adjacent-region", schedule.NewOperatorController(ctx, nil, nil), core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder("namespace-adjacent-region", nil))
+	c.Assert(err, IsNil)
+	c.Assert(sc.IsScheduleAllowed(tc), IsTrue)
+
+	tc.AddLeaderStore(1, 0)
+	tc.AddLeaderStore(2, 0)
+	tc.AddLeaderStore(3, 0)
+	// Region 1, 2, 4 and 5 are hot regions.
+	//| region_id | range | leader_store | follower_store | follower_store |
+	//|-----------|-------|--------------|----------------|----------------|
+	//|     1     |  -a   |       1      |        2       |       3        |
+	//|     2     |  a-b  |       1      |        2       |       3        |
+	//|     3     |  b-c  |       1      |        2       |       3        |
+	//|     4     |  c-d  |       2      |        1       |       3        |
+	//|     5     |  d-e  |       2      |        1       |       3        |
+	for _, id := range []uint64{1, 2, 4, 5} {
+		tc.AddLeaderRegionWithWriteInfo(id, 1, 512*1024*statistics.RegionHeartBeatReportInterval, statistics.RegionHeartBeatReportInterval, 2, 3)
+	}
+	tc.AddLeaderRegionWithRange(1, "", "a", 1, 2, 3)
+	tc.AddLeaderRegionWithRange(2, "a", "b", 1, 2, 3)
+	tc.AddLeaderRegionWithRange(3, "b", "c", 1, 2, 3)
+	tc.AddLeaderRegionWithRange(4, "c", "d", 2, 1, 3)
+	tc.AddLeaderRegionWithRange(5, "d", "e", 2, 1, 3)
+	c.Assert(tc.IsRegionHot(tc.GetRegion(3)), IsFalse)
+
+	// The operations are bounded by the limit.
+	ops := sc.Schedule(tc)
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].RegionID(), Equals, uint64(2))
+	c.Assert(ops[0].Kind()&operator.OpAdjacent, Equals, operator.OpAdjacent)
+	c.Assert(ops[0].Step(0).(operator.TransferLeader).FromStore, Equals, uint64(1))
+	c.Assert(ops[0].Step(0).(operator.TransferLeader).ToStore, Not(Equals), uint64(1))
+
+	// The scan resumes after the region of the last operator.
+	ops = sc.Schedule(tc)
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].RegionID(), Equals, uint64(5))
+	// The scan starts over once it reaches the end.
+	ops = sc.Schedule(tc)
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].RegionID(), Equals, uint64(2))
+
+	sc.(*namespaceAdjacentRegionScheduler).conf.Limit = 2
+	sc.(*namespaceAdjacentRegionScheduler).lastKey = nil
+	ops = sc.Schedule(tc)
+	c.Assert(ops, HasLen, 2)
+	c.Assert(ops[1].RegionID(), Equals, uint64(5))
+	c.Assert(ops[1].Step(0).(operator.TransferLeader).FromStore, Equals, uint64(2))
+
+	// Nothing to do once the leaders are separated.
+	tc.AddLeaderRegionWithRange(2, "a", "b", 2, 1, 3)
+	tc.AddLeaderRegionWithRange(5, "d", "e", 3, 1, 2)
+	c.Assert(sc.Schedule(tc), HasLen, 0)
+}
+
 var _ = Suite(&testShuffleRegionSuite{})
 
 type testShuffleRegionSuite struct{}
@@ -0,0 +1,143 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"strconv"
+
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule"
+	"github.com/pingcap/pd/server/schedule/filter"
+	"github.com/pingcap/pd/server/schedule/operator"
+	"github.com/pingcap/pd/server/schedule/opt"
+	"github.com/pingcap/pd/server/schedule/selector"
+)
+
+func init() {
+	schedule.RegisterSliceDecoderBuilder("shuffle-namespace-leader", func(args []string) schedule.ConfigDecoder {
+		return func(v interface{}) error {
+			conf, ok := v.(*shuffleNamespaceLeaderSchedulerConfig)
+			if !ok {
+				return ErrScheduleConfigNotExist
+			}
+			conf.Limit = uint64(1)
+			if len(args) == 1 {
+				limit, err := strconv.ParseUint(args[0], 10, 64)
+				if err != nil {
+					return err
+				}
+				conf.Limit = limit
+			}
+			return nil
+		}
+	})
+
+	schedule.RegisterScheduler("shuffle-namespace-leader", func(opController *schedule.OperatorController, storage *core.Storage, decoder schedule.ConfigDecoder) (schedule.Scheduler, error) {
+		conf := &shuffleNamespaceLeaderSchedulerConfig{Limit: uint64(1)}
+		decoder(conf)
+		return newShuffleNamespaceLeaderScheduler(opController, conf), nil
+	})
+}
+
+const shuffleNamespaceLeaderName = "shuffle-namespace-leader-scheduler"
+
+type shuffleNamespaceLeaderSchedulerConfig struct {
+	// Limit is the max number of leaders shuffled in one schedule.
+	Limit uint64 `json:"limit"`
+}
+
+type shuffleNamespaceLeaderScheduler struct {
+	*baseScheduler
+	conf     *shuffleNamespaceLeaderSchedulerConfig
+	selector *selector.RandomSelector
+}
+
+// newShuffleNamespaceLeaderScheduler creates an admin scheduler that shuffles
+// leaders among the stores of a namespace. The stores and regions are taken
+// from the cluster passed to Schedule, which is scoped to a namespace when the
+// scheduler is run by namespace.
+func newShuffleNamespaceLeaderScheduler(opController *schedule.OperatorController, conf *shuffleNamespaceLeaderSchedulerConfig) schedule.Scheduler {
+	filters := []filter.Filter{
+		filter.StoreStateFilter{ActionScope: shuffleNamespaceLeaderName, TransferLeader: true},
+	}
+	base := newBaseScheduler(opController)
+	return &shuffleNamespaceLeaderScheduler{
+		baseScheduler: base,
+		conf:          conf,
+		selector:      selector.NewRandomSelector(filters),
+	}
+}
+
+func (s *shuffleNamespaceLeaderScheduler) GetName() string {
+	return shuffleNamespaceLeaderName
+}
+
+func (s *shuffleNamespaceLeaderScheduler) GetType() string {
+	return "shuffle-namespace-leader"
+}
+
+func (s *shuffleNamespaceLeaderScheduler) EncodeConfig() ([]byte, error) {
+	return schedule.EncodeConfig(s.conf)
+}
+
+func (s *shuffleNamespaceLeaderScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
+	return s.opController.OperatorCount(operator.OpLeader) < cluster.GetLeaderScheduleLimit()
+}
+
+func (s *shuffleNamespaceLeaderScheduler) Schedule(cluster opt.Cluster) []*operator.Operator {
+	// We shuffle leaders by repeating at most limit times:
+	// 1. random select a valid store as the source.
+	// 2. random select a leader region of the source store.
+	// 3. transfer the leader to a random valid follower store of the region.
+	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
+	stores := cluster.GetStores()
+	validStores := make(map[uint64]struct{}, len(stores))
+	for _, store := range stores {
+		validStores[store.GetID()] = struct{}{}
+	}
+	var ops []*operator.Operator
+	shuffled := make(map[uint64]struct{})
+	for i := uint64(0); i < s.conf.Limit; i++ {
+		source := s.selector.SelectSource(cluster, stores)
+		if source == nil {
+			schedulerCounter.WithLabelValues(s.GetName(), "no-source-store").Inc()
+			break
+		}
+		region := cluster.RandLeaderRegion(source.GetID(), core.HealthRegion())
+		if region == nil {
+			schedulerCounter.WithLabelValues(s.GetName(), "no-leader").Inc()
+			continue
+		}
+		if _, ok := shuffled[region.GetID()]; ok {
+			continue
+		}
+		var targets []*core.StoreInfo
+		for _, store := range cluster.GetFollowerStores(region) {
+			if _, ok := validStores[store.GetID()]; ok {
+				targets = append(targets, store)
+			}
+		}
+		target := s.selector.SelectTarget(cluster, filterLeaderEligibleStores(cluster, targets))
+		if target == nil {
+			schedulerCounter.WithLabelValues(s.GetName(), "no-target-store").Inc()
+			continue
+		}
+		schedulerCounter.WithLabelValues(s.GetName(), "new-operator").Inc()
+		op := operator.CreateTransferLeaderOperator("shuffle-namespace-leader", region, source.GetID(), target.GetID(), operator.OpAdmin)
+		op.SetPriorityLevel(core.HighPriority)
+		ops = append(ops, op)
+		shuffled[region.GetID()] = struct{}{}
+	}
+	return ops
+}
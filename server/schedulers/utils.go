@@ -21,6 +21,7 @@ import (
 	"github.com/pingcap/log"
 	"github.com/pingcap/pd/pkg/cache"
 	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule/filter"
 	"github.com/pingcap/pd/server/schedule/operator"
 	"github.com/pingcap/pd/server/schedule/opt"
 	"github.com/pkg/errors"
@@ -149,3 +150,278 @@ const (
 func newTaintCache(ctx context.Context) *cache.TTLUint64 {
 	return cache.NewIDTTL(ctx, taintCacheGCInterval, taintCacheTTL)
 }
+
+// leaderEligibleCluster is implemented by clusters in which only some of the
+// stores are able to hold leaders.
+type leaderEligibleCluster interface {
+	GetLeaderEligibleStores() []*core.StoreInfo
+}
+
+// filterLeaderEligibleStores keeps the stores which are able to hold leaders,
+// if the cluster tells which stores are eligible.
+func filterLeaderEligibleStores(cluster opt.Cluster, stores []*core.StoreInfo) []*core.StoreInfo {
+	c, ok := cluster.(leaderEligibleCluster)
+	if !ok {
+		return stores
+	}
+	eligible := make(map[uint64]struct{})
+	for _, s := range c.GetLeaderEligibleStores() {
+		eligible[s.GetID()] = struct{}{}
+	}
+	res := make([]*core.StoreInfo, 0, len(stores))
+	for _, s := range stores {
+		if _, ok := eligible[s.GetID()]; ok {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// storeBlacklistCluster is implemented by clusters in which some of the stores
+// are blacklisted as scheduling targets.
+type storeBlacklistCluster interface {
+	GetBlacklistedStores() []uint64
+}
+
+// newStoreBlacklistFilter creates a filter that filters the blacklisted stores
+// out as targets, if the cluster has a store blacklist.
+func newStoreBlacklistFilter(scope string, cluster opt.Cluster) filter.Filter {
+	f := filter.NewBlacklistStoreFilter(scope, filter.BlacklistTarget)
+	if c, ok := cluster.(storeBlacklistCluster); ok {
+		for _, id := range c.GetBlacklistedStores() {
+			f.Add(id)
+		}
+	}
+	return f
+}
+
+// leaseAwareCluster is implemented by clusters which know whether the leader
+// leases of the stores are stable.
+type leaseAwareCluster interface {
+	IsLeaseStable(storeID uint64) bool
+}
+
+// newLeaseStableFilter creates a filter that filters the stores whose leader
+// leases are unstable out as targets, if the cluster knows the leases.
+func newLeaseStableFilter(scope string, cluster opt.Cluster) filter.Filter {
+	unstable := make(map[uint64]struct{})
+	if c, ok := cluster.(leaseAwareCluster); ok {
+		for _, s := range cluster.GetStores() {
+			if !c.IsLeaseStable(s.GetID()) {
+				unstable[s.GetID()] = struct{}{}
+			}
+		}
+	}
+	return filter.NewExcludedFilter(scope, nil, unstable)
+}
+
+// slowStoreCluster is implemented by clusters which detect slow stores.
+type slowStoreCluster interface {
+	IsSlowStore(storeID uint64) bool
+}
+
+// newSlowStoreFilter creates a filter that filters the slow stores out as
+// targets, if the cluster detects slow stores.
+func newSlowStoreFilter(scope string, cluster opt.Cluster) filter.Filter {
+	slow := make(map[uint64]struct{})
+	if c, ok := cluster.(slowStoreCluster); ok {
+		for _, s := range cluster.GetStores() {
+			if c.IsSlowStore(s.GetID()) {
+				slow[s.GetID()] = struct{}{}
+			}
+		}
+	}
+	return filter.NewExcludedFilter(scope, nil, slow)
+}
+
+// joinThrottleCluster is implemented by clusters which throttle the peers moved
+// onto the stores just joined.
+type joinThrottleCluster interface {
+	IsJoinThrottled(storeID uint64) bool
+}
+
+// newJoinThrottleFilter creates a filter that filters the stores throttled
+// after joining out as targets, if the cluster throttles them.
+func newJoinThrottleFilter(scope string, cluster opt.Cluster) filter.Filter {
+	throttled := make(map[uint64]struct{})
+	if c, ok := cluster.(joinThrottleCluster); ok {
+		for _, s := range cluster.GetStores() {
+			if c.IsJoinThrottled(s.GetID()) {
+				throttled[s.GetID()] = struct{}{}
+			}
+		}
+	}
+	return filter.NewExcludedFilter(scope, nil, throttled)
+}
+
+// zoneCluster is implemented by clusters which place the replicas of regions
+// in distinct availability zones and keep the leaders in a preferred zone.
+type zoneCluster interface {
+	GetZoneLabel() string
+	GetPreferredLeaderZone() string
+}
+
+// newRegionZoneFilter creates a filter that filters the stores in the zones
+// which have the peers of the region other than the one on the source store
+// out as targets, if the cluster sets the zone label, so that moving the peer
+// keeps the zone placement of the replica checker.
+func newRegionZoneFilter(scope string, cluster opt.Cluster, region *core.RegionInfo, sourceID uint64) filter.Filter {
+	excluded := make(map[uint64]struct{})
+	if c, ok := cluster.(zoneCluster); ok && c.GetZoneLabel() != "" {
+		label := c.GetZoneLabel()
+		zones := make(map[string]struct{})
+		for _, s := range cluster.GetRegionStores(region) {
+			if s.GetID() != sourceID {
+				zones[s.GetLabelValue(label)] = struct{}{}
+			}
+		}
+		for _, s := range cluster.GetStores() {
+			if _, ok := zones[s.GetLabelValue(label)]; ok {
+				excluded[s.GetID()] = struct{}{}
+			}
+		}
+	}
+	return filter.NewExcludedFilter(scope, nil, excluded)
+}
+
+// newLeaderZoneFilter creates a filter that filters the stores out of the
+// preferred leader zone out as targets of leaders, if the cluster sets one,
+// so that the leaders kept in the zone by the replica checker stay there.
+func newLeaderZoneFilter(scope string, cluster opt.Cluster) filter.Filter {
+	excluded := make(map[uint64]struct{})
+	if c, ok := cluster.(zoneCluster); ok && c.GetZoneLabel() != "" && c.GetPreferredLeaderZone() != "" {
+		for _, s := range cluster.GetStores() {
+			if s.GetLabelValue(c.GetZoneLabel()) != c.GetPreferredLeaderZone() {
+				excluded[s.GetID()] = struct{}{}
+			}
+		}
+	}
+	return filter.NewExcludedFilter(scope, nil, excluded)
+}
+
+// spareStoreCluster is implemented by clusters in which some of the stores are
+// kept idle as spares.
+type spareStoreCluster interface {
+	GetSpareStores() []uint64
+}
+
+// newSpareStoreFilter creates a filter that filters the spare stores out as
+// targets, if the cluster has spare stores.
+func newSpareStoreFilter(scope string, cluster opt.Cluster) filter.Filter {
+	spares := make(map[uint64]struct{})
+	if c, ok := cluster.(spareStoreCluster); ok {
+		for _, id := range c.GetSpareStores() {
+			spares[id] = struct{}{}
+		}
+	}
+	return filter.NewExcludedFilter(scope, nil, spares)
+}
+
+// readReplicaCluster is implemented by clusters which keep read replicas of
+// regions, which are learners on the read replica stores besides the replicas.
+type readReplicaCluster interface {
+	GetReadReplicaStores() []uint64
+}
+
+// isReadReplica checks if the peer of the region on the store is a read
+// replica.
+func isReadReplica(cluster opt.Cluster, region *core.RegionInfo, storeID uint64) bool {
+	c, ok := cluster.(readReplicaCluster)
+	if !ok || region.GetStoreLearner(storeID) == nil {
+		return false
+	}
+	for _, id := range c.GetReadReplicaStores() {
+		if id == storeID {
+			return true
+		}
+	}
+	return false
+}
+
+// isAbnormalReplicaCount checks if the number of peers of the region, excluding
+// the read replicas, is not the max replicas.
+func isAbnormalReplicaCount(cluster opt.Cluster, region *core.RegionInfo) bool {
+	return len(region.GetPeers())-countReadReplicas(cluster, region) != cluster.GetMaxReplicas()
+}
+
+// countReadReplicas returns the number of read replicas of the region.
+func countReadReplicas(cluster opt.Cluster, region *core.RegionInfo) int {
+	var count int
+	for _, p := range region.GetLearners() {
+		if isReadReplica(cluster, region, p.GetStoreId()) {
+			count++
+		}
+	}
+	return count
+}
+
+// healthRegion works like core.HealthRegion, except that the read replicas
+// are not treated as learners which make the region unhealthy.
+func healthRegion(cluster opt.Cluster) core.RegionOption {
+	return func(region *core.RegionInfo) bool {
+		return len(region.GetPendingPeers()) == 0 && !isRegionUnhealthyWithReadReplicas(cluster, region)
+	}
+}
+
+// healthRegionAllowPending works like core.HealthRegionAllowPending, except
+// that the read replicas are allowed.
+func healthRegionAllowPending(cluster opt.Cluster) core.RegionOption {
+	return func(region *core.RegionInfo) bool {
+		return !isRegionUnhealthyWithReadReplicas(cluster, region)
+	}
+}
+
+// isRegionUnhealthyWithReadReplicas works like isRegionUnhealthy, except that
+// the read replicas are allowed.
+func isRegionUnhealthyWithReadReplicas(cluster opt.Cluster, region *core.RegionInfo) bool {
+	return len(region.GetDownPeers()) != 0 || len(region.GetLearners()) != countReadReplicas(cluster, region)
+}
+
+// moveRegionToStore tries to move a region from the source store to the target
+// store by a move peer operator described by desc. Regions led and followed by
+// the source store are tried in turn, and a region is skipped if its replica
+// count is abnormal, it is not suitable, it already has a peer on the target
+// store, or moving it decreases its distinct score. suitable can be nil.
+func moveRegionToStore(cluster opt.Cluster, name, desc string, source, target *core.StoreInfo, suitable func(*core.RegionInfo) bool) *operator.Operator {
+	for i := 0; i < balanceRegionRetryLimit; i++ {
+		randRegions := []func(uint64, ...core.RegionOption) *core.RegionInfo{cluster.RandFollowerRegion, cluster.RandLeaderRegion}
+		if i%2 == 1 {
+			randRegions[0], randRegions[1] = randRegions[1], randRegions[0]
+		}
+		region := randRegions[0](source.GetID(), healthRegion(cluster))
+		if region == nil {
+			region = randRegions[1](source.GetID(), healthRegion(cluster))
+		}
+		if region == nil {
+			schedulerCounter.WithLabelValues(name, "no-region").Inc()
+			return nil
+		}
+		if isAbnormalReplicaCount(cluster, region) {
+			schedulerCounter.WithLabelValues(name, "abnormal-replica").Inc()
+			continue
+		}
+		if suitable != nil && !suitable(region) {
+			continue
+		}
+		if region.GetStorePeer(target.GetID()) != nil {
+			continue
+		}
+		scoreGuard := filter.NewDistinctScoreFilter(name, cluster.GetLocationLabels(), cluster.GetRegionStores(region), source)
+		zoneFilter := newRegionZoneFilter(name, cluster, region, source.GetID())
+		if filter.Target(cluster, target, []filter.Filter{scoreGuard, zoneFilter}) {
+			continue
+		}
+		newPeer, err := cluster.AllocPeer(target.GetID())
+		if err != nil {
+			schedulerCounter.WithLabelValues(name, "no-peer").Inc()
+			return nil
+		}
+		op, err := operator.CreateMovePeerOperator(desc, cluster, region, operator.OpBalance, source.GetID(), newPeer.GetStoreId(), newPeer.GetId())
+		if err != nil {
+			schedulerCounter.WithLabelValues(name, "create-operator-fail").Inc()
+			return nil
+		}
+		return op
+	}
+	return nil
+}
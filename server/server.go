@@ -606,17 +606,47 @@ func (s *Server) SetPDServerConfig(cfg config.PDServerConfig) error {
 
 // GetNamespaceConfig get the namespace config.
 func (s *Server) GetNamespaceConfig(name string) *config.NamespaceConfig {
-	if _, ok := s.scheduleOpt.GetNS(name); !ok {
+	n, ok := s.scheduleOpt.GetNS(name)
+	if !ok {
 		return &config.NamespaceConfig{}
 	}
 
 	cfg := &config.NamespaceConfig{
-		LeaderScheduleLimit:    s.scheduleOpt.GetLeaderScheduleLimit(name),
-		RegionScheduleLimit:    s.scheduleOpt.GetRegionScheduleLimit(name),
-		ReplicaScheduleLimit:   s.scheduleOpt.GetReplicaScheduleLimit(name),
-		HotRegionScheduleLimit: s.scheduleOpt.GetHotRegionScheduleLimit(name),
-		MergeScheduleLimit:     s.scheduleOpt.GetMergeScheduleLimit(name),
-		MaxReplicas:            uint64(s.scheduleOpt.GetMaxReplicas(name)),
+		LeaderScheduleLimit:         s.scheduleOpt.GetLeaderScheduleLimit(name),
+		RegionScheduleLimit:         s.scheduleOpt.GetRegionScheduleLimit(name),
+		ReplicaScheduleLimit:        s.scheduleOpt.GetReplicaScheduleLimit(name),
+		HotRegionScheduleLimit:      s.scheduleOpt.GetHotRegionScheduleLimit(name),
+		MergeScheduleLimit:          s.scheduleOpt.GetMergeScheduleLimit(name),
+		MaxReplicas:                 uint64(s.scheduleOpt.GetMaxReplicas(name)),
+		StoreLeaderWeights:          n.Load().StoreLeaderWeights,
+		MaxPendingPeerCount:         s.scheduleOpt.GetNamespaceMaxPendingPeerCount(name),
+		SamplingStrategy:            n.Load().SamplingStrategy,
+		SchedulePauseWindows:        n.Load().SchedulePauseWindows,
+		MaxConcurrentOperators:      s.scheduleOpt.GetMaxConcurrentOperators(name),
+		SpareStores:                 n.Load().SpareStores,
+		SnapshotSpeed:               s.scheduleOpt.GetSnapshotSpeed(name),
+		EnableStoreWeightAutoTuning: s.scheduleOpt.IsStoreWeightAutoTuningEnabled(name),
+		PlacementRules:              n.Load().PlacementRules,
+		MaxOperatorSteps:            s.scheduleOpt.GetMaxOperatorSteps(name),
+		ThermalSizeWeight:           s.scheduleOpt.GetThermalSizeWeight(name),
+		ThermalFlowWeight:           s.scheduleOpt.GetThermalFlowWeight(name),
+		RegionLabels:                n.Load().RegionLabels,
+		StoreReservedCapacities:     n.Load().StoreReservedCapacities,
+		AntiAffinityGroups:          n.Load().AntiAffinityGroups,
+		StoreMaintenanceWindows:     n.Load().StoreMaintenanceWindows,
+		MaxMigrationBytesPerSec:     s.scheduleOpt.GetMaxMigrationBytesPerSec(name),
+		ReadReplicaCount:            s.scheduleOpt.GetReadReplicaCount(name),
+		ReadReplicaStores:           n.Load().ReadReplicaStores,
+		EnableAdaptiveScheduleLimit: s.scheduleOpt.IsAdaptiveScheduleLimitEnabled(name),
+		MinRegionScheduleLimit:      s.scheduleOpt.GetMinRegionScheduleLimit(name),
+		MaxRegionScheduleLimit:      s.scheduleOpt.GetMaxRegionScheduleLimit(name),
+		JoinThrottleTicks:           s.scheduleOpt.GetJoinThrottleTicks(name),
+		MaxRegionsPerStore:          s.scheduleOpt.GetMaxRegionsPerStore(name),
+		RegionMaxSize:               s.scheduleOpt.GetRegionMaxSize(name),
+		RegionMaxKeys:               s.scheduleOpt.GetRegionMaxKeys(name),
+		ZoneLabel:                   s.scheduleOpt.GetZoneLabel(name),
+		PreferredLeaderZone:         s.scheduleOpt.GetPreferredLeaderZone(name),
+		MergeDirection:              s.scheduleOpt.GetMergeDirectionPreference(name),
 	}
 
 	return cfg
@@ -631,6 +661,9 @@ func (s *Server) GetNamespaceConfigWithAdjust(name string) *config.NamespaceConf
 
 // SetNamespaceConfig sets the namespace config.
 func (s *Server) SetNamespaceConfig(name string, cfg config.NamespaceConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
 	if n, ok := s.scheduleOpt.GetNS(name); ok {
 		old := n.Load()
 		n.Store(&cfg)
@@ -33,7 +33,9 @@ const (
 	EmptyRegion
 )
 
-const nonIsolation = "none"
+// NonIsolation is the isolation level of regions which are not isolated at any
+// location label.
+const NonIsolation = "none"
 
 // RegionStatistics is used to record the status of regions.
 type RegionStatistics struct {
@@ -181,7 +183,7 @@ func NewLabelStatistics() *LabelStatistics {
 // Observe records the current label status.
 func (l *LabelStatistics) Observe(region *core.RegionInfo, stores []*core.StoreInfo, labels []string) {
 	regionID := region.GetID()
-	regionIsolation := getRegionLabelIsolation(stores, labels)
+	regionIsolation := GetRegionLabelIsolation(stores, labels)
 	if label, ok := l.regionLabelStats[regionID]; ok {
 		if label == regionIsolation {
 			return
@@ -213,24 +215,26 @@ func (l *LabelStatistics) ClearDefunctRegion(regionID uint64, labels []string) {
 }
 
 func (l *LabelStatistics) counterInc(label string) {
-	if label == nonIsolation {
-		l.labelCounter[nonIsolation]++
+	if label == NonIsolation {
+		l.labelCounter[NonIsolation]++
 	} else {
 		l.labelCounter[label]++
 	}
 }
 
 func (l *LabelStatistics) counterDec(label string) {
-	if label == nonIsolation {
-		l.labelCounter[nonIsolation]--
+	if label == NonIsolation {
+		l.labelCounter[NonIsolation]--
 	} else {
 		l.labelCounter[label]--
 	}
 }
 
-func getRegionLabelIsolation(stores []*core.StoreInfo, labels []string) string {
+// GetRegionLabelIsolation returns the outermost location label at which the
+// stores of a region are isolated from each other.
+func GetRegionLabelIsolation(stores []*core.StoreInfo, labels []string) string {
 	if len(stores) == 0 || len(labels) == 0 {
-		return nonIsolation
+		return NonIsolation
 	}
 	queueStores := [][]*core.StoreInfo{stores}
 	for level, label := range labels {
@@ -246,7 +250,7 @@ func getRegionLabelIsolation(stores []*core.StoreInfo, labels []string) string {
 			return labels[level]
 		}
 	}
-	return nonIsolation
+	return NonIsolation
 }
 
 func notIsolatedStoresWithLabel(stores []*core.StoreInfo, label string) [][]*core.StoreInfo {
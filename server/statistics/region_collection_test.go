@@ -160,7 +160,7 @@ func (t *testRegionStatisticsSuite) TestRegionLabelIsolationLevel(c *C) {
 			stores = append(stores, s)
 		}
 		region := core.NewRegionInfo(&metapb.Region{Id: uint64(regionID)}, nil)
-		label := getRegionLabelIsolation(stores, locationLabels)
+		label := GetRegionLabelIsolation(stores, locationLabels)
 		labelLevelStats.Observe(region, stores, locationLabels)
 		c.Assert(label, Equals, res)
 		regionID++
@@ -173,10 +173,10 @@ func (t *testRegionStatisticsSuite) TestRegionLabelIsolationLevel(c *C) {
 		c.Assert(labelLevelStats.labelCounter[i], Equals, res)
 	}
 
-	label := getRegionLabelIsolation(nil, locationLabels)
-	c.Assert(label, Equals, nonIsolation)
-	label = getRegionLabelIsolation(nil, nil)
-	c.Assert(label, Equals, nonIsolation)
+	label := GetRegionLabelIsolation(nil, locationLabels)
+	c.Assert(label, Equals, NonIsolation)
+	label = GetRegionLabelIsolation(nil, nil)
+	c.Assert(label, Equals, NonIsolation)
 
 	regionID = 1
 	res = []string{"rack", "none", "zone", "rack", "none"}
@@ -14,6 +14,7 @@
 package table
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -146,7 +147,10 @@ func (c *tableNamespaceClassifier) GetRegionNamespace(regionInfo *core.RegionInf
 	c.RLock()
 	defer c.RUnlock()
 
-	isMeta, tableID := Key(regionInfo.GetStartKey()).MetaOrTable()
+	return c.getNamespaceLocked(Key(regionInfo.GetStartKey()).MetaOrTable())
+}
+
+func (c *tableNamespaceClassifier) getNamespaceLocked(isMeta bool, tableID int64) string {
 	if tableID == 0 && !isMeta {
 		return namespace.DefaultNamespace
 	}
@@ -160,6 +164,45 @@ func (c *tableNamespaceClassifier) GetRegionNamespace(regionInfo *core.RegionInf
 	return namespace.DefaultNamespace
 }
 
+// IsCrossBoundary returns true if the tables covered by the region belong to
+// more than one namespace. A region ending at the start key of a table does
+// not cover the table. If the region covers meta or non-table keys, only the
+// namespaces of its first and last keys are compared.
+func (c *tableNamespaceClassifier) IsCrossBoundary(regionInfo *core.RegionInfo) bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	startMeta, startID := Key(regionInfo.GetStartKey()).MetaOrTable()
+	endMeta, endID := false, int64(math.MaxInt64)
+	if endKey := regionInfo.GetEndKey(); len(endKey) > 0 {
+		endMeta, endID = Key(endKey).MetaOrTable()
+		if endID > 0 && bytes.Equal(endKey, EncodeBytes(GenerateTableKey(endID))) {
+			endID--
+		}
+	}
+	if startMeta || endMeta || startID == 0 || endID == 0 || startID >= endID {
+		return c.getNamespaceLocked(startMeta, startID) != c.getNamespaceLocked(endMeta, endID)
+	}
+
+	// The region covers the tables in [startID, endID], they belong to the
+	// same namespace only if all of them are in the namespace of the first
+	// one.
+	name := c.getNamespaceLocked(false, startID)
+	var count uint64
+	for n, ns := range c.nsInfo.namespaces {
+		for id := range ns.TableIDs {
+			if id < startID || id > endID {
+				continue
+			}
+			if n != name {
+				return true
+			}
+			count++
+		}
+	}
+	return name != namespace.DefaultNamespace && count != uint64(endID-startID)+1
+}
+
 func (c *tableNamespaceClassifier) AllowMerge(one *core.RegionInfo, other *core.RegionInfo) bool {
 	return Key(one.GetStartKey()).TableID() == Key(other.GetStartKey()).TableID()
 }
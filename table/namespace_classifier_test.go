@@ -135,6 +135,33 @@ func (s *testTableNamespaceSuite) TestTableNameSpaceGetRegionNamespace(c *C) {
 	}
 }
 
+func (s *testTableNamespaceSuite) TestTableNameSpaceIsCrossBoundary(c *C) {
+	tableKey := func(tableID int64) []byte { return EncodeBytes(GenerateTableKey(tableID)) }
+	rowKey := func(tableID, rowID int64) []byte { return EncodeBytes(GenerateRowKey(tableID, rowID)) }
+	testCases := []struct {
+		startKey, endKey []byte
+		cross            bool
+	}{
+		{tableKey(testTable1), tableKey(testTable2), false},
+		{rowKey(testTable1, 1), rowKey(testTable1, 9), false},
+		{rowKey(testTable1, 5), rowKey(testTable2, 3), true},
+		{tableKey(testTable3), tableKey(testTable3 + 2), false},
+		{tableKey(testTable2), tableKey(testTable3 + 1), true},
+		{tableKey(testTable3), nil, false},
+		{tableKey(testTable1), nil, true},
+		{nil, tableKey(testTable1), false},
+		{EncodeBytes([]byte("m\x80\x00\x00\x00\x00\x00\x00\x01")), tableKey(testTable1), true},
+	}
+	classifier := s.newClassifier(c)
+	for _, t := range testCases {
+		region := core.NewRegionInfo(&metapb.Region{
+			StartKey: t.startKey,
+			EndKey:   t.endKey,
+		}, &metapb.Peer{})
+		c.Assert(classifier.IsCrossBoundary(region), Equals, t.cross)
+	}
+}
+
 func (s *testTableNamespaceSuite) TestNamespaceOperation(c *C) {
 	memStorage := core.NewStorage(kv.NewMemoryKV())
 	classifier, err := NewTableNamespaceClassifier(memStorage, mockid.NewIDAllocator())
@@ -23,6 +23,8 @@ import (
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/pd/server"
 	"github.com/pingcap/pd/server/config"
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/namespace"
 	"github.com/pingcap/pd/tests"
 	"github.com/pingcap/pd/tests/pdctl"
 )
@@ -162,6 +164,27 @@ func (s *configTestSuite) TestConfig(c *C) {
 	c.Assert(json.Unmarshal(output, &labelPropertyCfg), IsNil)
 	c.Assert(labelPropertyCfg, DeepEquals, svr.GetLabelProperty())
 
+	// config set namespace-store-weight <name> <store_id> <leader_weight> <region_weight>
+	args1 = []string{"-u", pdAddr, "config", "set", "namespace-store-weight", namespace.DefaultNamespace, "1", "2", "3"}
+	_, _, err = pdctl.ExecuteCommandC(cmd, args1...)
+	c.Assert(err, IsNil)
+	weights := map[uint64]core.StoreWeight{1: {LeaderWeight: 2, RegionWeight: 3}}
+	c.Assert(svr.GetRaftCluster().GetNamespaceStoreWeights(namespace.DefaultNamespace), DeepEquals, weights)
+
+	// config show namespace-store-weights <name>
+	args1 = []string{"-u", pdAddr, "config", "show", "namespace-store-weights", namespace.DefaultNamespace}
+	_, output, err = pdctl.ExecuteCommandC(cmd, args1...)
+	c.Assert(err, IsNil)
+	shownWeights := make(map[uint64]core.StoreWeight)
+	c.Assert(json.Unmarshal(output, &shownWeights), IsNil)
+	c.Assert(shownWeights, DeepEquals, weights)
+
+	// config delete namespace-store-weight <name> <store_id>
+	args1 = []string{"-u", pdAddr, "config", "delete", "namespace-store-weight", namespace.DefaultNamespace, "1"}
+	_, _, err = pdctl.ExecuteCommandC(cmd, args1...)
+	c.Assert(err, IsNil)
+	c.Assert(svr.GetRaftCluster().GetNamespaceStoreWeights(namespace.DefaultNamespace), HasLen, 0)
+
 	// config set <option> <value>
 	args1 = []string{"-u", pdAddr, "config", "set", "leader-schedule-limit", "64"}
 	_, _, err = pdctl.ExecuteCommandC(cmd, args1...)
@@ -145,6 +145,13 @@ func (s *storeTestSuite) TestStore(c *C) {
 	_, ok = limits[2]
 	c.Assert(ok, IsFalse)
 
+	// store lease-unstable <store_id> <duration>
+	c.Assert(leaderServer.GetRaftCluster().IsStoreLeaseStable(1), IsTrue)
+	args = []string{"-u", pdAddr, "store", "lease-unstable", "1", "1h"}
+	_, _, err = pdctl.ExecuteCommandC(cmd, args...)
+	c.Assert(err, IsNil)
+	c.Assert(leaderServer.GetRaftCluster().IsStoreLeaseStable(1), IsFalse)
+
 	// store delete <store_id> command
 	c.Assert(storeInfo.Store.State, Equals, metapb.StoreState_Up)
 	args = []string{"-u", pdAddr, "store", "delete", "1"}
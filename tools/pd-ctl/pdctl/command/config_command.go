@@ -16,6 +16,7 @@ package command
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"path"
 	"strconv"
@@ -30,6 +31,7 @@ var (
 	namespacePrefix      = "pd/api/v1/config/namespace"
 	labelPropertyPrefix  = "pd/api/v1/config/label-property"
 	clusterVersionPrefix = "pd/api/v1/config/cluster-version"
+	storeWeightsPrefix   = "pd/api/v1/namespace/%s/store-weights"
 )
 
 // NewConfigCommand return a config subcommand of rootCmd
@@ -56,6 +58,17 @@ func NewShowConfigCommand() *cobra.Command {
 	sc.AddCommand(NewShowReplicationConfigCommand())
 	sc.AddCommand(NewShowLabelPropertyCommand())
 	sc.AddCommand(NewShowClusterVersionCommand())
+	sc.AddCommand(NewShowNamespaceStoreWeightsCommand())
+	return sc
+}
+
+// NewShowNamespaceStoreWeightsCommand returns a namespace store weights subcommand of show subcommand.
+func NewShowNamespaceStoreWeightsCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "namespace-store-weights <name>",
+		Short: "show the store weights of the namespace",
+		Run:   showNamespaceStoreWeightsCommandFunc,
+	}
 	return sc
 }
 
@@ -119,6 +132,17 @@ func NewSetConfigCommand() *cobra.Command {
 	sc.AddCommand(NewSetNamespaceConfigCommand())
 	sc.AddCommand(NewSetLabelPropertyCommand())
 	sc.AddCommand(NewSetClusterVersionCommand())
+	sc.AddCommand(NewSetNamespaceStoreWeightCommand())
+	return sc
+}
+
+// NewSetNamespaceStoreWeightCommand creates a namespace store weight subcommand of set subcommand.
+func NewSetNamespaceStoreWeightCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "namespace-store-weight <name> <store_id> <leader_weight> <region_weight>",
+		Short: "set the weights of the store in the namespace",
+		Run:   setNamespaceStoreWeightCommandFunc,
+	}
 	return sc
 }
 
@@ -155,11 +179,12 @@ func NewSetClusterVersionCommand() *cobra.Command {
 // NewDeleteConfigCommand a set subcommand of cfgCmd
 func NewDeleteConfigCommand() *cobra.Command {
 	sc := &cobra.Command{
-		Use:   "delete namespace|label-property",
+		Use:   "delete namespace|label-property|namespace-store-weight",
 		Short: "delete the config option",
 	}
 	sc.AddCommand(NewDeleteNamespaceConfigCommand())
 	sc.AddCommand(NewDeleteLabelPropertyConfigCommand())
+	sc.AddCommand(NewDeleteNamespaceStoreWeightCommand())
 	return sc
 }
 
@@ -183,6 +208,16 @@ func NewDeleteLabelPropertyConfigCommand() *cobra.Command {
 	return sc
 }
 
+// NewDeleteNamespaceStoreWeightCommand a namespace store weight subcommand of delete subcommand.
+func NewDeleteNamespaceStoreWeightCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "namespace-store-weight <name> <store_id>",
+		Short: "delete the weights of the store in the namespace",
+		Run:   deleteNamespaceStoreWeightCommandFunc,
+	}
+	return sc
+}
+
 func showConfigCommandFunc(cmd *cobra.Command, args []string) {
 	allR, err := doRequest(cmd, configPrefix, http.MethodGet)
 	if err != nil {
@@ -249,6 +284,19 @@ func showNamespaceConfigCommandFunc(cmd *cobra.Command, args []string) {
 	cmd.Println(r)
 }
 
+func showNamespaceStoreWeightsCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		cmd.Println(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, fmt.Sprintf(storeWeightsPrefix, args[0]), http.MethodGet)
+	if err != nil {
+		cmd.Printf("Failed to get store weights: %s\n", err)
+		return
+	}
+	cmd.Println(r)
+}
+
 func showClusterVersionCommandFunc(cmd *cobra.Command, args []string) {
 	r, err := doRequest(cmd, clusterVersionPrefix, http.MethodGet)
 	if err != nil {
@@ -367,3 +415,71 @@ func setClusterVersionCommandFunc(cmd *cobra.Command, args []string) {
 	}
 	postJSON(cmd, clusterVersionPrefix, input)
 }
+
+// updateNamespaceStoreWeights reads the store weights of the namespace, keyed
+// by store ID, updates them with the function and saves them back.
+func updateNamespaceStoreWeights(cmd *cobra.Command, name string, update func(weights map[string]interface{})) error {
+	prefix := fmt.Sprintf(storeWeightsPrefix, name)
+	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if err != nil {
+		return err
+	}
+	weights := make(map[string]interface{})
+	if err = json.Unmarshal([]byte(r), &weights); err != nil {
+		return err
+	}
+	update(weights)
+	reqData, err := json.Marshal(weights)
+	if err != nil {
+		return err
+	}
+	_, err = doRequest(cmd, prefix, http.MethodPost,
+		WithBody("application/json", bytes.NewBuffer(reqData)))
+	return err
+}
+
+func setNamespaceStoreWeightCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 4 {
+		cmd.Println(cmd.UsageString())
+		return
+	}
+	name, storeID := args[0], args[1]
+	if _, err := strconv.ParseUint(storeID, 10, 64); err != nil {
+		cmd.Println("store_id should be a number")
+		return
+	}
+	leader, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || leader < 0 {
+		cmd.Println("leader_weight should be a number that >= 0.")
+		return
+	}
+	region, err := strconv.ParseFloat(args[3], 64)
+	if err != nil || region < 0 {
+		cmd.Println("region_weight should be a number that >= 0")
+		return
+	}
+	err = updateNamespaceStoreWeights(cmd, name, func(weights map[string]interface{}) {
+		weights[storeID] = map[string]interface{}{"leader-weight": leader, "region-weight": region}
+	})
+	if err != nil {
+		cmd.Printf("Failed to set namespace %s store weight: %s\n", name, err)
+		return
+	}
+	cmd.Println("Success!")
+}
+
+func deleteNamespaceStoreWeightCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		cmd.Println(cmd.UsageString())
+		return
+	}
+	name, storeID := args[0], args[1]
+	err := updateNamespaceStoreWeights(cmd, name, func(weights map[string]interface{}) {
+		delete(weights, storeID)
+	})
+	if err != nil {
+		cmd.Printf("Failed to delete namespace %s store weight: %s\n", name, err)
+		return
+	}
+	cmd.Println("Success!")
+}
@@ -72,13 +72,22 @@ func NewAddSchedulerCommand() *cobra.Command {
 	c.AddCommand(NewShuffleLeaderSchedulerCommand())
 	c.AddCommand(NewShuffleRegionSchedulerCommand())
 	c.AddCommand(NewShuffleHotRegionSchedulerCommand())
+	c.AddCommand(NewShuffleNamespaceLeaderSchedulerCommand())
+	c.AddCommand(NewNamespaceAdjacentRegionSchedulerCommand())
 	c.AddCommand(NewScatterRangeSchedulerCommand())
 	c.AddCommand(NewBalanceLeaderSchedulerCommand())
 	c.AddCommand(NewBalanceRegionSchedulerCommand())
+	c.AddCommand(NewBalanceNamespaceFlowSchedulerCommand())
+	c.AddCommand(NewBalanceNamespaceThermalSchedulerCommand())
+	c.AddCommand(NewBalanceNamespaceDomainSchedulerCommand())
+	c.AddCommand(NewBalanceNamespaceLeaderRatioSchedulerCommand())
+	c.AddCommand(NewOptimizeNamespaceBalanceSchedulerCommand())
+	c.AddCommand(NewPopulateNamespaceStoreSchedulerCommand())
 	c.AddCommand(NewBalanceHotRegionSchedulerCommand())
 	c.AddCommand(NewRandomMergeSchedulerCommand())
 	c.AddCommand(NewBalanceAdjacentRegionSchedulerCommand())
 	c.AddCommand(NewLabelSchedulerCommand())
+	c.AddCommand(NewSchedulerChainCommand())
 	return c
 }
 
@@ -150,6 +159,26 @@ func NewShuffleHotRegionSchedulerCommand() *cobra.Command {
 	return c
 }
 
+// NewShuffleNamespaceLeaderSchedulerCommand returns a command to add a shuffle-namespace-leader-scheduler.
+func NewShuffleNamespaceLeaderSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "shuffle-namespace-leader-scheduler [limit]",
+		Short: "add a scheduler to shuffle leaders among stores within namespaces",
+		Run:   addSchedulerForShuffleHotRegionCommandFunc,
+	}
+	return c
+}
+
+// NewNamespaceAdjacentRegionSchedulerCommand returns a command to add a namespace-adjacent-region-scheduler.
+func NewNamespaceAdjacentRegionSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "namespace-adjacent-region-scheduler [limit]",
+		Short: "add a scheduler to separate leaders of adjacent hot regions within namespaces",
+		Run:   addSchedulerForShuffleHotRegionCommandFunc,
+	}
+	return c
+}
+
 func addSchedulerForShuffleHotRegionCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) > 1 {
 		cmd.Println(cmd.UsageString())
@@ -180,6 +209,100 @@ func NewBalanceLeaderSchedulerCommand() *cobra.Command {
 	return c
 }
 
+// NewBalanceNamespaceFlowSchedulerCommand returns a command to add a balance-namespace-flow-scheduler.
+func NewBalanceNamespaceFlowSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "balance-namespace-flow-scheduler",
+		Short: "add a scheduler to balance region flow between stores within namespaces",
+		Run:   addSchedulerCommandFunc,
+	}
+	return c
+}
+
+// NewBalanceNamespaceThermalSchedulerCommand returns a command to add a balance-namespace-thermal-scheduler.
+func NewBalanceNamespaceThermalSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "balance-namespace-thermal-scheduler",
+		Short: "add a scheduler to balance region size and flow between stores within namespaces",
+		Run:   addSchedulerCommandFunc,
+	}
+	return c
+}
+
+// NewBalanceNamespaceDomainSchedulerCommand returns a command to add a balance-namespace-domain-scheduler.
+func NewBalanceNamespaceDomainSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "balance-namespace-domain-scheduler",
+		Short: "add a scheduler to balance region size between failure domains within namespaces",
+		Run:   addSchedulerCommandFunc,
+	}
+	return c
+}
+
+// NewBalanceNamespaceLeaderRatioSchedulerCommand returns a command to add a balance-namespace-leader-ratio-scheduler.
+func NewBalanceNamespaceLeaderRatioSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "balance-namespace-leader-ratio-scheduler",
+		Short: "add a scheduler to balance the leader-to-peer ratio of stores within namespaces",
+		Run:   addSchedulerCommandFunc,
+	}
+	return c
+}
+
+// NewOptimizeNamespaceBalanceSchedulerCommand returns a command to add an optimize-namespace-balance-scheduler.
+func NewOptimizeNamespaceBalanceSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "optimize-namespace-balance-scheduler [max-load|load-variance]",
+		Short: "add a scheduler to move the peers which balance the region sizes of stores within namespaces the most",
+		Run:   addSchedulerForOptimizeNamespaceBalanceCommandFunc,
+	}
+	return c
+}
+
+func addSchedulerForOptimizeNamespaceBalanceCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) > 1 {
+		cmd.Println(cmd.UsageString())
+		return
+	}
+	input := make(map[string]interface{})
+	input["name"] = cmd.Name()
+	if len(args) == 1 {
+		input["objective"] = args[0]
+	}
+	postJSON(cmd, schedulersPrefix, input)
+}
+
+// NewSchedulerChainCommand returns a command to add a scheduler chain.
+func NewSchedulerChainCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "scheduler-chain <scheduler_type> [<scheduler_type>...]",
+		Short: "add a scheduler chain which tries the schedulers in order within each namespace",
+		Run:   addSchedulerChainCommandFunc,
+	}
+	return c
+}
+
+func addSchedulerChainCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		cmd.Println(cmd.UsageString())
+		return
+	}
+	input := make(map[string]interface{})
+	input["name"] = cmd.Name()
+	input["scheduler_types"] = args
+	postJSON(cmd, schedulersPrefix, input)
+}
+
+// NewPopulateNamespaceStoreSchedulerCommand returns a command to add a populate-namespace-store-scheduler.
+func NewPopulateNamespaceStoreSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "populate-namespace-store-scheduler",
+		Short: "add a scheduler to move regions onto the stores just joined namespaces",
+		Run:   addSchedulerCommandFunc,
+	}
+	return c
+}
+
 // NewBalanceRegionSchedulerCommand returns a command to add a balance-region-scheduler.
 func NewBalanceRegionSchedulerCommand() *cobra.Command {
 	c := &cobra.Command{
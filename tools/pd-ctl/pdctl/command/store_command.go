@@ -39,6 +39,7 @@ func NewStoreCommand() *cobra.Command {
 	s.AddCommand(NewLabelStoreCommand())
 	s.AddCommand(NewSetStoreWeightCommand())
 	s.AddCommand(NewStoreLimitCommand())
+	s.AddCommand(NewStoreLeaseUnstableCommand())
 	s.AddCommand(NewRemoveTombStoneCommand())
 	s.Flags().String("jq", "", "jq query")
 	return s
@@ -93,6 +94,15 @@ func NewStoreLimitCommand() *cobra.Command {
 	}
 }
 
+// NewStoreLeaseUnstableCommand returns a lease-unstable subcommand of storeCmd.
+func NewStoreLeaseUnstableCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "lease-unstable <store_id> <duration>",
+		Short: "mark a store's leader lease as unstable for the duration, e.g. 30s",
+		Run:   storeLeaseUnstableCommandFunc,
+	}
+}
+
 // NewStoresCommand returns a store subcommand of rootCmd
 func NewStoresCommand() *cobra.Command {
 	s := &cobra.Command{
@@ -295,6 +305,17 @@ func setStoreWeightCommandFunc(cmd *cobra.Command, args []string) {
 	})
 }
 
+func storeLeaseUnstableCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		cmd.Usage()
+		return
+	}
+	prefix := fmt.Sprintf(path.Join(storePrefix, "lease-unstable"), args[0])
+	postJSON(cmd, prefix, map[string]interface{}{
+		"duration": args[1],
+	})
+}
+
 func storeLimitCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) == 0 {
 		showAllLimitCommandFunc(cmd, args)